@@ -0,0 +1,331 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gc
+
+import (
+	"cmd/compile/internal/syntax"
+	"cmd/compile/internal/types"
+	"cmd/internal/obj"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// embedCfg is the configuration read from -embedcfg, written by the
+// go command: the files matched by each //go:embed pattern of the
+// package, and where to find each file on disk.
+var embedCfg struct {
+	Patterns map[string][]string
+	Files    map[string]string
+}
+
+func readEmbedCfg(file string) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		log.Fatalf("-embedcfg: %v", err)
+	}
+	if err := json.Unmarshal(data, &embedCfg); err != nil {
+		log.Fatalf("%s: %v", file, err)
+	}
+	if embedCfg.Patterns == nil {
+		log.Fatalf("%s: invalid embedcfg: missing Patterns", file)
+	}
+	if embedCfg.Files == nil {
+		log.Fatalf("%s: invalid embedcfg: missing Files", file)
+	}
+}
+
+// pragmaEmbed records a //go:embed directive.
+type pragmaEmbed struct {
+	pos      syntax.Pos
+	patterns []string
+}
+
+// parseGoEmbed parses the text following "//go:embed" to extract the
+// patterns. Patterns are separated by spaces and may be written as Go
+// string literals to include spaces.
+func parseGoEmbed(args string) ([]string, error) {
+	var list []string
+	for args = strings.TrimSpace(args); args != ""; args = strings.TrimSpace(args) {
+		var pattern string
+	Switch:
+		switch args[0] {
+		default:
+			i := len(args)
+			for j, c := range args {
+				if unicode.IsSpace(c) {
+					i = j
+					break
+				}
+			}
+			pattern = args[:i]
+			args = args[i:]
+
+		case '`':
+			i := strings.Index(args[1:], "`")
+			if i < 0 {
+				return nil, fmt.Errorf("invalid quoted string in //go:embed: %s", args)
+			}
+			pattern = args[1 : 1+i]
+			args = args[1+i+1:]
+
+		case '"':
+			i := 1
+			for ; i < len(args); i++ {
+				if args[i] == '\\' {
+					i++
+					continue
+				}
+				if args[i] == '"' {
+					q, err := strconv.Unquote(args[:i+1])
+					if err != nil {
+						return nil, fmt.Errorf("invalid quoted string in //go:embed: %s", args[:i+1])
+					}
+					pattern = q
+					args = args[i+1:]
+					break Switch
+				}
+			}
+			if i >= len(args) {
+				return nil, fmt.Errorf("invalid quoted string in //go:embed: %s", args)
+			}
+		}
+		if args != "" {
+			r, _ := utf8.DecodeRuneInString(args)
+			if !unicode.IsSpace(r) {
+				return nil, fmt.Errorf("invalid quoted string in //go:embed: %s", args)
+			}
+		}
+		list = append(list, pattern)
+	}
+	return list, nil
+}
+
+// matchEmbeds assigns each //go:embed directive in the file to the
+// package-level variable declaration that follows it. Directives
+// followed by anything else, including declarations inside function
+// bodies, are reported as misplaced.
+func (p *noder) matchEmbeds() map[*syntax.VarDecl][]pragmaEmbed {
+	if len(p.embeds) == 0 {
+		return nil
+	}
+	m := make(map[*syntax.VarDecl][]pragmaEmbed)
+	embeds := p.embeds
+	var end syntax.Pos // end of the previous declaration
+	for _, decl := range p.file.DeclList {
+		for len(embeds) > 0 && posBefore(embeds[0].pos, decl.Pos()) {
+			e := embeds[0]
+			embeds = embeds[1:]
+			v, ok := decl.(*syntax.VarDecl)
+			if !ok || posBefore(e.pos, end) {
+				p.yyerrorpos(e.pos, "misplaced //go:embed directive")
+				continue
+			}
+			m[v] = append(m[v], e)
+		}
+		end = decl.End()
+	}
+	for _, e := range embeds {
+		p.yyerrorpos(e.pos, "misplaced //go:embed directive")
+	}
+	return m
+}
+
+// posBefore reports whether x comes before y in the same file.
+func posBefore(x, y syntax.Pos) bool {
+	return x.Line() < y.Line() || x.Line() == y.Line() && x.Col() < y.Col()
+}
+
+const (
+	embedUnknown = iota
+	embedBytes
+	embedString
+	embedFiles
+)
+
+// An embedVar is a variable initialized from //go:embed directives.
+type embedVar struct {
+	v     *Node
+	files []string // files to embed, in pattern order
+}
+
+var embedlist []embedVar
+
+// varEmbed records the files to embed in the variable declared by
+// names, typ and exprs, as requested by the directives embeds.
+func (p *noder) varEmbed(names []*Node, typ *Node, exprs []*Node, embeds []pragmaEmbed) {
+	pos := embeds[0].pos
+	if !p.importedEmbed {
+		p.yyerrorpos(pos, "//go:embed only allowed in Go files that import \"embed\"")
+		return
+	}
+	if embedCfg.Patterns == nil {
+		p.yyerrorpos(pos, "invalid //go:embed: build system did not supply embed configuration")
+		return
+	}
+	if len(names) > 1 {
+		p.yyerrorpos(pos, "invalid //go:embed: cannot apply to multiple vars")
+		return
+	}
+	if len(exprs) > 0 {
+		p.yyerrorpos(pos, "invalid //go:embed: cannot apply to var with initializer")
+		return
+	}
+	if typ == nil {
+		p.yyerrorpos(pos, "invalid //go:embed: cannot apply to var without type")
+		return
+	}
+	have := make(map[string]bool)
+	var list []string
+	for _, e := range embeds {
+		for _, pattern := range e.patterns {
+			files, ok := embedCfg.Patterns[pattern]
+			if !ok {
+				p.yyerrorpos(e.pos, "invalid //go:embed: build system did not map pattern: %s", pattern)
+			}
+			for _, file := range files {
+				if embedCfg.Files[file] == "" {
+					p.yyerrorpos(e.pos, "invalid //go:embed: build system did not map file: %s", file)
+					continue
+				}
+				if !have[file] {
+					have[file] = true
+					list = append(list, file)
+				}
+			}
+		}
+	}
+	embedlist = append(embedlist, embedVar{names[0], list})
+}
+
+// embedKind determines the kind of embedding variable of type typ.
+func embedKind(typ *types.Type) int {
+	if typ.Sym != nil && typ.Sym.Name == "FS" && (typ.Sym.Pkg.Path == "embed" || typ.Sym.Pkg == localpkg && myimportpath == "embed") {
+		return embedFiles
+	}
+	if typ == types.Types[TSTRING] {
+		return embedString
+	}
+	if typ.Sym == nil && typ.IsSlice() && typ.Elem() == types.Bytetype {
+		return embedBytes
+	}
+	return embedUnknown
+}
+
+// embedFileNameSplit splits an embedded file name into its directory
+// and final element. Directory names end in a slash.
+func embedFileNameSplit(name string) (dir, elem string, isDir bool) {
+	if name[len(name)-1] == '/' {
+		isDir = true
+		name = name[:len(name)-1]
+	}
+	i := strings.LastIndex(name, "/")
+	if i < 0 {
+		return ".", name, isDir
+	}
+	return name[:i], name[i+1:], isDir
+}
+
+// embedFileLess implements the sort order for the files of an
+// embed.FS: by directory, then by name within the directory, so that
+// the entries of each directory are adjacent.
+// It must match the search in ../../../../embed/embed.go.
+func embedFileLess(x, y string) bool {
+	xdir, xelem, _ := embedFileNameSplit(x)
+	ydir, yelem, _ := embedFileNameSplit(y)
+	return xdir < ydir || xdir == ydir && xelem < yelem
+}
+
+// dumpembeds emits the data of the //go:embed variables.
+func dumpembeds() {
+	for _, e := range embedlist {
+		initEmbed(e)
+	}
+}
+
+// initEmbed emits the initial data for e.v, a string, []byte, or
+// embed.FS variable holding e.files.
+func initEmbed(e embedVar) {
+	v, files := e.v, e.files
+	switch kind := embedKind(v.Type); kind {
+	case embedUnknown:
+		yyerrorl(v.Pos, "//go:embed cannot apply to var of type %v", v.Type)
+
+	case embedString, embedBytes:
+		if len(files) > 1 {
+			yyerrorl(v.Pos, "invalid //go:embed: multiple files for type %v", v.Type)
+			return
+		}
+		if len(files) == 0 {
+			// The go command reports patterns without matches.
+			return
+		}
+		data, err := ioutil.ReadFile(embedCfg.Files[files[0]])
+		if err != nil {
+			yyerrorl(v.Pos, "embed %s: %v", files[0], err)
+			return
+		}
+		if kind == embedBytes {
+			slicebytes(v, string(data), len(data))
+			return
+		}
+		sym := v.Sym.Linksym()
+		off := dsymptr(sym, 0, stringsym(v.Pos, string(data)), 0)
+		duintptr(sym, off, uint64(len(data)))
+
+	case embedFiles:
+		// The data of an embed.FS is a pointer to a []file, where
+		// package embed declares
+		//	type file struct {
+		//		name string
+		//		data string
+		//	}
+		// The slice header and its elements are laid out in one symbol.
+		// The list includes the parent directories of the files, so
+		// that ReadDir can list them, and is sorted by embedFileLess.
+		have := make(map[string]bool)
+		for _, file := range files {
+			have[file] = true
+		}
+		for _, file := range e.files {
+			for dir := path.Dir(file); dir != "." && !have[dir+"/"]; dir = path.Dir(dir) {
+				have[dir+"/"] = true
+				files = append(files, dir+"/")
+			}
+		}
+		sort.Slice(files, func(i, j int) bool {
+			return embedFileLess(files[i], files[j])
+		})
+		slicedata := Ctxt.Lookup(`"".` + v.Sym.Name + `.files`)
+		off := dsymptr(slicedata, 0, slicedata, 3*Widthptr)
+		off = duintptr(slicedata, off, uint64(len(files)))
+		off = duintptr(slicedata, off, uint64(len(files)))
+		for _, file := range files {
+			off = dsymptr(slicedata, off, stringsym(v.Pos, file), 0)
+			off = duintptr(slicedata, off, uint64(len(file)))
+			if strings.HasSuffix(file, "/") {
+				// Directories have no data.
+				off = duintptr(slicedata, off, 0)
+				off = duintptr(slicedata, off, 0)
+				continue
+			}
+			data, err := ioutil.ReadFile(embedCfg.Files[file])
+			if err != nil {
+				yyerrorl(v.Pos, "embed %s: %v", file, err)
+			}
+			off = dsymptr(slicedata, off, stringsym(v.Pos, string(data)), 0)
+			off = duintptr(slicedata, off, uint64(len(data)))
+		}
+		ggloblsym(slicedata, int32(off), obj.RODATA|obj.LOCAL)
+		dsymptr(v.Sym.Linksym(), 0, slicedata, 0)
+	}
+}
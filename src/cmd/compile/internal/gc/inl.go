@@ -46,6 +46,8 @@ const (
 
 	inlineBigFunctionNodes   = 5000 // Functions with this many nodes are considered "big".
 	inlineBigFunctionMaxCost = 20   // Max cost of inlinee when inlining into a "big" function.
+
+	inlineHotMaxBudget = 2000 // Budget of the callees of hot call edges, with -pgoprofile; see pgo.go.
 )
 
 // Get the function's package. For ordinary functions it's on the ->sym, but for imported methods
@@ -200,8 +202,12 @@ func caninl(fn *Node) {
 	// locals, and we use this map to produce a pruned Inline.Dcl
 	// list. See issue 25249 for more context.
 
+	budget := int32(inlineMaxBudget)
+	if pgoHotCallee(n) {
+		budget = inlineHotMaxBudget
+	}
 	visitor := hairyVisitor{
-		budget:        inlineMaxBudget,
+		budget:        budget,
 		extraCallCost: cc,
 		usedLocals:    make(map[*Node]bool),
 	}
@@ -210,12 +216,12 @@ func caninl(fn *Node) {
 		return
 	}
 	if visitor.budget < 0 {
-		reason = fmt.Sprintf("function too complex: cost %d exceeds budget %d", inlineMaxBudget-visitor.budget, inlineMaxBudget)
+		reason = fmt.Sprintf("function too complex: cost %d exceeds budget %d", budget-visitor.budget, budget)
 		return
 	}
 
 	n.Func.Inl = &Inline{
-		Cost: inlineMaxBudget - visitor.budget,
+		Cost: budget - visitor.budget,
 		Dcl:  inlcopylist(pruneUnusedAutos(n.Name.Defn.Func.Dcl, &visitor)),
 		Body: inlcopylist(fn.Nbody.Slice()),
 	}
@@ -230,7 +236,7 @@ func caninl(fn *Node) {
 		fmt.Printf("%v: can inline %v\n", fn.Line(), n)
 	}
 	if logopt.Enabled() {
-		logopt.LogOpt(fn.Pos, "canInlineFunction", "inline", fn.funcname(), fmt.Sprintf("cost: %d", inlineMaxBudget-visitor.budget))
+		logopt.LogOpt(fn.Pos, "canInlineFunction", "inline", fn.funcname(), fmt.Sprintf("cost: %d", budget-visitor.budget))
 	}
 }
 
@@ -326,12 +332,12 @@ func (v *hairyVisitor) visit(n *Node) bool {
 		}
 
 		if fn := n.Left.Func; fn != nil && fn.Inl != nil {
-			v.budget -= fn.Inl.Cost
+			v.budget -= v.inlCost(fn.Inl)
 			break
 		}
 		if n.Left.isMethodExpression() {
 			if d := asNode(n.Left.Sym.Def); d != nil && d.Func.Inl != nil {
-				v.budget -= d.Func.Inl.Cost
+				v.budget -= v.inlCost(d.Func.Inl)
 				break
 			}
 		}
@@ -362,7 +368,7 @@ func (v *hairyVisitor) visit(n *Node) bool {
 			}
 		}
 		if inlfn := asNode(t.FuncType().Nname).Func; inlfn.Inl != nil {
-			v.budget -= inlfn.Inl.Cost
+			v.budget -= v.inlCost(inlfn.Inl)
 			break
 		}
 		// Call cost for non-leaf inlining.
@@ -430,6 +436,16 @@ func (v *hairyVisitor) visit(n *Node) bool {
 		v.visitList(n.Ninit) || v.visitList(n.Nbody)
 }
 
+// inlCost returns the cost of a call to a function with inlinable body inl.
+// Bodies over the normal budget, which only the callees of hot call edges
+// have, are inlined only at hot call sites, so they cost a call.
+func (v *hairyVisitor) inlCost(inl *Inline) int32 {
+	if inl.Cost > inlineMaxBudget {
+		return v.extraCallCost
+	}
+	return inl.Cost
+}
+
 // Inlcopy and inlcopylist recursively copy the body of a function.
 // Any name-like node of non-local class is marked for re-export by adding it to
 // the exportlist.
@@ -708,6 +724,9 @@ func inlnode(n *Node, maxCost int32) *Node {
 		}
 
 		n = mkinlcall(n, asNode(n.Left.Type.FuncType().Nname), maxCost)
+
+	case OCALLINTER:
+		n = pgoDevirtualize(n, maxCost)
 	}
 
 	lineno = lno
@@ -832,7 +851,7 @@ func mkinlcall(n, fn *Node, maxCost int32) *Node {
 		// No inlinable body.
 		return n
 	}
-	if fn.Func.Inl.Cost > maxCost {
+	if fn.Func.Inl.Cost > maxCost && !pgoHotCall(n, fn) {
 		// The inlined function body is too big. Typically we use this check to restrict
 		// inlining into very big functions.  See issue 26546 and 17566.
 		if logopt.Enabled() {
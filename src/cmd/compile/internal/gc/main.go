@@ -232,6 +232,7 @@ func Main(archInit func(*Arch)) {
 	objabi.Flagcount("h", "halt on error", &Debug['h'])
 	objabi.Flagfn1("importmap", "add `definition` of the form source=actual to import map", addImportMap)
 	objabi.Flagfn1("importcfg", "read import configuration from `file`", readImportCfg)
+	objabi.Flagfn1("embedcfg", "read go:embed configuration from `file`", readEmbedCfg)
 	flag.StringVar(&flag_installsuffix, "installsuffix", "", "set pkg directory `suffix`")
 	objabi.Flagcount("j", "debug runtime-initialized variables", &Debug['j'])
 	objabi.Flagcount("l", "disable inlining", &Debug['l'])
@@ -246,6 +247,8 @@ func Main(archInit func(*Arch)) {
 	flag.StringVar(&outfile, "o", "", "write output to `file`")
 	flag.StringVar(&myimportpath, "p", "", "set expected package import `path`")
 	flag.BoolVar(&writearchive, "pack", false, "write to file.a instead of file.o")
+	var pgoProfile string
+	flag.StringVar(&pgoProfile, "pgoprofile", "", "read CPU profile from `file` for profile-guided optimization")
 	objabi.Flagcount("r", "debug generated wrappers", &Debug['r'])
 	if sys.RaceDetectorSupported(objabi.GOOS, objabi.GOARCH) {
 		flag.BoolVar(&flag_race, "race", false, "enable race detector")
@@ -323,6 +326,10 @@ func Main(archInit func(*Arch)) {
 		readSymABIs(symabisPath, myimportpath)
 	}
 
+	if pgoProfile != "" {
+		readPGOProfile(pgoProfile)
+	}
+
 	thearch.LinkArch.Init(Ctxt)
 
 	if outfile == "" {
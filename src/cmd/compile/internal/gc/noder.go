@@ -130,11 +130,14 @@ type noder struct {
 		base *src.PosBase
 	}
 
-	file       *syntax.File
-	linknames  []linkname
-	pragcgobuf [][]string
-	err        chan syntax.Error
-	scope      ScopeID
+	file          *syntax.File
+	linknames     []linkname
+	pragcgobuf    [][]string
+	embeds        []pragmaEmbed
+	embedVars     map[*syntax.VarDecl][]pragmaEmbed
+	importedEmbed bool
+	err           chan syntax.Error
+	scope         ScopeID
 
 	// scopeVars is a stack tracking the number of variables declared in the
 	// current function at the moment each open scope was opened.
@@ -241,6 +244,7 @@ func (p *noder) node() {
 	p.setlineno(p.file.PkgName)
 	mkpackage(p.file.PkgName.Value)
 
+	p.embedVars = p.matchEmbeds()
 	xtop = append(xtop, p.decls(p.file.DeclList)...)
 
 	for _, n := range p.linknames {
@@ -324,6 +328,9 @@ func (p *noder) importDecl(imp *syntax.ImportDecl) {
 	}
 
 	ipkg.Direct = true
+	if ipkg.Path == "embed" {
+		p.importedEmbed = true
+	}
 
 	var my *types.Sym
 	if imp.LocalPkgName != nil {
@@ -363,6 +370,10 @@ func (p *noder) varDecl(decl *syntax.VarDecl) []*Node {
 		exprs = p.exprList(decl.Values)
 	}
 
+	if embeds, ok := p.embedVars[decl]; ok {
+		p.varEmbed(names, typ, exprs, embeds)
+	}
+
 	p.setlineno(decl)
 	return variter(names, typ, exprs)
 }
@@ -1503,6 +1514,18 @@ func (p *noder) pragma(pos syntax.Pos, text string) syntax.Pragma {
 		}
 		p.linknames = append(p.linknames, linkname{pos, f[1], target})
 
+	case strings.HasPrefix(text, "go:embed "):
+		patterns, err := parseGoEmbed(text[len("go:embed "):])
+		if err != nil {
+			p.error(syntax.Error{Pos: pos, Msg: err.Error()})
+			break
+		}
+		if len(patterns) == 0 {
+			p.error(syntax.Error{Pos: pos, Msg: "usage: //go:embed pattern..."})
+			break
+		}
+		p.embeds = append(p.embeds, pragmaEmbed{pos, patterns})
+
 	case strings.HasPrefix(text, "go:cgo_import_dynamic "):
 		// This is permitted for general use because Solaris
 		// code relies on it in golang.org/x/sys/unix and others.
@@ -0,0 +1,360 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Profile-guided optimization.
+//
+// With -pgoprofile, the compiler reads a CPU profile in pprof format
+// and builds from its samples a weighted graph of the calls made by
+// the profiled program. The heaviest call edges, which together make
+// up pgoHotCDF percent of the total weight, are hot. The inliner then
+// gives the callees of hot edges a budget of inlineHotMaxBudget instead
+// of inlineMaxBudget, though it only inlines the ones that exceed the
+// normal budget at hot call sites; and a hot interface method call
+// whose profile is dominated by a concrete method T.M is devirtualized:
+//
+//	i.M(x)
+//
+// becomes
+//
+//	if t, ok := i.(T); ok {
+//		t.M(x) // may now be inlined
+//	} else {
+//		i.M(x)
+//	}
+//
+// Functions are identified by their symbol names, which are the names
+// recorded in the profile, and call sites by the calling function and
+// the line number of the call.
+//
+// Although the hot edges are chosen from the whole profile, the
+// compiler only uses those made by functions of the package being
+// compiled, and only raises the budget of its own functions. A package
+// none of whose functions appear in the profile is therefore compiled
+// exactly as it would be without one, which lets the go command leave
+// the profile out of the build of such packages.
+
+package gc
+
+import (
+	"cmd/compile/internal/types"
+	"cmd/internal/objabi"
+	"fmt"
+	"internal/profile"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// pgoHotCDF is the percentage of the total call edge weight
+// accounted for by the hot edges.
+const pgoHotCDF = 99
+
+// A pgoCallSite is a call made by a function at a source line.
+type pgoCallSite struct {
+	caller string
+	line   int64
+}
+
+// A pgoEdge is a call from a call site to a function.
+type pgoEdge struct {
+	pgoCallSite
+	callee string
+}
+
+var pgo struct {
+	hotEdges   map[pgoEdge]bool
+	hotCallees map[string]bool
+
+	// hotTargets lists the callees of the hot edges from each call
+	// site, hottest first.
+	hotTargets map[pgoCallSite][]string
+}
+
+// readPGOProfile reads the CPU profile in file and records its hot
+// call edges.
+func readPGOProfile(file string) {
+	f, err := os.Open(file)
+	if err != nil {
+		log.Fatalf("-pgoprofile: %v", err)
+	}
+	defer f.Close()
+	p, err := profile.Parse(f)
+	if err != nil {
+		log.Fatalf("%s: %v", file, err)
+	}
+
+	// Runtime/pprof CPU profiles have "samples" and "cpu" values;
+	// weigh by CPU time when it is there.
+	vi := -1
+	for i, st := range p.SampleType {
+		if st.Type == "cpu" || st.Type == "samples" && vi < 0 {
+			vi = i
+		}
+	}
+	if vi < 0 {
+		log.Fatalf("%s: not a CPU profile", file)
+	}
+
+	// Each sample's stack, flattened to include inlined frames, is
+	// a list of calls from each frame to the one before it.
+	weights := make(map[pgoEdge]int64)
+	var total int64
+	for _, s := range p.Sample {
+		w := s.Value[vi]
+		if w <= 0 {
+			continue
+		}
+		callee := ""
+		for _, loc := range s.Location {
+			if len(loc.Line) == 0 {
+				callee = ""
+				continue
+			}
+			for _, line := range loc.Line {
+				if line.Function == nil {
+					callee = ""
+					continue
+				}
+				if callee != "" {
+					weights[pgoEdge{pgoCallSite{line.Function.Name, line.Line}, callee}] += w
+					total += w
+				}
+				callee = line.Function.Name
+			}
+		}
+	}
+
+	edges := make([]pgoEdge, 0, len(weights))
+	for e := range weights {
+		edges = append(edges, e)
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		x, y := edges[i], edges[j]
+		if weights[x] != weights[y] {
+			return weights[x] > weights[y]
+		}
+		if x.caller != y.caller {
+			return x.caller < y.caller
+		}
+		if x.line != y.line {
+			return x.line < y.line
+		}
+		return x.callee < y.callee
+	})
+
+	local := objabi.PathToPrefix(myimportpath)
+	pgo.hotEdges = make(map[pgoEdge]bool)
+	pgo.hotCallees = make(map[string]bool)
+	pgo.hotTargets = make(map[pgoCallSite][]string)
+	var cum int64
+	for _, e := range edges {
+		if cum*100 >= total*pgoHotCDF {
+			break
+		}
+		cum += weights[e]
+		if pgoPkgPrefix(e.caller) == local {
+			pgo.hotEdges[e] = true
+			pgo.hotTargets[e.pgoCallSite] = append(pgo.hotTargets[e.pgoCallSite], e.callee)
+		}
+		if pgoPkgPrefix(e.callee) == local {
+			pgo.hotCallees[e.callee] = true
+		}
+	}
+}
+
+// pgoPkgPrefix returns the package part of the symbol name, which is
+// everything before the first dot following the last slash.
+func pgoPkgPrefix(name string) string {
+	i := strings.LastIndex(name, "/") + 1
+	if j := strings.Index(name[i:], "."); j >= 0 {
+		return name[:i+j]
+	}
+	return ""
+}
+
+// pgoSymName returns the name used in profiles for the symbol name.
+func pgoSymName(name string) string {
+	if strings.HasPrefix(name, `"".`) {
+		return objabi.PathToPrefix(myimportpath) + name[len(`""`):]
+	}
+	return name
+}
+
+// pgoFuncName returns the name used in profiles for the function fn.
+func pgoFuncName(fn *Node) string {
+	return pgoSymName(fn.Sym.Linksym().Name)
+}
+
+// pgoCallSiteOf returns the call site of the call n in Curfn,
+// attributing calls in inlined bodies to the inlined function.
+func pgoCallSiteOf(n *Node) pgoCallSite {
+	pos := Ctxt.InnermostPos(n.Pos)
+	caller := pgoFuncName(Curfn.Func.Nname)
+	if b := pos.Base(); b != nil && b.InliningIndex() >= 0 {
+		caller = pgoSymName(Ctxt.InlTree.InlinedFunction(b.InliningIndex()).Name)
+	}
+	return pgoCallSite{caller, int64(pos.RelLine())}
+}
+
+// pgoHotCallee reports whether fn is the callee of a hot call edge.
+func pgoHotCallee(fn *Node) bool {
+	if pgo.hotCallees == nil {
+		return false
+	}
+	return pgo.hotCallees[pgoFuncName(fn)]
+}
+
+// pgoHotCall reports whether the call n to fn is a hot call edge.
+func pgoHotCall(n, fn *Node) bool {
+	if pgo.hotEdges == nil {
+		return false
+	}
+	return pgo.hotEdges[pgoEdge{pgoCallSiteOf(n), pgoFuncName(fn)}]
+}
+
+// pgoDevirtualize rewrites the interface method call n, if it is hot
+// and its hottest callee is a method of a concrete type, into an
+// OINLCALL that tests for that type and calls the method directly.
+func pgoDevirtualize(n *Node, maxCost int32) *Node {
+	if pgo.hotTargets == nil || n.Left.Op != ODOTINTER {
+		return n
+	}
+	targets := pgo.hotTargets[pgoCallSiteOf(n)]
+	if len(targets) == 0 {
+		return n
+	}
+	sel := n.Left
+	iface := sel.Left.Type
+	var typ *types.Type
+	for _, target := range targets {
+		t := pgoMethodRecv(target, sel.Sym)
+		if t == nil || t.IsInterface() {
+			continue
+		}
+		var missing, have *types.Field
+		var ptr int
+		if implements(t, iface, &missing, &have, &ptr) {
+			typ = t
+			break
+		}
+	}
+	if typ == nil {
+		return n
+	}
+	if Debug['m'] != 0 {
+		fmt.Printf("%v: PGO devirtualizing %v to %v\n", n.Line(), sel, typ)
+	}
+
+	// Evaluate the receiver and arguments once, in order.
+	ninit := n.Ninit
+	tmp := func(x *Node) *Node {
+		t := temp(x.Type)
+		ninit.Append(nod(ODCL, t, nil))
+		ninit.Append(typecheck(nod(OAS, t, x), ctxStmt))
+		return t
+	}
+	recv := tmp(sel.Left)
+	var args []*Node
+	for _, arg := range n.List.Slice() {
+		args = append(args, tmp(arg))
+	}
+	concrete := temp(typ)
+	ok := temp(types.Types[TBOOL])
+	ninit.Append(nod(ODCL, concrete, nil))
+	ninit.Append(nod(ODCL, ok, nil))
+	as := nod(OAS2, nil, nil)
+	as.List.Set2(concrete, ok)
+	as.Rlist.Set1(nod(ODOTTYPE, recv, typenod(typ)))
+	ninit.Append(typecheck(as, ctxStmt))
+
+	var retvars []*Node
+	for _, f := range sel.Type.Results().FieldSlice() {
+		r := temp(f.Type)
+		ninit.Append(nod(ODCL, r, nil))
+		retvars = append(retvars, r)
+	}
+	call := func(x *Node) *Node {
+		c := nod(OCALL, nodSym(OXDOT, x, sel.Sym), nil)
+		c.List.Set(append([]*Node(nil), args...))
+		c.SetIsDDD(n.IsDDD())
+		switch len(retvars) {
+		case 0:
+			return typecheck(c, ctxStmt)
+		case 1:
+			return typecheck(nod(OAS, retvars[0], c), ctxStmt)
+		}
+		as := nod(OAS2, nil, nil)
+		as.List.Set(retvars)
+		as.Rlist.Set1(c)
+		return typecheck(as, ctxStmt)
+	}
+
+	nif := nod(OIF, ok, nil)
+	nif.Nbody.Set1(call(concrete))
+	nif.Rlist.Set1(call(recv))
+	nif = typecheck(nif, ctxStmt)
+
+	// Inline the direct call. The indirect call is left alone:
+	// it has the same call site and would be rewritten again.
+	inlnodelist(nif.Nbody, maxCost)
+	for _, n := range nif.Nbody.Slice() {
+		if n.Op == OINLCALL {
+			inlconv2stmt(n)
+		}
+	}
+
+	res := nod(OINLCALL, nil, nil)
+	res.Ninit.Set(ninit.Slice())
+	res.Nbody.Set1(nif)
+	res.Rlist.Set(retvars)
+	res.Type = n.Type
+	res.SetTypecheck(1)
+	return res
+}
+
+// pgoMethodRecv returns the receiver type of target, the profile name
+// of a method named by method, or nil if target is not such a method
+// or its type is unknown to this compilation.
+func pgoMethodRecv(target string, method *types.Sym) *types.Type {
+	name := strings.TrimSuffix(target, "."+method.Name)
+	if name == target {
+		return nil
+	}
+	// name is "pkg.T" or "pkg.(*T)".
+	ptr := false
+	var prefix, tname string
+	if strings.HasSuffix(name, ")") {
+		i := strings.LastIndex(name, ".(*")
+		if i < 0 {
+			return nil
+		}
+		prefix, tname, ptr = name[:i], name[i+len(".(*"):len(name)-1], true
+	} else {
+		i := strings.LastIndex(name, ".")
+		if i < 0 {
+			return nil
+		}
+		prefix, tname = name[:i], name[i+1:]
+	}
+
+	pkg := localpkg
+	if prefix != objabi.PathToPrefix(myimportpath) {
+		if pkg = types.PkgByPrefix(prefix); pkg == nil {
+			return nil
+		}
+	}
+	s, ok := pkg.LookupOK(tname)
+	if !ok {
+		return nil
+	}
+	d := resolve(asNode(s.Def))
+	if d == nil || d.Op != OTYPE || d.Type == nil {
+		return nil
+	}
+	if ptr {
+		return types.NewPtr(d.Type)
+	}
+	return d.Type
+}
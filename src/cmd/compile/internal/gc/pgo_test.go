@@ -0,0 +1,171 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gc
+
+import (
+	"internal/profile"
+	"internal/testenv"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const pgoSrc = `package main
+
+import "fmt"
+
+type Shape interface{ Area() int }
+
+type Square struct{ n int }
+
+func (s *Square) Area() int { return s.n * s.n }
+
+type Rect struct{ w, h int }
+
+func (r Rect) Area() int { return r.w * r.h }
+
+func big(x int) int {
+	x = x*3 + 1
+	x = x*5 + 2
+	x = x*7 + 3
+	x = x*11 + 4
+	x = x*13 + 5
+	x = x*17 + 6
+	x = x*19 + 7
+	x = x*23 + 8
+	x = x*29 + 9
+	x = x*31 + 10
+	x = x*37 + 11
+	x = x*41 + 12
+	x = x*43 + 13
+	x = x*47 + 14
+	x = x*53 + 15
+	x = x*59 + 16
+	x = x*61 + 17
+	x = x*67 + 18
+	x = x*71 + 19
+	x = x*73 + 20
+	return x
+}
+
+func area(s Shape) int {
+	return s.Area()
+}
+
+func run(x int) int {
+	return big(x)
+}
+
+func main() {
+	fmt.Println(area(&Square{3}), area(Rect{2, 5}), run(1) == big(1))
+}
+`
+
+// pgoProfile returns a CPU profile in which main.run calls main.big
+// and main.area calls (*main.Square).Area, both at the lines of pgoSrc
+// that make those calls.
+func pgoProfile(t *testing.T) *profile.Profile {
+	line := func(s string) int64 {
+		for i, l := range strings.Split(pgoSrc, "\n") {
+			if strings.Contains(l, s) {
+				return int64(i + 1)
+			}
+		}
+		t.Fatalf("no line containing %q", s)
+		return 0
+	}
+	p := &profile.Profile{
+		SampleType: []*profile.ValueType{
+			{Type: "samples", Unit: "count"},
+			{Type: "cpu", Unit: "nanoseconds"},
+		},
+		PeriodType: &profile.ValueType{Type: "cpu", Unit: "nanoseconds"},
+		Period:     10000000,
+	}
+	loc := func(fn string, line int64) *profile.Location {
+		f := &profile.Function{ID: uint64(len(p.Function) + 1), Name: fn, SystemName: fn, Filename: "main.go"}
+		p.Function = append(p.Function, f)
+		l := &profile.Location{ID: uint64(len(p.Location) + 1), Line: []profile.Line{{Function: f, Line: line}}}
+		p.Location = append(p.Location, l)
+		return l
+	}
+	mainLoc := loc("main.main", line("fmt.Println"))
+	p.Sample = []*profile.Sample{
+		{
+			Location: []*profile.Location{loc("main.big", line("x = x*3")), loc("main.run", line("return big(x)")), mainLoc},
+			Value:    []int64{100, 1000000000},
+		},
+		{
+			Location: []*profile.Location{loc("main.(*Square).Area", line("return s.n")), loc("main.area", line("return s.Area()")), mainLoc},
+			Value:    []int64{100, 1000000000},
+		},
+	}
+	return p
+}
+
+// TestPGO tests that a profile makes the compiler inline a hot call
+// to a function over the normal inlining budget and devirtualize a
+// hot interface method call, and that the result runs correctly.
+func TestPGO(t *testing.T) {
+	testenv.MustHaveGoBuild(t)
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "pgo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "main.go")
+	if err := ioutil.WriteFile(src, []byte(pgoSrc), 0666); err != nil {
+		t.Fatal(err)
+	}
+	prof := filepath.Join(dir, "cpu.pprof")
+	f, err := os.Create(prof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pgoProfile(t).Write(f); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	build := func(gcflags string) string {
+		exe := filepath.Join(dir, "main.exe")
+		cmd := exec.Command(testenv.GoToolPath(t), "build", "-gcflags="+gcflags, "-o", exe, src)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("go build -gcflags=%q: %v\n%s", gcflags, err, out)
+		}
+		run, err := exec.Command(exe).CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s: %v\n%s", exe, err, run)
+		}
+		if want := "9 10 true\n"; string(run) != want {
+			t.Errorf("with -gcflags=%q, program printed %q, want %q", gcflags, run, want)
+		}
+		return string(out)
+	}
+
+	const (
+		inlineBig    = "inlining call to big"
+		devirtualize = "PGO devirtualizing s.Area to *Square"
+	)
+	out := build("-m")
+	if strings.Contains(out, inlineBig) || strings.Contains(out, devirtualize) {
+		t.Errorf("without profile, unexpected optimizations:\n%s", out)
+	}
+	out = build("-m -pgoprofile=" + prof)
+	for _, want := range []string{inlineBig, devirtualize} {
+		if !strings.Contains(out, want) {
+			t.Errorf("with profile, missing %q in output:\n%s", want, out)
+		}
+	}
+}
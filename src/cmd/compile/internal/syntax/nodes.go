@@ -46,7 +46,9 @@ type File struct {
 type (
 	Decl interface {
 		Node
+		End() Pos // position of the ";" or ")" following the declaration
 		aDecl()
+		setEnd(Pos)
 	}
 
 	//              Path
@@ -105,9 +107,14 @@ type (
 	}
 )
 
-type decl struct{ node }
+type decl struct {
+	node
+	end Pos
+}
 
-func (*decl) aDecl() {}
+func (d *decl) End() Pos       { return d.end }
+func (*decl) aDecl()           {}
+func (d *decl) setEnd(pos Pos) { d.end = pos }
 
 // All declarations belonging to the same group point to the same Group node.
 type Group struct {
@@ -394,6 +394,7 @@ func (p *parser) fileOrNil() *File {
 		case _Func:
 			p.next()
 			if d := p.funcDeclOrNil(); d != nil {
+				d.setEnd(p.pos())
 				f.DeclList = append(f.DeclList, d)
 			}
 
@@ -470,11 +471,15 @@ func (p *parser) appendGroup(list []Decl, f func(*Group) Decl) []Decl {
 	if p.tok == _Lparen {
 		g := new(Group)
 		p.list(_Lparen, _Semi, _Rparen, func() bool {
-			list = append(list, f(g))
+			d := f(g)
+			d.setEnd(p.pos())
+			list = append(list, d)
 			return false
 		})
 	} else {
-		list = append(list, f(nil))
+		d := f(nil)
+		d.setEnd(p.pos())
+		list = append(list, d)
 	}
 
 	if debug {
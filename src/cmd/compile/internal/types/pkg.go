@@ -69,6 +69,17 @@ func ImportedPkgList() []*Pkg {
 	return list
 }
 
+// PkgByPrefix returns the package whose symbol name prefix is prefix,
+// or nil if there is none.
+func PkgByPrefix(prefix string) *Pkg {
+	for _, p := range pkgMap {
+		if p.Prefix == prefix {
+			return p
+		}
+	}
+	return nil
+}
+
 type byPath []*Pkg
 
 func (a byPath) Len() int           { return len(a) }
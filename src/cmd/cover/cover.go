@@ -35,25 +35,34 @@ Write out an HTML file instead of launching a web browser:
 Display coverage percentages to stdout for each function:
 	go tool cover -func=c.out
 
-Finally, to generate modified source code with coverage annotations
+To generate modified source code with coverage annotations
 (what go test -cover does):
 	go tool cover -mode=set -var=CoverageVariableName program.go
+
+Finally, to run the tests of a package against mutated versions of its
+source and write a profile of the mutants that the tests did not catch:
+	go tool cover -mutate=./pkg -o m.out
+
+Show the surviving mutants in the HTML coverage report:
+	go tool cover -html=c.out -mutants=m.out
 `
 
 func usage() {
 	fmt.Fprintln(os.Stderr, usageMessage)
 	fmt.Fprintln(os.Stderr, "Flags:")
 	flag.PrintDefaults()
-	fmt.Fprintln(os.Stderr, "\n  Only one of -html, -func, or -mode may be set.")
+	fmt.Fprintln(os.Stderr, "\n  Only one of -html, -func, -mode, or -mutate may be set.")
 	os.Exit(2)
 }
 
 var (
-	mode    = flag.String("mode", "", "coverage mode: set, count, atomic")
-	varVar  = flag.String("var", "GoCover", "name of coverage variable to generate")
-	output  = flag.String("o", "", "file for output; default: stdout")
-	htmlOut = flag.String("html", "", "generate HTML representation of coverage profile")
-	funcOut = flag.String("func", "", "output coverage profile information for each function")
+	mode           = flag.String("mode", "", "coverage mode: set, count, atomic")
+	varVar         = flag.String("var", "GoCover", "name of coverage variable to generate")
+	output         = flag.String("o", "", "file for output; default: stdout")
+	htmlOut        = flag.String("html", "", "generate HTML representation of coverage profile")
+	funcOut        = flag.String("func", "", "output coverage profile information for each function")
+	mutate         = flag.String("mutate", "", "run the tests of package against mutations of its source and write a mutation profile")
+	mutantsProfile = flag.String("mutants", "", "with -html, mark the mutants that survived in the mutation profile")
 )
 
 var profile string // The profile to read; the value of -html or -func
@@ -88,6 +97,15 @@ func main() {
 		return
 	}
 
+	// Run mutation tests.
+	if *mutate != "" {
+		if err := mutateOutput(*mutate, *output); err != nil {
+			fmt.Fprintf(os.Stderr, "cover: %v\n", err)
+			os.Exit(2)
+		}
+		return
+	}
+
 	// Output HTML or function coverage information.
 	if *htmlOut != "" {
 		err = htmlOutput(profile, *output)
@@ -111,11 +129,28 @@ func parseFlags() error {
 		profile = *funcOut
 	}
 
-	// Must either display a profile or rewrite Go source.
-	if (profile == "") == (*mode == "") {
+	if *mutantsProfile != "" && *htmlOut == "" {
+		return fmt.Errorf("-mutants requires -html")
+	}
+
+	// Must either display a profile, rewrite Go source, or run mutation tests.
+	n := 0
+	for _, s := range []string{profile, *mode, *mutate} {
+		if s != "" {
+			n++
+		}
+	}
+	if n != 1 {
 		return fmt.Errorf("too many options")
 	}
 
+	if *mutate != "" {
+		if flag.NArg() > 0 {
+			return fmt.Errorf("too many arguments")
+		}
+		return nil
+	}
+
 	if *varVar != "" && !token.IsIdentifier(*varVar) {
 		return fmt.Errorf("-var: %q is not a valid identifier", *varVar)
 	}
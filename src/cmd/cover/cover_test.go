@@ -21,6 +21,7 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 const (
@@ -54,6 +55,7 @@ var (
 	lineDupGo      string
 	lineDupTestGo  string
 	lineDupProfile string
+	mutateDir      string
 )
 
 var (
@@ -104,6 +106,7 @@ func TestMain(m *testing.M) {
 	lineDupGo = filepath.Join(lineDupDir, "linedup.go")
 	lineDupTestGo = filepath.Join(lineDupDir, "linedup_test.go")
 	lineDupProfile = filepath.Join(lineDupDir, "linedup.out")
+	mutateDir = filepath.Join(dir, "mutate")
 
 	status := m.Run()
 
@@ -561,6 +564,136 @@ func TestFuncWithDuplicateLines(t *testing.T) {
 	run(cmd, t)
 }
 
+// mutateContents becomes mutate.go in TestMutate.
+const mutateContents = `package mutate
+
+func Max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func Count(s []int) int {
+	n := 0
+	for _, x := range s {
+		if x > 0 {
+			n++
+		}
+	}
+	return n
+}
+`
+
+// mutateTestContents becomes mutate_test.go in TestMutate.
+// It misses the cases where the arguments are equal,
+// so the mutants changing > to >= survive.
+const mutateTestContents = `package mutate
+
+import "testing"
+
+func TestMax(t *testing.T) {
+	if Max(1, 2) != 2 || Max(3, 2) != 3 {
+		t.Fatal("bad Max")
+	}
+}
+
+func TestCount(t *testing.T) {
+	if Count([]int{1, -1, 2}) != 2 {
+		t.Fatal("bad Count")
+	}
+}
+`
+
+// Test -mutate and the display of its results by -html -mutants.
+func TestMutate(t *testing.T) {
+	t.Parallel()
+	testenv.MustHaveGoRun(t)
+	buildCover(t)
+
+	if err := os.Mkdir(mutateDir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	for name, contents := range map[string]string{
+		"go.mod":         "module mutate\n",
+		"mutate.go":      mutateContents,
+		"mutate_test.go": mutateTestContents,
+	} {
+		if err := ioutil.WriteFile(filepath.Join(mutateDir, name), []byte(contents), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Date the source back, so that any write to it shows in its
+	// modification time.
+	old := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(mutateDir, "mutate.go"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	// testcover -mutate=. -o TMPDIR/mutate.out
+	mutateProfile := filepath.Join(mutateDir, "mutate.out")
+	cmd := exec.Command(testcover, "-mutate=.", "-o", mutateProfile)
+	cmd.Dir = mutateDir
+	run(cmd, t)
+
+	got, err := ioutil.ReadFile(mutateProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `mode: mutate
+mutate/mutate.go:4.5,4.10 Max killed negated condition
+mutate/mutate.go:4.7,4.8 Max survived changed > to >=
+mutate/mutate.go:11.7,11.8 Count killed changed 0 to 1
+mutate/mutate.go:13.6,13.11 Count killed negated condition
+mutate/mutate.go:13.8,13.9 Count survived changed > to >=
+mutate/mutate.go:13.10,13.11 Count killed changed 0 to 1
+mutate/mutate.go:14.4,14.7 Count killed removed statement
+`
+	if string(got) != want {
+		t.Errorf("mutation profile:\n%s\nwant:\n%s", got, want)
+	}
+	src, err := ioutil.ReadFile(filepath.Join(mutateDir, "mutate.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(src) != mutateContents {
+		t.Errorf("mutate.go was changed; it contains:\n%s", src)
+	}
+	if info, err := os.Stat(filepath.Join(mutateDir, "mutate.go")); err != nil {
+		t.Fatal(err)
+	} else if !info.ModTime().Equal(old) {
+		t.Errorf("mutate.go was written during -mutate")
+	}
+
+	// go test -coverprofile TMPDIR/mutate.cov
+	coverProfile := filepath.Join(mutateDir, "mutate.cov")
+	cmd = exec.Command(testenv.GoToolPath(t), "test", toolexecArg, "-coverprofile", coverProfile)
+	cmd.Dir = mutateDir
+	run(cmd, t)
+
+	// testcover -html TMPDIR/mutate.cov -mutants TMPDIR/mutate.out -o TMPDIR/mutate.html
+	htmlFile := filepath.Join(mutateDir, "mutate.html")
+	cmd = exec.Command(testcover, "-html", coverProfile, "-mutants", mutateProfile, "-o", htmlFile)
+	cmd.Dir = mutateDir
+	run(cmd, t)
+
+	html, err := ioutil.ReadFile(htmlFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"2 mutants survived",
+		"Max (line 3): 1 of 2 survived",
+		"Count (line 10): 1 of 5 survived",
+		`if a <span class="mutant" title="changed &gt; to &gt;="></span>&gt; b`,
+	} {
+		if !bytes.Contains(html, []byte(s)) {
+			t.Errorf("HTML output does not contain %q", s)
+		}
+	}
+}
+
 func run(c *exec.Cmd, t *testing.T) {
 	t.Helper()
 	t.Log("running", c.Args)
@@ -14,6 +14,18 @@ than binary-rewriting coverage tools, but also a little less capable.
 For instance, it does not probe inside && and || expressions, and can
 be mildly confused by single statements with multiple function literals.
 
+Line coverage shows that code ran, not that the tests would notice if it
+were wrong. With the -mutate flag, the cover tool runs mutation tests:
+it uses the same source rewriting to make small changes to a package,
+such as flipping a comparison operator, negating the condition of an if
+statement, replacing an integer or boolean constant, or removing a
+statement, and runs the package's tests against each such mutant.
+Mutants that the tests do not catch survive; the -mutants flag marks
+them, and lists them per function, in the HTML coverage report.
+The mutants are built in a temporary mirror of the tree holding the
+package, made of symbolic links, so the package's own files are never
+changed.
+
 When computing coverage of a package that uses cgo, the cover tool
 must be applied to the output of cgo preprocessing, not the input,
 because cover deletes comments that are significant to cgo.
@@ -29,6 +29,15 @@ func htmlOutput(profile, outfile string) error {
 
 	var d templateData
 
+	var mutants map[string][]*Mutant
+	if *mutantsProfile != "" {
+		mutants, err = ParseMutants(*mutantsProfile)
+		if err != nil {
+			return err
+		}
+		d.Mutants = true
+	}
+
 	dirs, err := findPkgs(profiles)
 	if err != nil {
 		return err
@@ -47,16 +56,33 @@ func htmlOutput(profile, outfile string) error {
 		if err != nil {
 			return fmt.Errorf("can't read %q: %v", fn, err)
 		}
+		tf := &templateFile{
+			Name:     fn,
+			Coverage: percentCovered(profile),
+		}
+		var marks []mark
+		if d.Mutants {
+			tf.Funcs, err = summarizeMutants(file, mutants[fn])
+			if err != nil {
+				return err
+			}
+			for _, f := range tf.Funcs {
+				tf.Survived += len(f.Survivors)
+				for _, m := range f.Survivors {
+					marks = append(marks, mark{
+						Offset: lineColOffset(src, m.StartLine, m.StartCol),
+						Title:  m.Desc,
+					})
+				}
+			}
+		}
 		var buf strings.Builder
-		err = htmlGen(&buf, src, profile.Boundaries(src))
+		err = htmlGen(&buf, src, profile.Boundaries(src), marks)
 		if err != nil {
 			return err
 		}
-		d.Files = append(d.Files, &templateFile{
-			Name:     fn,
-			Body:     template.HTML(buf.String()),
-			Coverage: percentCovered(profile),
-		})
+		tf.Body = template.HTML(buf.String())
+		d.Files = append(d.Files, tf)
 	}
 
 	var out *os.File
@@ -107,9 +133,35 @@ func percentCovered(p *Profile) float64 {
 	return float64(covered) / float64(total) * 100
 }
 
+// A mark is a point in the source annotated with a title,
+// such as the location of a mutant that survived.
+type mark struct {
+	Offset int // Location as a byte offset in the source file.
+	Title  string
+}
+
+// lineColOffset returns the byte offset in src of the 1-based
+// line and column.
+func lineColOffset(src []byte, line, col int) int {
+	off := 0
+	for line > 1 {
+		i := bytes.IndexByte(src[off:], '\n')
+		if i < 0 {
+			return len(src)
+		}
+		off += i + 1
+		line--
+	}
+	if off += col - 1; off > len(src) {
+		off = len(src)
+	}
+	return off
+}
+
 // htmlGen generates an HTML coverage report with the provided filename,
-// source code, and tokens, and writes it to the given Writer.
-func htmlGen(w io.Writer, src []byte, boundaries []Boundary) error {
+// source code, tokens, and marks, and writes it to the given Writer.
+// The marks must be sorted by offset.
+func htmlGen(w io.Writer, src []byte, boundaries []Boundary, marks []mark) error {
 	dst := bufio.NewWriter(w)
 	for i := range src {
 		for len(boundaries) > 0 && boundaries[0].Offset == i {
@@ -125,6 +177,10 @@ func htmlGen(w io.Writer, src []byte, boundaries []Boundary) error {
 			}
 			boundaries = boundaries[1:]
 		}
+		for len(marks) > 0 && marks[0].Offset == i {
+			fmt.Fprintf(dst, `<span class="mutant" title="%s"></span>`, template.HTMLEscapeString(marks[0].Title))
+			marks = marks[1:]
+		}
 		switch b := src[i]; b {
 		case '>':
 			dst.WriteString("&gt;")
@@ -168,14 +224,17 @@ var htmlTemplate = template.Must(template.New("html").Funcs(template.FuncMap{
 }).Parse(tmplHTML))
 
 type templateData struct {
-	Files []*templateFile
-	Set   bool
+	Files   []*templateFile
+	Set     bool
+	Mutants bool // whether to show mutants
 }
 
 type templateFile struct {
 	Name     string
 	Body     template.HTML
 	Coverage float64
+	Funcs    []*funcMutants // mutants by function, if shown
+	Survived int            // number of surviving mutants
 }
 
 const tmplHTML = `
@@ -215,6 +274,13 @@ const tmplHTML = `
 			#legend span {
 				margin: 0 5px;
 			}
+			.mutant::before {
+				content: "\2020";
+				color: rgb(255, 160, 0);
+			}
+			.mutants {
+				color: rgb(255, 160, 0);
+			}
 			{{colors}}
 		</style>
 	</head>
@@ -223,7 +289,7 @@ const tmplHTML = `
 			<div id="nav">
 				<select id="files">
 				{{range $i, $f := .Files}}
-				<option value="file{{$i}}">{{$f.Name}} ({{printf "%.1f" $f.Coverage}}%)</option>
+				<option value="file{{$i}}">{{$f.Name}} ({{printf "%.1f" $f.Coverage}}%{{if $.Mutants}}, {{$f.Survived}} mutants survived{{end}})</option>
 				{{end}}
 				</select>
 			</div>
@@ -245,11 +311,19 @@ const tmplHTML = `
 				<span class="cov9">*</span>
 				<span class="cov10">high coverage</span>
 			{{end}}
+			{{if .Mutants}}
+				<span class="mutant">surviving mutant</span>
+			{{end}}
 			</div>
 		</div>
 		<div id="content">
 		{{range $i, $f := .Files}}
-		<pre class="file" id="file{{$i}}" style="display: none">{{$f.Body}}</pre>
+		<pre class="file" id="file{{$i}}" style="display: none">{{if $f.Funcs}}<span class="mutants">Surviving mutants by function:
+{{range $f.Funcs}}
+{{.Name}} (line {{.Line}}): {{len .Survivors}} of {{.Tested}} survived
+{{range .Survivors}}    line {{.StartLine}}: {{.Desc}}
+{{end}}{{end}}</span>
+{{end}}{{$f.Body}}</pre>
 		{{end}}
 		</div>
 	</body>
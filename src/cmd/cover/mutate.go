@@ -0,0 +1,566 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file implements mutation testing: running a package's tests
+// against versions of its source with small deliberate bugs.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cmd/internal/edit"
+)
+
+// A Mutant is a single small change to a source file, such as flipping
+// a comparison operator, together with the result of running the
+// package's tests against it.
+type Mutant struct {
+	FileName            string // file name as in coverage profiles: import path and base name
+	StartLine, StartCol int
+	EndLine, EndCol     int
+	Func                string // name of the enclosing function
+	Status              string // "killed", "survived", or "invalid"
+	Desc                string // description of the change
+
+	start, end int    // byte offsets of the changed source
+	repl       string // replacement text for src[start:end]
+}
+
+// Mutant status values.
+const (
+	mutantKilled   = "killed"   // the tests failed
+	mutantSurvived = "survived" // the tests passed
+	mutantInvalid  = "invalid"  // the mutated package did not build
+)
+
+// comparisonMutations maps each comparison operator to the operator
+// it is changed to. The relational operators are moved across the
+// boundary of equality, which tests of edge cases should catch.
+var comparisonMutations = map[token.Token]token.Token{
+	token.LSS: token.LEQ,
+	token.LEQ: token.LSS,
+	token.GTR: token.GEQ,
+	token.GEQ: token.GTR,
+	token.EQL: token.NEQ,
+	token.NEQ: token.EQL,
+}
+
+// mutator is the ast.Visitor that collects the mutants of a file.
+type mutator struct {
+	fset    *token.FileSet
+	content []byte
+	fn      string // name of the function being visited
+	mutants []*Mutant
+}
+
+func (m *mutator) add(start, end token.Pos, repl, desc string) {
+	s := m.fset.Position(start)
+	e := m.fset.Position(end)
+	m.mutants = append(m.mutants, &Mutant{
+		StartLine: s.Line,
+		StartCol:  s.Column,
+		EndLine:   e.Line,
+		EndCol:    e.Column,
+		Func:      m.fn,
+		Desc:      desc,
+		start:     s.Offset,
+		end:       e.Offset,
+		repl:      repl,
+	})
+}
+
+// Visit implements the ast.Visitor interface.
+func (m *mutator) Visit(node ast.Node) ast.Visitor {
+	switch n := node.(type) {
+	case *ast.FuncDecl:
+		if n.Body == nil {
+			return nil
+		}
+		m.fn = n.Name.Name
+		ast.Walk(m, n.Body)
+		m.fn = ""
+		return nil
+	case *ast.GenDecl:
+		// Constants and types must stay as they are for the package
+		// to build; mutate only executable code.
+		if n.Tok != token.VAR || m.fn == "" {
+			return nil
+		}
+	case *ast.BlockStmt:
+		m.dropStmts(n.List)
+	case *ast.CaseClause:
+		m.dropStmts(n.Body)
+	case *ast.CommClause:
+		m.dropStmts(n.Body)
+	case *ast.IfStmt:
+		src := string(m.content[m.fset.Position(n.Cond.Pos()).Offset:m.fset.Position(n.Cond.End()).Offset])
+		m.add(n.Cond.Pos(), n.Cond.End(), "!("+src+")", "negated condition")
+	case *ast.BinaryExpr:
+		if op, ok := comparisonMutations[n.Op]; ok {
+			m.add(n.OpPos, n.OpPos+token.Pos(len(n.Op.String())), op.String(),
+				fmt.Sprintf("changed %s to %s", n.Op, op))
+		}
+	case *ast.BasicLit:
+		if n.Kind == token.INT {
+			repl := "0"
+			if v, err := strconv.ParseInt(n.Value, 0, 64); err == nil && v == 0 {
+				repl = "1"
+			}
+			m.add(n.Pos(), n.End(), repl, fmt.Sprintf("changed %s to %s", n.Value, repl))
+		}
+	case *ast.Ident:
+		switch n.Name {
+		case "true":
+			m.add(n.Pos(), n.End(), "false", "changed true to false")
+		case "false":
+			m.add(n.Pos(), n.End(), "true", "changed false to true")
+		}
+	}
+	return m
+}
+
+// dropStmts adds mutants that remove the simple statements in list.
+// Declarations are kept, since removing them rarely builds.
+func (m *mutator) dropStmts(list []ast.Stmt) {
+	for _, s := range list {
+		switch s := s.(type) {
+		case *ast.AssignStmt:
+			if s.Tok == token.DEFINE {
+				continue
+			}
+		case *ast.ExprStmt, *ast.IncDecStmt, *ast.SendStmt:
+		default:
+			continue
+		}
+		m.add(s.Pos(), s.End(), "", "removed statement")
+	}
+}
+
+// findMutants parses the named file and returns its mutants,
+// sorted by position.
+func findMutants(name string, content []byte) ([]*Mutant, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, name, content, 0)
+	if err != nil {
+		return nil, err
+	}
+	m := &mutator{fset: fset, content: content}
+	ast.Walk(m, f)
+	sort.SliceStable(m.mutants, func(i, j int) bool {
+		return m.mutants[i].start < m.mutants[j].start
+	})
+	return m.mutants, nil
+}
+
+// mutatePkg describes a package to mutate,
+// compatible with the JSON output from 'go list'.
+type mutatePkg struct {
+	ImportPath string
+	Dir        string
+	Root       string // GOROOT or GOPATH root containing the package
+	Goroot     bool
+	GoFiles    []string
+	Module     *struct {
+		Dir string
+	}
+	Error *struct {
+		Err string
+	}
+}
+
+// mutateOutput runs mutation testing on the package named by pkg, a
+// package path or directory as accepted by 'go test', and writes the
+// mutation profile to outputFile ("" means to write to standard output).
+//
+// The mutants are built in a temporary mirror of the package's module or
+// GOPATH tree (see mirrorTree), so the original source files are never
+// changed, even if the program is killed.
+// The profile looks like this:
+//
+//	mode: mutate
+//	strconv/atoi.go:38.14,38.16 syntaxError survived changed == to !=
+//	strconv/atoi.go:49.2,49.19 rangeError killed removed statement
+//
+// where the fields are the file, the range of the changed source,
+// the enclosing function, the test result, and a description.
+func mutateOutput(pkg, outputFile string) error {
+	goTool := filepath.Join(runtime.GOROOT(), "bin/go")
+	cmd := exec.Command(goTool, "list", "-e", "-json", pkg)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("cannot run go list: %v\n%s", err, stderr.Bytes())
+	}
+	var p mutatePkg
+	if err := json.Unmarshal(stdout, &p); err != nil {
+		return fmt.Errorf("decoding go list json: %v", err)
+	}
+	if p.Error != nil {
+		return fmt.Errorf("%s", p.Error.Err)
+	}
+
+	tmpDir, err := ioutil.TempDir("", "cover-mutate")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+	t, err := newMutantTree(&p, goTool, tmpDir)
+	if err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	if outputFile != "" {
+		fd, err := os.Create(outputFile)
+		if err != nil {
+			return err
+		}
+		defer fd.Close()
+		out = bufio.NewWriter(fd)
+	}
+	defer out.Flush()
+
+	// The tests must pass before any mutation.
+	// Their running time bounds the time allowed for each mutant,
+	// which may well loop forever.
+	start := time.Now()
+	if status, output := t.runTests(0); status != mutantSurvived {
+		return fmt.Errorf("tests of %s fail without mutations:\n%s", p.ImportPath, output)
+	}
+	timeout := 10*time.Since(start) + 10*time.Second
+
+	fmt.Fprintf(out, "mode: mutate\n")
+	for _, name := range p.GoFiles {
+		content, err := ioutil.ReadFile(filepath.Join(p.Dir, name))
+		if err != nil {
+			return err
+		}
+		mutants, err := findMutants(filepath.Join(p.Dir, name), content)
+		if err != nil {
+			return err
+		}
+		file := filepath.Join(t.dir, name)
+		for _, m := range mutants {
+			buf := edit.NewBuffer(content)
+			buf.Replace(m.start, m.end, m.repl)
+			if err := ioutil.WriteFile(file, buf.Bytes(), 0666); err != nil {
+				return err
+			}
+			m.FileName = p.ImportPath + "/" + name
+			m.Status, _ = t.runTests(timeout)
+			fmt.Fprintf(out, "%s:%d.%d,%d.%d %s %s %s\n", m.FileName,
+				m.StartLine, m.StartCol, m.EndLine, m.EndCol, m.Func, m.Status, m.Desc)
+			out.Flush()
+		}
+		if err := ioutil.WriteFile(file, content, 0666); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// A mutantTree is a mirror of the tree holding a package,
+// in which the package's tests run against its mutants.
+type mutantTree struct {
+	goTool string
+	dir    string   // the package directory in the mirror
+	env    []string // environment for running the go command
+}
+
+// newMutantTree creates in tmpDir a mirror of the module, GOPATH tree, or
+// GOROOT holding p, with copies of the Go files of p to be mutated.
+func newMutantTree(p *mutatePkg, goTool, tmpDir string) (*mutantTree, error) {
+	root := p.Root
+	if p.Module != nil {
+		root = p.Module.Dir
+	} else if !p.Goroot {
+		root = filepath.Join(root, "src")
+	}
+	if root == "" {
+		return nil, fmt.Errorf("cannot mutate %s: package is not in a module, GOPATH, or GOROOT", p.ImportPath)
+	}
+	rel, err := filepath.Rel(root, p.Dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("cannot mutate %s: directory %s is not in %s", p.ImportPath, p.Dir, root)
+	}
+	// In GOPATH mode, the mirror is the src directory of a GOPATH root.
+	mirror := filepath.Join(tmpDir, "src")
+	if err := os.Mkdir(mirror, 0777); err != nil {
+		return nil, err
+	}
+	if err := mirrorTree(root, mirror, rel, p.GoFiles); err != nil {
+		return nil, err
+	}
+
+	t := &mutantTree{
+		goTool: goTool,
+		dir:    filepath.Join(mirror, rel),
+		env:    os.Environ(),
+	}
+	switch {
+	case p.Module != nil:
+		// The go command may update go.mod and go.sum;
+		// let it update copies.
+		for _, name := range []string{"go.mod", "go.sum"} {
+			if err := copyFile(filepath.Join(root, name), filepath.Join(mirror, name)); err != nil && !os.IsNotExist(err) {
+				return nil, err
+			}
+		}
+		if err := fixModReplacements(goTool, root, mirror); err != nil {
+			return nil, err
+		}
+	case p.Goroot:
+		t.env = append(t.env, "GOROOT="+mirror)
+	default:
+		gopath := tmpDir
+		if build.Default.GOPATH != "" {
+			gopath += string(filepath.ListSeparator) + build.Default.GOPATH
+		}
+		t.env = append(t.env, "GOPATH="+gopath)
+	}
+	return t, nil
+}
+
+// mirrorTree fills the empty directory mirror with a mirror of the tree
+// at root in which only the directories on the path rel to the package
+// directory are real. All other files and directories are symbolic
+// links to the originals, except for the files named in copies in the
+// package directory, which are copies that can be mutated.
+func mirrorTree(root, mirror, rel string, copies []string) error {
+	next, rest := rel, ""
+	if i := strings.IndexRune(rel, filepath.Separator); i >= 0 {
+		next, rest = rel[:i], rel[i+1:]
+	}
+	copied := make(map[string]bool)
+	if rel == "." {
+		for _, name := range copies {
+			copied[name] = true
+		}
+	}
+	infos, err := ioutil.ReadDir(root)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		name := info.Name()
+		src, dst := filepath.Join(root, name), filepath.Join(mirror, name)
+		switch {
+		case name == next && rel != ".":
+			if err := os.Mkdir(dst, 0777); err != nil {
+				return err
+			}
+			if rest == "" {
+				rest = "."
+			}
+			if err := mirrorTree(src, dst, rest, copies); err != nil {
+				return err
+			}
+		case copied[name]:
+			if err := copyFile(src, dst); err != nil {
+				return err
+			}
+		default:
+			if err := os.Symlink(src, dst); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// copyFile copies the file src to dst, replacing dst if it exists.
+func copyFile(src, dst string) error {
+	data, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return ioutil.WriteFile(dst, data, 0666)
+}
+
+// fixModReplacements rewrites the replacements of modules by directories
+// relative to the module root in the mirror's go.mod file to absolute
+// paths, which stay valid in the mirror.
+func fixModReplacements(goTool, root, mirror string) error {
+	var mod struct {
+		Replace []struct {
+			Old, New struct {
+				Path    string
+				Version string
+			}
+		}
+	}
+	cmd := exec.Command(goTool, "mod", "edit", "-json")
+	cmd.Dir = mirror
+	data, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("cannot read go.mod: %v", err)
+	}
+	if err := json.Unmarshal(data, &mod); err != nil {
+		return fmt.Errorf("decoding go mod edit json: %v", err)
+	}
+	args := []string{"mod", "edit"}
+	for _, r := range mod.Replace {
+		if r.New.Version != "" || !(strings.HasPrefix(r.New.Path, "./") || strings.HasPrefix(r.New.Path, "../")) {
+			continue
+		}
+		old := r.Old.Path
+		if r.Old.Version != "" {
+			old += "@" + r.Old.Version
+		}
+		args = append(args, "-replace="+old+"="+filepath.Join(root, r.New.Path))
+	}
+	if len(args) == 2 {
+		return nil
+	}
+	cmd = exec.Command(goTool, args...)
+	cmd.Dir = mirror
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("cannot edit go.mod: %v\n%s", err, out)
+	}
+	return nil
+}
+
+// runTests runs the tests of the package in the mirror, reporting
+// whether they passed (survived), failed (killed), or did not build
+// (invalid). A timeout of 0 means the default test timeout.
+// The tests always run: a cached result would belong to another mutant.
+func (t *mutantTree) runTests(timeout time.Duration) (status string, output []byte) {
+	args := []string{"test", "-vet=off", "-count=1"}
+	if timeout != 0 {
+		args = append(args, "-timeout="+timeout.String())
+	}
+	cmd := exec.Command(t.goTool, append(args, ".")...)
+	cmd.Dir = t.dir
+	cmd.Env = t.env
+	output, err := cmd.CombinedOutput()
+	switch {
+	case err == nil:
+		return mutantSurvived, output
+	case bytes.Contains(output, []byte("[build failed]")), bytes.Contains(output, []byte("[setup failed]")):
+		return mutantInvalid, output
+	}
+	return mutantKilled, output
+}
+
+// ParseMutants parses the mutation profile written by -mutate and
+// returns the mutants for each file name.
+func ParseMutants(fileName string) (map[string][]*Mutant, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseMutants(f)
+}
+
+func parseMutants(r io.Reader) (map[string][]*Mutant, error) {
+	mutants := make(map[string][]*Mutant)
+	s := bufio.NewScanner(r)
+	first := true
+	for s.Scan() {
+		line := s.Text()
+		if first {
+			if line != "mode: mutate" {
+				return nil, fmt.Errorf("bad mutation profile mode line: %q", line)
+			}
+			first = false
+			continue
+		}
+		f := strings.SplitN(line, " ", 4)
+		if len(f) != 4 {
+			return nil, fmt.Errorf("line %q does not match expected format", line)
+		}
+		m := &Mutant{Func: f[1], Status: f[2], Desc: f[3]}
+		i := strings.LastIndex(f[0], ":")
+		if i < 0 {
+			return nil, fmt.Errorf("line %q does not match expected format", line)
+		}
+		m.FileName = f[0][:i]
+		if _, err := fmt.Sscanf(f[0][i+1:], "%d.%d,%d.%d", &m.StartLine, &m.StartCol, &m.EndLine, &m.EndCol); err != nil {
+			return nil, fmt.Errorf("line %q does not match expected format: %v", line, err)
+		}
+		mutants[m.FileName] = append(mutants[m.FileName], m)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if first {
+		return nil, fmt.Errorf("empty mutation profile")
+	}
+	return mutants, nil
+}
+
+// funcMutants summarizes the mutants of one function for the HTML report.
+type funcMutants struct {
+	Name      string
+	Line      int
+	Killed    int
+	Survivors []*Mutant
+}
+
+// Tested returns the number of mutants of the function that built.
+func (f *funcMutants) Tested() int {
+	return f.Killed + len(f.Survivors)
+}
+
+// summarizeMutants groups the mutants of the named source file by
+// the function containing them, in source order.
+func summarizeMutants(file string, mutants []*Mutant) ([]*funcMutants, error) {
+	funcs, err := findFuncs(file)
+	if err != nil {
+		return nil, err
+	}
+	var sums []*funcMutants
+	for _, fe := range funcs {
+		sum := &funcMutants{Name: fe.name, Line: fe.startLine}
+		for _, m := range mutants {
+			if !fe.contains(m.StartLine, m.StartCol) {
+				continue
+			}
+			switch m.Status {
+			case mutantKilled:
+				sum.Killed++
+			case mutantSurvived:
+				sum.Survivors = append(sum.Survivors, m)
+			}
+		}
+		if sum.Tested() > 0 {
+			sums = append(sums, sum)
+		}
+	}
+	return sums, nil
+}
+
+// contains reports whether the position line:col lies within f.
+func (f *FuncExtent) contains(line, col int) bool {
+	if line < f.startLine || line == f.startLine && col < f.startCol {
+		return false
+	}
+	if line > f.endLine || line == f.endLine && col >= f.endCol {
+		return false
+	}
+	return true
+}
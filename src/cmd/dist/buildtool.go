@@ -96,6 +96,7 @@ var bootstrapDirs = []string{
 	"debug/macho",
 	"debug/pe",
 	"internal/goversion",
+	"internal/profile",
 	"internal/race",
 	"internal/xcoff",
 	"math/big",
@@ -30,6 +30,7 @@
 // 	tool        run specified go tool
 // 	version     print Go version
 // 	vet         report likely mistakes in packages
+// 	work        workspace maintenance
 //
 // Use "go help <command>" for more information about a command.
 //
@@ -129,6 +130,19 @@
 // 		arguments to pass on each go tool asm invocation.
 // 	-buildmode mode
 // 		build mode to use. See 'go help buildmode' for more.
+// 	-buildvcs
+// 		whether to stamp binaries with version control information.
+// 		By default, when the main package is in a Git repository, the
+// 		current commit, its time, and whether there are uncommitted
+// 		changes are recorded in the binary, together with the build
+// 		settings. Test binaries and binaries built by 'go run' are
+// 		not stamped. If git is not installed, the build proceeds with
+// 		a warning and no version control information. Use
+// 		-buildvcs=false to omit version control information.
+// 		Like all build information, it is recorded only in module
+// 		mode: binaries built in GOPATH mode record neither the build
+// 		settings nor the version control information.
+// 		See 'go help version' and runtime/debug.BuildInfo.
 // 	-compiler name
 // 		name of compiler to use, as in runtime.Compiler (gccgo or gc).
 // 	-gccgoflags '[pattern=]arg list'
@@ -160,6 +174,13 @@
 // 		directory, but it is not accessed. When -modfile is specified, an
 // 		alternate go.sum file is also used: its path is derived from the
 // 		-modfile flag by trimming the ".mod" extension and appending ".sum".
+// 	-pgo file
+// 		build with profile-guided optimization, using the CPU profile in
+// 		file, as written by runtime/pprof, to inform optimization decisions.
+// 		The compiler inlines hot calls to functions too large to inline
+// 		otherwise and devirtualizes hot interface method calls.
+// 		Only packages whose functions appear in the profile are compiled
+// 		with it, and only they are rebuilt when the profile changes.
 // 	-pkgdir dir
 // 		install and load all packages from dir instead of the usual locations.
 // 		For example, when building with a non-standard configuration,
@@ -677,6 +698,15 @@
 // the named packages, including downloading necessary dependencies,
 // but not to build and install them.
 //
+// The -diff flag instructs get to print the changes it would make to the
+// build list, without updating go.mod or building any packages. Each
+// line of output gives a module path followed by its old and new
+// versions, as in "golang.org/x/text v0.3.0 => v0.3.2". A module that
+// would be added to the build list has old version "none", and a module
+// that would be removed has new version "none". For example,
+// 'go get -diff rsc.io/quote@none' shows what dropping the requirement
+// on rsc.io/quote would remove or downgrade.
+//
 // With no package arguments, 'go get' applies to Go package in the
 // current directory, if any. In particular, 'go get -u' and
 // 'go get -u=patch' update all the dependencies of that package.
@@ -780,6 +810,14 @@
 //         TestGoFiles     []string // _test.go files in package
 //         XTestGoFiles    []string // _test.go files outside package
 //
+//         // Embedded files
+//         EmbedPatterns      []string // //go:embed patterns
+//         EmbedFiles         []string // files matched by EmbedPatterns
+//         TestEmbedPatterns  []string // //go:embed patterns in TestGoFiles
+//         TestEmbedFiles     []string // files matched by TestEmbedPatterns
+//         XTestEmbedPatterns []string // //go:embed patterns in XTestGoFiles
+//         XTestEmbedFiles    []string // files matched by XTestEmbedPatterns
+//
 //         // Cgo directives
 //         CgoCFLAGS    []string // cgo: flags for C compiler
 //         CgoCPPFLAGS  []string // cgo: flags for C preprocessor
@@ -909,6 +947,7 @@
 //         Dir       string       // directory holding files for this module, if any
 //         GoMod     string       // path to go.mod file used when loading this module, if any
 //         GoVersion string       // go version used in module
+//         Retracted []string     // retraction rationale, if any (with -u)
 //         Error     *ModuleError // error loading module
 //     }
 //
@@ -949,11 +988,18 @@
 //     golang.org/x/text v0.3.0 [v0.4.0] => /tmp/text
 //     rsc.io/pdf v0.1.1 [v0.1.2]
 //
+// The -u flag also reports versions that have been retracted by their
+// module's author (see 'go help go.mod'): list -u sets the Module's
+// Retracted field to the author's rationale, and the String method
+// appends "(retracted)" to the version. Upgrades never suggest a
+// retracted version.
+//
 // (For tools, 'go list -m -u -json all' may be more convenient to parse.)
 //
 // The -versions flag causes list to set the Module's Versions field
 // to a list of all known versions of that module, ordered according
-// to semantic versioning, earliest to latest. The flag also changes
+// to semantic versioning, earliest to latest. Retracted versions
+// are omitted. The flag also changes
 // the default output format to display the module path followed by the
 // space-separated version list.
 //
@@ -1005,6 +1051,7 @@
 // 	tidy        add missing and remove unused modules
 // 	vendor      make vendored copy of dependencies
 // 	verify      verify dependencies have expected content
+// 	vuln        report known vulnerabilities that affect the build
 // 	why         explain why packages or modules are needed
 //
 // Use "go help mod <command>" for more information about a command.
@@ -1092,9 +1139,14 @@
 // module path and version pair. If the @v is omitted, a replacement without
 // a version on the left side is dropped.
 //
+// The -retract=version and -dropretract=version flags add and drop a
+// retraction on the given version. The version may be a single version
+// like "v1.2.3" or a closed interval like "[v1.1.0,v1.1.9]". Note that
+// -retract=version is a no-op if that retraction already exists.
+//
 // The -require, -droprequire, -exclude, -dropexclude, -replace,
-// and -dropreplace editing flags may be repeated, and the changes
-// are applied in the order given.
+// -dropreplace, -retract, and -dropretract editing flags may be repeated,
+// and the changes are applied in the order given.
 //
 // The -go=version flag sets the expected Go language version.
 //
@@ -1115,6 +1167,7 @@
 // 		Require []Require
 // 		Exclude []Module
 // 		Replace []Replace
+// 		Retract []Retract
 // 	}
 //
 // 	type Require struct {
@@ -1128,6 +1181,12 @@
 // 		New Module
 // 	}
 //
+// 	type Retract struct {
+// 		Low       string
+// 		High      string
+// 		Rationale string
+// 	}
+//
 // Note that this only describes the go.mod file itself, not other modules
 // referred to indirectly. For the full set of modules available to a build,
 // use 'go list -m -json all'.
@@ -1207,11 +1266,91 @@
 // non-zero status.
 //
 //
+// Report known vulnerabilities that affect the build
+//
+// Usage:
+//
+// 	go mod vuln [-db dir] [-json] [-m] [packages]
+//
+// Vuln reports the known vulnerabilities, as listed in a local database,
+// that affect the named packages. With no arguments, it checks the
+// packages of the main module.
+//
+// A vulnerability affects the build only if a module providing packages
+// to the build is at an affected version and, when the database lists
+// the affected packages and functions, one of those packages is imported
+// and one of those functions may be called from the named packages.
+// Calls are found by a conservative analysis of the source code: a call
+// to a method M through an interface is assumed to reach every method
+// named M, so vuln may report vulnerabilities that cannot actually be
+// reached, but it does not miss calls made by Go code. Calls made by
+// reflection, assembly, or code linked by cgo are not seen.
+//
+// For commands, the analysis starts at main.main; for other packages, at
+// each exported function and method. Init functions of all imported
+// packages are always included.
+//
+// The -m flag reports every vulnerability affecting a module in the
+// module graph ('go list -m all'), whether or not the build uses the
+// affected code.
+//
+// The -db flag gives the directory holding the vulnerability database.
+// It defaults to the value of the GOVULNDB environment variable.
+// Every file with a .json extension in the directory or its subdirectories
+// holds one entry, in the format described by these Go types:
+//
+// 	type Entry struct {
+// 		ID       string     // unique identifier, such as "GO-2020-0001"
+// 		Aliases  []string   // other identifiers, such as CVE numbers
+// 		Summary  string     // one-line description
+// 		Details  string     // longer description
+// 		Affected []Affected // affected modules
+// 	}
+//
+// 	type Affected struct {
+// 		Module   string    // module path, or "std" for the standard library
+// 		Ranges   []Range   // affected versions; all versions if empty
+// 		Packages []Package // affected packages; all packages if empty
+// 	}
+//
+// 	type Range struct {
+// 		Introduced string // first affected version; all earlier versions if empty
+// 		Fixed      string // first fixed version; no fix if empty
+// 	}
+//
+// 	type Package struct {
+// 		Path    string   // import path
+// 		Symbols []string // affected "Func" or "Type.Method"; all if empty
+// 	}
+//
+// Versions are semantic versions. For the standard library, Go release
+// go1.X.Y is version v1.X.Y.
+//
+// By default, vuln prints each vulnerability with the affected module
+// and, when known, the affected package and a chain of calls reaching
+// the vulnerable function. The -json flag prints each finding as a JSON
+// object instead, in the format described by these Go types:
+//
+// 	type Finding struct {
+// 		ID      string   // vulnerability identifier
+// 		Aliases []string // other identifiers
+// 		Summary string   // one-line description
+// 		Module  string   // affected module path
+// 		Version string   // affected module version
+// 		Fixed   string   // earliest fixed version, if any
+// 		Package string   // affected package, if known
+// 		Symbol  string   // affected function reached, if known
+// 		Stack   []string // calls reaching Symbol, starting at a root
+// 	}
+//
+// Vuln exits with a non-zero status if it reports any vulnerability.
+//
+//
 // Explain why packages or modules are needed
 //
 // Usage:
 //
-// 	go mod why [-m] [-vendor] packages...
+// 	go mod why [-m] [-mvs] [-vendor] packages...
 //
 // Why shows a shortest path in the import graph from the main module to
 // each of the listed packages. If the -m flag is given, why treats the
@@ -1242,6 +1381,26 @@
 // 	(main module does not need package golang.org/x/text/encoding)
 // 	$
 //
+// The -mvs flag causes why to explain the module versions selected by
+// minimal version selection instead of the import graph. The arguments
+// are modules, as with -m; with no arguments, why explains every module
+// in the build list. For each module, why shows a shortest path in the
+// module requirement graph (the one printed by 'go mod graph') from the
+// main module to the module's selected version, one module per line.
+// The last requirement on the path is one that forces that version to be
+// selected. For example:
+//
+// 	$ go mod why -mvs golang.org/x/text
+// 	# golang.org/x/text v0.0.0-20170915032832-14c0d48ead0c
+// 	example.com/hello
+// 	rsc.io/quote v1.5.2
+// 	rsc.io/sampler v1.3.0
+// 	golang.org/x/text v0.0.0-20170915032832-14c0d48ead0c
+// 	$
+//
+// To see how the build list would change if a requirement were
+// dropped or changed, use 'go get -diff'.
+//
 //
 // Compile and run Go program
 //
@@ -1360,6 +1519,12 @@
 // so a successful package test result will be cached and reused
 // regardless of -timeout setting.
 //
+// When the -shard or -saveshardtimes flag is set, go test records in the
+// build cache how long each test and example of each package takes to run.
+// Later runs, sharded or not, start the packages whose tests took longest
+// first, so that they do not delay the end of the run. The results are
+// still printed in the order of the packages on the command line.
+//
 // In addition to the build flags, the flags handled by 'go test' itself are:
 //
 // 	-args
@@ -1417,7 +1582,7 @@
 //
 // Usage:
 //
-// 	go version [-m] [-v] [file ...]
+// 	go version [-m] [-v] [-verify] [file ...]
 //
 // Version prints the build information for Go executables.
 //
@@ -1435,7 +1600,26 @@
 // The -m flag causes go version to print each executable's embedded
 // module version information, when available. In the output, the module
 // information consists of multiple lines following the version line, each
-// indented by a leading tab character.
+// indented by a leading tab character. For binaries built in module mode,
+// the information ends with the settings used for the build, one per
+// "build" line: the compiler and build flags, the cgo setting, GOOS and
+// GOARCH, and, for binaries built by 'go build' or 'go install' inside
+// a Git checkout, the current commit, its time, and whether the checkout
+// had uncommitted changes (see the -buildvcs build flag). Binaries built
+// in GOPATH mode carry no module information and no build settings.
+//
+// The -verify flag causes go version to check that each named executable
+// can be reproduced. It rebuilds the executable with the current Go
+// toolchain and the recorded build settings, from the source named by its
+// build information: for a binary built inside a Git checkout, the
+// recorded revision of the repository containing the current directory;
+// otherwise, the recorded versions of the modules providing the main
+// package and its dependencies. The binary must have been built with
+// -trimpath by the same Go version, and not from a checkout with
+// uncommitted changes. Go version then reports whether the rebuilt
+// binary is bit-identical to the original. If not, it reports whether
+// the main package archive or the other link inputs differ, and which
+// packages define symbols that differ, and exits with a non-zero status.
 //
 // See also: go doc runtime/debug.BuildInfo.
 //
@@ -1463,6 +1647,41 @@
 //   go install golang.org/x/tools/go/analysis/passes/shadow/cmd/shadow
 //   go vet -vettool=$(which shadow)
 //
+// In module mode, a go.vet file in the main module's root directory
+// configures the analyzers that go vet runs. Its syntax is that of go.mod
+// files, with these directives:
+//
+// 	analyzer path [var]
+// 		Run the analyzer declared by the exported variable var,
+// 		of type *analysis.Analyzer, in the package with import path
+// 		path. The default var is Analyzer.
+//
+// 	enable name
+// 	disable name
+// 		Enable or disable the analyzer with the given name, as the
+// 		-name=true and -name=false vet flags do. Flags given on the
+// 		command line override these settings.
+//
+// 	severity name error|warning
+// 		Set the severity of the analyzer's diagnostics. Diagnostics
+// 		of severity warning are reported but do not make go vet fail.
+// 		The default severity is error.
+//
+// For example:
+//
+// 	analyzer example.com/lint/nilctx
+//
+// 	disable composites
+// 	severity nilctx warning
+//
+// The go command builds a vet tool containing the standard analyzers and
+// those named by analyzer directives from the packages of the main
+// module's build list, which must therefore provide golang.org/x/tools.
+// The tool is kept in the build cache directory and rebuilt only when its
+// sources change. The analyzer directive cannot be combined with -vettool.
+// Because go vet checks its command-line flags against those of cmd/vet,
+// flags of the added analyzers cannot be given on the command line.
+//
 // The build flags supported by go vet are those that control package resolution
 // and execution, such as -n, -x, -v, -tags, and -toolexec.
 // For more about these flags, see 'go help build'.
@@ -1470,6 +1689,93 @@
 // See also: go fmt, go fix.
 //
 //
+// Workspace maintenance
+//
+// Go work provides access to operations on workspaces.
+//
+// A workspace is a set of modules, each in its own directory, that are
+// developed together. The workspace is described by a go.work file, which
+// lists the module directories in use directives:
+//
+// 	go 1.14
+//
+// 	use (
+// 		./api
+// 		./server
+// 	)
+//
+// Relative directories are interpreted relative to the directory
+// containing the go.work file.
+//
+// When the go command finds a go.work file in the current directory or
+// one of its parents, it runs in workspace mode: every module listed in
+// go.work is a main module. Packages in any of them may be named on the
+// command line, and imports of their packages resolve to the workspace
+// directories, as if each go.mod file held a directory replacement for
+// the others. Replacements in the go.mod files of all the workspace
+// modules apply. 'go list -m' lists all the workspace modules.
+//
+// In workspace mode the go.mod files of the workspace modules are never
+// rewritten, so imports of packages in modules not already required
+// cannot be resolved, and 'go get', 'go mod tidy' and 'go mod vendor' are
+// not allowed. Checksums are read from the go.sum files of all the
+// workspace modules; checksums of other modules downloaded while in
+// workspace mode are recorded in go.work.sum, next to go.work.
+//
+// The GOWORK environment variable names the go.work file to use instead
+// of searching for one; GOWORK=off disables workspace mode.
+//
+// Usage:
+//
+// 	go work <command> [arguments]
+//
+// The commands are:
+//
+// 	drop        remove modules from workspace file
+// 	init        initialize workspace file
+// 	use         add modules to workspace file
+//
+// Use "go help work <command>" for more information about a command.
+//
+// Remove modules from workspace file
+//
+// Usage:
+//
+// 	go work drop [moddirs]
+//
+// Drop removes the use directives for the named module directories from
+// the go.work file in effect. The directories need not exist, so that
+// modules that were moved or deleted can be dropped. It is an error to
+// name a directory that is not in the workspace.
+//
+//
+// Initialize workspace file
+//
+// Usage:
+//
+// 	go work init [moddirs]
+//
+// Init initializes and writes a new go.work file in the current
+// directory, in effect creating a new workspace there. The file go.work
+// must not already exist.
+//
+// Init optionally accepts paths to the workspace modules as arguments.
+// Each argument must be a directory containing a go.mod file.
+//
+//
+// Add modules to workspace file
+//
+// Usage:
+//
+// 	go work use [moddirs]
+//
+// Use adds use directives for the named module directories to the
+// go.work file in effect, which is the one found in the current directory
+// or its parents, or the one named by GOWORK. Each argument must be a
+// directory containing a go.mod file. Directories already in the
+// workspace are left alone.
+//
+//
 // Build modes
 //
 // The 'go build' and 'go install' commands take a -buildmode argument which
@@ -1576,6 +1882,49 @@
 // GODEBUG=gocachetest=1 causes the go command to print details of its
 // decisions about whether to reuse a cached test result.
 //
+// The GOCACHEPROG environment variable names a helper program, with
+// optional space-separated arguments, that extends the build cache
+// beyond the local directory, for example to share build outputs between
+// the machines of a CI fleet through a network file system or an object
+// store. The go command starts the helper once and forwards to it every
+// lookup that misses in the local cache, copying found entries into
+// GOCACHE, and every new cache entry. Failures of the helper never fail
+// the build: the go command reports them and continues with the local
+// cache alone.
+//
+// The go command and the helper exchange JSON objects, one per line, over
+// the helper's standard input and output. The helper first writes
+//
+// 	{"ID": 0, "KnownCommands": ["get", "put", "close"]}
+//
+// listing the commands it implements. The go command then writes requests
+//
+// 	type Request struct {
+// 		ID       int64  // unique request ID, starting at 1
+// 		Command  string // "get", "put", or "close"
+// 		ActionID []byte // cache key (get and put)
+// 		OutputID []byte // SHA-256 hash of the output (put)
+// 		BodySize int64  // size of the output (put)
+// 	}
+//
+// and the helper writes, in any order, one response for each:
+//
+// 	type Response struct {
+// 		ID       int64      // ID of the request
+// 		Err      string     // error, if the command failed
+// 		Miss     bool       // get: no entry for ActionID
+// 		OutputID []byte     // get: hash of the output
+// 		Size     int64      // get: size of the output
+// 		Time     *time.Time // get: when the entry was stored, if known
+// 		DiskPath string     // get: local file holding the output
+// 	}
+//
+// []byte values are encoded in base64, as by encoding/json. A put request
+// with a non-zero BodySize is followed by a line holding the output as a
+// JSON base64 string. The go command reads the file at DiskPath right
+// after receiving a get response. A close request is sent when the go
+// command exits; the helper should respond and then exit.
+//
 //
 // Environment variables
 //
@@ -1603,6 +1952,10 @@
 // 	GOCACHE
 // 		The directory where the go command will store cached
 // 		information for reuse in future builds.
+// 	GOCACHEPROG
+// 		A command (with optional space-separated flags) that implements an
+// 		external build cache shared with the local one in GOCACHE.
+// 		See 'go help cache'.
 // 	GODEBUG
 // 		Enable various debugging facilities. See 'go doc runtime'
 // 		for details.
@@ -1640,6 +1993,10 @@
 // 	GOTMPDIR
 // 		The directory where the go command will write
 // 		temporary source files, packages, and binaries.
+// 	GOWORK
+// 		The path of the go.work file to use, overriding the search
+// 		for go.work in the current directory and its parents.
+// 		If set to 'off', workspace mode is disabled. See 'go help work'.
 //
 // Environment variables for use with cgo:
 //
@@ -1792,16 +2149,39 @@
 // 	require new/thing/v2 v2.3.4
 // 	exclude old/thing v1.2.3
 // 	replace bad/thing v1.4.5 => good/thing v1.4.5
+// 	retract v1.5.6
 //
 // The verbs are
 // 	module, to define the module path;
 // 	go, to set the expected language version;
 // 	require, to require a particular module at a given version or later;
-// 	exclude, to exclude a particular module version from use; and
-// 	replace, to replace a module version with a different module version.
+// 	exclude, to exclude a particular module version from use;
+// 	replace, to replace a module version with a different module version; and
+// 	retract, to indicate a previously released version should not be used.
 // Exclude and replace apply only in the main module's go.mod and are ignored
 // in dependencies.  See https://research.swtch.com/vgo-mvs for details.
 //
+// A retract directive names a single version or a closed interval of
+// versions, written "[low, high]", that the module's author has withdrawn,
+// typically because it was published by mistake or has a severe problem.
+// The comment on the directive, if any, is the rationale shown to users:
+//
+// 	retract (
+// 		v1.0.0 // Published accidentally.
+// 		[v1.1.0, v1.1.3] // Breaks the Parse API.
+// 	)
+//
+// Retractions are read from the go.mod file of the latest version of the
+// module, which may itself be retracted; a module is not required to
+// retract anything in earlier go.mod files. Queries such as "latest" and
+// "upgrade" never select a retracted version, although a retracted version
+// can still be requested explicitly by its exact version. 'go get' warns
+// when it selects a retracted version, and 'go list -m -u' reports them.
+// Build commands such as 'go build' and 'go test' also warn about each
+// retracted version that provides packages to the build. Checking this
+// may access the network, and retractions that cannot be determined,
+// for example with GOPROXY=off or -mod=vendor, are not reported.
+//
 // The leading verb can be factored out of adjacent lines to create a block,
 // like in Go imports:
 //
@@ -3029,6 +3409,17 @@
 // 	-failfast
 // 	    Do not start new tests after the first test failure.
 //
+// 	-leakcheck
+// 	    Fail each test that leaves goroutines running after it and its
+// 	    subtests have completed. Goroutines that are about to exit are
+// 	    given a short grace period. Parallel tests are not checked
+// 	    individually; goroutines they leave behind are reported by the
+// 	    nearest enclosing test that is not parallel.
+//
+// 	-leakallow regexp
+// 	    With -leakcheck, ignore leaked goroutines whose stack trace
+// 	    matches the regular expression.
+//
 // 	-list regexp
 // 	    List tests, benchmarks, or examples matching the regular expression.
 // 	    No tests, benchmarks or examples will be run. This will only
@@ -3053,6 +3444,35 @@
 // 	    of all tests matching X, even those without sub-tests matching Y,
 // 	    because it must run them to look for those sub-tests.
 //
+// 	-shard i/n
+// 	    Run only shard i of n (counting from 0) of each package's tests
+// 	    and examples, so that n machines can each run one shard of the
+// 	    same 'go test' command. Top-level tests and examples are divided
+// 	    between the shards; subtests run in the shard of their parent.
+// 	    The division depends only on the names of the tests, so every
+// 	    test runs in exactly one shard. Test results are not cached when
+// 	    -shard is set.
+//
+// 	-shardtimes file
+// 	    With -shard, balance the shards using the running times of the
+// 	    tests listed in file, instead of dividing the tests by name.
+// 	    Each line of the file holds the import path of a package, the
+// 	    name of one of its tests or examples, and the test's running
+// 	    time, such as "encoding/json TestMarshal 1.5s". Tests missing
+// 	    from the file count as taking the average listed time of their
+// 	    package. For each test to run in exactly one shard, every shard
+// 	    must be given the same file. The -saveshardtimes flag writes
+// 	    such a file.
+//
+// 	-saveshardtimes file
+// 	    Record the running times of the tests in the build cache, as
+// 	    -shard does, and after the run write the times recorded for the
+// 	    tests of the listed packages to file, in the form read by
+// 	    -shardtimes. Each shard records the times of the tests it runs,
+// 	    so the files written by all the shards of a run can be
+// 	    concatenated into one -shardtimes file for later runs; when a
+// 	    test is listed more than once, its last time counts.
+//
 // 	-short
 // 	    Tell long-running tests to shorten their run time.
 // 	    It is off by default but set during all.bash so that installing
@@ -3064,6 +3484,11 @@
 // 	    If d is 0, the timeout is disabled.
 // 	    The default is 10 minutes (10m).
 //
+// 	-update
+// 	    Rewrite golden files with the current output of the tests,
+// 	    instead of comparing the output against them.
+// 	    See 'go doc testing/golden'.
+//
 // 	-v
 // 	    Verbose output: log all tests as they are run. Also print all
 // 	    text from Log and Logf calls even if the test succeeds.
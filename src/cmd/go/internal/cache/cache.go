@@ -32,8 +32,9 @@ type OutputID [HashSize]byte
 
 // A Cache is a package cache, backed by a file system directory tree.
 type Cache struct {
-	dir string
-	now func() time.Time
+	dir  string
+	now  func() time.Time
+	prog *progCache // external helper named by GOCACHEPROG, if any
 }
 
 // Open opens and returns the cache in the given directory.
@@ -140,7 +141,33 @@ func (c *Cache) Get(id ActionID) (Entry, error) {
 	if verify {
 		return Entry{}, &entryNotFoundError{Err: errVerifyMode}
 	}
-	return c.get(id)
+	entry, err := c.get(id)
+	if err != nil && c.prog != nil {
+		if e, perr := c.getProg(id); perr == nil {
+			return e, nil
+		}
+	}
+	return entry, err
+}
+
+// getProg looks up the action ID using the GOCACHEPROG helper and,
+// if it is found, copies the entry into the local cache.
+func (c *Cache) getProg(id ActionID) (Entry, error) {
+	out, data, t, err := c.prog.get(id)
+	if err != nil {
+		return Entry{}, err
+	}
+	size := int64(len(data))
+	if err := c.copyFile(bytes.NewReader(data), out, size); err != nil {
+		return Entry{}, err
+	}
+	if err := c.putIndexEntry(id, out, size, false); err != nil {
+		return Entry{}, err
+	}
+	if t.IsZero() {
+		t = c.now()
+	}
+	return Entry{out, size, t}, nil
 }
 
 type Entry struct {
@@ -430,7 +457,20 @@ func (c *Cache) put(id ActionID, file io.ReadSeeker, allowVerify bool) (OutputID
 	}
 
 	// Add to cache index.
-	return out, size, c.putIndexEntry(id, out, size, allowVerify)
+	if err := c.putIndexEntry(id, out, size, allowVerify); err != nil {
+		return out, size, err
+	}
+
+	// Share the entry with the GOCACHEPROG helper. Failing to do so is
+	// not an error: the entry is in the local cache.
+	if c.prog != nil {
+		if _, err := file.Seek(0, 0); err == nil {
+			if data, err := ioutil.ReadAll(file); err == nil && int64(len(data)) == size {
+				c.prog.put(id, out, data)
+			}
+		}
+	}
+	return out, size, nil
 }
 
 // PutBytes stores the given bytes in the cache as the output for the action ID.
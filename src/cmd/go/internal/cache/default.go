@@ -56,6 +56,9 @@ func initDefaultCache() {
 	if err != nil {
 		base.Fatalf("failed to initialize build cache at %s: %s\n", dir, err)
 	}
+	if prog := cfg.Getenv("GOCACHEPROG"); prog != "" {
+		c.prog = startProg(prog)
+	}
 	defaultCache = c
 }
 
@@ -0,0 +1,288 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"cmd/go/internal/base"
+	"cmd/go/internal/str"
+)
+
+// A progCache forwards the operations of a Cache to an external helper
+// program, named by the GOCACHEPROG environment variable, so that
+// cache entries can be shared between machines. The local cache
+// directory is still used: entries found by the helper are copied into
+// it, and entries stored locally are also sent to the helper.
+//
+// The go command and the helper exchange JSON values, one per line,
+// over the helper's standard input and output. The go command writes
+// progRequests; the helper writes one progResponse for each, with the
+// same ID, in any order. Before any request, the helper writes a
+// response with ID 0 listing the commands it supports in KnownCommands.
+// The commands are:
+//
+//	"get": look up ActionID. The response sets Miss if the helper has
+//	no entry; otherwise it sets OutputID, Size, Time, and DiskPath, the
+//	name of a local file holding the output, which the go command reads
+//	before its next request for the same ActionID.
+//
+//	"put": store the output with OutputID and BodySize bytes for
+//	ActionID. The request is followed by a line holding the body as a
+//	JSON string in base64 (the encoding/json encoding of a []byte),
+//	omitted when BodySize is zero.
+//
+//	"close": the go command is exiting. The helper should finish any
+//	pending work, respond, and exit.
+//
+// A response with a non-empty Err reports that the command failed.
+// The go command never fails a build because of the helper: if the
+// helper fails, the go command reports the problem once and uses
+// only the local cache from then on.
+type progCache struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+
+	writeMu sync.Mutex // serializes writes to stdin
+	bw      *bufio.Writer
+
+	mu       sync.Mutex
+	nextID   int64
+	inFlight map[int64]chan<- *progResponse
+	known    map[string]bool
+	err      error // first failure of the helper; no more requests are sent
+	closing  bool  // close has been called; the helper's exit is expected
+
+	readLoopDone chan struct{}
+}
+
+// A progRequest is a request from the go command to the helper.
+type progRequest struct {
+	ID       int64
+	Command  string
+	ActionID []byte `json:",omitempty"`
+	OutputID []byte `json:",omitempty"`
+	BodySize int64  `json:",omitempty"`
+}
+
+// A progResponse is a response from the helper to the go command.
+type progResponse struct {
+	ID            int64
+	Err           string     `json:",omitempty"`
+	KnownCommands []string   `json:",omitempty"`
+	Miss          bool       `json:",omitempty"`
+	OutputID      []byte     `json:",omitempty"`
+	Size          int64      `json:",omitempty"`
+	Time          *time.Time `json:",omitempty"`
+	DiskPath      string     `json:",omitempty"`
+}
+
+// startProg starts the helper program given by the GOCACHEPROG value
+// prog and waits for it to announce the commands it supports.
+func startProg(prog string) *progCache {
+	args, err := str.SplitQuotedFields(prog)
+	if err != nil {
+		base.Fatalf("go: invalid GOCACHEPROG: %v", err)
+	}
+	if len(args) == 0 {
+		base.Fatalf("go: invalid GOCACHEPROG: no program")
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stderr = os.Stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		base.Fatalf("go: GOCACHEPROG: %v", err)
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		base.Fatalf("go: GOCACHEPROG: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		base.Fatalf("go: starting GOCACHEPROG program %q: %v", args[0], err)
+	}
+
+	p := &progCache{
+		cmd:          cmd,
+		stdin:        stdin,
+		bw:           bufio.NewWriter(stdin),
+		inFlight:     make(map[int64]chan<- *progResponse),
+		known:        make(map[string]bool),
+		readLoopDone: make(chan struct{}),
+	}
+
+	dec := json.NewDecoder(bufio.NewReader(stdout))
+	var hello progResponse
+	if err := dec.Decode(&hello); err != nil {
+		cmd.Process.Kill()
+		base.Fatalf("go: GOCACHEPROG program %q did not announce its capabilities: %v", args[0], err)
+	}
+	if hello.ID != 0 || len(hello.KnownCommands) == 0 {
+		cmd.Process.Kill()
+		base.Fatalf("go: GOCACHEPROG program %q sent unexpected first response (want ID 0 with KnownCommands)", args[0])
+	}
+	for _, c := range hello.KnownCommands {
+		p.known[c] = true
+	}
+
+	go p.readLoop(dec)
+	base.AtExit(p.close)
+	return p
+}
+
+// readLoop delivers the helper's responses to the waiting requests.
+func (p *progCache) readLoop(dec *json.Decoder) {
+	defer close(p.readLoopDone)
+	for {
+		res := new(progResponse)
+		if err := dec.Decode(res); err != nil {
+			if err == io.EOF {
+				err = errors.New("helper exited")
+			}
+			p.fail(err)
+			return
+		}
+		p.mu.Lock()
+		ch, ok := p.inFlight[res.ID]
+		delete(p.inFlight, res.ID)
+		p.mu.Unlock()
+		if !ok {
+			p.fail(fmt.Errorf("response for unknown request ID %d", res.ID))
+			return
+		}
+		ch <- res
+	}
+}
+
+// fail records the first failure of the helper, reports it,
+// and abandons all pending requests.
+func (p *progCache) fail(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return
+	}
+	p.err = err
+	if p.closing {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "go: GOCACHEPROG helper failed: %v; using only the local build cache\n", err)
+	for id, ch := range p.inFlight {
+		delete(p.inFlight, id)
+		close(ch)
+	}
+}
+
+// send sends req, followed by body if req is a put, and
+// waits for the response.
+func (p *progCache) send(req *progRequest, body []byte) (*progResponse, error) {
+	ch := make(chan *progResponse, 1)
+	p.mu.Lock()
+	if p.err != nil {
+		p.mu.Unlock()
+		return nil, p.err
+	}
+	if !p.known[req.Command] {
+		p.mu.Unlock()
+		return nil, fmt.Errorf("helper does not support %q", req.Command)
+	}
+	p.nextID++
+	req.ID = p.nextID
+	p.inFlight[req.ID] = ch
+	p.mu.Unlock()
+
+	if err := p.write(req, body); err != nil {
+		p.fail(err)
+		return nil, err
+	}
+	res, ok := <-ch
+	if !ok {
+		p.mu.Lock()
+		err := p.err
+		p.mu.Unlock()
+		return nil, err
+	}
+	if res.Err != "" {
+		return nil, errors.New(res.Err)
+	}
+	return res, nil
+}
+
+func (p *progCache) write(req *progRequest, body []byte) error {
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	p.bw.Write(data)
+	p.bw.WriteByte('\n')
+	if req.Command == "put" && req.BodySize > 0 {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		p.bw.Write(data)
+		p.bw.WriteByte('\n')
+	}
+	return p.bw.Flush()
+}
+
+// get asks the helper for the output of the action id. On success,
+// it returns the output's ID and the contents of the helper's file.
+func (p *progCache) get(id ActionID) (OutputID, []byte, time.Time, error) {
+	res, err := p.send(&progRequest{Command: "get", ActionID: id[:]}, nil)
+	if err != nil {
+		return OutputID{}, nil, time.Time{}, err
+	}
+	if res.Miss {
+		return OutputID{}, nil, time.Time{}, errors.New("helper cache miss")
+	}
+	var out OutputID
+	if len(res.OutputID) != len(out) || res.DiskPath == "" {
+		return OutputID{}, nil, time.Time{}, errors.New("malformed helper response")
+	}
+	copy(out[:], res.OutputID)
+	data, err := ioutil.ReadFile(res.DiskPath)
+	if err != nil {
+		return OutputID{}, nil, time.Time{}, err
+	}
+	if int64(len(data)) != res.Size || sha256.Sum256(data) != out {
+		return OutputID{}, nil, time.Time{}, errors.New("helper returned corrupt output")
+	}
+	var t time.Time
+	if res.Time != nil {
+		t = *res.Time
+	}
+	return out, data, t, nil
+}
+
+// put sends the output data, with ID out, of the action id to the helper.
+func (p *progCache) put(id ActionID, out OutputID, data []byte) error {
+	_, err := p.send(&progRequest{Command: "put", ActionID: id[:], OutputID: out[:], BodySize: int64(len(data))}, data)
+	return err
+}
+
+// close asks the helper to exit and waits for it.
+func (p *progCache) close() {
+	p.mu.Lock()
+	known := p.known["close"] && p.err == nil
+	p.closing = true
+	p.mu.Unlock()
+	if known {
+		p.send(&progRequest{Command: "close"}, nil)
+	}
+	p.stdin.Close()
+	p.cmd.Wait()
+}
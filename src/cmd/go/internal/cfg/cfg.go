@@ -25,6 +25,7 @@ import (
 var (
 	BuildA                 bool   // -a flag
 	BuildBuildmode         string // -buildmode flag
+	BuildBuildvcs          = true // -buildvcs flag
 	BuildContext           = defaultContext()
 	BuildMod               string             // -mod flag
 	BuildModReason         string             // reason -mod flag is set, if set by default
@@ -34,6 +35,7 @@ var (
 	BuildN                 bool               // -n flag
 	BuildO                 string             // -o flag
 	BuildP                 = runtime.NumCPU() // -p flag
+	BuildPGO               string             // -pgo flag
 	BuildPkgdir            string             // -pkgdir flag
 	BuildRace              bool               // -race flag
 	BuildToolexec          []string           // -toolexec flag
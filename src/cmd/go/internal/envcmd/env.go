@@ -71,6 +71,7 @@ func MkEnv() []cfg.EnvVar {
 		{Name: "GOARCH", Value: cfg.Goarch},
 		{Name: "GOBIN", Value: cfg.GOBIN},
 		{Name: "GOCACHE", Value: cache.DefaultDir()},
+		{Name: "GOCACHEPROG", Value: cfg.Getenv("GOCACHEPROG")},
 		{Name: "GOENV", Value: envFile},
 		{Name: "GOEXE", Value: cfg.ExeSuffix},
 		{Name: "GOFLAGS", Value: cfg.Getenv("GOFLAGS")},
@@ -87,6 +88,7 @@ func MkEnv() []cfg.EnvVar {
 		{Name: "GOSUMDB", Value: cfg.GOSUMDB},
 		{Name: "GOTMPDIR", Value: cfg.Getenv("GOTMPDIR")},
 		{Name: "GOTOOLDIR", Value: base.ToolDir},
+		{Name: "GOWORK", Value: cfg.Getenv("GOWORK")},
 	}
 
 	if work.GccgoBin != "" {
@@ -493,6 +493,10 @@ General-purpose environment variables:
 	GOCACHE
 		The directory where the go command will store cached
 		information for reuse in future builds.
+	GOCACHEPROG
+		A command (with optional space-separated flags) that implements an
+		external build cache shared with the local one in GOCACHE.
+		See 'go help cache'.
 	GODEBUG
 		Enable various debugging facilities. See 'go doc runtime'
 		for details.
@@ -530,6 +534,10 @@ General-purpose environment variables:
 	GOTMPDIR
 		The directory where the go command will write
 		temporary source files, packages, and binaries.
+	GOWORK
+		The path of the go.work file to use, overriding the search
+		for go.work in the current directory and its parents.
+		If set to 'off', workspace mode is disabled. See 'go help work'.
 
 Environment variables for use with cgo:
 
@@ -760,5 +768,48 @@ The output is voluminous but can be useful for debugging the cache.
 
 GODEBUG=gocachetest=1 causes the go command to print details of its
 decisions about whether to reuse a cached test result.
+
+The GOCACHEPROG environment variable names a helper program, with
+optional space-separated arguments, that extends the build cache
+beyond the local directory, for example to share build outputs between
+the machines of a CI fleet through a network file system or an object
+store. The go command starts the helper once and forwards to it every
+lookup that misses in the local cache, copying found entries into
+GOCACHE, and every new cache entry. Failures of the helper never fail
+the build: the go command reports them and continues with the local
+cache alone.
+
+The go command and the helper exchange JSON objects, one per line, over
+the helper's standard input and output. The helper first writes
+
+	{"ID": 0, "KnownCommands": ["get", "put", "close"]}
+
+listing the commands it implements. The go command then writes requests
+
+	type Request struct {
+		ID       int64  // unique request ID, starting at 1
+		Command  string // "get", "put", or "close"
+		ActionID []byte // cache key (get and put)
+		OutputID []byte // SHA-256 hash of the output (put)
+		BodySize int64  // size of the output (put)
+	}
+
+and the helper writes, in any order, one response for each:
+
+	type Response struct {
+		ID       int64      // ID of the request
+		Err      string     // error, if the command failed
+		Miss     bool       // get: no entry for ActionID
+		OutputID []byte     // get: hash of the output
+		Size     int64      // get: size of the output
+		Time     *time.Time // get: when the entry was stored, if known
+		DiskPath string     // get: local file holding the output
+	}
+
+[]byte values are encoded in base64, as by encoding/json. A put request
+with a non-zero BodySize is followed by a line holding the output as a
+JSON base64 string. The go command reads the file at DiskPath right
+after receiving a get response. A close request is sent when the go
+command exits; the helper should respond and then exit.
 `,
 }
@@ -84,6 +84,14 @@ to -f '{{.ImportPath}}'. The struct being passed to the template is:
         TestGoFiles     []string // _test.go files in package
         XTestGoFiles    []string // _test.go files outside package
 
+        // Embedded files
+        EmbedPatterns      []string // //go:embed patterns
+        EmbedFiles         []string // files matched by EmbedPatterns
+        TestEmbedPatterns  []string // //go:embed patterns in TestGoFiles
+        TestEmbedFiles     []string // files matched by TestEmbedPatterns
+        XTestEmbedPatterns []string // //go:embed patterns in XTestGoFiles
+        XTestEmbedFiles    []string // files matched by XTestEmbedPatterns
+
         // Cgo directives
         CgoCFLAGS    []string // cgo: flags for C compiler
         CgoCPPFLAGS  []string // cgo: flags for C preprocessor
@@ -213,6 +221,7 @@ applied to a Go struct, but now a Module struct:
         Dir       string       // directory holding files for this module, if any
         GoMod     string       // path to go.mod file used when loading this module, if any
         GoVersion string       // go version used in module
+        Retracted []string     // retraction rationale, if any (with -u)
         Error     *ModuleError // error loading module
     }
 
@@ -253,11 +262,18 @@ For example, 'go list -m -u all' might print:
     golang.org/x/text v0.3.0 [v0.4.0] => /tmp/text
     rsc.io/pdf v0.1.1 [v0.1.2]
 
+The -u flag also reports versions that have been retracted by their
+module's author (see 'go help go.mod'): list -u sets the Module's
+Retracted field to the author's rationale, and the String method
+appends "(retracted)" to the version. Upgrades never suggest a
+retracted version.
+
 (For tools, 'go list -m -u -json all' may be more convenient to parse.)
 
 The -versions flag causes list to set the Module's Versions field
 to a list of all known versions of that module, ordered according
-to semantic versioning, earliest to latest. The flag also changes
+to semantic versioning, earliest to latest. Retracted versions
+are omitted. The flag also changes
 the default output format to display the module path followed by the
 space-separated version list.
 
@@ -30,6 +30,7 @@ type PerPackageFlag struct {
 type ppfValue struct {
 	match func(*Package) bool // compiled pattern
 	flags []string
+	raw   string // value as given on the command line
 }
 
 // Set is called each time the flag is encountered on the command line.
@@ -40,13 +41,14 @@ func (f *PerPackageFlag) Set(v string) error {
 // set is the implementation of Set, taking a cwd (current working directory) for easier testing.
 func (f *PerPackageFlag) set(v, cwd string) error {
 	f.present = true
+	raw := v
 	match := func(p *Package) bool { return p.Internal.CmdlinePkg || p.Internal.CmdlineFiles } // default predicate with no pattern
 	// For backwards compatibility with earlier flag splitting, ignore spaces around flags.
 	v = strings.TrimSpace(v)
 	if v == "" {
 		// Special case: -gcflags="" means no flags for command-line arguments
 		// (overrides previous -gcflags="-whatever").
-		f.values = append(f.values, ppfValue{match, []string{}})
+		f.values = append(f.values, ppfValue{match, []string{}, raw})
 		return nil
 	}
 	if !strings.HasPrefix(v, "-") {
@@ -68,7 +70,7 @@ func (f *PerPackageFlag) set(v, cwd string) error {
 	if flags == nil {
 		flags = []string{}
 	}
-	f.values = append(f.values, ppfValue{match, flags})
+	f.values = append(f.values, ppfValue{match, flags, raw})
 	return nil
 }
 
@@ -81,6 +83,16 @@ func (f *PerPackageFlag) Present() bool {
 	return f.present
 }
 
+// Values returns the values of the flag as given on the command line,
+// including any package patterns, in order.
+func (f *PerPackageFlag) Values() []string {
+	var list []string
+	for _, v := range f.values {
+		list = append(list, v.raw)
+	}
+	return list
+}
+
 // For returns the flags to use for the given package.
 func (f *PerPackageFlag) For(p *Package) []string {
 	flags := []string{}
@@ -30,6 +30,8 @@ import (
 	"cmd/go/internal/par"
 	"cmd/go/internal/search"
 	"cmd/go/internal/str"
+
+	"golang.org/x/mod/module"
 )
 
 var (
@@ -45,6 +47,7 @@ var (
 	ModInfoProg          func(info string, isgccgo bool) []byte                                                   // wrap module info in .go code for binary
 	ModImportFromFiles   func([]string)                                                                           // update go.mod to add modules for imports in these files
 	ModDirImportPath     func(string) string                                                                      // return effective import path for directory
+	ModWarnRetracted     func([]module.Version)                                                                   // warn about retracted module versions in a build
 )
 
 var IgnoreImports bool // control whether we ignore imports in packages
@@ -101,6 +104,10 @@ type PackagePublic struct {
 	SwigCXXFiles    []string `json:",omitempty"` // .swigcxx files
 	SysoFiles       []string `json:",omitempty"` // .syso system object files added to package
 
+	// Embedded files
+	EmbedPatterns []string `json:",omitempty"` // //go:embed patterns
+	EmbedFiles    []string `json:",omitempty"` // files matched by EmbedPatterns
+
 	// Cgo directives
 	CgoCFLAGS    []string `json:",omitempty"` // cgo: flags for C compiler
 	CgoCPPFLAGS  []string `json:",omitempty"` // cgo: flags for C preprocessor
@@ -122,10 +129,14 @@ type PackagePublic struct {
 	// Test information
 	// If you add to this list you MUST add to p.AllFiles (below) too.
 	// Otherwise file name security lists will not apply to any new additions.
-	TestGoFiles  []string `json:",omitempty"` // _test.go files in package
-	TestImports  []string `json:",omitempty"` // imports from TestGoFiles
-	XTestGoFiles []string `json:",omitempty"` // _test.go files outside package
-	XTestImports []string `json:",omitempty"` // imports from XTestGoFiles
+	TestGoFiles        []string `json:",omitempty"` // _test.go files in package
+	TestImports        []string `json:",omitempty"` // imports from TestGoFiles
+	TestEmbedPatterns  []string `json:",omitempty"` // //go:embed patterns
+	TestEmbedFiles     []string `json:",omitempty"` // files matched by TestEmbedPatterns
+	XTestGoFiles       []string `json:",omitempty"` // _test.go files outside package
+	XTestImports       []string `json:",omitempty"` // imports from XTestGoFiles
+	XTestEmbedPatterns []string `json:",omitempty"` // //go:embed patterns
+	XTestEmbedFiles    []string `json:",omitempty"` // files matched by XTestEmbedPatterns
 }
 
 // AllFiles returns the names of all the files considered for the package.
@@ -179,7 +190,9 @@ type PackageInternal struct {
 	OmitDebug         bool                 // tell linker not to write debug information
 	GobinSubdir       bool                 // install target would be subdir of GOBIN
 	BuildInfo         string               // add this info to package main
+	BuildInfoStamped  bool                 // build settings have been added to BuildInfo
 	TestmainGo        *[]byte              // content for _testmain.go
+	Embed             map[string][]string  // //go:embed comment mapping
 
 	Asmflags   []string // -asmflags for this package
 	Gcflags    []string // -gcflags for this package
@@ -294,6 +307,9 @@ func (p *Package) copyBuild(pp *build.Package) {
 	p.TestImports = pp.TestImports
 	p.XTestGoFiles = pp.XTestGoFiles
 	p.XTestImports = pp.XTestImports
+	p.EmbedPatterns = pp.EmbedPatterns
+	p.TestEmbedPatterns = pp.TestEmbedPatterns
+	p.XTestEmbedPatterns = pp.XTestEmbedPatterns
 	if IgnoreImports {
 		p.Imports = nil
 		p.Internal.RawImports = nil
@@ -1769,6 +1785,19 @@ func (p *Package) load(stk *ImportStack, bp *build.Package, err error) {
 		return
 	}
 
+	// Find the files matched by //go:embed patterns. The files are
+	// hashed into the build cache key by the build action.
+	// Errors in the test patterns are reported for the test packages.
+	if p.EmbedFiles, p.Internal.Embed, err = p.resolveEmbed(p.EmbedPatterns); err != nil {
+		if p.Error == nil {
+			p.Error = embedPackageError(err, p.Internal.Build.EmbedPatternPos)
+			p.Error.ImportStack = stk.Copy()
+		}
+		return
+	}
+	p.TestEmbedFiles, _, _ = p.resolveEmbed(p.TestEmbedPatterns)
+	p.XTestEmbedFiles, _, _ = p.resolveEmbed(p.XTestEmbedPatterns)
+
 	if cfg.ModulesEnabled && p.Error == nil {
 		mainPath := p.ImportPath
 		if p.Internal.CmdlineFiles {
@@ -1781,6 +1810,207 @@ func (p *Package) load(stk *ImportStack, bp *build.Package, err error) {
 	}
 }
 
+// An EmbedError indicates a problem with a //go:embed pattern.
+type EmbedError struct {
+	Pattern string
+	Err     error
+}
+
+func (e *EmbedError) Error() string {
+	return fmt.Sprintf("pattern %s: %v", e.Pattern, e.Err)
+}
+
+func (e *EmbedError) Unwrap() error {
+	return e.Err
+}
+
+// embedPackageError returns a PackageError for err, an error resolving
+// //go:embed patterns, positioned at the offending pattern.
+func embedPackageError(err error, patternPos map[string][]token.Position) *PackageError {
+	perr := &PackageError{Err: err}
+	if e, ok := err.(*EmbedError); ok {
+		if pos := patternPos[e.Pattern]; len(pos) > 0 {
+			p := pos[0]
+			p.Filename = base.ShortPath(p.Filename)
+			perr.Pos = p.String()
+		}
+	}
+	return perr
+}
+
+// resolveEmbed resolves //go:embed patterns and returns the sorted
+// list of files matched by any pattern, along with a map from each
+// pattern to the files it matches. All file names are slash-separated
+// and relative to the package directory.
+//
+// A pattern naming a directory matches all the files in the subtree
+// rooted at that directory, except files and directories with names
+// beginning with '.' or '_', and subdirectories in other modules.
+func (p *Package) resolveEmbed(patterns []string) (files []string, pmap map[string][]string, err error) {
+	if len(patterns) == 0 {
+		return nil, nil, nil
+	}
+	pkgdir := p.Dir
+	pmap = make(map[string][]string)
+	have := make(map[string]int)
+	dirOK := make(map[string]bool)
+	pid := 0 // pattern ID, to allow reuse of have map
+	for _, pattern := range patterns {
+		pid++
+
+		if !validEmbedPattern(pattern) {
+			return nil, nil, &EmbedError{pattern, errors.New("invalid pattern syntax")}
+		}
+		match, err := filepath.Glob(quoteGlob(pkgdir) + string(filepath.Separator) + filepath.FromSlash(pattern))
+		if err != nil {
+			return nil, nil, &EmbedError{pattern, err}
+		}
+
+		// Filter list of matches down to the ones that will still exist
+		// when the package is built: no files in other modules, and no
+		// version control metadata.
+		var list []string
+		for _, file := range match {
+			rel := filepath.ToSlash(file[len(pkgdir)+1:])
+
+			// Check that directories along path do not begin a new module
+			// (do not contain a go.mod).
+			for dir := file; len(dir) > len(pkgdir)+1 && !dirOK[dir]; dir = filepath.Dir(dir) {
+				if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+					return nil, nil, &EmbedError{pattern, fmt.Errorf("cannot embed %s %s: in different module", what(file), rel)}
+				}
+				dirOK[dir] = true
+			}
+			if err := isBadEmbedName(rel); err != nil {
+				return nil, nil, &EmbedError{pattern, fmt.Errorf("cannot embed %s %s: %v", what(file), rel, err)}
+			}
+
+			info, err := os.Lstat(file)
+			if err != nil {
+				return nil, nil, &EmbedError{pattern, err}
+			}
+			switch {
+			default:
+				return nil, nil, &EmbedError{pattern, fmt.Errorf("cannot embed irregular file %s", rel)}
+
+			case info.Mode().IsRegular():
+				if have[rel] != pid {
+					have[rel] = pid
+					list = append(list, rel)
+				}
+
+			case info.IsDir():
+				// Gather all files in the named directory, stopping at module boundaries
+				// and ignoring files that wouldn't be packaged into a module.
+				count := 0
+				err := filepath.Walk(file, func(path string, info os.FileInfo, err error) error {
+					if err != nil {
+						return err
+					}
+					rel := filepath.ToSlash(path[len(pkgdir)+1:])
+					name := info.Name()
+					if path != file && (isBadEmbedName(name) != nil || name[0] == '.' || name[0] == '_') {
+						// Ignore bad names, assuming they won't go into modules.
+						// Also avoid hidden files that user may not know about.
+						if info.IsDir() {
+							return filepath.SkipDir
+						}
+						return nil
+					}
+					if info.IsDir() {
+						if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+							return filepath.SkipDir
+						}
+						return nil
+					}
+					if !info.Mode().IsRegular() {
+						return fmt.Errorf("cannot embed irregular file %s", rel)
+					}
+					count++
+					if have[rel] != pid {
+						have[rel] = pid
+						list = append(list, rel)
+					}
+					return nil
+				})
+				if err != nil {
+					return nil, nil, &EmbedError{pattern, err}
+				}
+				if count == 0 {
+					return nil, nil, &EmbedError{pattern, fmt.Errorf("cannot embed directory %s: contains no embeddable files", rel)}
+				}
+			}
+		}
+
+		if len(list) == 0 {
+			return nil, nil, &EmbedError{pattern, errors.New("no matching files found")}
+		}
+		sort.Strings(list)
+		pmap[pattern] = list
+	}
+
+	for file := range have {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files, pmap, nil
+}
+
+// validEmbedPattern reports whether pattern is a valid //go:embed
+// pattern: a valid path.Match pattern naming a slash-separated,
+// unrooted path with no empty, "." or ".." elements.
+func validEmbedPattern(pattern string) bool {
+	if _, err := pathpkg.Match(pattern, ""); err != nil {
+		return false
+	}
+	for _, elem := range strings.Split(pattern, "/") {
+		if elem == "" || elem == "." || elem == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// quoteGlob returns s with all glob metacharacters escaped,
+// so that it matches only itself.
+func quoteGlob(s string) string {
+	if !strings.ContainsAny(s, `*?[\`) || filepath.Separator == '\\' {
+		return s
+	}
+	var b strings.Builder
+	for _, c := range s {
+		switch c {
+		case '*', '?', '[', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// isBadEmbedName reports whether name is the base name of a file that
+// can't or won't be included in modules and therefore shouldn't be
+// treated as existing for embedding.
+func isBadEmbedName(name string) error {
+	switch name {
+	// Version control directories won't be present in module.
+	case ".bzr", ".hg", ".git", ".svn":
+		return errors.New("version control metadata")
+	}
+	if err := module.CheckFilePath(name); err != nil {
+		return err
+	}
+	return nil
+}
+
+// what returns "file" or "directory" depending on what file is.
+func what(file string) string {
+	if info, err := os.Stat(file); err == nil && info.IsDir() {
+		return "directory"
+	}
+	return "file"
+}
+
 // collectDeps populates p.Deps and p.DepsErrors by iterating over
 // p.Internal.Imports.
 //
@@ -2156,6 +2386,22 @@ func PackagesForBuild(args []string) []*Package {
 	}
 	base.ExitIfErrors()
 
+	if cfg.ModulesEnabled {
+		var mods []module.Version
+		seenMod := map[module.Version]bool{}
+		for _, pkg := range PackageList(pkgs) {
+			if pkg.Module == nil || pkg.Module.Version == "" {
+				continue
+			}
+			m := module.Version{Path: pkg.Module.Path, Version: pkg.Module.Version}
+			if !seenMod[m] {
+				seenMod[m] = true
+				mods = append(mods, m)
+			}
+		}
+		ModWarnRetracted(mods)
+	}
+
 	return pkgs
 }
 
@@ -132,6 +132,24 @@ func TestPackagesAndErrors(p *Package, cover *TestCover) (pmain, ptest, pxtest *
 	}
 	stk.Pop()
 
+	// The files matched by the test patterns were checked when
+	// loading p; only the pattern mappings are needed here.
+	var testEmbed, xtestEmbed map[string][]string
+	if len(p.TestEmbedPatterns) > 0 {
+		var err error
+		_, testEmbed, err = p.resolveEmbed(p.TestEmbedPatterns)
+		if err != nil && ptestErr == nil {
+			ptestErr = embedPackageError(err, p.Internal.Build.TestEmbedPatternPos)
+		}
+	}
+	if len(p.XTestEmbedPatterns) > 0 {
+		var err error
+		_, xtestEmbed, err = p.resolveEmbed(p.XTestEmbedPatterns)
+		if err != nil && pxtestErr == nil {
+			pxtestErr = embedPackageError(err, p.Internal.Build.XTestEmbedPatternPos)
+		}
+	}
+
 	// Test package.
 	if len(p.TestGoFiles) > 0 || p.Name == "main" || cover != nil && cover.Local {
 		ptest = new(Package)
@@ -169,6 +187,17 @@ func TestPackagesAndErrors(p *Package, cover *TestCover) (pmain, ptest, pxtest *
 			m[k] = append(m[k], v...)
 		}
 		ptest.Internal.Build.ImportPos = m
+		if testEmbed != nil {
+			ptest.EmbedPatterns = str.StringList(p.EmbedPatterns, p.TestEmbedPatterns)
+			ptest.EmbedFiles = str.StringList(p.EmbedFiles, p.TestEmbedFiles)
+			ptest.Internal.Embed = make(map[string][]string)
+			for k, v := range p.Internal.Embed {
+				ptest.Internal.Embed[k] = v
+			}
+			for k, v := range testEmbed {
+				ptest.Internal.Embed[k] = v
+			}
+		}
 		ptest.collectDeps()
 	} else {
 		ptest = p
@@ -178,15 +207,17 @@ func TestPackagesAndErrors(p *Package, cover *TestCover) (pmain, ptest, pxtest *
 	if len(p.XTestGoFiles) > 0 {
 		pxtest = &Package{
 			PackagePublic: PackagePublic{
-				Name:       p.Name + "_test",
-				ImportPath: p.ImportPath + "_test",
-				Root:       p.Root,
-				Dir:        p.Dir,
-				Goroot:     p.Goroot,
-				GoFiles:    p.XTestGoFiles,
-				Imports:    p.XTestImports,
-				ForTest:    p.ImportPath,
-				Error:      pxtestErr,
+				Name:          p.Name + "_test",
+				ImportPath:    p.ImportPath + "_test",
+				Root:          p.Root,
+				Dir:           p.Dir,
+				Goroot:        p.Goroot,
+				GoFiles:       p.XTestGoFiles,
+				Imports:       p.XTestImports,
+				ForTest:       p.ImportPath,
+				Error:         pxtestErr,
+				EmbedPatterns: p.XTestEmbedPatterns,
+				EmbedFiles:    p.XTestEmbedFiles,
 			},
 			Internal: PackageInternal{
 				LocalPrefix: p.Internal.LocalPrefix,
@@ -195,6 +226,7 @@ func TestPackagesAndErrors(p *Package, cover *TestCover) (pmain, ptest, pxtest *
 				},
 				Imports:    ximports,
 				RawImports: rawXTestImports,
+				Embed:      xtestEmbed,
 
 				Asmflags:   p.Internal.Asmflags,
 				Gcflags:    p.Internal.Gcflags,
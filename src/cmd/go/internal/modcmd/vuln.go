@@ -0,0 +1,353 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modcmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"cmd/go/internal/base"
+	"cmd/go/internal/load"
+	"cmd/go/internal/modload"
+	"cmd/go/internal/str"
+	"cmd/go/internal/vulncheck"
+	"cmd/go/internal/work"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+var cmdVuln = &base.Command{
+	UsageLine: "go mod vuln [-db dir] [-json] [-m] [packages]",
+	Short:     "report known vulnerabilities that affect the build",
+	Long: `
+Vuln reports the known vulnerabilities, as listed in a local database,
+that affect the named packages. With no arguments, it checks the
+packages of the main module.
+
+A vulnerability affects the build only if a module providing packages
+to the build is at an affected version and, when the database lists
+the affected packages and functions, one of those packages is imported
+and one of those functions may be called from the named packages.
+Calls are found by a conservative analysis of the source code: a call
+to a method M through an interface is assumed to reach every method
+named M, so vuln may report vulnerabilities that cannot actually be
+reached, but it does not miss calls made by Go code. Calls made by
+reflection, assembly, or code linked by cgo are not seen.
+
+For commands, the analysis starts at main.main; for other packages, at
+each exported function and method. Init functions of all imported
+packages are always included.
+
+The -m flag reports every vulnerability affecting a module in the
+module graph ('go list -m all'), whether or not the build uses the
+affected code.
+
+The -db flag gives the directory holding the vulnerability database.
+It defaults to the value of the GOVULNDB environment variable.
+Every file with a .json extension in the directory or its subdirectories
+holds one entry, in the format described by these Go types:
+
+	type Entry struct {
+		ID       string     // unique identifier, such as "GO-2020-0001"
+		Aliases  []string   // other identifiers, such as CVE numbers
+		Summary  string     // one-line description
+		Details  string     // longer description
+		Affected []Affected // affected modules
+	}
+
+	type Affected struct {
+		Module   string    // module path, or "std" for the standard library
+		Ranges   []Range   // affected versions; all versions if empty
+		Packages []Package // affected packages; all packages if empty
+	}
+
+	type Range struct {
+		Introduced string // first affected version; all earlier versions if empty
+		Fixed      string // first fixed version; no fix if empty
+	}
+
+	type Package struct {
+		Path    string   // import path
+		Symbols []string // affected "Func" or "Type.Method"; all if empty
+	}
+
+Versions are semantic versions. For the standard library, Go release
+go1.X.Y is version v1.X.Y.
+
+By default, vuln prints each vulnerability with the affected module
+and, when known, the affected package and a chain of calls reaching
+the vulnerable function. The -json flag prints each finding as a JSON
+object instead, in the format described by these Go types:
+
+	type Finding struct {
+		ID      string   // vulnerability identifier
+		Aliases []string // other identifiers
+		Summary string   // one-line description
+		Module  string   // affected module path
+		Version string   // affected module version
+		Fixed   string   // earliest fixed version, if any
+		Package string   // affected package, if known
+		Symbol  string   // affected function reached, if known
+		Stack   []string // calls reaching Symbol, starting at a root
+	}
+
+Vuln exits with a non-zero status if it reports any vulnerability.
+	`,
+}
+
+var (
+	vulnDB   = cmdVuln.Flag.String("db", os.Getenv("GOVULNDB"), "")
+	vulnJSON = cmdVuln.Flag.Bool("json", false, "")
+	vulnM    = cmdVuln.Flag.Bool("m", false, "")
+)
+
+func init() {
+	cmdVuln.Run = runVuln // break init cycle
+	work.AddBuildFlags(cmdVuln, work.DefaultBuildFlags)
+}
+
+// A vulnFinding is one vulnerability affecting the build,
+// as printed by 'go mod vuln -json'.
+type vulnFinding struct {
+	ID      string
+	Aliases []string `json:",omitempty"`
+	Summary string   `json:",omitempty"`
+	Module  string
+	Version string
+	Fixed   string   `json:",omitempty"`
+	Package string   `json:",omitempty"`
+	Symbol  string   `json:",omitempty"`
+	Stack   []string `json:",omitempty"`
+}
+
+func runVuln(cmd *base.Command, args []string) {
+	if *vulnDB == "" {
+		base.Fatalf("go mod vuln: no vulnerability database: use -db or set GOVULNDB")
+	}
+	entries, err := vulncheck.LoadDB(*vulnDB)
+	if err != nil {
+		base.Fatalf("go mod vuln: reading database: %v", err)
+	}
+
+	var findings []*vulnFinding
+	if *vulnM {
+		if len(args) > 0 {
+			base.Fatalf("go mod vuln: -m does not accept package arguments")
+		}
+		mods := modload.LoadBuildList()
+		mods = mods[:len(mods):len(mods)] // copy on append
+		if v := goVersionToSemver(runtime.Version()); v != "" {
+			mods = append(mods, module.Version{Path: "std", Version: v})
+		}
+		for _, e := range entries {
+			for _, m := range e.AffectedModules(mods) {
+				findings = append(findings, newVulnFinding(e, m))
+			}
+		}
+	} else {
+		findings = vulnPackages(entries, args)
+	}
+
+	for i, f := range findings {
+		if *vulnJSON {
+			data, err := json.MarshalIndent(f, "", "\t")
+			if err != nil {
+				base.Fatalf("go mod vuln: %v", err)
+			}
+			os.Stdout.Write(append(data, '\n'))
+			continue
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("%s: %s\n", f.ID, f.Summary)
+		if len(f.Aliases) > 0 {
+			fmt.Printf("\taliases: %s\n", strings.Join(f.Aliases, ", "))
+		}
+		fixed := "no fix available"
+		if f.Fixed != "" {
+			fixed = "fixed in " + f.Fixed
+		}
+		fmt.Printf("\tmodule: %s@%s (%s)\n", f.Module, f.Version, fixed)
+		if f.Package != "" {
+			fmt.Printf("\tpackage: %s\n", f.Package)
+		}
+		if len(f.Stack) > 0 {
+			fmt.Printf("\tcall stack:\n")
+			for _, s := range f.Stack {
+				fmt.Printf("\t\t%s\n", s)
+			}
+		}
+	}
+	if len(findings) > 0 {
+		base.SetExitStatus(1)
+	}
+}
+
+// vulnPackages returns the vulnerabilities among entries
+// affecting the packages matched by patterns.
+func vulnPackages(entries []*vulncheck.Entry, patterns []string) []*vulnFinding {
+	if len(patterns) == 0 {
+		modload.LoadBuildList()
+		patterns = []string{modload.Target.Path + "/..."}
+	}
+	pkgs := load.PackagesForBuild(patterns)
+	all := load.PackageList(pkgs)
+
+	// Record the module of each package in the build.
+	var mods []module.Version
+	modOf := make(map[string]module.Version)
+	haveMod := make(map[module.Version]bool)
+	stdVersion := goVersionToSemver(runtime.Version())
+	for _, p := range all {
+		var m module.Version
+		switch {
+		case p.Standard:
+			m = module.Version{Path: "std", Version: stdVersion}
+			if strings.HasPrefix(p.ImportPath, "cmd/") {
+				m.Path = "cmd"
+			}
+		case p.Module != nil && p.Module.Replace != nil:
+			m = module.Version{Path: p.Module.Replace.Path, Version: p.Module.Replace.Version}
+		case p.Module != nil:
+			m = module.Version{Path: p.Module.Path, Version: p.Module.Version}
+		default:
+			continue
+		}
+		modOf[p.ImportPath] = m
+		if !haveMod[m] {
+			haveMod[m] = true
+			mods = append(mods, m)
+		}
+	}
+
+	// Parse only if some entry names vulnerable functions in the build.
+	var reached map[vulncheck.Func]vulncheck.Func
+	needGraph := false
+	for _, e := range entries {
+		for _, m := range e.AffectedModules(mods) {
+			for _, ap := range m.Affected.Packages {
+				if _, ok := modOf[ap.Path]; ok && len(ap.Symbols) > 0 {
+					needGraph = true
+				}
+			}
+		}
+	}
+	if needGraph {
+		srcs := make(map[*load.Package]*vulncheck.Source)
+		fset := token.NewFileSet()
+		for _, p := range all {
+			srcs[p] = parseVulnSource(fset, p)
+		}
+		var roots, deps, list []*vulncheck.Source
+		for _, p := range pkgs {
+			roots = append(roots, srcs[p])
+		}
+		for _, p := range all {
+			deps = append(deps, srcs[p])
+			list = append(list, srcs[p])
+		}
+		g := vulncheck.NewGraph(list)
+		reached = g.Reachable(vulncheck.Roots(roots, deps))
+	}
+
+	var findings []*vulnFinding
+	for _, e := range entries {
+		for _, m := range e.AffectedModules(mods) {
+			if len(m.Affected.Packages) == 0 {
+				findings = append(findings, newVulnFinding(e, m))
+				continue
+			}
+			for _, ap := range m.Affected.Packages {
+				if pm, ok := modOf[ap.Path]; !ok || pm != m.Module {
+					continue
+				}
+				if len(ap.Symbols) == 0 {
+					f := newVulnFinding(e, m)
+					f.Package = ap.Path
+					findings = append(findings, f)
+					continue
+				}
+				// Report the reachable symbol with the shortest call stack.
+				var best []vulncheck.Func
+				var bestSym string
+				for _, sym := range ap.Symbols {
+					fn := vulncheck.Func{Pkg: ap.Path, Name: sym}
+					if _, ok := reached[fn]; !ok {
+						continue
+					}
+					stack := vulncheck.Stack(reached, fn)
+					if best == nil || len(stack) < len(best) {
+						best, bestSym = stack, sym
+					}
+				}
+				if best == nil {
+					continue
+				}
+				f := newVulnFinding(e, m)
+				f.Package = ap.Path
+				f.Symbol = bestSym
+				for _, fn := range best {
+					f.Stack = append(f.Stack, fn.String())
+				}
+				findings = append(findings, f)
+			}
+		}
+	}
+	return findings
+}
+
+func newVulnFinding(e *vulncheck.Entry, m vulncheck.ModuleMatch) *vulnFinding {
+	return &vulnFinding{
+		ID:      e.ID,
+		Aliases: e.Aliases,
+		Summary: e.Summary,
+		Module:  m.Module.Path,
+		Version: m.Module.Version,
+		Fixed:   m.Affected.FixedIn(m.Module.Version),
+	}
+}
+
+// parseVulnSource parses the non-test Go files of p.
+// Files that fail to parse are skipped: the build reports them.
+func parseVulnSource(fset *token.FileSet, p *load.Package) *vulncheck.Source {
+	src := &vulncheck.Source{
+		Path:    p.ImportPath,
+		Imports: p.ImportMap,
+		Names:   make(map[string]string),
+	}
+	for _, imp := range p.Internal.Imports {
+		src.Names[imp.ImportPath] = imp.Name
+	}
+	for _, name := range str.StringList(p.GoFiles, p.CgoFiles) {
+		f, err := parser.ParseFile(fset, filepath.Join(p.Dir, name), nil, 0)
+		if err != nil {
+			continue
+		}
+		src.Files = append(src.Files, f)
+	}
+	return src
+}
+
+// goVersionToSemver converts a Go release version like "go1.14.13"
+// to the semantic version "v1.14.13", or returns "" for development
+// versions.
+func goVersionToSemver(v string) string {
+	if !strings.HasPrefix(v, "go") {
+		return ""
+	}
+	v = "v" + v[len("go"):]
+	if !semver.IsValid(v) {
+		return ""
+	}
+	return semver.Canonical(v)
+}
@@ -10,13 +10,14 @@ import (
 
 	"cmd/go/internal/base"
 	"cmd/go/internal/modload"
+	"cmd/go/internal/mvs"
 	"cmd/go/internal/work"
 
 	"golang.org/x/mod/module"
 )
 
 var cmdWhy = &base.Command{
-	UsageLine: "go mod why [-m] [-vendor] packages...",
+	UsageLine: "go mod why [-m] [-mvs] [-vendor] packages...",
 	Short:     "explain why packages or modules are needed",
 	Long: `
 Why shows a shortest path in the import graph from the main module to
@@ -47,11 +48,32 @@ For example:
 	# golang.org/x/text/encoding
 	(main module does not need package golang.org/x/text/encoding)
 	$
+
+The -mvs flag causes why to explain the module versions selected by
+minimal version selection instead of the import graph. The arguments
+are modules, as with -m; with no arguments, why explains every module
+in the build list. For each module, why shows a shortest path in the
+module requirement graph (the one printed by 'go mod graph') from the
+main module to the module's selected version, one module per line.
+The last requirement on the path is one that forces that version to be
+selected. For example:
+
+	$ go mod why -mvs golang.org/x/text
+	# golang.org/x/text v0.0.0-20170915032832-14c0d48ead0c
+	example.com/hello
+	rsc.io/quote v1.5.2
+	rsc.io/sampler v1.3.0
+	golang.org/x/text v0.0.0-20170915032832-14c0d48ead0c
+	$
+
+To see how the build list would change if a requirement were
+dropped or changed, use 'go get -diff'.
 	`,
 }
 
 var (
 	whyM      = cmdWhy.Flag.Bool("m", false, "")
+	whyMVS    = cmdWhy.Flag.Bool("mvs", false, "")
 	whyVendor = cmdWhy.Flag.Bool("vendor", false, "")
 )
 
@@ -65,6 +87,13 @@ func runWhy(cmd *base.Command, args []string) {
 	if *whyVendor {
 		loadALL = modload.LoadVendor
 	}
+	if *whyMVS {
+		if *whyVendor {
+			base.Fatalf("go mod why: -mvs cannot be used with -vendor")
+		}
+		whyVersions(args)
+		return
+	}
 	if *whyM {
 		listU := false
 		listVersions := false
@@ -123,3 +152,52 @@ func runWhy(cmd *base.Command, args []string) {
 		}
 	}
 }
+
+// whyVersions prints, for each module named by args, the chain of
+// requirements that forces its selected version.
+func whyVersions(args []string) {
+	for _, arg := range args {
+		if strings.Contains(arg, "@") {
+			base.Fatalf("go mod why: module query not allowed")
+		}
+	}
+	all := len(args) == 0
+	if all {
+		args = []string{"all"}
+	}
+	var mods []module.Version
+	for _, m := range modload.ListModules(args, false, false) {
+		if m.Error != nil {
+			base.Errorf("go mod why: %v", m.Error.Err)
+			continue
+		}
+		mv := module.Version{Path: m.Path, Version: m.Version}
+		if all && mv == modload.Target {
+			continue
+		}
+		mods = append(mods, mv)
+	}
+	chains, err := mvs.Why(modload.Target, modload.MinReqs(), mods)
+	if err != nil {
+		base.Fatalf("go: %v", err)
+	}
+	sep := ""
+	for i, m := range mods {
+		if m == modload.Target {
+			fmt.Printf("%s# %s\n(main module)\n", sep, m.Path)
+		} else if chains[i] == nil {
+			fmt.Printf("%s# %s %s\n(main module does not require module %s)\n", sep, m.Path, m.Version, m.Path)
+		} else {
+			fmt.Printf("%s# %s %s\n", sep, m.Path, m.Version)
+			for _, r := range chains[i] {
+				if r.Version == "" {
+					fmt.Printf("%s\n", r.Path)
+				} else {
+					fmt.Printf("%s %s\n", r.Path, r.Version)
+				}
+			}
+		}
+		sep = "\n"
+	}
+	base.ExitIfErrors()
+}
@@ -272,6 +272,25 @@ func (r *cachingRepo) GoMod(version string) ([]byte, error) {
 	return append([]byte(nil), c.text...), nil
 }
 
+// GoModNoSum is like Lookup(proxy, path).GoMod(version), but it neither
+// checks nor records the checksum of the go.mod file in go.sum.
+// It is meant for advisory reads, such as reading the retractions of a
+// module's latest version, that must not change the go.sum file.
+func GoModNoSum(proxy, path, version string) ([]byte, error) {
+	_, data, err := readDiskCache(path, version, "mod")
+	if err == nil && !bytes.HasPrefix(data, oldVgoPrefix) {
+		return data, nil
+	}
+	repo, err := Lookup(proxy, path)
+	if err != nil {
+		return nil, err
+	}
+	if cr, ok := repo.(*cachingRepo); ok {
+		repo = cr.r
+	}
+	return repo.GoMod(version)
+}
+
 func (r *cachingRepo) Zip(dst io.Writer, version string) error {
 	return r.r.Zip(dst, version)
 }
@@ -326,6 +326,10 @@ func RemoveAll(dir string) error {
 
 var GoSumFile string // path to go.sum; set by package modload
 
+// WorkspaceGoSumFiles lists the go.sum files of the modules in a workspace,
+// which are consulted but never written; set by package modload.
+var WorkspaceGoSumFiles []string
+
 type modSum struct {
 	mod module.Version
 	sum string
@@ -334,6 +338,7 @@ type modSum struct {
 var goSum struct {
 	mu        sync.Mutex
 	m         map[module.Version][]string // content of go.sum file (+ go.modverify if present)
+	w         map[module.Version][]string // content of the workspace modules' go.sum files
 	checked   map[modSum]bool             // sums actually checked during execution
 	dirty     bool                        // whether we added any new sums to m
 	overwrite bool                        // if true, overwrite go.sum without incorporating its contents
@@ -362,6 +367,17 @@ func initGoSum() (bool, error) {
 	goSum.enabled = true
 	readGoSum(goSum.m, GoSumFile, data)
 
+	goSum.w = make(map[module.Version][]string)
+	for _, f := range WorkspaceGoSumFiles {
+		data, err := lockedfile.Read(f)
+		if err != nil && !os.IsNotExist(err) {
+			return false, err
+		}
+		if err := readGoSum(goSum.w, f, data); err != nil {
+			return false, err
+		}
+	}
+
 	// Add old go.modverify file.
 	// We'll delete go.modverify in WriteGoSum.
 	alt := strings.TrimSuffix(GoSumFile, ".sum") + ".modverify"
@@ -504,6 +520,14 @@ func checkModSum(mod module.Version, h string) error {
 // If it finds a conflicting pair instead, it calls base.Fatalf.
 // goSum.mu must be locked.
 func haveModSumLocked(mod module.Version, h string) bool {
+	for _, vh := range goSum.w[mod] {
+		if h == vh {
+			return true
+		}
+		if strings.HasPrefix(vh, "h1:") {
+			base.Fatalf("verifying %s@%s: checksum mismatch\n\tdownloaded: %v\n\tgo.sum:     %v"+goSumMismatch, mod.Path, mod.Version, h, vh)
+		}
+	}
 	goSum.checked[modSum{mod, h}] = true
 	for _, vh := range goSum.m[mod] {
 		if h == vh {
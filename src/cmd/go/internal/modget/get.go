@@ -134,6 +134,15 @@ The -d flag instructs get to download the source code needed to build
 the named packages, including downloading necessary dependencies,
 but not to build and install them.
 
+The -diff flag instructs get to print the changes it would make to the
+build list, without updating go.mod or building any packages. Each
+line of output gives a module path followed by its old and new
+versions, as in "golang.org/x/text v0.3.0 => v0.3.2". A module that
+would be added to the build list has old version "none", and a module
+that would be removed has new version "none". For example,
+'go get -diff rsc.io/quote@none' shows what dropping the requirement
+on rsc.io/quote would remove or downgrade.
+
 With no package arguments, 'go get' applies to Go package in the
 current directory, if any. In particular, 'go get -u' and
 'go get -u=patch' update all the dependencies of that package.
@@ -171,12 +180,13 @@ Usage: ` + CmdGet.UsageLine + `
 }
 
 var (
-	getD   = CmdGet.Flag.Bool("d", false, "")
-	getF   = CmdGet.Flag.Bool("f", false, "")
-	getFix = CmdGet.Flag.Bool("fix", false, "")
-	getM   = CmdGet.Flag.Bool("m", false, "")
-	getT   = CmdGet.Flag.Bool("t", false, "")
-	getU   upgradeFlag
+	getD    = CmdGet.Flag.Bool("d", false, "")
+	getDiff = CmdGet.Flag.Bool("diff", false, "")
+	getF    = CmdGet.Flag.Bool("f", false, "")
+	getFix  = CmdGet.Flag.Bool("fix", false, "")
+	getM    = CmdGet.Flag.Bool("m", false, "")
+	getT    = CmdGet.Flag.Bool("t", false, "")
+	getU    upgradeFlag
 	// -insecure is get.Insecure
 	// -v is cfg.BuildV
 )
@@ -276,6 +286,7 @@ func runGet(cmd *base.Command, args []string) {
 
 	buildList := modload.LoadBuildList()
 	buildList = buildList[:len(buildList):len(buildList)] // copy on append
+	origBuildList := buildList
 	versionByPath := make(map[string]string)
 	for _, m := range buildList {
 		versionByPath[m.Path] = m.Version
@@ -670,10 +681,22 @@ func runGet(cmd *base.Command, args []string) {
 		base.Fatalf("%v", buf.String())
 	}
 
-	// Everything succeeded. Update go.mod.
+	// Everything succeeded. With -diff, report the changes instead of
+	// making them.
+	if *getDiff {
+		printBuildListDiff(origBuildList, modload.BuildList())
+		return
+	}
+
+	// Update go.mod.
 	modload.AllowWriteGoMod()
 	modload.WriteGoMod()
 
+	// Warn about retracted versions among the modules that were
+	// requested or changed. Other retracted versions in the build list
+	// were already there and are reported by 'go list -m -u'.
+	reportRetractions(versionByPath, byPath)
+
 	// If -d was specified, we're done after the module work.
 	// We've already downloaded modules by loading packages above.
 	// Otherwise, we need to build and install the packages matched by
@@ -690,6 +713,39 @@ func runGet(cmd *base.Command, args []string) {
 	work.InstallPackages(pkgPatterns, pkgs)
 }
 
+// printBuildListDiff prints the modules whose versions differ between
+// the build lists old and new, sorted by path, as "path old => new".
+// A module missing from either list has version "none" there.
+func printBuildListDiff(old, new []module.Version) {
+	oldVersion := make(map[string]string)
+	newVersion := make(map[string]string)
+	var paths []string
+	for _, m := range old[1:] {
+		oldVersion[m.Path] = m.Version
+		paths = append(paths, m.Path)
+	}
+	for _, m := range new[1:] {
+		newVersion[m.Path] = m.Version
+		if _, ok := oldVersion[m.Path]; !ok {
+			paths = append(paths, m.Path)
+		}
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		ov, ok := oldVersion[path]
+		if !ok {
+			ov = "none"
+		}
+		nv, ok := newVersion[path]
+		if !ok {
+			nv = "none"
+		}
+		if ov != nv {
+			fmt.Printf("%s %s => %s\n", path, ov, nv)
+		}
+	}
+}
+
 // runQueries looks up modules at target versions in parallel. Results will be
 // cached. If the same module is referenced by multiple queries at different
 // versions (including earlier queries in the modOnly map), an error will be
@@ -1006,6 +1062,39 @@ func (r *lostUpgradeReqs) Required(mod module.Version) ([]module.Version, error)
 	return r.Reqs.Required(mod)
 }
 
+// reportRetractions prints a warning for each module in the build list
+// that was named on the command line or whose version changed from
+// oldVersionByPath, if its author has retracted the selected version.
+func reportRetractions(oldVersionByPath map[string]string, byPath map[string]*query) {
+	var check []module.Version
+	for _, m := range modload.BuildList() {
+		if m == modload.Target || m.Version == "" {
+			continue
+		}
+		if byPath[m.Path] != nil || oldVersionByPath[m.Path] != m.Version {
+			check = append(check, m)
+		}
+	}
+
+	errs := make([]error, len(check))
+	var work par.Work
+	for i := range check {
+		work.Add(i)
+	}
+	work.Do(10, func(item interface{}) {
+		i := item.(int)
+		errs[i] = modload.CheckRetractions(check[i])
+	})
+
+	for i, err := range errs {
+		if errors.Is(err, modload.ErrRetracted) {
+			m := check[i]
+			fmt.Fprintf(os.Stderr, "go: warning: %s@%s: %v\n", m.Path, m.Version, err)
+			fmt.Fprintf(os.Stderr, "go: to switch to the latest unretracted version, run:\n\tgo get %s@latest\n", m.Path)
+		}
+	}
+}
+
 var loggedLines sync.Map
 
 func logOncef(format string, args ...interface{}) {
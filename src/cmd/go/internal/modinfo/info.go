@@ -21,6 +21,7 @@ type ModulePublic struct {
 	Dir       string        `json:",omitempty"` // directory holding local copy of files, if any
 	GoMod     string        `json:",omitempty"` // path to go.mod file describing module, if any
 	GoVersion string        `json:",omitempty"` // go version used in module
+	Retracted []string      `json:",omitempty"` // retraction rationale, if retracted (with -u)
 	Error     *ModuleError  `json:",omitempty"` // error loading module
 }
 
@@ -35,6 +36,9 @@ func (m *ModulePublic) String() string {
 		if m.Update != nil {
 			s += " [" + m.Update.Version + "]"
 		}
+		if m.Retracted != nil {
+			s += " (retracted)"
+		}
 	}
 	if m.Replace != nil {
 		s += " => " + m.Replace.Path
@@ -43,6 +47,9 @@ func (m *ModulePublic) String() string {
 			if m.Replace.Update != nil {
 				s += " [" + m.Replace.Update.Version + "]"
 			}
+			if m.Replace.Retracted != nil {
+				s += " (retracted)"
+			}
 		}
 	}
 	return s
@@ -7,6 +7,7 @@ package modload
 import (
 	"bytes"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"internal/goroot"
 	"os"
@@ -98,9 +99,32 @@ func addUpdate(m *modinfo.ModulePublic) {
 	}
 }
 
-// addVersions fills in m.Versions with the list of known versions.
+// addVersions fills in m.Versions with the list of known versions,
+// omitting those retracted by the module's author.
 func addVersions(m *modinfo.ModulePublic) {
-	m.Versions, _ = versions(m.Path)
+	list, _ := versions(m.Path)
+	retracted := retractedVersions(m.Path)
+	for _, v := range list {
+		if !retracted(v) {
+			m.Versions = append(m.Versions, v)
+		}
+	}
+}
+
+// addRetraction fills in m.Retracted if m's version has been retracted
+// by the module's author. Errors determining the retractions are ignored.
+func addRetraction(m *modinfo.ModulePublic) {
+	if m.Version == "" {
+		return
+	}
+	err := CheckRetractions(module.Version{Path: m.Path, Version: m.Version})
+	var rerr *ModuleRetractedError
+	if errors.As(err, &rerr) {
+		m.Retracted = rerr.Rationale
+		if len(m.Retracted) == 0 {
+			m.Retracted = []string{"retracted by module author"}
+		}
+	}
 }
 
 func moduleInfo(m module.Version, fromBuildList bool) *modinfo.ModulePublic {
@@ -119,6 +143,18 @@ func moduleInfo(m module.Version, fromBuildList bool) *modinfo.ModulePublic {
 		}
 		return info
 	}
+	if wm := workModuleByPath[m.Path]; wm != nil && m.Version == "" {
+		info := &modinfo.ModulePublic{
+			Path:  m.Path,
+			Main:  true,
+			Dir:   wm.dir,
+			GoMod: filepath.Join(wm.dir, "go.mod"),
+		}
+		if wm.file.Go != nil {
+			info.GoVersion = wm.file.Go.Version
+		}
+		return info
+	}
 
 	info := &modinfo.ModulePublic{
 		Path:     m.Path,
@@ -233,7 +269,7 @@ func PackageBuildInfo(path string, deps []string) string {
 		}
 		r := Replacement(mod)
 		h := ""
-		if r.Path == "" {
+		if r.Path == "" && mod.Version != "" {
 			h = "\t" + modfetch.Sum(mod)
 		}
 		fmt.Fprintf(&buf, "dep\t%s\t%s%s\n", mod.Path, mv, h)
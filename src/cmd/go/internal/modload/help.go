@@ -432,16 +432,39 @@ verb followed by arguments. For example:
 	require new/thing/v2 v2.3.4
 	exclude old/thing v1.2.3
 	replace bad/thing v1.4.5 => good/thing v1.4.5
+	retract v1.5.6
 
 The verbs are
 	module, to define the module path;
 	go, to set the expected language version;
 	require, to require a particular module at a given version or later;
-	exclude, to exclude a particular module version from use; and
-	replace, to replace a module version with a different module version.
+	exclude, to exclude a particular module version from use;
+	replace, to replace a module version with a different module version; and
+	retract, to indicate a previously released version should not be used.
 Exclude and replace apply only in the main module's go.mod and are ignored
 in dependencies.  See https://research.swtch.com/vgo-mvs for details.
 
+A retract directive names a single version or a closed interval of
+versions, written "[low, high]", that the module's author has withdrawn,
+typically because it was published by mistake or has a severe problem.
+The comment on the directive, if any, is the rationale shown to users:
+
+	retract (
+		v1.0.0 // Published accidentally.
+		[v1.1.0, v1.1.3] // Breaks the Parse API.
+	)
+
+Retractions are read from the go.mod file of the latest version of the
+module, which may itself be retracted; a module is not required to
+retract anything in earlier go.mod files. Queries such as "latest" and
+"upgrade" never select a retracted version, although a retracted version
+can still be requested explicitly by its exact version. 'go get' warns
+when it selects a retracted version, and 'go list -m -u' reports them.
+Build commands such as 'go build' and 'go test' also warn about each
+retracted version that provides packages to the build. Checking this
+may access the network, and retractions that cannot be determined,
+for example with GOPROXY=off or -mod=vendor, are not reported.
+
 The leading verb can be factored out of adjacent lines to create a block,
 like in Go imports:
 
@@ -179,6 +179,14 @@ func Import(path string) (m module.Version, dir string, err error) {
 
 	// Look up module containing the package, for addition to the build list.
 	// Goal is to determine the module, download it to dir, and return m, dir, ErrMissing.
+	if workFilePath != "" && !pathIsStd {
+		// The go.mod files of the workspace modules are never rewritten,
+		// so there is nowhere to record a new requirement.
+		return module.Version{}, "", &ImportMissingError{
+			Path:     path,
+			QueryErr: fmt.Errorf("import lookup disabled in workspace mode\n\t(to add a requirement, run 'go get' in the importing module with GOWORK=off)"),
+		}
+	}
 	if cfg.BuildMod == "readonly" {
 		var queryErr error
 		if !pathIsStd {
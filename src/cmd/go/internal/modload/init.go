@@ -157,6 +157,13 @@ func Init() {
 	if CmdModInit {
 		// Running 'go mod init': go.mod will be created in current directory.
 		modRoot = base.Cwd
+	} else if workFilePath = FindWorkFile(base.Cwd); workFilePath != "" {
+		// Workspace mode: the modules listed in go.work are all main modules.
+		switch cfg.CmdName {
+		case "get", "mod tidy", "mod vendor":
+			base.Fatalf("go: 'go %s' cannot be used in workspace mode (%s)\n\trun it in the module directory with GOWORK=off", cfg.CmdName, base.ShortPath(workFilePath))
+		}
+		modRoot = initWorkspace()
 	} else {
 		modRoot = findModuleRoot(base.Cwd)
 		if modRoot == "" {
@@ -219,6 +226,7 @@ func Init() {
 	load.ModInfoProg = ModInfoProg
 	load.ModImportFromFiles = ImportFromFiles
 	load.ModDirImportPath = DirImportPath
+	load.ModWarnRetracted = WarnRetracted
 
 	if modRoot == "" {
 		// We're in module mode, but not inside a module.
@@ -239,8 +247,16 @@ func Init() {
 		//
 		// See golang.org/issue/32027.
 	} else {
-		modfetch.GoSumFile = strings.TrimSuffix(ModFilePath(), ".mod") + ".sum"
-		search.SetModRoot(modRoot)
+		if workFilePath == "" {
+			modfetch.GoSumFile = strings.TrimSuffix(ModFilePath(), ".mod") + ".sum"
+			search.SetModRoot(modRoot)
+		} else {
+			var roots []string
+			for _, wm := range workModules {
+				roots = append(roots, wm.dir)
+			}
+			search.SetModRoots(roots)
+		}
 	}
 }
 
@@ -288,6 +304,9 @@ func WillBeEnabled() bool {
 		// Running 'go mod init': go.mod will be created in current directory.
 		return true
 	}
+	if FindWorkFile(base.Cwd) != "" {
+		return true
+	}
 	if modRoot := findModuleRoot(base.Cwd); modRoot == "" {
 		// GO111MODULE is 'auto', and we can't find a module root.
 		// Stay in GOPATH mode.
@@ -403,9 +422,9 @@ func InitMod() {
 	}
 
 	var fixed bool
-	f, err := modfile.Parse(gomod, data, fixVersion(&fixed))
+	f, err := ParseModFile(gomod, data, fixVersion(&fixed))
 	if err != nil {
-		// Errors returned by modfile.Parse begin with file:line.
+		// Errors returned by ParseModFile begin with file:line.
 		base.Fatalf("go: errors parsing go.mod:\n%s\n", err)
 	}
 	modFile = f
@@ -504,6 +523,11 @@ func modFileToBuildList() {
 	}
 
 	list := []module.Version{Target}
+	for _, m := range mainModules()[1:] {
+		// The other workspace modules are required at the empty
+		// version, which takes precedence over any other version.
+		list = append(list, m)
+	}
 	for _, r := range modFile.Require {
 		list = append(list, r.Mod)
 	}
@@ -513,6 +537,9 @@ func modFileToBuildList() {
 // setDefaultBuildMod sets a default value for cfg.BuildMod
 // if it is currently empty.
 func setDefaultBuildMod() {
+	if workFilePath != "" && cfg.BuildMod == "vendor" {
+		base.Fatalf("go: -mod=vendor cannot be used in workspace mode (%s)", base.ShortPath(workFilePath))
+	}
 	if cfg.BuildMod != "" {
 		// Don't override an explicit '-mod=' argument.
 		return
@@ -523,7 +550,7 @@ func setDefaultBuildMod() {
 		// manipulate the build list.
 		return
 	}
-	if modRoot == "" {
+	if modRoot == "" || workFilePath != "" {
 		return
 	}
 
@@ -900,6 +927,14 @@ func WriteGoMod() {
 		return
 	}
 
+	// In workspace mode, the go.mod files of the workspace modules are
+	// not rewritten: only the checksums of newly downloaded modules are
+	// recorded, in the go.work.sum file.
+	if workFilePath != "" {
+		modfetch.WriteGoSum()
+		return
+	}
+
 	if cfg.BuildMod != "readonly" {
 		addGoStmt()
 	}
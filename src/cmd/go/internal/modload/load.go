@@ -124,7 +124,9 @@ func ImportPathsQuiet(patterns []string, tags map[string]bool) []*search.Match {
 					// Note: The checks for @ here are just to avoid misinterpreting
 					// the module cache directories (formerly GOPATH/src/mod/foo@v1.5.2/bar).
 					// It's not strictly necessary but helpful to keep the checks.
-					if modRoot != "" && dir == modRoot {
+					if path := pathInWorkspace(dir); path != "" {
+						pkg = path
+					} else if modRoot != "" && dir == modRoot {
 						pkg = targetPrefix
 					} else if modRoot != "" && strings.HasPrefix(dir, modRoot+string(filepath.Separator)) && !strings.Contains(dir[len(modRoot):], "@") {
 						suffix := filepath.ToSlash(dir[len(modRoot):])
@@ -170,9 +172,9 @@ func ImportPathsQuiet(patterns []string, tags map[string]bool) []*search.Match {
 			case m.Pattern == "all":
 				loaded.testAll = true
 				if iterating {
-					// Enumerate the packages in the main module.
+					// Enumerate the packages in the main modules.
 					// We'll load the dependencies as we find them.
-					m.Pkgs = matchPackages("...", loaded.tags, false, []module.Version{Target})
+					m.Pkgs = matchPackages("...", loaded.tags, false, mainModules())
 				} else {
 					// Starting with the packages in the main module,
 					// enumerate the full list of "all".
@@ -327,6 +329,9 @@ func DirImportPath(dir string) string {
 		dir = filepath.Clean(dir)
 	}
 
+	if path := pathInWorkspace(dir); path != "" {
+		return path
+	}
 	if dir == modRoot {
 		return targetPrefix
 	}
@@ -695,7 +700,7 @@ func (ld *loader) load(roots func() []string) {
 	// Compute directly referenced dependency modules.
 	ld.direct = make(map[string]bool)
 	for _, pkg := range ld.pkgs {
-		if pkg.mod == Target {
+		if isMainModule(pkg.mod) {
 			for _, dep := range pkg.imports {
 				if dep.mod.Path != "" {
 					ld.direct[dep.mod.Path] = true
@@ -997,6 +1002,20 @@ func WhyDepth(path string) int {
 // If there is no replacement for mod, Replacement returns
 // a module.Version with Path == "".
 func Replacement(mod module.Version) module.Version {
+	if workFilePath != "" {
+		// In workspace mode, replacements come from all the workspace
+		// modules, and the workspace modules themselves are never replaced.
+		if workModuleByPath[mod.Path] != nil {
+			return module.Version{}
+		}
+		if r, ok := workReplace[mod]; ok {
+			return r
+		}
+		if r, ok := workReplace[module.Version{Path: mod.Path}]; ok {
+			return r
+		}
+		return module.Version{}
+	}
 	if index != nil {
 		if r, ok := index.replace[mod]; ok {
 			return r
@@ -1038,6 +1057,13 @@ func (r *mvsReqs) Required(mod module.Version) ([]module.Version, error) {
 			return cached{nil, err}
 		}
 		for i, mv := range list {
+			if workModuleByPath[mv.Path] != nil {
+				// A workspace module supersedes every version of its path:
+				// treat any requirement on it as a requirement on the
+				// main module at the empty version.
+				list[i] = module.Version{Path: mv.Path}
+				continue
+			}
 			if index != nil {
 				for index.exclude[mv] {
 					mv1, err := r.next(mv)
@@ -1184,6 +1210,15 @@ func (r *mvsReqs) required(mod module.Version) ([]module.Version, error) {
 		return append([]module.Version(nil), r.buildList[1:]...), nil
 	}
 
+	if wm := workModuleByPath[mod.Path]; wm != nil {
+		// A workspace module supersedes every version of its path,
+		// so its requirements are those in its own go.mod file.
+		if wm.file.Go != nil {
+			r.versions.LoadOrStore(mod, wm.file.Go.Version)
+		}
+		return r.modFileToList(wm.file), nil
+	}
+
 	if cfg.BuildMod == "vendor" {
 		// For every module other than the target,
 		// return the full list of modules from modules.txt.
@@ -1314,6 +1349,9 @@ func fetch(mod module.Version) (dir string, isLocal bool, err error) {
 	if mod == Target {
 		return ModRoot(), true, nil
 	}
+	if wm := workModuleByPath[mod.Path]; wm != nil && mod.Version == "" {
+		return wm.dir, true, nil
+	}
 	if r := Replacement(mod); r.Path != "" {
 		if r.Version == "" {
 			dir = r.Path
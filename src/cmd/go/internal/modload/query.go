@@ -220,6 +220,23 @@ func queryProxy(proxy, path, query, current string, allowed func(module.Version)
 		return info, nil
 	}
 
+	// Queries other than exact versions never select a retracted version.
+	// "upgrade" and "patch" may still keep the current version, since
+	// they never move to an older one.
+	// Check this last: loading the retractions may need to fetch
+	// the latest go.mod file.
+	matches := ok
+	retracted := retractedVersions(path)
+	ok = func(m module.Version) bool {
+		if !matches(m) {
+			return false
+		}
+		if m.Version == current && (query == "upgrade" || query == "patch") {
+			return true
+		}
+		return !retracted(m.Version)
+	}
+
 	if path == Target.Path {
 		if query != "latest" {
 			return nil, fmt.Errorf("can't query specific version (%q) for the main module (%s)", query, path)
@@ -287,7 +304,7 @@ func queryProxy(proxy, path, query, current string, allowed func(module.Version)
 		// provided it is not excluded.
 		latest, err := repo.Latest()
 		if err == nil {
-			if allowed(module.Version{Path: path, Version: latest.Version}) {
+			if ok(module.Version{Path: path, Version: latest.Version}) {
 				return lookup(latest.Version)
 			}
 		} else if !errors.Is(err, os.ErrNotExist) {
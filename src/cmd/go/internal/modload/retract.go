@@ -0,0 +1,487 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modload
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"cmd/go/internal/cfg"
+	"cmd/go/internal/modfetch"
+	"cmd/go/internal/par"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// A ModuleRetractedError indicates that a module version was retracted
+// by the module's author.
+type ModuleRetractedError struct {
+	Rationale []string
+}
+
+func (e *ModuleRetractedError) Error() string {
+	msg := "retracted by module author"
+	if len(e.Rationale) > 0 {
+		// This is meant to be a short error printed on a terminal, so just
+		// print the first rationale.
+		msg += ": " + ShortRetractRationale(e.Rationale[0])
+	}
+	return msg
+}
+
+func (e *ModuleRetractedError) Is(err error) bool {
+	return err == ErrRetracted
+}
+
+// ErrRetracted matches any ModuleRetractedError in errors.Is.
+var ErrRetracted = errors.New("retracted by module author")
+
+// ShortRetractRationale returns the first line of a retraction
+// rationale, trimmed to a length suitable for one line of terminal
+// output. Rationales containing characters other than printable ASCII
+// are replaced by a generic description.
+func ShortRetractRationale(rationale string) string {
+	const maxRationaleBytes = 500
+	if i := strings.Index(rationale, "\n"); i >= 0 {
+		rationale = rationale[:i]
+	}
+	rationale = strings.TrimSpace(rationale)
+	if rationale == "" {
+		return "retracted by module author"
+	}
+	if len(rationale) > maxRationaleBytes {
+		return "(rationale omitted: too long)"
+	}
+	for _, r := range rationale {
+		if r < ' ' || r > '~' {
+			return "(rationale omitted: contains non-printable or non-ASCII characters)"
+		}
+	}
+	return rationale
+}
+
+// A VersionInterval is a closed interval of module versions.
+// A single version v is the interval [v, v].
+type VersionInterval struct {
+	Low, High string
+}
+
+// A Retract is a single retract directive in a go.mod file.
+type Retract struct {
+	VersionInterval
+	Rationale string
+	Syntax    *modfile.Line
+}
+
+// ParseVersionInterval parses a single version like "v1.2.3" or a closed
+// interval like "[v1.2.3, v1.4.5]". Both bounds must be canonical versions.
+func ParseVersionInterval(s string) (VersionInterval, error) {
+	if !strings.HasPrefix(s, "[") {
+		if !isCanonicalVersion(s) {
+			return VersionInterval{}, fmt.Errorf("invalid version: %q", s)
+		}
+		return VersionInterval{Low: s, High: s}, nil
+	}
+	if !strings.HasSuffix(s, "]") {
+		return VersionInterval{}, fmt.Errorf("invalid version interval: %q", s)
+	}
+	i := strings.Index(s, ",")
+	if i < 0 {
+		return VersionInterval{}, fmt.Errorf("invalid version interval: %q", s)
+	}
+	low := strings.TrimSpace(s[1:i])
+	high := strings.TrimSpace(s[i+1 : len(s)-1])
+	if !isCanonicalVersion(low) || !isCanonicalVersion(high) {
+		return VersionInterval{}, fmt.Errorf("invalid version interval: %q", s)
+	}
+	if semver.Compare(low, high) > 0 {
+		return VersionInterval{}, fmt.Errorf("invalid version interval: %q: low version is greater than high version", s)
+	}
+	return VersionInterval{Low: low, High: high}, nil
+}
+
+func isCanonicalVersion(v string) bool {
+	return v != "" && module.CanonicalVersion(v) == v
+}
+
+// String returns vi in the form accepted by ParseVersionInterval.
+func (vi VersionInterval) String() string {
+	if vi.Low == vi.High {
+		return vi.Low
+	}
+	return "[" + vi.Low + ", " + vi.High + "]"
+}
+
+// ParseModFile is like modfile.Parse, but it also accepts retract
+// directives, which the vendored modfile package does not know about.
+// The retract directives stay in the returned file's syntax, so that
+// formatting the file preserves them; Retractions interprets them.
+func ParseModFile(file string, data []byte, fix modfile.VersionFixer) (*modfile.File, error) {
+	// Find the retract directives with a lax parse that accepts any
+	// version, since fix may not have been applied yet. If even that
+	// fails, the strict parse reports the problem.
+	anyVersion := func(path, vers string) (string, error) { return "v0.0.0", nil }
+	lax, err := modfile.ParseLax(file, data, anyVersion)
+	if err != nil {
+		return modfile.Parse(file, data, fix)
+	}
+
+	// Blank out the retract directives and their comments, keeping the
+	// line breaks so that the line numbers in errors do not change,
+	// and parse the rest of the file strictly.
+	var retract []modfile.Expr
+	rest := data
+	for _, x := range lax.Syntax.Stmt {
+		if !isRetractStmt(x) {
+			continue
+		}
+		if retract == nil {
+			rest = append([]byte(nil), data...)
+		}
+		retract = append(retract, x)
+		start, end := x.Span()
+		for _, c := range x.Comment().Before {
+			if c.Start.Byte < start.Byte {
+				start = c.Start
+			}
+		}
+		for i := start.Byte; i < len(rest) && (i < end.Byte || rest[i] != '\n'); i++ {
+			if rest[i] != '\n' {
+				rest[i] = ' '
+			}
+		}
+	}
+	f, err := modfile.Parse(file, rest, fix)
+	if err != nil || retract == nil {
+		return f, err
+	}
+	if f.Module == nil {
+		start, _ := retract[0].Span()
+		return nil, fmt.Errorf("%s:%d: no module directive found, so retract cannot be used", file, start.Line)
+	}
+
+	// Put the retract directives back where they were.
+	f.Syntax.Stmt = append(f.Syntax.Stmt, retract...)
+	sort.SliceStable(f.Syntax.Stmt, func(i, j int) bool {
+		si, _ := f.Syntax.Stmt[i].Span()
+		sj, _ := f.Syntax.Stmt[j].Span()
+		return si.Byte < sj.Byte
+	})
+	if _, err := Retractions(f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func isRetractStmt(x modfile.Expr) bool {
+	switch x := x.(type) {
+	case *modfile.Line:
+		return len(x.Token) > 0 && x.Token[0] == "retract"
+	case *modfile.LineBlock:
+		return len(x.Token) == 1 && x.Token[0] == "retract"
+	}
+	return false
+}
+
+// Retractions returns the retract directives in the go.mod file f,
+// which must have been parsed by ParseModFile or modfile.ParseLax.
+func Retractions(f *modfile.File) ([]*Retract, error) {
+	var (
+		list []*Retract
+		errs strings.Builder
+	)
+	add := func(block *modfile.LineBlock, line *modfile.Line, args []string) {
+		// The modfile lexer splits an interval like "[v1.0.0, v1.1.0]"
+		// into the tokens "[v1.0.0," and "v1.1.0]".
+		vi, err := ParseVersionInterval(strings.Join(args, " "))
+		if err != nil {
+			fmt.Fprintf(&errs, "%s:%d: retract: %v\n", f.Syntax.Name, line.Start.Line, err)
+			return
+		}
+		list = append(list, &Retract{
+			VersionInterval: vi,
+			Rationale:       retractRationale(block, line),
+			Syntax:          line,
+		})
+	}
+	for _, x := range f.Syntax.Stmt {
+		switch x := x.(type) {
+		case *modfile.Line:
+			if len(x.Token) > 0 && x.Token[0] == "retract" {
+				add(nil, x, x.Token[1:])
+			}
+		case *modfile.LineBlock:
+			if len(x.Token) == 1 && x.Token[0] == "retract" {
+				for _, l := range x.Line {
+					if l.Token != nil { // not dropped by DropRetract
+						add(x, l, l.Token)
+					}
+				}
+			}
+		}
+	}
+	if errs.Len() > 0 {
+		return nil, errors.New(strings.TrimRight(errs.String(), "\n"))
+	}
+	return list, nil
+}
+
+// retractRationale extracts the rationale for a retract directive
+// from the comments before and after it. If the line is in a block
+// and has no comments of its own, the block's comments are used.
+func retractRationale(block *modfile.LineBlock, line *modfile.Line) string {
+	comments := line.Comment()
+	if block != nil && len(comments.Before) == 0 && len(comments.Suffix) == 0 {
+		comments = block.Comment()
+	}
+	var lines []string
+	for _, g := range [][]modfile.Comment{comments.Before, comments.Suffix} {
+		for _, c := range g {
+			if !strings.HasPrefix(c.Token, "//") {
+				continue // blank line
+			}
+			lines = append(lines, strings.TrimSpace(strings.TrimPrefix(c.Token, "//")))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// AddRetract adds a retract directive for vi to f,
+// unless vi is already retracted.
+func AddRetract(f *modfile.File, vi VersionInterval) {
+	retract, _ := Retractions(f)
+	for _, r := range retract {
+		if r.VersionInterval == vi {
+			return
+		}
+	}
+	addBlockLine(f.Syntax, "retract", vi.String())
+}
+
+// DropRetract removes the retract directives for vi from f.
+// The caller must call f.Cleanup afterward.
+func DropRetract(f *modfile.File, vi VersionInterval) {
+	retract, _ := Retractions(f)
+	for _, r := range retract {
+		if r.VersionInterval == vi {
+			r.Syntax.Token = nil
+		}
+	}
+}
+
+// SortRetractions sorts the lines of each retract block in f by
+// descending version, first by low version, then by high version.
+// It undoes the textual order imposed by f.SortBlocks, which treats
+// version intervals as plain tokens.
+func SortRetractions(f *modfile.File) {
+	for _, x := range f.Syntax.Stmt {
+		block, ok := x.(*modfile.LineBlock)
+		if !ok || !isRetractStmt(block) {
+			continue
+		}
+		vis := make(map[*modfile.Line]VersionInterval)
+		for _, l := range block.Line {
+			vis[l], _ = ParseVersionInterval(strings.Join(l.Token, " "))
+		}
+		sort.SliceStable(block.Line, func(i, j int) bool {
+			vi, vj := vis[block.Line[i]], vis[block.Line[j]]
+			if c := semver.Compare(vi.Low, vj.Low); c != 0 {
+				return c > 0
+			}
+			return semver.Compare(vi.High, vj.High) > 0
+		})
+	}
+}
+
+// CheckRetractions returns a *ModuleRetractedError if m has been
+// retracted by its author, and nil if it has not.
+//
+// Retractions are read from the go.mod file of the latest version of
+// the module, so CheckRetractions may need to access the network.
+// Errors finding or reading that file are returned as is.
+func CheckRetractions(m module.Version) error {
+	if m.Version == "" || isMainModule(m) {
+		// Main modules and modules replaced by directories are never retracted.
+		return nil
+	}
+	retract, err := retractions(m.Path)
+	if err != nil {
+		return err
+	}
+	var rationale []string
+	isRetracted := false
+	for _, r := range retract {
+		if r.contains(m.Version) {
+			isRetracted = true
+			if r.Rationale != "" {
+				rationale = append(rationale, r.Rationale)
+			}
+		}
+	}
+	if !isRetracted {
+		return nil
+	}
+	return &ModuleRetractedError{Rationale: rationale}
+}
+
+func (vi VersionInterval) contains(v string) bool {
+	return semver.Compare(vi.Low, v) <= 0 && semver.Compare(v, vi.High) <= 0
+}
+
+// retractedVersions returns a function that reports whether a version
+// of the module at path has been retracted by its author. It loads the
+// module's retractions once, on the first call of the returned function,
+// so that filters applied to many versions do not look them up for each.
+// Versions whose retractions cannot be determined are assumed not
+// to be retracted.
+func retractedVersions(path string) func(version string) bool {
+	var retract []*Retract
+	loaded := false
+	return func(v string) bool {
+		if !loaded {
+			retract, _ = retractions(path)
+			loaded = true
+		}
+		for _, r := range retract {
+			if r.contains(v) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+var retractCache par.Cache // module path → retractionsResult
+
+type retractionsResult struct {
+	retract []*Retract
+	err     error
+}
+
+// retractions returns the retract directives that apply to the module
+// at path: those in the go.mod file of its latest version, whether or
+// not that version is itself retracted. If that version is replaced,
+// the go.mod file of the replacement is used instead.
+// The result, including any error, is cached for each path.
+func retractions(path string) ([]*Retract, error) {
+	r := retractCache.Do(path, func() interface{} {
+		latest, err := latestVersionIgnoringRetractions(path)
+		if err != nil {
+			return retractionsResult{err: err}
+		}
+		if latest == "" {
+			return retractionsResult{}
+		}
+
+		m := module.Version{Path: path, Version: latest}
+		// Retractions are advisory: read the go.mod file without
+		// checking or recording its checksum in go.sum.
+		var data []byte
+		if repl := Replacement(m); repl.Path != "" && repl.Version == "" {
+			dir := repl.Path
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(ModRoot(), dir)
+			}
+			data, err = ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+		} else {
+			src := m
+			if repl.Path != "" {
+				src = repl
+			}
+			err = modfetch.TryProxies(func(proxy string) error {
+				data, err = modfetch.GoModNoSum(proxy, src.Path, src.Version)
+				return err
+			})
+		}
+		if err != nil {
+			return retractionsResult{err: err}
+		}
+		f, err := modfile.ParseLax("go.mod", data, nil)
+		if err != nil {
+			return retractionsResult{err: module.VersionError(m, fmt.Errorf("parsing go.mod: %v", err))}
+		}
+		if f.Module == nil || f.Module.Mod.Path != path {
+			// A go.mod file for another module cannot retract versions of this one.
+			return retractionsResult{}
+		}
+		retract, err := Retractions(f)
+		if err != nil {
+			return retractionsResult{err: module.VersionError(m, fmt.Errorf("parsing go.mod: %v", err))}
+		}
+		return retractionsResult{retract: retract}
+	}).(retractionsResult)
+	return r.retract, r.err
+}
+
+// latestVersionIgnoringRetractions returns the latest version of the
+// module at path, counting retracted versions: the highest release if
+// there is one, else the highest prerelease, else the pseudo-version
+// of the latest commit. It returns "" if the module does not exist.
+func latestVersionIgnoringRetractions(path string) (string, error) {
+	var latest string
+	err := modfetch.TryProxies(func(proxy string) error {
+		repo, err := modfetch.Lookup(proxy, path)
+		if err != nil {
+			return err
+		}
+		list, err := repo.Versions("")
+		if err != nil {
+			return err
+		}
+		// list is sorted in semver order.
+		for i := len(list) - 1; i >= 0; i-- {
+			if semver.Prerelease(list[i]) == "" {
+				latest = list[i]
+				return nil
+			}
+		}
+		if len(list) > 0 {
+			latest = list[len(list)-1]
+			return nil
+		}
+		info, err := repo.Latest()
+		if err != nil {
+			return err
+		}
+		latest = info.Version
+		return nil
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	return latest, err
+}
+
+// WarnRetracted prints a warning for each of mods, the modules providing
+// packages to a build, whose version has been retracted by its author.
+// Retractions that cannot be determined are not reported.
+func WarnRetracted(mods []module.Version) {
+	if cfg.BuildMod == "vendor" {
+		// The vendor directory is all there is: don't go looking
+		// for the latest versions of the vendored modules.
+		return
+	}
+	errs := make([]error, len(mods))
+	var work par.Work
+	for i := range mods {
+		work.Add(i)
+	}
+	work.Do(10, func(item interface{}) {
+		i := item.(int)
+		errs[i] = CheckRetractions(mods[i])
+	})
+	for i, err := range errs {
+		if errors.Is(err, ErrRetracted) {
+			fmt.Fprintf(os.Stderr, "go: warning: %s@%s: %v\n", mods[i].Path, mods[i].Version, err)
+		}
+	}
+}
@@ -0,0 +1,356 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modload
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"cmd/go/internal/base"
+	"cmd/go/internal/cfg"
+	"cmd/go/internal/lockedfile"
+	"cmd/go/internal/modfetch"
+	"cmd/go/internal/search"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+)
+
+// A WorkFile is the parsed, interpreted form of a go.work file.
+//
+// A go.work file lists the directories of the modules in a workspace:
+//
+//	go 1.14
+//
+//	use (
+//		./api
+//		./server
+//	)
+//
+// Its syntax is that of go.mod files, but only the go and use
+// directives are allowed.
+type WorkFile struct {
+	Go  string     // go directive, or ""
+	Use []*WorkUse // use directives, in file order
+
+	Syntax *modfile.FileSyntax
+}
+
+// A WorkUse is a single directory listed by a use directive.
+type WorkUse struct {
+	Path   string // directory, as written in go.work
+	Syntax *modfile.Line
+}
+
+// ParseWorkFile parses the data, reported in errors as being from file,
+// into a WorkFile.
+func ParseWorkFile(file string, data []byte) (*WorkFile, error) {
+	f, err := modfile.ParseLax(file, data, nil)
+	if err != nil {
+		return nil, err
+	}
+	wf := &WorkFile{Syntax: f.Syntax}
+	if f.Go != nil {
+		wf.Go = f.Go.Version
+	}
+
+	var errs bytes.Buffer
+	add := func(line *modfile.Line, verb string, args []string) {
+		switch verb {
+		default:
+			fmt.Fprintf(&errs, "%s:%d: unknown directive: %s\n", file, line.Start.Line, verb)
+		case "go":
+			// Checked by ParseLax.
+		case "use":
+			if len(args) != 1 {
+				fmt.Fprintf(&errs, "%s:%d: usage: use dir\n", file, line.Start.Line)
+				return
+			}
+			dir := args[0]
+			if strings.HasPrefix(dir, `"`) {
+				if dir, err = strconv.Unquote(dir); err != nil {
+					fmt.Fprintf(&errs, "%s:%d: invalid quoted string: %v\n", file, line.Start.Line, err)
+					return
+				}
+			}
+			wf.Use = append(wf.Use, &WorkUse{Path: dir, Syntax: line})
+		}
+	}
+	for _, x := range f.Syntax.Stmt {
+		switch x := x.(type) {
+		case *modfile.Line:
+			add(x, x.Token[0], x.Token[1:])
+		case *modfile.LineBlock:
+			if len(x.Token) > 1 || x.Token[0] != "use" {
+				fmt.Fprintf(&errs, "%s:%d: unknown block type: %s\n", file, x.Start.Line, strings.Join(x.Token, " "))
+				continue
+			}
+			for _, l := range x.Line {
+				add(l, x.Token[0], l.Token)
+			}
+		}
+	}
+	if errs.Len() > 0 {
+		return nil, fmt.Errorf("%s", strings.TrimRight(errs.String(), "\n"))
+	}
+	return wf, nil
+}
+
+// AddUse adds a use directive for dir to the end of the last use block,
+// unless dir is already listed.
+func (f *WorkFile) AddUse(dir string) {
+	for _, u := range f.Use {
+		if u.Path == dir {
+			return
+		}
+	}
+	line := addBlockLine(f.Syntax, "use", modfile.AutoQuote(dir))
+	f.Use = append(f.Use, &WorkUse{Path: dir, Syntax: line})
+}
+
+// addBlockLine adds a "verb tok" line to the last verb block in syntax,
+// converting the last single verb line into a block if there is no block,
+// or else appends the line to the end of the file. It returns the new line.
+func addBlockLine(syntax *modfile.FileSyntax, verb, tok string) *modfile.Line {
+	for i := len(syntax.Stmt) - 1; i >= 0; i-- {
+		switch x := syntax.Stmt[i].(type) {
+		case *modfile.LineBlock:
+			if len(x.Token) == 1 && x.Token[0] == verb {
+				line := &modfile.Line{Token: []string{tok}, InBlock: true}
+				x.Line = append(x.Line, line)
+				return line
+			}
+		case *modfile.Line:
+			if len(x.Token) > 0 && x.Token[0] == verb {
+				// Convert the single directive to a block.
+				x.InBlock = true
+				block := &modfile.LineBlock{Token: x.Token[:1], Line: []*modfile.Line{x}}
+				x.Token = x.Token[1:]
+				syntax.Stmt[i] = block
+				line := &modfile.Line{Token: []string{tok}, InBlock: true}
+				block.Line = append(block.Line, line)
+				return line
+			}
+		}
+	}
+	line := &modfile.Line{Token: []string{verb, tok}}
+	syntax.Stmt = append(syntax.Stmt, line)
+	return line
+}
+
+// DropUse removes the use directive for dir, if any.
+func (f *WorkFile) DropUse(dir string) {
+	w := 0
+	for _, u := range f.Use {
+		if u.Path == dir {
+			u.Syntax.Token = nil
+			continue
+		}
+		f.Use[w] = u
+		w++
+	}
+	f.Use = f.Use[:w]
+}
+
+// Format returns the go.work file in standard format.
+func (f *WorkFile) Format() []byte {
+	f.Syntax.Cleanup()
+	return modfile.Format(f.Syntax)
+}
+
+// FindWorkFile returns the path of the go.work file in effect for dir,
+// or "" if there is none. The GOWORK environment variable, when set,
+// overrides the search of dir and its parents: GOWORK=off disables
+// workspace mode and any other value names the go.work file to use.
+func FindWorkFile(dir string) string {
+	switch gowork := cfg.Getenv("GOWORK"); gowork {
+	case "off":
+		return ""
+	case "":
+		// Search below.
+	default:
+		if !filepath.IsAbs(gowork) {
+			base.Fatalf("go: invalid GOWORK: not an absolute path")
+		}
+		return filepath.Clean(gowork)
+	}
+
+	dir = filepath.Clean(dir)
+	for {
+		f := filepath.Join(dir, "go.work")
+		if fi, err := os.Stat(f); err == nil && !fi.IsDir() {
+			if search.InDir(dir, os.TempDir()) == "." {
+				// As with go.mod, ignore go.work in the system temp root.
+				return ""
+			}
+			return f
+		}
+		d := filepath.Dir(dir)
+		if d == dir {
+			break
+		}
+		dir = d
+	}
+	return ""
+}
+
+var (
+	// workFilePath is the path of the go.work file in use,
+	// or "" if the go command is not in workspace mode.
+	workFilePath string
+
+	// workModules lists the modules of the workspace, in go.work order.
+	// The main module (Target) is one of them.
+	workModules []*workModule
+
+	// workModuleByPath indexes workModules by module path.
+	workModuleByPath map[string]*workModule
+
+	// workReplace holds the replacements of all workspace modules,
+	// with directory replacements made absolute.
+	workReplace map[module.Version]module.Version
+)
+
+// A workModule is one of the modules listed in go.work.
+type workModule struct {
+	mod  module.Version // module path, with an empty version
+	dir  string         // absolute module root directory
+	file *modfile.File  // parsed go.mod file
+}
+
+// initWorkspace reads the go.work file at workFilePath and the go.mod
+// files of the modules it lists, and returns the root directory of
+// the main module: the workspace module containing the current
+// directory, or else the first one listed.
+func initWorkspace() (root string) {
+	if cfg.ModFile != "" {
+		base.Fatalf("go: -modfile cannot be used in workspace mode (%s)", base.ShortPath(workFilePath))
+	}
+	data, err := lockedfile.Read(workFilePath)
+	if err != nil {
+		base.Fatalf("go: %v", err)
+	}
+	wf, err := ParseWorkFile(workFilePath, data)
+	if err != nil {
+		base.Fatalf("go: errors parsing go.work:\n%s\n", err)
+	}
+	if len(wf.Use) == 0 {
+		base.Fatalf("go: %s does not use any modules", base.ShortPath(workFilePath))
+	}
+
+	workModuleByPath = make(map[string]*workModule)
+	workReplace = make(map[module.Version]module.Version)
+	for _, u := range wf.Use {
+		dir := filepath.FromSlash(u.Path)
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(filepath.Dir(workFilePath), dir)
+		}
+		gomod := filepath.Join(dir, "go.mod")
+		data, err := lockedfile.Read(gomod)
+		if err != nil {
+			base.Fatalf("go: %v", err)
+		}
+		f, err := ParseModFile(gomod, data, nil)
+		if err != nil {
+			base.Fatalf("go: errors parsing %s:\n%s\n", base.ShortPath(gomod), err)
+		}
+		if f.Module == nil {
+			base.Fatalf("go: %s: missing module declaration", base.ShortPath(gomod))
+		}
+		wm := &workModule{mod: module.Version{Path: f.Module.Mod.Path}, dir: dir, file: f}
+		if prev := workModuleByPath[wm.mod.Path]; prev != nil {
+			base.Fatalf("go: module %s appears multiple times in workspace:\n\t%s\n\t%s", wm.mod.Path, base.ShortPath(prev.dir), base.ShortPath(dir))
+		}
+		workModules = append(workModules, wm)
+		workModuleByPath[wm.mod.Path] = wm
+
+		for _, r := range f.Replace {
+			repl := r.New
+			if repl.Version == "" && !filepath.IsAbs(repl.Path) {
+				repl.Path = filepath.Join(dir, repl.Path)
+			}
+			if prev, dup := workReplace[r.Old]; dup && prev != repl {
+				base.Fatalf("go: conflicting replacements for %v in workspace:\n\t%v\n\t%v", r.Old, prev, repl)
+			}
+			workReplace[r.Old] = repl
+		}
+	}
+
+	for _, wm := range workModules {
+		if search.InDir(base.Cwd, wm.dir) != "" && len(wm.dir) > len(root) {
+			root = wm.dir
+		}
+	}
+	if root == "" {
+		root = workModules[0].dir
+	}
+	if modRoot := findModuleRoot(base.Cwd); modRoot != "" && workModuleForDir(modRoot) == nil {
+		base.Fatalf("go: current directory is contained in a module that is not in the workspace\n\tto add it, run:\n\tgo work use %s", base.ShortPath(modRoot))
+	}
+
+	modfetch.GoSumFile = workFilePath + ".sum"
+	for _, wm := range workModules {
+		modfetch.WorkspaceGoSumFiles = append(modfetch.WorkspaceGoSumFiles, filepath.Join(wm.dir, "go.sum"))
+	}
+	return root
+}
+
+// workModuleForDir returns the workspace module whose root is dir,
+// or nil if there is none.
+func workModuleForDir(dir string) *workModule {
+	for _, wm := range workModules {
+		if wm.dir == dir {
+			return wm
+		}
+	}
+	return nil
+}
+
+// mainModules returns the main modules: the workspace modules
+// in workspace mode, or just Target.
+func mainModules() []module.Version {
+	if workFilePath == "" {
+		return []module.Version{Target}
+	}
+	mods := []module.Version{Target}
+	for _, wm := range workModules {
+		if wm.mod != Target {
+			mods = append(mods, wm.mod)
+		}
+	}
+	return mods
+}
+
+// isMainModule reports whether m is one of the main modules.
+func isMainModule(m module.Version) bool {
+	if m == Target {
+		return true
+	}
+	return m.Version == "" && workModuleByPath[m.Path] != nil
+}
+
+// pathInWorkspace returns the import path of the directory dir
+// if it lies within a workspace module other than Target, or else "".
+// Directories in nested modules belong to the innermost module.
+func pathInWorkspace(dir string) string {
+	var best *workModule
+	var sub string
+	for _, wm := range workModules {
+		if s := search.InDir(dir, wm.dir); s != "" && (best == nil || len(wm.dir) > len(best.dir)) {
+			best, sub = wm, s
+		}
+	}
+	if best == nil || best.mod == Target || strings.Contains(sub, "@") {
+		return ""
+	}
+	if sub == "." {
+		return best.mod.Path
+	}
+	return best.mod.Path + "/" + filepath.ToSlash(sub)
+}
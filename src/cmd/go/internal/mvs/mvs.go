@@ -338,6 +338,56 @@ func Req(target module.Version, base []string, reqs Reqs) ([]module.Version, err
 	return min, nil
 }
 
+// Why returns, for each module in mods, a shortest chain of requirements
+// by which the module graph of target comes to include it: a list that
+// begins with target and ends with the module, in which each module
+// requires the next. Because the build list selects the maximum required
+// version of each module path, the chain for a module in the build list
+// shows a requirement that forces its selected version.
+// The chain is nil for a module that is not in the module graph.
+func Why(target module.Version, reqs Reqs, mods []module.Version) ([][]module.Version, error) {
+	// neededBy[m] is the module that first added m to the graph
+	// in a breadth-first walk from target.
+	neededBy := map[module.Version]module.Version{}
+	seen := map[module.Version]bool{target: true}
+	q := []module.Version{target}
+	for len(q) > 0 {
+		m := q[0]
+		q = q[1:]
+		required, err := reqs.Required(m)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range required {
+			if r.Version == "none" || seen[r] {
+				continue
+			}
+			seen[r] = true
+			neededBy[r] = m
+			q = append(q, r)
+		}
+	}
+
+	chains := make([][]module.Version, len(mods))
+	for i, m := range mods {
+		if !seen[m] {
+			continue
+		}
+		var chain []module.Version
+		for x := m; ; x = neededBy[x] {
+			chain = append(chain, x)
+			if x == target {
+				break
+			}
+		}
+		for j, k := 0, len(chain)-1; j < k; j, k = j+1, k-1 {
+			chain[j], chain[k] = chain[k], chain[j]
+		}
+		chains[i] = chain
+	}
+	return chains, nil
+}
+
 // UpgradeAll returns a build list for the target module
 // in which every module is upgraded to its latest version.
 func UpgradeAll(target module.Version, reqs Reqs) ([]module.Version, error) {
@@ -29,6 +29,12 @@ D5: E2
 G1: C4
 A2: B1 C4 D4
 build A: A B1 C2 D4 E2 F1
+why A D4: A C2 D4
+why A E2: A B1 D3 E2
+why A F1: A C2 D4 F1
+why A D3: A B1 D3
+why A G1:
+why A A: A
 upgrade* A: A B1 C4 D5 E2 F1 G1
 upgrade A C4: A B1 C4 D4 E2 F1 G1
 downgrade A2 D2: A2 C4 D2
@@ -435,6 +441,19 @@ func Test(t *testing.T) {
 				checkList(t, key, list, err, val)
 			})
 			continue
+		case "why":
+			if len(kf) != 3 {
+				t.Fatalf("why takes two arguments: %q", line)
+			}
+			fns = append(fns, func(t *testing.T) {
+				chains, err := Why(m(kf[1]), reqs, []module.Version{m(kf[2])})
+				var chain []module.Version
+				if err == nil {
+					chain = chains[0]
+				}
+				checkList(t, key, chain, err, val)
+			})
+			continue
 		case "req":
 			if len(kf) < 2 {
 				t.Fatalf("req takes at least one argument: %q", line)
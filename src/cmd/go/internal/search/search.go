@@ -119,10 +119,16 @@ func MatchPackages(pattern string) *Match {
 	return m
 }
 
-var modRoot string
+var modRoots []string
 
 func SetModRoot(dir string) {
-	modRoot = dir
+	modRoots = []string{dir}
+}
+
+// SetModRoots sets the root directories of the main modules
+// when there are several, as in workspace mode.
+func SetModRoots(dirs []string) {
+	modRoots = dirs
 }
 
 // MatchPackagesInFS is like MatchPackages but is passed a pattern that
@@ -163,13 +169,23 @@ func MatchPackagesInFS(pattern string) *Match {
 	// We need to preserve the ./ for pattern matching
 	// and in the returned import paths.
 
-	if modRoot != "" {
+	if len(modRoots) > 0 {
 		abs, err := filepath.Abs(dir)
 		if err != nil {
 			base.Fatalf("go: %v", err)
 		}
-		if !hasFilepathPrefix(abs, modRoot) {
-			base.Fatalf("go: pattern %s refers to dir %s, outside module root %s", pattern, abs, modRoot)
+		inRoot := false
+		for _, root := range modRoots {
+			if hasFilepathPrefix(abs, root) {
+				inRoot = true
+				break
+			}
+		}
+		if !inRoot {
+			if len(modRoots) == 1 {
+				base.Fatalf("go: pattern %s refers to dir %s, outside module root %s", pattern, abs, modRoots[0])
+			}
+			base.Fatalf("go: pattern %s refers to dir %s, outside the workspace modules", pattern, abs)
 			return nil
 		}
 	}
@@ -128,6 +128,12 @@ A cached test result is treated as executing in no time at all,
 so a successful package test result will be cached and reused
 regardless of -timeout setting.
 
+When the -shard or -saveshardtimes flag is set, go test records in the
+build cache how long each test and example of each package takes to run.
+Later runs, sharded or not, start the packages whose tests took longest
+first, so that they do not delay the end of the run. The results are
+still printed in the order of the packages on the command line.
+
 In addition to the build flags, the flags handled by 'go test' itself are:
 
 	-args
@@ -239,6 +245,17 @@ control the execution of any test:
 	-failfast
 	    Do not start new tests after the first test failure.
 
+	-leakcheck
+	    Fail each test that leaves goroutines running after it and its
+	    subtests have completed. Goroutines that are about to exit are
+	    given a short grace period. Parallel tests are not checked
+	    individually; goroutines they leave behind are reported by the
+	    nearest enclosing test that is not parallel.
+
+	-leakallow regexp
+	    With -leakcheck, ignore leaked goroutines whose stack trace
+	    matches the regular expression.
+
 	-list regexp
 	    List tests, benchmarks, or examples matching the regular expression.
 	    No tests, benchmarks or examples will be run. This will only
@@ -263,6 +280,35 @@ control the execution of any test:
 	    of all tests matching X, even those without sub-tests matching Y,
 	    because it must run them to look for those sub-tests.
 
+	-shard i/n
+	    Run only shard i of n (counting from 0) of each package's tests
+	    and examples, so that n machines can each run one shard of the
+	    same 'go test' command. Top-level tests and examples are divided
+	    between the shards; subtests run in the shard of their parent.
+	    The division depends only on the names of the tests, so every
+	    test runs in exactly one shard. Test results are not cached when
+	    -shard is set.
+
+	-shardtimes file
+	    With -shard, balance the shards using the running times of the
+	    tests listed in file, instead of dividing the tests by name.
+	    Each line of the file holds the import path of a package, the
+	    name of one of its tests or examples, and the test's running
+	    time, such as "encoding/json TestMarshal 1.5s". Tests missing
+	    from the file count as taking the average listed time of their
+	    package. For each test to run in exactly one shard, every shard
+	    must be given the same file. The -saveshardtimes flag writes
+	    such a file.
+
+	-saveshardtimes file
+	    Record the running times of the tests in the build cache, as
+	    -shard does, and after the run write the times recorded for the
+	    tests of the listed packages to file, in the form read by
+	    -shardtimes. Each shard records the times of the tests it runs,
+	    so the files written by all the shards of a run can be
+	    concatenated into one -shardtimes file for later runs; when a
+	    test is listed more than once, its last time counts.
+
 	-short
 	    Tell long-running tests to shorten their run time.
 	    It is off by default but set during all.bash so that installing
@@ -274,6 +320,11 @@ control the execution of any test:
 	    If d is 0, the timeout is disabled.
 	    The default is 10 minutes (10m).
 
+	-update
+	    Rewrite golden files with the current output of the tests,
+	    instead of comparing the output against them.
+	    See 'go doc testing/golden'.
+
 	-v
 	    Verbose output: log all tests as they are run. Also print all
 	    text from Log and Logf calls even if the test succeeds.
@@ -482,6 +533,9 @@ var (
 	testArgs         []string
 	testBench        bool
 	testList         bool
+	testShard        string // -shard flag
+	testShardTimes   string // -shardtimes flag
+	testSaveTimes    string // -saveshardtimes flag
 	testShowPass     bool   // show passing output
 	testVetList      string // -vet flag
 	pkgArgs          []string
@@ -553,6 +607,12 @@ func runTest(cmd *base.Command, args []string) {
 	if testProfile != "" && len(pkgs) != 1 {
 		base.Fatalf("cannot use %s flag with multiple packages", testProfile)
 	}
+	if testShardTimes != "" {
+		if testShard == "" {
+			base.Fatalf("cannot use -shardtimes flag without -shard")
+		}
+		readShardTimes(testShardTimes)
+	}
 	initCoverProfile()
 	defer closeCoverProfile()
 
@@ -746,6 +806,14 @@ func runTest(cmd *base.Command, args []string) {
 	// Ultimately the goal is to print the output.
 	root := &work.Action{Mode: "go test", Func: printExitStatus, Deps: prints}
 
+	// Start the packages whose tests took longest in earlier runs first,
+	// so that they do not hold up the end of the whole run.
+	// Listing their runs ahead of the prints gives them priority
+	// in b.Do; the results are still printed in command-line order.
+	if !testC && !testBench && len(runs) > 1 {
+		root.Deps = append(slowestRunsFirst(runs), prints...)
+	}
+
 	// Force the printing of results to happen in order,
 	// one at a time.
 	for i, a := range prints {
@@ -768,6 +836,10 @@ func runTest(cmd *base.Command, args []string) {
 	}
 
 	b.Do(root)
+
+	if testSaveTimes != "" {
+		writeShardTimes(testSaveTimes, pkgs)
+	}
 }
 
 // ensures that package p imports the named package
@@ -1023,6 +1095,158 @@ func declareCoverVars(p *load.Package, files ...string) map[string]*load.CoverVa
 	return coverVars
 }
 
+// recordsTestTimes reports whether the running times of the tests in p
+// are recorded in the build cache.
+func recordsTestTimes(p *load.Package) bool {
+	return p.Root != "" && p.ImportPath != "command-line-arguments" && cache.Default() != nil
+}
+
+// testTimesKey returns the cache key for the recorded running times
+// of the tests in p.
+func testTimesKey(p *load.Package) cache.ActionID {
+	h := cache.NewHash("testTimes")
+	fmt.Fprintf(h, "test times %s %s/%s\n", p.ImportPath, cfg.Goos, cfg.Goarch)
+	return h.Sum()
+}
+
+// cachedTestTimes returns the running times of the tests and examples
+// in p recorded by earlier runs, or nil if there are none.
+func cachedTestTimes(p *load.Package) map[string]time.Duration {
+	if !recordsTestTimes(p) {
+		return nil
+	}
+	data, _, err := cache.Default().GetBytes(testTimesKey(p))
+	if err != nil {
+		return nil
+	}
+	return parseTestTimes(data)
+}
+
+// saveTestTimes merges the running times written by the test binary
+// to file into the times recorded for p. Times of tests that did not
+// run this time, because of -run or -shard, are kept.
+func saveTestTimes(p *load.Package, file string) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		// The test binary exited before writing the times.
+		return
+	}
+	times := parseTestTimes(data)
+	if len(times) == 0 {
+		return
+	}
+	all := cachedTestTimes(p)
+	if all == nil {
+		all = make(map[string]time.Duration)
+	}
+	for name, d := range times {
+		all[name] = d
+	}
+	if err := cache.Default().PutBytes(testTimesKey(p), formatTestTimes(all)); err != nil && cache.DebugTest {
+		fmt.Fprintf(os.Stderr, "testcache: %s: saving test times: %v\n", p.ImportPath, err)
+	}
+}
+
+// parseTestTimes parses test running times in the format of the test
+// binary's -test.timesfile: lines holding a test name and a duration.
+// Malformed lines are ignored.
+func parseTestTimes(data []byte) map[string]time.Duration {
+	times := make(map[string]time.Duration)
+	for _, line := range strings.Split(string(data), "\n") {
+		f := strings.Fields(line)
+		if len(f) != 2 {
+			continue
+		}
+		if d, err := time.ParseDuration(f[1]); err == nil {
+			times[f[0]] = d
+		}
+	}
+	return times
+}
+
+func formatTestTimes(times map[string]time.Duration) []byte {
+	names := make([]string, 0, len(times))
+	for name := range times {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var buf bytes.Buffer
+	for _, name := range names {
+		fmt.Fprintf(&buf, "%s %v\n", name, times[name])
+	}
+	return buf.Bytes()
+}
+
+// shardTimes holds the running times read from the -shardtimes file,
+// indexed by package import path and test name.
+var shardTimes map[string]map[string]time.Duration
+
+// readShardTimes reads the -shardtimes file into shardTimes.
+func readShardTimes(file string) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		base.Fatalf("go test: %v", err)
+	}
+	shardTimes = make(map[string]map[string]time.Duration)
+	for i, line := range strings.Split(string(data), "\n") {
+		f := strings.Fields(line)
+		if len(f) == 0 {
+			continue
+		}
+		var d time.Duration
+		if len(f) == 3 {
+			d, err = time.ParseDuration(f[2])
+		}
+		if len(f) != 3 || err != nil {
+			base.Fatalf("go test: %s:%d: malformed line, want import path, test name and duration", file, i+1)
+		}
+		if shardTimes[f[0]] == nil {
+			shardTimes[f[0]] = make(map[string]time.Duration)
+		}
+		shardTimes[f[0]][f[1]] = d
+	}
+}
+
+// writeShardTimes writes the running times recorded in the build cache
+// for the tests of pkgs to file, in the format read by readShardTimes.
+func writeShardTimes(file string, pkgs []*load.Package) {
+	var buf bytes.Buffer
+	for _, p := range pkgs {
+		times := cachedTestTimes(p)
+		names := make([]string, 0, len(times))
+		for name := range times {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(&buf, "%s %s %v\n", p.ImportPath, name, times[name])
+		}
+	}
+	if err := ioutil.WriteFile(file, buf.Bytes(), 0666); err != nil {
+		base.Fatalf("go test: %v", err)
+	}
+}
+
+// slowestRunsFirst returns the test run actions sorted by the total
+// recorded running time of their tests, longest first. Runs without
+// recorded times keep their relative order at the end.
+func slowestRunsFirst(runs []*work.Action) []*work.Action {
+	total := make(map[*work.Action]time.Duration)
+	for _, a := range runs {
+		if a.Package == nil {
+			continue
+		}
+		for _, d := range cachedTestTimes(a.Package) {
+			total[a] += d
+		}
+	}
+	sorted := append([]*work.Action(nil), runs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return total[sorted[i]] > total[sorted[j]]
+	})
+	return sorted
+}
+
 var noTestsToRun = []byte("\ntesting: warning: no tests to run\n")
 
 type runCache struct {
@@ -1122,7 +1346,21 @@ func (c *runCache) builderRunTest(b *work.Builder, a *work.Action) error {
 	if !c.disableCache && len(execCmd) == 0 {
 		testlogArg = []string{"-test.testlogfile=" + a.Objdir + "testlog.txt"}
 	}
-	args := str.StringList(execCmd, a.Deps[0].BuiltTarget(), testlogArg, testArgs)
+	var timesArg []string
+	var pkgShardTimes map[string]time.Duration
+	recordTimes := (testShard != "" || testSaveTimes != "") && len(execCmd) == 0 && recordsTestTimes(a.Package)
+	if recordTimes {
+		timesArg = []string{"-test.timesfile=" + a.Objdir + "times.txt"}
+	}
+	if testShard != "" && len(execCmd) == 0 {
+		// Only times given with -shardtimes are used to divide the
+		// tests: every shard sees the same file, so the shards agree
+		// on which tests each of them runs.
+		if pkgShardTimes = shardTimes[a.Package.ImportPath]; len(pkgShardTimes) > 0 {
+			timesArg = append(timesArg, "-test.shardtimes="+a.Objdir+"shardtimes.txt")
+		}
+	}
+	args := str.StringList(execCmd, a.Deps[0].BuiltTarget(), testlogArg, timesArg, testArgs)
 
 	if testCoverProfile != "" {
 		// Write coverage to temporary profile, for merging later.
@@ -1140,6 +1378,12 @@ func (c *runCache) builderRunTest(b *work.Builder, a *work.Action) error {
 		}
 	}
 
+	if len(pkgShardTimes) > 0 {
+		if err := ioutil.WriteFile(a.Objdir+"shardtimes.txt", formatTestTimes(pkgShardTimes), 0666); err != nil {
+			return err
+		}
+	}
+
 	cmd := exec.Command(args[0], args[1:]...)
 	cmd.Dir = a.Package.Dir
 	cmd.Env = base.EnvForDir(cmd.Dir, cfg.OrigEnv[:len(cfg.OrigEnv):len(cfg.OrigEnv)])
@@ -1206,6 +1450,9 @@ func (c *runCache) builderRunTest(b *work.Builder, a *work.Action) error {
 	t := fmt.Sprintf("%.3fs", time.Since(t0).Seconds())
 
 	mergeCoverProfile(cmd.Stdout, a.Objdir+"_cover_.out")
+	if recordTimes {
+		saveTestTimes(a.Package, a.Objdir+"times.txt")
+	}
 
 	if err == nil {
 		norun := ""
@@ -33,6 +33,8 @@ var testFlagDefn = []*cmdflag.Defn{
 	{Name: "covermode"},
 	{Name: "coverpkg"},
 	{Name: "exec"},
+	{Name: "shardtimes"},
+	{Name: "saveshardtimes"},
 	{Name: "json", BoolVar: &testJSON},
 	{Name: "vet"},
 
@@ -47,6 +49,8 @@ var testFlagDefn = []*cmdflag.Defn{
 	{Name: "cpu", PassToTest: true},
 	{Name: "cpuprofile", PassToTest: true},
 	{Name: "failfast", BoolVar: new(bool), PassToTest: true},
+	{Name: "leakallow", PassToTest: true},
+	{Name: "leakcheck", BoolVar: new(bool), PassToTest: true},
 	{Name: "list", PassToTest: true},
 	{Name: "memprofile", PassToTest: true},
 	{Name: "memprofilerate", PassToTest: true},
@@ -55,9 +59,11 @@ var testFlagDefn = []*cmdflag.Defn{
 	{Name: "outputdir", PassToTest: true},
 	{Name: "parallel", PassToTest: true},
 	{Name: "run", PassToTest: true},
+	{Name: "shard", PassToTest: true},
 	{Name: "short", BoolVar: new(bool), PassToTest: true},
 	{Name: "timeout", PassToTest: true},
 	{Name: "trace", PassToTest: true},
+	{Name: "update", BoolVar: new(bool), PassToTest: true},
 	{Name: "v", BoolVar: &testV, PassToTest: true},
 }
 
@@ -161,6 +167,8 @@ func testFlags(usage func(), args []string) (packageNames, passToTest []string)
 				testList = true
 			case "timeout":
 				testTimeout = value
+			case "shard":
+				testShard = value
 			case "blockprofile", "cpuprofile", "memprofile", "mutexprofile":
 				testProfile = "-" + f.Name
 				testNeedBinary = true
@@ -188,6 +196,10 @@ func testFlags(usage func(), args []string) (packageNames, passToTest []string)
 				testOutputDir = value
 			case "vet":
 				testVetList = value
+			case "shardtimes":
+				testShardTimes = value
+			case "saveshardtimes":
+				testSaveTimes = value
 			}
 		}
 		if extraWord {
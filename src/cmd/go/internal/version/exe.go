@@ -25,6 +25,17 @@ type exe interface {
 
 	// DataStart returns the writable data segment start address.
 	DataStart() uint64
+
+	// Symbols returns the symbols defined in the symbol table.
+	// The sizes of symbols are zero if the format does not record them.
+	Symbols() ([]exeSym, error)
+}
+
+// An exeSym is a symbol defined in an executable.
+type exeSym struct {
+	name string
+	addr uint64
+	size uint64
 }
 
 // openExe opens file and returns it as an exe.
@@ -116,6 +127,21 @@ func (x *elfExe) DataStart() uint64 {
 	return 0
 }
 
+func (x *elfExe) Symbols() ([]exeSym, error) {
+	syms, err := x.f.Symbols()
+	if err != nil {
+		return nil, err
+	}
+	var list []exeSym
+	for _, s := range syms {
+		if s.Section == elf.SHN_UNDEF || s.Section >= elf.SHN_LORESERVE {
+			continue
+		}
+		list = append(list, exeSym{s.Name, s.Value, s.Size})
+	}
+	return list, nil
+}
+
 // peExe is the PE (Windows Portable Executable) implementation of the exe interface.
 type peExe struct {
 	os *os.File
@@ -177,6 +203,18 @@ func (x *peExe) DataStart() uint64 {
 	return 0
 }
 
+func (x *peExe) Symbols() ([]exeSym, error) {
+	var list []exeSym
+	for _, s := range x.f.Symbols {
+		if s.SectionNumber <= 0 || int(s.SectionNumber) > len(x.f.Sections) {
+			continue
+		}
+		sect := x.f.Sections[s.SectionNumber-1]
+		list = append(list, exeSym{s.Name, x.imageBase() + uint64(sect.VirtualAddress) + uint64(s.Value), 0})
+	}
+	return list, nil
+}
+
 // machoExe is the Mach-O (Apple macOS/iOS) implementation of the exe interface.
 type machoExe struct {
 	os *os.File
@@ -230,6 +268,21 @@ func (x *machoExe) DataStart() uint64 {
 	return 0
 }
 
+func (x *machoExe) Symbols() ([]exeSym, error) {
+	if x.f.Symtab == nil {
+		return nil, nil
+	}
+	const stab = 0xe0
+	var list []exeSym
+	for _, s := range x.f.Symtab.Syms {
+		if s.Sect == 0 || s.Type&stab != 0 {
+			continue
+		}
+		list = append(list, exeSym{s.Name, s.Value, 0})
+	}
+	return list, nil
+}
+
 // xcoffExe is the XCOFF (AIX eXtended COFF) implementation of the exe interface.
 type xcoffExe struct {
 	os *os.File
@@ -261,3 +314,14 @@ func (x *xcoffExe) ReadData(addr, size uint64) ([]byte, error) {
 func (x *xcoffExe) DataStart() uint64 {
 	return x.f.SectionByType(xcoff.STYP_DATA).VirtualAddress
 }
+
+func (x *xcoffExe) Symbols() ([]exeSym, error) {
+	var list []exeSym
+	for _, s := range x.f.Symbols {
+		if s.SectionNumber <= 0 || int(s.SectionNumber) > len(x.f.Sections) {
+			continue
+		}
+		list = append(list, exeSym{s.Name, s.Value, uint64(s.AuxCSect.Length)})
+	}
+	return list, nil
+}
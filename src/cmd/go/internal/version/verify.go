@@ -0,0 +1,453 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"cmd/go/internal/base"
+	"cmd/internal/buildid"
+
+	"golang.org/x/mod/modfile"
+)
+
+// A buildInfo is the parsed form of the module build information
+// embedded in a binary, as printed by go version -m.
+type buildInfo struct {
+	path     string     // main package path
+	main     infoModule // module containing the main package
+	deps     []infoModule
+	settings []infoSetting
+}
+
+type infoModule struct {
+	path, version, sum string
+	replace            *infoModule
+}
+
+type infoSetting struct {
+	key, value string
+}
+
+// parseBuildInfo parses the module build information mod.
+func parseBuildInfo(mod string) (*buildInfo, error) {
+	info := new(buildInfo)
+	var last *infoModule
+	for _, line := range strings.Split(strings.TrimSuffix(mod, "\n"), "\n") {
+		f := strings.Split(line, "\t")
+		switch {
+		case len(f) == 2 && f[0] == "path":
+			info.path = f[1]
+		case len(f) >= 3 && f[0] == "mod":
+			info.main = infoModule{path: f[1], version: f[2]}
+			if len(f) > 3 {
+				info.main.sum = f[3]
+			}
+			last = &info.main
+		case len(f) >= 3 && f[0] == "dep":
+			m := infoModule{path: f[1], version: f[2]}
+			if len(f) > 3 {
+				m.sum = f[3]
+			}
+			info.deps = append(info.deps, m)
+			last = &info.deps[len(info.deps)-1]
+		case len(f) >= 3 && f[0] == "=>" && last != nil:
+			r := &infoModule{path: f[1], version: f[2]}
+			if len(f) > 3 {
+				r.sum = f[3]
+			}
+			last.replace = r
+		case len(f) == 2 && f[0] == "build":
+			i := strings.Index(f[1], "=")
+			if i < 0 {
+				return nil, fmt.Errorf("malformed build setting %q", f[1])
+			}
+			info.settings = append(info.settings, infoSetting{f[1][:i], f[1][i+1:]})
+		default:
+			return nil, fmt.Errorf("malformed build information line %q", line)
+		}
+	}
+	if info.path == "" || info.main.path == "" {
+		return nil, errors.New("incomplete build information")
+	}
+	return info, nil
+}
+
+// setting returns the value of the build setting key, or "".
+func (info *buildInfo) setting(key string) string {
+	for _, s := range info.settings {
+		if s.key == key {
+			return s.value
+		}
+	}
+	return ""
+}
+
+// verifyFile rebuilds the binary file from the information recorded
+// in it and reports whether the result is identical.
+func verifyFile(file string) {
+	x, err := openExe(file)
+	if err != nil {
+		base.Errorf("go version -verify: %s: %v", file, err)
+		return
+	}
+	vers, mod := findVers(x)
+	x.Close()
+	if vers == "" {
+		base.Errorf("go version -verify: %s: go version not found", file)
+		return
+	}
+	if mod == "" {
+		base.Errorf("go version -verify: %s: no module build information", file)
+		return
+	}
+	if vers != runtime.Version() {
+		base.Errorf("go version -verify: %s: built with %s, cannot rebuild with %s", file, vers, runtime.Version())
+		return
+	}
+	info, err := parseBuildInfo(mod)
+	if err != nil {
+		base.Errorf("go version -verify: %s: %v", file, err)
+		return
+	}
+
+	tmp, err := ioutil.TempDir("", "go-verify-")
+	if err != nil {
+		base.Fatalf("go version -verify: %v", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	from, err := rebuild(info, tmp)
+	if err != nil {
+		base.Errorf("go version -verify: %s: %v", file, err)
+		return
+	}
+
+	orig, err := ioutil.ReadFile(file)
+	if err != nil {
+		base.Errorf("go version -verify: %v", err)
+		return
+	}
+	exe := filepath.Join(tmp, "exe")
+	data, err := ioutil.ReadFile(exe)
+	if err != nil {
+		base.Errorf("go version -verify: %v", err)
+		return
+	}
+	if bytes.Equal(orig, data) {
+		fmt.Printf("%s: reproducible from %s\n", file, from)
+		return
+	}
+	fmt.Printf("%s: not reproducible from %s\n", file, from)
+	for _, d := range explainDiff(file, exe) {
+		fmt.Printf("\t%s\n", d)
+	}
+	base.SetExitStatus(1)
+}
+
+// rebuild builds the main package described by info in the directory
+// tmp, writing the binary to tmp/exe. It returns a description of the
+// source it was built from.
+func rebuild(info *buildInfo, tmp string) (from string, err error) {
+	if info.setting("-trimpath") != "true" {
+		return "", errors.New("not built with -trimpath")
+	}
+	if c := info.setting("-compiler"); c != "gc" {
+		return "", fmt.Errorf("built with -compiler=%s", c)
+	}
+	if info.setting("vcs.modified") == "true" {
+		return "", errors.New("built from a modified checkout")
+	}
+
+	var dir string
+	env := []string{"GO111MODULE=on", "GOFLAGS="}
+	if rev := info.setting("vcs.revision"); rev != "" {
+		if info.setting("vcs") != "git" {
+			return "", fmt.Errorf("unsupported version control system %s", info.setting("vcs"))
+		}
+		dir, err = checkoutModule(info, tmp, rev)
+		if err != nil {
+			return "", err
+		}
+		from = "git revision " + rev
+	} else if info.main.version != "(devel)" && info.main.replace == nil {
+		dir, err = requireModules(info, tmp)
+		if err != nil {
+			return "", err
+		}
+		env = []string{"GO111MODULE=on", "GOFLAGS=-mod=mod"}
+		from = info.main.path + "@" + info.main.version
+	} else {
+		return "", errors.New("no module version or version control revision recorded")
+	}
+
+	args := []string{"build", "-o", filepath.Join(tmp, "exe")}
+	if info.setting("vcs") == "" {
+		args = append(args, "-buildvcs=false")
+	}
+	for _, s := range info.settings {
+		switch s.key {
+		case "-race", "-msan", "-trimpath":
+			args = append(args, s.key)
+		case "-buildmode", "-compiler", "-asmflags", "-gcflags", "-gccgoflags", "-ldflags", "-tags", "-pgo":
+			args = append(args, s.key+"="+s.value)
+		}
+	}
+	args = append(args, info.path)
+	for _, key := range []string{"CGO_ENABLED", "CGO_CFLAGS", "CGO_CPPFLAGS", "CGO_CXXFLAGS", "CGO_LDFLAGS", "GOARCH", "GOOS", "GOARM", "GO386", "GOMIPS", "GOMIPS64", "GOPPC64", "GOWASM"} {
+		env = append(env, key+"="+info.setting(key))
+	}
+
+	gocmd, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	cmd := exec.Command(gocmd, args...)
+	cmd.Dir = dir
+	cmd.Env = base.EnvForDir(dir, append(os.Environ(), env...))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("rebuilding from %s: go %s: %v\n%s", from, strings.Join(args, " "), err, out)
+	}
+	return from, nil
+}
+
+// checkoutModule clones the Git repository containing the current
+// directory into tmp, checks out revision rev, and returns the
+// directory of the main module of info in the checkout.
+func checkoutModule(info *buildInfo, tmp, rev string) (string, error) {
+	out, err := runGit(base.Cwd, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", fmt.Errorf("built from git revision %s: run go version -verify in a checkout of its repository", rev)
+	}
+	root := strings.TrimSpace(string(out))
+	src := filepath.Join(tmp, "src")
+	if _, err := runGit(tmp, "clone", "-q", "--no-checkout", root, src); err != nil {
+		return "", err
+	}
+	if _, err := runGit(src, "-c", "advice.detachedHead=false", "checkout", "-q", rev); err != nil {
+		return "", err
+	}
+
+	// Find the main module in the checkout.
+	var dir string
+	filepath.Walk(src, func(path string, fi os.FileInfo, err error) error {
+		if err != nil || dir != "" {
+			return filepath.SkipDir
+		}
+		if fi.IsDir() {
+			switch fi.Name() {
+			case ".git", "testdata", "vendor":
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if fi.Name() == "go.mod" {
+			data, err := ioutil.ReadFile(path)
+			if err == nil && modfile.ModulePath(data) == info.main.path {
+				dir = filepath.Dir(path)
+			}
+		}
+		return nil
+	})
+	if dir == "" {
+		return "", fmt.Errorf("module %s not found at git revision %s", info.main.path, rev)
+	}
+	return dir, nil
+}
+
+// requireModules writes to tmp a module requiring exactly the module
+// versions recorded in info, with the recorded checksums, and returns
+// its directory.
+func requireModules(info *buildInfo, tmp string) (string, error) {
+	var gomod, gosum bytes.Buffer
+	fmt.Fprintf(&gomod, "module verify\n\nrequire %s %s\n", info.main.path, info.main.version)
+	fmt.Fprintf(&gosum, "%s %s %s\n", info.main.path, info.main.version, info.main.sum)
+	for _, m := range info.deps {
+		fmt.Fprintf(&gomod, "require %s %s\n", m.path, m.version)
+		r := m.replace
+		if r == nil {
+			fmt.Fprintf(&gosum, "%s %s %s\n", m.path, m.version, m.sum)
+			continue
+		}
+		if r.version == "" {
+			return "", fmt.Errorf("dependency %s replaced by directory %s", m.path, r.path)
+		}
+		fmt.Fprintf(&gomod, "replace %s %s => %s %s\n", m.path, m.version, r.path, r.version)
+		fmt.Fprintf(&gosum, "%s %s %s\n", r.path, r.version, r.sum)
+	}
+	if err := ioutil.WriteFile(filepath.Join(tmp, "go.mod"), gomod.Bytes(), 0666); err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(filepath.Join(tmp, "go.sum"), gosum.Bytes(), 0666); err != nil {
+		return "", err
+	}
+	return tmp, nil
+}
+
+// explainDiff describes how the binaries orig and rebuilt differ,
+// as far as their build IDs and symbol tables tell.
+func explainDiff(orig, rebuilt string) []string {
+	var diffs []string
+
+	// A binary's build ID is
+	//	actionID(binary)/actionID(main.a)/contentID(main.a)/contentID(binary)
+	// (see cmd/go/internal/work/buildid.go). The link action ID
+	// summarizes all the link inputs.
+	id1, err1 := buildid.ReadFile(orig)
+	id2, err2 := buildid.ReadFile(rebuilt)
+	f1, f2 := strings.Split(id1, "/"), strings.Split(id2, "/")
+	if err1 == nil && err2 == nil && len(f1) == 4 && len(f2) == 4 {
+		switch {
+		case f1[2] != f2[2]:
+			diffs = append(diffs, "main package archive differs")
+			fallthrough
+		case f1[0] != f2[0]:
+			diffs = append(diffs, "link inputs differ")
+		default:
+			diffs = append(diffs, "link inputs are identical; linker output differs")
+		}
+	}
+
+	syms1, err1 := readSymbols(orig)
+	syms2, err2 := readSymbols(rebuilt)
+	if err1 != nil || err2 != nil || len(syms1) == 0 || len(syms2) == 0 {
+		return append(diffs, "no symbol table to compare")
+	}
+
+	// If the layout is unchanged, compare the symbols' contents.
+	// Otherwise, symbols that moved differ too, so report only
+	// the symbols that appear, disappear or change size.
+	sameLayout := len(syms1) == len(syms2)
+	for name, s1 := range syms1 {
+		if s2, ok := syms2[name]; !ok || s1.addr != s2.addr || s1.size != s2.size {
+			sameLayout = false
+			break
+		}
+	}
+	changed := make(map[string]int)
+	if sameLayout {
+		x1, err1 := openExe(orig)
+		x2, err2 := openExe(rebuilt)
+		if err1 != nil || err2 != nil {
+			return append(diffs, "cannot read symbols")
+		}
+		defer x1.Close()
+		defer x2.Close()
+		for name, s := range syms1 {
+			if s.size == 0 {
+				continue
+			}
+			d1, err1 := x1.ReadData(s.addr, s.size)
+			d2, err2 := x2.ReadData(s.addr, s.size)
+			if err1 == nil && err2 == nil && !bytes.Equal(d1, d2) {
+				changed[symPackage(name)]++
+			}
+		}
+	} else {
+		for name, s1 := range syms1 {
+			if s2, ok := syms2[name]; !ok || s1.size != s2.size {
+				changed[symPackage(name)]++
+			}
+		}
+		for name := range syms2 {
+			if _, ok := syms1[name]; !ok {
+				changed[symPackage(name)]++
+			}
+		}
+	}
+
+	var pkgs []string
+	for pkg := range changed {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+	for _, pkg := range pkgs {
+		what := "package " + pkg
+		if pkg == "" {
+			what = "linker-generated data"
+		}
+		n := changed[pkg]
+		syms := "symbols"
+		if n == 1 {
+			syms = "symbol"
+		}
+		if sameLayout {
+			diffs = append(diffs, fmt.Sprintf("%s: %d %s differ", what, n, syms))
+		} else {
+			diffs = append(diffs, fmt.Sprintf("%s: %d %s added, removed or resized", what, n, syms))
+		}
+	}
+	return diffs
+}
+
+// readSymbols returns the symbols of the executable file by name.
+// Symbols without a recorded size extend to the next symbol.
+func readSymbols(file string) (map[string]exeSym, error) {
+	x, err := openExe(file)
+	if err != nil {
+		return nil, err
+	}
+	defer x.Close()
+	list, err := x.Symbols()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].addr < list[j].addr })
+	syms := make(map[string]exeSym)
+	for i, s := range list {
+		if s.size == 0 && i+1 < len(list) {
+			s.size = list[i+1].addr - s.addr
+		}
+		syms[s.name] = s
+	}
+	return syms, nil
+}
+
+// symPackage returns the import path of the package defining the Go
+// symbol name, or "" for symbols generated by the linker.
+func symPackage(name string) string {
+	if strings.HasPrefix(name, "type.") || strings.HasPrefix(name, "go.") {
+		return ""
+	}
+	// The package path ends at the first dot after its last slash,
+	// not counting slashes in the symbol's own name, which follow
+	// a parenthesis or bracket as in "p.(*T).M" or "p.F[...]".
+	path := name
+	if i := strings.IndexAny(path, "(["); i >= 0 {
+		path = path[:i]
+	}
+	i := strings.LastIndex(path, "/")
+	j := strings.Index(name[i+1:], ".")
+	if j < 0 {
+		return ""
+	}
+	return name[:i+1+j]
+}
+
+// runGit runs git with the given arguments in dir and returns its
+// standard output.
+func runGit(dir string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if stderr.Len() > 0 {
+			return nil, fmt.Errorf("git %s: %s", strings.Join(args, " "), bytes.TrimSpace(stderr.Bytes()))
+		}
+		return nil, fmt.Errorf("git %s: %v", strings.Join(args, " "), err)
+	}
+	return stdout.Bytes(), nil
+}
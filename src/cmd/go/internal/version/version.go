@@ -18,7 +18,7 @@ import (
 )
 
 var CmdVersion = &base.Command{
-	UsageLine: "go version [-m] [-v] [file ...]",
+	UsageLine: "go version [-m] [-v] [-verify] [file ...]",
 	Short:     "print Go version",
 	Long: `Version prints the build information for Go executables.
 
@@ -36,7 +36,26 @@ during a directory scan. The -v flag causes it to report unrecognized files.
 The -m flag causes go version to print each executable's embedded
 module version information, when available. In the output, the module
 information consists of multiple lines following the version line, each
-indented by a leading tab character.
+indented by a leading tab character. For binaries built in module mode,
+the information ends with the settings used for the build, one per
+"build" line: the compiler and build flags, the cgo setting, GOOS and
+GOARCH, and, for binaries built by 'go build' or 'go install' inside
+a Git checkout, the current commit, its time, and whether the checkout
+had uncommitted changes (see the -buildvcs build flag). Binaries built
+in GOPATH mode carry no module information and no build settings.
+
+The -verify flag causes go version to check that each named executable
+can be reproduced. It rebuilds the executable with the current Go
+toolchain and the recorded build settings, from the source named by its
+build information: for a binary built inside a Git checkout, the
+recorded revision of the repository containing the current directory;
+otherwise, the recorded versions of the modules providing the main
+package and its dependencies. The binary must have been built with
+-trimpath by the same Go version, and not from a checkout with
+uncommitted changes. Go version then reports whether the rebuilt
+binary is bit-identical to the original. If not, it reports whether
+the main package archive or the other link inputs differ, and which
+packages define symbols that differ, and exits with a non-zero status.
 
 See also: go doc runtime/debug.BuildInfo.
 `,
@@ -47,8 +66,9 @@ func init() {
 }
 
 var (
-	versionM = CmdVersion.Flag.Bool("m", false, "")
-	versionV = CmdVersion.Flag.Bool("v", false, "")
+	versionM      = CmdVersion.Flag.Bool("m", false, "")
+	versionV      = CmdVersion.Flag.Bool("v", false, "")
+	versionVerify = CmdVersion.Flag.Bool("verify", false, "")
 )
 
 func runVersion(cmd *base.Command, args []string) {
@@ -57,6 +77,13 @@ func runVersion(cmd *base.Command, args []string) {
 		return
 	}
 
+	if *versionVerify {
+		for _, arg := range args {
+			verifyFile(arg)
+		}
+		return
+	}
+
 	for _, arg := range args {
 		info, err := os.Stat(arg)
 		if err != nil {
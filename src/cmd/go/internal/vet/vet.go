@@ -36,6 +36,41 @@ For example, the 'shadow' analyzer can be built and run using these commands:
   go install golang.org/x/tools/go/analysis/passes/shadow/cmd/shadow
   go vet -vettool=$(which shadow)
 
+In module mode, a go.vet file in the main module's root directory
+configures the analyzers that go vet runs. Its syntax is that of go.mod
+files, with these directives:
+
+	analyzer path [var]
+		Run the analyzer declared by the exported variable var,
+		of type *analysis.Analyzer, in the package with import path
+		path. The default var is Analyzer.
+
+	enable name
+	disable name
+		Enable or disable the analyzer with the given name, as the
+		-name=true and -name=false vet flags do. Flags given on the
+		command line override these settings.
+
+	severity name error|warning
+		Set the severity of the analyzer's diagnostics. Diagnostics
+		of severity warning are reported but do not make go vet fail.
+		The default severity is error.
+
+For example:
+
+	analyzer example.com/lint/nilctx
+
+	disable composites
+	severity nilctx warning
+
+The go command builds a vet tool containing the standard analyzers and
+those named by analyzer directives from the packages of the main
+module's build list, which must therefore provide golang.org/x/tools.
+The tool is kept in the build cache directory and rebuilt only when its
+sources change. The analyzer directive cannot be combined with -vettool.
+Because go vet checks its command-line flags against those of cmd/vet,
+flags of the added analyzers cannot be given on the command line.
+
 The build flags supported by go vet are those that control package resolution
 and execution, such as -n, -x, -v, -tags, and -toolexec.
 For more about these flags, see 'go help build'.
@@ -61,6 +96,22 @@ func runVet(cmd *base.Command, args []string) {
 			base.Fatalf("%v", err)
 		}
 	}
+	if vc := readVetConfig(); vc != nil {
+		if len(vc.analyzers) > 0 {
+			if vetTool != "" {
+				base.Fatalf("go vet: -vettool cannot be used with analyzers listed in %s", base.ShortPath(vc.file))
+			}
+			work.VetTool = vc.buildTool()
+		}
+		tool := work.VetTool
+		if tool == "" {
+			tool = base.Tool("vet")
+		}
+		vc.check(vetToolFlags(tool))
+		// Flags on the command line override the go.vet settings.
+		work.VetFlags = append(vc.flags(), vetFlags...)
+		work.VetWarnings = vc.warnings()
+	}
 
 	pkgs := load.PackagesForBuild(pkgArgs)
 	if len(pkgs) == 0 {
@@ -0,0 +1,308 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vet
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"cmd/go/internal/base"
+	"cmd/go/internal/cache"
+	"cmd/go/internal/cfg"
+	"cmd/go/internal/load"
+	"cmd/go/internal/modload"
+	"cmd/go/internal/work"
+
+	"golang.org/x/mod/modfile"
+)
+
+// A vetConfig is the parsed, interpreted form of a go.vet file.
+//
+// A go.vet file in the root directory of the main module configures
+// the analyzers run by go vet:
+//
+//	analyzer example.com/lint/nilctx
+//	disable composites
+//	severity nilctx warning
+//
+// Its syntax is that of go.mod files. Each directive may also be
+// written as a block, as in go.mod.
+type vetConfig struct {
+	file      string
+	analyzers []vetAnalyzer
+	settings  []vetSetting
+}
+
+// A vetAnalyzer is an analyzer added by an analyzer directive:
+// the exported variable Var, of type *analysis.Analyzer,
+// in the package with import path Path.
+type vetAnalyzer struct {
+	Path string
+	Var  string
+}
+
+// A vetSetting is an enable, disable or severity directive
+// applying to the analyzer named Name.
+type vetSetting struct {
+	Verb  string
+	Name  string
+	Value string // severity, for severity directives
+	Line  int
+}
+
+// readVetConfig reads the go.vet file in the main module's root
+// directory. It returns nil if there is no main module or no go.vet file.
+func readVetConfig() *vetConfig {
+	if !modload.HasModRoot() {
+		return nil
+	}
+	file := filepath.Join(modload.ModRoot(), "go.vet")
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		base.Fatalf("go: %v", err)
+	}
+	vc, err := parseVetConfig(file, data)
+	if err != nil {
+		base.Fatalf("go: %v", err)
+	}
+	return vc
+}
+
+// parseVetConfig parses the data, reported in errors as being from file,
+// into a vetConfig.
+func parseVetConfig(file string, data []byte) (*vetConfig, error) {
+	f, err := modfile.ParseLax(file, data, nil)
+	if err != nil {
+		return nil, err
+	}
+	vc := &vetConfig{file: file}
+
+	var errs bytes.Buffer
+	add := func(line *modfile.Line, verb string, args []string) {
+		for i, arg := range args {
+			if strings.HasPrefix(arg, `"`) {
+				if args[i], err = strconv.Unquote(arg); err != nil {
+					fmt.Fprintf(&errs, "%s:%d: invalid quoted string: %v\n", file, line.Start.Line, err)
+					return
+				}
+			}
+		}
+		switch verb {
+		default:
+			fmt.Fprintf(&errs, "%s:%d: unknown directive: %s\n", file, line.Start.Line, verb)
+		case "analyzer":
+			if len(args) != 1 && len(args) != 2 {
+				fmt.Fprintf(&errs, "%s:%d: usage: analyzer path [var]\n", file, line.Start.Line)
+				return
+			}
+			a := vetAnalyzer{Path: args[0], Var: "Analyzer"}
+			if len(args) == 2 {
+				a.Var = args[1]
+			}
+			vc.analyzers = append(vc.analyzers, a)
+		case "enable", "disable":
+			if len(args) != 1 {
+				fmt.Fprintf(&errs, "%s:%d: usage: %s name\n", file, line.Start.Line, verb)
+				return
+			}
+			vc.settings = append(vc.settings, vetSetting{Verb: verb, Name: args[0], Line: line.Start.Line})
+		case "severity":
+			if len(args) != 2 {
+				fmt.Fprintf(&errs, "%s:%d: usage: severity name error|warning\n", file, line.Start.Line)
+				return
+			}
+			if args[1] != "error" && args[1] != "warning" {
+				fmt.Fprintf(&errs, "%s:%d: invalid severity %q: must be error or warning\n", file, line.Start.Line, args[1])
+				return
+			}
+			vc.settings = append(vc.settings, vetSetting{Verb: verb, Name: args[0], Value: args[1], Line: line.Start.Line})
+		}
+	}
+	for _, x := range f.Syntax.Stmt {
+		switch x := x.(type) {
+		case *modfile.Line:
+			add(x, x.Token[0], x.Token[1:])
+		case *modfile.LineBlock:
+			if len(x.Token) > 1 {
+				fmt.Fprintf(&errs, "%s:%d: unknown block type: %s\n", file, x.Start.Line, strings.Join(x.Token, " "))
+				continue
+			}
+			for _, l := range x.Line {
+				add(l, x.Token[0], l.Token)
+			}
+		}
+	}
+	if errs.Len() > 0 {
+		return nil, fmt.Errorf("%s", strings.TrimRight(errs.String(), "\n"))
+	}
+	return vc, nil
+}
+
+// check reports an error for each setting that names an analyzer
+// not known to the vet tool, whose flags are described by flags.
+func (vc *vetConfig) check(flags []vetToolFlag) {
+	known := make(map[string]bool)
+	for _, f := range flags {
+		if f.Bool {
+			known[f.Name] = true
+		}
+	}
+	for _, s := range vc.settings {
+		if !known[s.Name] {
+			base.Errorf("go: %s:%d: unknown analyzer %s", base.ShortPath(vc.file), s.Line, s.Name)
+		}
+	}
+	base.ExitIfErrors()
+}
+
+// flags returns the vet tool flags that implement the enable
+// and disable directives.
+func (vc *vetConfig) flags() []string {
+	var flags []string
+	for _, s := range vc.settings {
+		switch s.Verb {
+		case "enable":
+			flags = append(flags, "-"+s.Name+"=true")
+		case "disable":
+			flags = append(flags, "-"+s.Name+"=false")
+		}
+	}
+	return flags
+}
+
+// warnings returns the set of analyzers whose severity is warning.
+func (vc *vetConfig) warnings() map[string]bool {
+	m := make(map[string]bool)
+	for _, s := range vc.settings {
+		switch {
+		case s.Verb != "severity":
+		case s.Value == "warning":
+			m[s.Name] = true
+		default:
+			delete(m, s.Name)
+		}
+	}
+	return m
+}
+
+// stdAnalyzers lists the packages, in golang.org/x/tools/go/analysis/passes,
+// of the analyzers run by cmd/vet. It must be kept in sync with cmd/vet/main.go.
+var stdAnalyzers = []string{
+	"asmdecl",
+	"assign",
+	"atomic",
+	"bools",
+	"buildtag",
+	"cgocall",
+	"composite",
+	"copylock",
+	"errorsas",
+	"httpresponse",
+	"loopclosure",
+	"lostcancel",
+	"nilfunc",
+	"printf",
+	"shift",
+	"stdmethods",
+	"structtag",
+	"tests",
+	"unmarshal",
+	"unreachable",
+	"unsafeptr",
+	"unusedresult",
+}
+
+// toolSource returns the source of a vet tool main package running
+// the analyzers of cmd/vet and those added by analyzer directives.
+func (vc *vetConfig) toolSource() []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by go vet from go.vet. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package main\n\n")
+	fmt.Fprintf(&buf, "import (\n")
+	fmt.Fprintf(&buf, "\t%q\n\n", "golang.org/x/tools/go/analysis/unitchecker")
+	for _, name := range stdAnalyzers {
+		fmt.Fprintf(&buf, "\t%q\n", "golang.org/x/tools/go/analysis/passes/"+name)
+	}
+	for i, a := range vc.analyzers {
+		fmt.Fprintf(&buf, "\ta%d %q\n", i, a.Path)
+	}
+	fmt.Fprintf(&buf, ")\n\n")
+	fmt.Fprintf(&buf, "func main() {\n")
+	fmt.Fprintf(&buf, "\tunitchecker.Main(\n")
+	for _, name := range stdAnalyzers {
+		fmt.Fprintf(&buf, "\t\t%s.Analyzer,\n", name)
+	}
+	for i, a := range vc.analyzers {
+		fmt.Fprintf(&buf, "\t\ta%d.%s,\n", i, a.Var)
+	}
+	fmt.Fprintf(&buf, "\t)\n")
+	fmt.Fprintf(&buf, "}\n")
+	return buf.Bytes()
+}
+
+// buildTool builds the vet tool for the analyzer directives of vc
+// from the sources in the module graph and returns its path.
+// The tool is installed in the build cache directory, in a location
+// determined by its source, so that later runs with the same
+// go.vet file reuse it if its dependencies are unchanged.
+func (vc *vetConfig) buildTool() string {
+	if cfg.Goos != runtime.GOOS || cfg.Goarch != runtime.GOARCH {
+		base.Fatalf("go vet: cannot build analyzers in %s for GOOS=%s GOARCH=%s", base.ShortPath(vc.file), cfg.Goos, cfg.Goarch)
+	}
+	dir := cache.DefaultDir()
+	if dir == "off" {
+		base.Fatalf("go vet: analyzers in %s require the build cache", base.ShortPath(vc.file))
+	}
+
+	// The source is kept next to the tool: the package's directory
+	// is part of its build cache key, so it must not change.
+	src := vc.toolSource()
+	dir = filepath.Join(dir, "vet", fmt.Sprintf("%x", sha256.Sum256(src)))
+	file := filepath.Join(dir, "main.go")
+	if _, err := os.Stat(file); err != nil {
+		if err := os.MkdirAll(dir, 0777); err != nil {
+			base.Fatalf("go vet: %v", err)
+		}
+		tmp := fmt.Sprintf("%s.%d.tmp", file, os.Getpid())
+		if err := ioutil.WriteFile(tmp, src, 0666); err != nil {
+			base.Fatalf("go vet: %v", err)
+		}
+		if err := os.Rename(tmp, file); err != nil {
+			base.Fatalf("go vet: %v", err)
+		}
+	}
+
+	p := load.GoFilesPackage([]string{file})
+	if p.Error != nil {
+		base.Fatalf("go vet: %s", p.Error)
+	}
+	printed := make(map[*load.PackageError]bool)
+	for _, err := range p.DepsErrors {
+		if !printed[err] {
+			printed[err] = true
+			base.Errorf("go vet: %s", err)
+		}
+	}
+	base.ExitIfErrors()
+
+	var b work.Builder
+	b.Init()
+	p.Target = filepath.Join(dir, "vet"+cfg.ExeSuffix)
+	p.Stale = true
+	p.StaleReason = "go.vet analyzers"
+	b.Do(b.AutoAction(work.ModeInstall, work.ModeBuild, p))
+	base.ExitIfErrors()
+	return p.Target
+}
@@ -71,24 +71,7 @@ func vetFlags(usage func(), args []string) (passToVet, packageNames []string) {
 	} else {
 		tool = base.Tool("vet")
 	}
-	out := new(bytes.Buffer)
-	vetcmd := exec.Command(tool, "-flags")
-	vetcmd.Stdout = out
-	if err := vetcmd.Run(); err != nil {
-		fmt.Fprintf(os.Stderr, "go vet: can't execute %s -flags: %v\n", tool, err)
-		base.SetExitStatus(2)
-		base.Exit()
-	}
-	var analysisFlags []struct {
-		Name  string
-		Bool  bool
-		Usage string
-	}
-	if err := json.Unmarshal(out.Bytes(), &analysisFlags); err != nil {
-		fmt.Fprintf(os.Stderr, "go vet: can't unmarshal JSON from %s -flags: %v", tool, err)
-		base.SetExitStatus(2)
-		base.Exit()
-	}
+	analysisFlags := vetToolFlags(tool)
 
 	// Add vet's flags to vetflagDefn.
 	//
@@ -174,6 +157,33 @@ func vetFlags(usage func(), args []string) (passToVet, packageNames []string) {
 	return args, nil
 }
 
+// A vetToolFlag describes a flag of a vet tool.
+type vetToolFlag struct {
+	Name  string
+	Bool  bool
+	Usage string
+}
+
+// vetToolFlags runs the vet tool with -flags and returns
+// the flags it describes.
+func vetToolFlags(tool string) []vetToolFlag {
+	out := new(bytes.Buffer)
+	vetcmd := exec.Command(tool, "-flags")
+	vetcmd.Stdout = out
+	if err := vetcmd.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "go vet: can't execute %s -flags: %v\n", tool, err)
+		base.SetExitStatus(2)
+		base.Exit()
+	}
+	var flags []vetToolFlag
+	if err := json.Unmarshal(out.Bytes(), &flags); err != nil {
+		fmt.Fprintf(os.Stderr, "go vet: can't unmarshal JSON from %s -flags: %v", tool, err)
+		base.SetExitStatus(2)
+		base.Exit()
+	}
+	return flags
+}
+
 var vetUsage func()
 
 func init() { vetUsage = usage } // break initialization cycle
@@ -0,0 +1,178 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package vulncheck matches a database of known vulnerabilities
+// against the packages and modules of a build.
+package vulncheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// An Entry is a single vulnerability report in the database.
+// Each entry is stored as a JSON file in the database directory;
+// the format is documented in 'go help mod vuln'.
+type Entry struct {
+	ID       string     // unique identifier, such as "GO-2020-0001"
+	Aliases  []string   `json:",omitempty"` // other identifiers, such as CVE numbers
+	Summary  string     `json:",omitempty"` // one-line description
+	Details  string     `json:",omitempty"` // longer description
+	Affected []Affected // affected modules
+}
+
+// Affected describes the versions and packages of one module
+// affected by a vulnerability.
+type Affected struct {
+	Module   string    // module path, or "std" for the standard library
+	Ranges   []Range   `json:",omitempty"` // affected versions; all versions if empty
+	Packages []Package `json:",omitempty"` // affected packages; all packages if empty
+}
+
+// A Range is a half-open interval of affected versions:
+// Introduced ≤ v < Fixed. An empty Introduced means all versions
+// before Fixed; an empty Fixed means all versions from Introduced on.
+type Range struct {
+	Introduced string `json:",omitempty"`
+	Fixed      string `json:",omitempty"`
+}
+
+// A Package names an affected package and, optionally, the
+// vulnerable functions and methods in it, written as "Func" or
+// "Type.Method". An empty Symbols list means the whole package is
+// affected.
+type Package struct {
+	Path    string
+	Symbols []string `json:",omitempty"`
+}
+
+// LoadDB reads the vulnerability entries in the database directory
+// dir: every file with a .json extension in dir or its subdirectories.
+// Entries are returned sorted by ID.
+func LoadDB(dir string) ([]*Entry, error) {
+	var entries []*Entry
+	seen := make(map[string]string)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(path, ".json") {
+			return nil
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		e := new(Entry)
+		if err := json.Unmarshal(data, e); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		if err := e.check(); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		if prev, ok := seen[e.ID]; ok {
+			return fmt.Errorf("%s: duplicate entry %s (also in %s)", path, e.ID, prev)
+		}
+		seen[e.ID] = path
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+	return entries, nil
+}
+
+// check reports an error if e is malformed.
+func (e *Entry) check() error {
+	if e.ID == "" {
+		return fmt.Errorf("missing ID")
+	}
+	if len(e.Affected) == 0 {
+		return fmt.Errorf("%s: no affected modules", e.ID)
+	}
+	for _, a := range e.Affected {
+		if a.Module == "" {
+			return fmt.Errorf("%s: missing module path", e.ID)
+		}
+		for _, r := range a.Ranges {
+			for _, v := range []string{r.Introduced, r.Fixed} {
+				if v != "" && !semver.IsValid(v) {
+					return fmt.Errorf("%s: %s: invalid version %q", e.ID, a.Module, v)
+				}
+			}
+			if r.Introduced != "" && r.Fixed != "" && semver.Compare(r.Introduced, r.Fixed) >= 0 {
+				return fmt.Errorf("%s: %s: empty version range [%s, %s)", e.ID, a.Module, r.Introduced, r.Fixed)
+			}
+		}
+		for _, p := range a.Packages {
+			if p.Path == "" {
+				return fmt.Errorf("%s: %s: missing package path", e.ID, a.Module)
+			}
+		}
+	}
+	return nil
+}
+
+// AffectsVersion reports whether version v of the module is affected.
+// Versions that are not valid semantic versions, such as those of
+// modules replaced by directories, are never affected.
+func (a *Affected) AffectsVersion(v string) bool {
+	if !semver.IsValid(v) {
+		return false
+	}
+	if len(a.Ranges) == 0 {
+		return true
+	}
+	for _, r := range a.Ranges {
+		if (r.Introduced == "" || semver.Compare(r.Introduced, v) <= 0) &&
+			(r.Fixed == "" || semver.Compare(v, r.Fixed) < 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// FixedIn returns the lowest version that fixes the vulnerability
+// for a module at version v, or "" if there is none.
+func (a *Affected) FixedIn(v string) string {
+	fixed := ""
+	for _, r := range a.Ranges {
+		if r.Fixed != "" && semver.Compare(v, r.Fixed) < 0 && (fixed == "" || semver.Compare(r.Fixed, fixed) < 0) {
+			fixed = r.Fixed
+		}
+	}
+	return fixed
+}
+
+// AffectedModules returns the elements of e.Affected that apply to
+// the listed module versions, paired with the affected version.
+func (e *Entry) AffectedModules(mods []module.Version) []ModuleMatch {
+	var matches []ModuleMatch
+	for i := range e.Affected {
+		a := &e.Affected[i]
+		for _, m := range mods {
+			if m.Path == a.Module && a.AffectsVersion(m.Version) {
+				matches = append(matches, ModuleMatch{Affected: a, Module: m})
+			}
+		}
+	}
+	return matches
+}
+
+// A ModuleMatch records that a module version in the build is
+// affected by a vulnerability.
+type ModuleMatch struct {
+	Affected *Affected
+	Module   module.Version
+}
@@ -0,0 +1,247 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"go/ast"
+	"path"
+	"sort"
+	"strconv"
+)
+
+// A Source is the parsed source of one package in a build.
+type Source struct {
+	Path    string            // import path
+	Files   []*ast.File       // parsed Go files, excluding tests
+	Imports map[string]string // import path as written in Files → resolved import path
+	Names   map[string]string // resolved import path → package name
+}
+
+// A Func identifies a function or method: Name is "F" for a function
+// and "T.M" for a method. Every package also has a pseudo-function
+// named "init" that stands for its init functions and the
+// initializers of its package-level variables.
+type Func struct {
+	Pkg  string
+	Name string
+}
+
+func (f Func) String() string {
+	return f.Pkg + "." + f.Name
+}
+
+// A Graph is a conservative approximation of the call graph of a
+// build, computed from syntax alone. A function refers to another
+// if it mentions it by name; a method call x.M, whose receiver type
+// is unknown without type checking, refers to every method named M
+// in the build. Dynamic calls through interfaces and function values
+// are therefore included, at the cost of some false positives.
+type Graph struct {
+	funcs   map[Func][]Func   // function → functions it refers to
+	methods map[string][]Func // method name → methods with that name
+}
+
+// anyMethod returns the pseudo-function standing for all methods named m.
+func anyMethod(m string) Func {
+	return Func{Name: "." + m}
+}
+
+// NewGraph returns the call graph of the packages srcs.
+func NewGraph(srcs []*Source) *Graph {
+	g := &Graph{
+		funcs:   make(map[Func][]Func),
+		methods: make(map[string][]Func),
+	}
+	for _, src := range srcs {
+		for _, file := range src.Files {
+			g.addFile(src, file)
+		}
+	}
+	for _, list := range g.methods {
+		sort.Slice(list, func(i, j int) bool { return list[i].String() < list[j].String() })
+	}
+	return g
+}
+
+// addFile adds the functions declared in file to g.
+func (g *Graph) addFile(src *Source, file *ast.File) {
+	// Map the names by which the file refers to imported packages.
+	imports := make(map[string]string)
+	for _, spec := range file.Imports {
+		p, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		if r, ok := src.Imports[p]; ok {
+			p = r
+		}
+		var name string
+		switch {
+		case spec.Name != nil:
+			name = spec.Name.Name
+		case src.Names[p] != "":
+			name = src.Names[p]
+		default:
+			name = path.Base(p)
+		}
+		if name != "_" && name != "." {
+			imports[name] = p
+		}
+	}
+
+	init := Func{src.Path, "init"}
+	if _, ok := g.funcs[init]; !ok {
+		g.funcs[init] = nil
+	}
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			f := Func{src.Path, decl.Name.Name}
+			if decl.Recv != nil && len(decl.Recv.List) > 0 {
+				f.Name = recvTypeName(decl.Recv.List[0].Type) + "." + decl.Name.Name
+				g.methods[decl.Name.Name] = append(g.methods[decl.Name.Name], f)
+			} else if decl.Name.Name == "init" {
+				f = init
+			}
+			if _, ok := g.funcs[f]; !ok {
+				g.funcs[f] = nil
+			}
+			if decl.Body != nil {
+				g.funcs[f] = append(g.funcs[f], refs(src.Path, imports, decl.Body)...)
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				if vs, ok := spec.(*ast.ValueSpec); ok {
+					for _, v := range vs.Values {
+						g.funcs[init] = append(g.funcs[init], refs(src.Path, imports, v)...)
+					}
+				}
+			}
+		}
+	}
+}
+
+// recvTypeName returns the name of the receiver type expression x.
+func recvTypeName(x ast.Expr) string {
+	for {
+		switch t := x.(type) {
+		case *ast.StarExpr:
+			x = t.X
+		case *ast.ParenExpr:
+			x = t.X
+		case *ast.Ident:
+			return t.Name
+		default:
+			return "?"
+		}
+	}
+}
+
+// refs returns the functions referred to by the syntax n in package
+// pkg, whose files refer to imported packages by the names in imports.
+func refs(pkg string, imports map[string]string, n ast.Node) []Func {
+	var list []Func
+	ast.Inspect(n, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			if x, ok := n.X.(*ast.Ident); ok && x.Obj == nil {
+				if p, ok := imports[x.Name]; ok {
+					list = append(list, Func{p, n.Sel.Name})
+					return false
+				}
+			}
+			list = append(list, anyMethod(n.Sel.Name))
+			list = append(list, refs(pkg, imports, n.X)...)
+			return false
+		case *ast.Ident:
+			// Identifiers resolved by the parser are declared in this
+			// file; those other than functions cannot refer to one.
+			if n.Obj == nil || n.Obj.Kind == ast.Fun {
+				list = append(list, Func{pkg, n.Name})
+			}
+		}
+		return true
+	})
+	return list
+}
+
+// Reachable returns the functions reachable from roots. For each
+// reachable function, the map holds the function through which it
+// was first reached, or the zero Func for the roots themselves.
+func (g *Graph) Reachable(roots []Func) map[Func]Func {
+	parent := make(map[Func]Func)
+	var queue []Func
+	visit := func(f, from Func) {
+		if _, ok := g.funcs[f]; !ok {
+			return
+		}
+		if _, ok := parent[f]; ok {
+			return
+		}
+		parent[f] = from
+		queue = append(queue, f)
+	}
+	for _, r := range roots {
+		visit(r, Func{})
+	}
+	for len(queue) > 0 {
+		f := queue[0]
+		queue = queue[1:]
+		for _, to := range g.funcs[f] {
+			if to.Pkg == "" {
+				for _, m := range g.methods[to.Name[1:]] {
+					visit(m, f)
+				}
+				continue
+			}
+			visit(to, f)
+		}
+	}
+	return parent
+}
+
+// Roots returns the functions from which execution of the packages
+// srcs can begin: main.main for commands, every exported function and
+// method of the other packages, and the init functions of all of deps.
+func Roots(srcs, deps []*Source) []Func {
+	var roots []Func
+	for _, src := range srcs {
+		for _, file := range src.Files {
+			if file.Name.Name == "main" {
+				roots = append(roots, Func{src.Path, "main"})
+				break
+			}
+			for _, decl := range file.Decls {
+				fd, ok := decl.(*ast.FuncDecl)
+				if !ok || !ast.IsExported(fd.Name.Name) {
+					continue
+				}
+				name := fd.Name.Name
+				if fd.Recv != nil && len(fd.Recv.List) > 0 {
+					name = recvTypeName(fd.Recv.List[0].Type) + "." + name
+				}
+				roots = append(roots, Func{src.Path, name})
+			}
+		}
+	}
+	for _, src := range deps {
+		roots = append(roots, Func{src.Path, "init"})
+	}
+	return roots
+}
+
+// Stack returns the chain of calls by which f was reached,
+// starting at a root and ending with f.
+func Stack(reached map[Func]Func, f Func) []Func {
+	var stack []Func
+	for f != (Func{}) {
+		stack = append(stack, f)
+		f = reached[f]
+	}
+	for i, j := 0, len(stack)-1; i < j; i, j = i+1, j-1 {
+		stack[i], stack[j] = stack[j], stack[i]
+	}
+	return stack
+}
@@ -0,0 +1,100 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"testing"
+)
+
+var affectsTests = []struct {
+	ranges []Range
+	v      string
+	want   bool
+	fixed  string
+}{
+	{nil, "v1.0.0", true, ""},
+	{nil, "", false, ""},
+	{[]Range{{Introduced: "v1.1.0", Fixed: "v1.2.0"}}, "v1.0.0", false, "v1.2.0"},
+	{[]Range{{Introduced: "v1.1.0", Fixed: "v1.2.0"}}, "v1.1.0", true, "v1.2.0"},
+	{[]Range{{Introduced: "v1.1.0", Fixed: "v1.2.0"}}, "v1.1.9-pre", true, "v1.2.0"},
+	{[]Range{{Introduced: "v1.1.0", Fixed: "v1.2.0"}}, "v1.2.0", false, ""},
+	{[]Range{{Fixed: "v1.2.0"}}, "v0.0.0-20200101000000-abcdefabcdef", true, "v1.2.0"},
+	{[]Range{{Introduced: "v2.0.0"}}, "v2.5.0", true, ""},
+	{[]Range{{Fixed: "v1.2.0"}, {Introduced: "v1.5.0", Fixed: "v1.5.3"}}, "v1.5.1", true, "v1.5.3"},
+	{[]Range{{Fixed: "v1.2.0"}, {Introduced: "v1.5.0", Fixed: "v1.5.3"}}, "v1.1.0", true, "v1.2.0"},
+	{[]Range{{Fixed: "v1.2.0"}, {Introduced: "v1.5.0", Fixed: "v1.5.3"}}, "v1.3.0", false, "v1.5.3"},
+}
+
+func TestAffectsVersion(t *testing.T) {
+	for _, tt := range affectsTests {
+		a := &Affected{Module: "m", Ranges: tt.ranges}
+		if got := a.AffectsVersion(tt.v); got != tt.want {
+			t.Errorf("AffectsVersion(%v, %q) = %v, want %v", tt.ranges, tt.v, got, tt.want)
+		}
+		if got := a.FixedIn(tt.v); got != tt.fixed {
+			t.Errorf("FixedIn(%v, %q) = %q, want %q", tt.ranges, tt.v, got, tt.fixed)
+		}
+	}
+}
+
+var reachSources = map[string]string{
+	"main": `package main
+
+import (
+	"fmt"
+	v "vuln"
+)
+
+type S struct{}
+
+func (S) String() string { return v.Format() }
+
+func main() {
+	var x fmt.Stringer = S{}
+	println(x.String())
+}
+`,
+	"vuln": `package vuln
+
+var table = build()
+
+func build() int { return 0 }
+
+func Format() string { return format() }
+
+func format() string { return "" }
+
+func Unused() { format() }
+`,
+}
+
+func TestReachable(t *testing.T) {
+	fset := token.NewFileSet()
+	var srcs []*Source
+	for _, path := range []string{"main", "vuln"} {
+		f, err := parser.ParseFile(fset, path+".go", reachSources[path], 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		srcs = append(srcs, &Source{Path: path, Files: []*ast.File{f}})
+	}
+	g := NewGraph(srcs)
+	reached := g.Reachable(Roots(srcs[:1], srcs))
+
+	want := []Func{{"main", "main"}, {"main", "S.String"}, {"vuln", "Format"}, {"vuln", "format"}}
+	if got := Stack(reached, Func{"vuln", "format"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Stack(vuln.format) = %v, want %v", got, want)
+	}
+	if _, ok := reached[Func{"vuln", "build"}]; !ok {
+		t.Errorf("vuln.build, called by a package initializer, not reached")
+	}
+	if _, ok := reached[Func{"vuln", "Unused"}]; ok {
+		t.Errorf("vuln.Unused reached; stack %v", Stack(reached, Func{"vuln", "Unused"}))
+	}
+}
@@ -81,6 +81,19 @@ and test commands:
 		arguments to pass on each go tool asm invocation.
 	-buildmode mode
 		build mode to use. See 'go help buildmode' for more.
+	-buildvcs
+		whether to stamp binaries with version control information.
+		By default, when the main package is in a Git repository, the
+		current commit, its time, and whether there are uncommitted
+		changes are recorded in the binary, together with the build
+		settings. Test binaries and binaries built by 'go run' are
+		not stamped. If git is not installed, the build proceeds with
+		a warning and no version control information. Use
+		-buildvcs=false to omit version control information.
+		Like all build information, it is recorded only in module
+		mode: binaries built in GOPATH mode record neither the build
+		settings nor the version control information.
+		See 'go help version' and runtime/debug.BuildInfo.
 	-compiler name
 		name of compiler to use, as in runtime.Compiler (gccgo or gc).
 	-gccgoflags '[pattern=]arg list'
@@ -112,6 +125,13 @@ and test commands:
 		directory, but it is not accessed. When -modfile is specified, an
 		alternate go.sum file is also used: its path is derived from the
 		-modfile flag by trimming the ".mod" extension and appending ".sum".
+	-pgo file
+		build with profile-guided optimization, using the CPU profile in
+		file, as written by runtime/pprof, to inform optimization decisions.
+		The compiler inlines hot calls to functions too large to inline
+		otherwise and devirtualizes hot interface method calls.
+		Only packages whose functions appear in the profile are compiled
+		with it, and only they are rebuilt when the profile changes.
 	-pkgdir dir
 		install and load all packages from dir instead of the usual locations.
 		For example, when building with a non-standard configuration,
@@ -246,6 +266,7 @@ func AddBuildFlags(cmd *base.Command, mask BuildFlagMask) {
 	cmd.Flag.Var(&load.BuildAsmflags, "asmflags", "")
 	cmd.Flag.Var(buildCompiler{}, "compiler", "")
 	cmd.Flag.StringVar(&cfg.BuildBuildmode, "buildmode", "default", "")
+	cmd.Flag.BoolVar(&cfg.BuildBuildvcs, "buildvcs", true, "")
 	cmd.Flag.Var(&load.BuildGcflags, "gcflags", "")
 	cmd.Flag.Var(&load.BuildGccgoflags, "gccgoflags", "")
 	if mask&OmitModFlag == 0 {
@@ -257,6 +278,7 @@ func AddBuildFlags(cmd *base.Command, mask BuildFlagMask) {
 	cmd.Flag.StringVar(&cfg.BuildContext.InstallSuffix, "installsuffix", "", "")
 	cmd.Flag.Var(&load.BuildLdflags, "ldflags", "")
 	cmd.Flag.BoolVar(&cfg.BuildLinkshared, "linkshared", false, "")
+	cmd.Flag.StringVar(&cfg.BuildPGO, "pgo", "", "")
 	cmd.Flag.StringVar(&cfg.BuildPkgdir, "pkgdir", "", "")
 	cmd.Flag.BoolVar(&cfg.BuildRace, "race", false, "")
 	cmd.Flag.BoolVar(&cfg.BuildMSan, "msan", false, "")
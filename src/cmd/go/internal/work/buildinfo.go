@@ -0,0 +1,231 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package work
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cmd/go/internal/base"
+	"cmd/go/internal/cfg"
+	"cmd/go/internal/load"
+)
+
+// A buildSetting is a key-value pair describing one input to a build,
+// recorded in the build information of a main package.
+type buildSetting struct {
+	key, value string
+}
+
+// stampBuildInfo appends the settings of the current build to the
+// module build information of the main package p, as "build" lines
+// that runtime/debug.ReadBuildInfo reports as BuildInfo.Settings.
+// Unless -buildvcs=false, it also records the state of the Git
+// checkout containing p, if any, provided git is installed.
+//
+// stampBuildInfo must be called before p's compile action runs,
+// since the build information is part of its action ID.
+func stampBuildInfo(p *load.Package) {
+	if p.Internal.BuildInfo == "" || p.Internal.BuildInfoStamped {
+		return
+	}
+	p.Internal.BuildInfoStamped = true
+
+	settings := buildSettings(p)
+	if stampVCS(p) {
+		vcs, err := gitStatus(p.Dir)
+		if errors.Is(err, errGitNotInstalled) {
+			// Source trees are often copied, .git directory and all,
+			// to machines without git, such as build containers.
+			// Build anyway, just without the version control settings.
+			warnGitOnce.Do(func() {
+				fmt.Fprintf(os.Stderr, "go: warning: %v; not recording VCS status\n", err)
+			})
+			err, vcs = nil, nil
+		}
+		if err != nil {
+			base.Fatalf("go: %s: error obtaining VCS status: %v\n\tUse -buildvcs=false to disable VCS stamping.", p.ImportPath, err)
+		}
+		settings = append(settings, vcs...)
+	}
+
+	var buf strings.Builder
+	buf.WriteString(p.Internal.BuildInfo)
+	for _, s := range settings {
+		value := s.value
+		if strings.ContainsAny(value, "\"\t\r\n") || strings.TrimSpace(value) != value {
+			value = strconv.Quote(value)
+		}
+		fmt.Fprintf(&buf, "build\t%s=%s\n", s.key, value)
+	}
+	p.Internal.BuildInfo = buf.String()
+}
+
+// buildSettings returns the flags and environment that affect
+// the build of the main package p.
+func buildSettings(p *load.Package) []buildSetting {
+	var settings []buildSetting
+	add := func(key, value string) {
+		settings = append(settings, buildSetting{key, value})
+	}
+
+	add("-compiler", cfg.BuildToolchainName)
+	if cfg.BuildBuildmode != "default" {
+		add("-buildmode", cfg.BuildBuildmode)
+	}
+	for _, f := range []struct {
+		name string
+		flag *load.PerPackageFlag
+	}{
+		{"-asmflags", &load.BuildAsmflags},
+		{"-gcflags", &load.BuildGcflags},
+		{"-gccgoflags", &load.BuildGccgoflags},
+		{"-ldflags", &load.BuildLdflags},
+	} {
+		for _, v := range f.flag.Values() {
+			add(f.name, v)
+		}
+	}
+	if cfg.BuildRace {
+		add("-race", "true")
+	}
+	if cfg.BuildMSan {
+		add("-msan", "true")
+	}
+	if tags := cfg.BuildContext.BuildTags; len(tags) > 0 {
+		add("-tags", strings.Join(tags, ","))
+	}
+	if cfg.BuildTrimpath {
+		add("-trimpath", "true")
+	}
+	if cfg.BuildPGO != "" {
+		pgo := cfg.BuildPGO
+		if cfg.BuildTrimpath {
+			pgo = filepath.Base(pgo)
+		}
+		add("-pgo", pgo)
+	}
+
+	cgo := "0"
+	if cfg.BuildContext.CgoEnabled {
+		cgo = "1"
+	}
+	add("CGO_ENABLED", cgo)
+	if cfg.BuildContext.CgoEnabled {
+		for _, name := range []string{"CGO_CFLAGS", "CGO_CPPFLAGS", "CGO_CXXFLAGS", "CGO_LDFLAGS"} {
+			if v := cfg.Getenv(name); v != "" {
+				add(name, v)
+			}
+		}
+	}
+	add("GOARCH", cfg.BuildContext.GOARCH)
+	if key, val := cfg.GetArchEnv(); key != "" && val != "" {
+		add(key, val)
+	}
+	add("GOOS", cfg.BuildContext.GOOS)
+	return settings
+}
+
+// stampVCS reports whether the build information of p should include
+// version control information. Only binaries built from the main
+// module by 'go build' and 'go install' are stamped: test binaries and
+// 'go run' programs are rebuilt too often for the commit to matter,
+// and recording it would defeat the build cache on every commit.
+func stampVCS(p *load.Package) bool {
+	if !cfg.BuildBuildvcs || cfg.BuildN {
+		return false
+	}
+	if cfg.CmdName != "build" && cfg.CmdName != "install" {
+		return false
+	}
+	return p.Module != nil && p.Module.Main && p.Internal.TestmainGo == nil
+}
+
+var (
+	errGitNotInstalled = errors.New("git is not installed")
+	warnGitOnce        sync.Once
+)
+
+// gitStatus returns the version control settings for the Git
+// checkout containing dir, or nil if dir is not in a Git checkout.
+// If git itself cannot be found, the error wraps errGitNotInstalled.
+func gitStatus(dir string) ([]buildSetting, error) {
+	root := findGitRoot(dir)
+	if root == "" {
+		return nil, nil
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("directory %s is in a Git repository, but %w", base.ShortPath(root), errGitNotInstalled)
+	}
+
+	out, err := runGit(root, "status", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+	modified := len(bytes.TrimSpace(out)) > 0
+
+	out, err = runGit(root, "-c", "log.showsignature=false", "log", "-1", "--format=%H:%ct")
+	if err != nil {
+		return nil, err
+	}
+	f := strings.SplitN(strings.TrimSpace(string(out)), ":", 2)
+	if len(f) != 2 || len(f[0]) != 40 {
+		return nil, fmt.Errorf("unexpected output from git log: %q", out)
+	}
+	secs, err := strconv.ParseInt(f[1], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected output from git log: %q", out)
+	}
+
+	return []buildSetting{
+		{"vcs", "git"},
+		{"vcs.revision", f[0]},
+		{"vcs.time", time.Unix(secs, 0).UTC().Format(time.RFC3339)},
+		{"vcs.modified", strconv.FormatBool(modified)},
+	}, nil
+}
+
+// findGitRoot returns the root directory of the Git checkout
+// containing dir, or "" if there is none.
+func findGitRoot(dir string) string {
+	dir = filepath.Clean(dir)
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		d := filepath.Dir(dir)
+		if d == dir {
+			return ""
+		}
+		dir = d
+	}
+}
+
+// runGit runs git with the given arguments in dir and returns its
+// standard output.
+func runGit(dir string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = base.EnvForDir(dir, os.Environ())
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		var ee *exec.ExitError
+		if errors.As(err, &ee) && stderr.Len() > 0 {
+			return nil, fmt.Errorf("git %s: %s", strings.Join(args, " "), bytes.TrimSpace(stderr.Bytes()))
+		}
+		return nil, fmt.Errorf("git %s: %v", strings.Join(args, " "), err)
+	}
+	return stdout.Bytes(), nil
+}
@@ -26,6 +26,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -248,6 +249,14 @@ func (b *Builder) buildActionID(a *Action) cache.ActionID {
 		base.Fatalf("buildActionID: unknown build toolchain %q", cfg.BuildToolchainName)
 	case "gc":
 		fmt.Fprintf(h, "compile %s %q %q\n", b.toolID("compile"), forcedGcflags, p.Internal.Gcflags)
+		if usesPGO(p) {
+			// The profile's contents matter, not its location.
+			sum, err := cache.FileHash(cfg.BuildPGO)
+			if err != nil {
+				base.Fatalf("go: -pgo: %v", err)
+			}
+			fmt.Fprintf(h, "pgo %x\n", sum)
+		}
 		if len(p.SFiles) > 0 {
 			fmt.Fprintf(h, "asm %q %q %q\n", b.toolID("asm"), forcedAsmflags, p.Internal.Asmflags)
 		}
@@ -323,6 +332,9 @@ func (b *Builder) buildActionID(a *Action) cache.ActionID {
 	for _, file := range inputFiles {
 		fmt.Fprintf(h, "file %s %s\n", file, b.fileHash(filepath.Join(p.Dir, file)))
 	}
+	for _, file := range p.EmbedFiles {
+		fmt.Fprintf(h, "embed %s %s\n", file, b.fileHash(filepath.Join(p.Dir, file)))
+	}
 	for _, a1 := range a.Deps {
 		p1 := a1.Package
 		if p1 != nil {
@@ -669,6 +681,26 @@ func (b *Builder) build(a *Action) (err error) {
 		fmt.Fprintf(&icfg, "packagefile %s=%s\n", p1.ImportPath, a1.built)
 	}
 
+	// Prepare Go embed config if needed.
+	// Unlike the import config, it's okay for the embed config to be empty.
+	var embedcfg []byte
+	if len(p.Internal.Embed) > 0 {
+		var embed struct {
+			Patterns map[string][]string
+			Files    map[string]string
+		}
+		embed.Patterns = p.Internal.Embed
+		embed.Files = make(map[string]string)
+		for _, file := range p.EmbedFiles {
+			embed.Files[file] = filepath.Join(p.Dir, file)
+		}
+		js, err := json.MarshalIndent(&embed, "", "\t")
+		if err != nil {
+			return fmt.Errorf("marshal embedcfg: %v", err)
+		}
+		embedcfg = js
+	}
+
 	if p.Internal.BuildInfo != "" && cfg.ModulesEnabled {
 		if err := b.writeFile(objdir+"_gomod_.go", load.ModInfoProg(p.Internal.BuildInfo, cfg.BuildToolchainName == "gccgo")); err != nil {
 			return err
@@ -678,7 +710,7 @@ func (b *Builder) build(a *Action) (err error) {
 
 	// Compile Go.
 	objpkg := objdir + "_pkg_.a"
-	ofile, out, err := BuildToolchain.gc(b, a, objpkg, icfg.Bytes(), symabis, len(sfiles) > 0, gofiles)
+	ofile, out, err := BuildToolchain.gc(b, a, objpkg, icfg.Bytes(), embedcfg, symabis, len(sfiles) > 0, gofiles)
 	if len(out) > 0 {
 		output := b.processOutput(out)
 		if p.Module != nil && !allowedVersion(p.Module.GoVersion) {
@@ -986,6 +1018,10 @@ var VetFlags []string
 // VetExplicit records whether the vet flags were set explicitly on the command line.
 var VetExplicit bool
 
+// VetWarnings is the set of analyzers whose diagnostics are warnings,
+// which are reported but do not cause vet to fail.
+var VetWarnings map[string]bool
+
 func (b *Builder) vet(a *Action) error {
 	// a.Deps[0] is the build of the package being vetted.
 	// a.Deps[1] is the build of the "fmt" package.
@@ -1085,7 +1121,16 @@ func (b *Builder) vet(a *Action) error {
 	if tool == "" {
 		tool = base.Tool("vet")
 	}
-	runErr := b.run(a, p.Dir, p.ImportPath, env, cfg.BuildToolexec, tool, vetFlags, a.Objdir+"vet.cfg")
+	var runErr error
+	if len(VetWarnings) > 0 && !vcfg.VetxOnly && !vetJSON(vetFlags) {
+		// Ask for JSON output to learn which analyzer
+		// reported each diagnostic.
+		flags := append(vetFlags[:len(vetFlags):len(vetFlags)], "-json")
+		out, err := b.runOut(a, p.Dir, env, cfg.BuildToolexec, tool, flags, a.Objdir+"vet.cfg")
+		runErr = b.vetReport(a, out, err)
+	} else {
+		runErr = b.run(a, p.Dir, p.ImportPath, env, cfg.BuildToolexec, tool, vetFlags, a.Objdir+"vet.cfg")
+	}
 
 	// If vet wrote export data, save it for input to future vets.
 	if f, err := os.Open(vcfg.VetxOutput); err == nil {
@@ -1097,6 +1142,83 @@ func (b *Builder) vet(a *Action) error {
 	return runErr
 }
 
+// vetJSON reports whether the vet flags request JSON output.
+func vetJSON(flags []string) bool {
+	on := false
+	for _, f := range flags {
+		switch strings.TrimPrefix(strings.TrimPrefix(f, "-"), "-") {
+		case "json", "json=true":
+			on = true
+		case "json=false":
+			on = false
+		}
+	}
+	return on
+}
+
+// vetReport prints the diagnostics in out, the JSON output of vet
+// for a.Package, and reports an error if any of them are not warnings.
+func (b *Builder) vetReport(a *Action, out []byte, err error) error {
+	p := a.Package
+	var tree map[string]map[string]json.RawMessage
+	if err != nil || json.Unmarshal(out, &tree) != nil {
+		// Vet failed without producing a report.
+		if len(out) > 0 {
+			b.showOutput(a, p.Dir, p.ImportPath, b.processOutput(out))
+			if err != nil {
+				err = errPrintedOutput
+			}
+		}
+		return err
+	}
+
+	var ids []string
+	for id := range tree {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	var report bytes.Buffer
+	failed := false
+	for _, id := range ids {
+		results := tree[id]
+		var names []string
+		for name := range results {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			var diags []struct {
+				Posn    string `json:"posn"`
+				Message string `json:"message"`
+			}
+			if err := json.Unmarshal(results[name], &diags); err != nil {
+				var e struct {
+					Err string `json:"error"`
+				}
+				json.Unmarshal(results[name], &e)
+				fmt.Fprintf(&report, "%s: %s\n", name, e.Err)
+				failed = true
+				continue
+			}
+			for _, d := range diags {
+				if VetWarnings[name] {
+					fmt.Fprintf(&report, "%s: warning: %s\n", d.Posn, d.Message)
+				} else {
+					fmt.Fprintf(&report, "%s: %s\n", d.Posn, d.Message)
+					failed = true
+				}
+			}
+		}
+	}
+	if report.Len() > 0 {
+		b.showOutput(a, p.Dir, p.ImportPath, b.processOutput(report.Bytes()))
+	}
+	if failed {
+		return errPrintedOutput
+	}
+	return nil
+}
+
 // linkActionID computes the action ID for a link action.
 func (b *Builder) linkActionID(a *Action) cache.ActionID {
 	p := a.Package
@@ -2045,7 +2167,7 @@ type toolchain interface {
 	// and returns the name of the generated output file.
 	//
 	// TODO: This argument list is long. Consider putting it in a struct.
-	gc(b *Builder, a *Action, archive string, importcfg []byte, symabis string, asmhdr bool, gofiles []string) (ofile string, out []byte, err error)
+	gc(b *Builder, a *Action, archive string, importcfg, embedcfg []byte, symabis string, asmhdr bool, gofiles []string) (ofile string, out []byte, err error)
 	// cc runs the toolchain's C compiler in a directory on a C file
 	// to produce an output file.
 	cc(b *Builder, a *Action, ofile, cfile string) error
@@ -2085,7 +2207,7 @@ func (noToolchain) linker() string {
 	return ""
 }
 
-func (noToolchain) gc(b *Builder, a *Action, archive string, importcfg []byte, symabis string, asmhdr bool, gofiles []string) (ofile string, out []byte, err error) {
+func (noToolchain) gc(b *Builder, a *Action, archive string, importcfg, embedcfg []byte, symabis string, asmhdr bool, gofiles []string) (ofile string, out []byte, err error) {
 	return "", nil, noCompiler()
 }
 
@@ -2881,7 +3003,7 @@ func (b *Builder) swigDoIntSize(objdir string) (intsize string, err error) {
 
 	p := load.GoFilesPackage(srcs)
 
-	if _, _, e := BuildToolchain.gc(b, &Action{Mode: "swigDoIntSize", Package: p, Objdir: objdir}, "", nil, "", false, srcs); e != nil {
+	if _, _, e := BuildToolchain.gc(b, &Action{Mode: "swigDoIntSize", Package: p, Objdir: objdir}, "", nil, nil, "", false, srcs); e != nil {
 		return "32", nil
 	}
 	return "64", nil
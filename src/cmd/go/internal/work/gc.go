@@ -23,6 +23,8 @@ import (
 	"cmd/internal/objabi"
 	"cmd/internal/sys"
 	"crypto/sha1"
+	"internal/profile"
+	"sync"
 )
 
 // The Go toolchain.
@@ -37,7 +39,7 @@ func (gcToolchain) linker() string {
 	return base.Tool("link")
 }
 
-func (gcToolchain) gc(b *Builder, a *Action, archive string, importcfg []byte, symabis string, asmhdr bool, gofiles []string) (ofile string, output []byte, err error) {
+func (gcToolchain) gc(b *Builder, a *Action, archive string, importcfg, embedcfg []byte, symabis string, asmhdr bool, gofiles []string) (ofile string, output []byte, err error) {
 	p := a.Package
 	objdir := a.Objdir
 	if archive != "" {
@@ -79,7 +81,7 @@ func (gcToolchain) gc(b *Builder, a *Action, archive string, importcfg []byte, s
 	extFiles := len(p.CgoFiles) + len(p.CFiles) + len(p.CXXFiles) + len(p.MFiles) + len(p.FFiles) + len(p.SFiles) + len(p.SysoFiles) + len(p.SwigFiles) + len(p.SwigCXXFiles)
 	if p.Standard {
 		switch p.ImportPath {
-		case "bytes", "internal/poll", "net", "os", "runtime/pprof", "runtime/trace", "sync", "syscall", "time":
+		case "bytes", "internal/poll", "net", "os", "runtime/metrics", "runtime/pprof", "runtime/trace", "sync", "syscall", "time":
 			extFiles++
 		}
 	}
@@ -101,6 +103,9 @@ func (gcToolchain) gc(b *Builder, a *Action, archive string, importcfg []byte, s
 	if symabis != "" {
 		gcargs = append(gcargs, "-symabis", symabis)
 	}
+	if usesPGO(p) {
+		gcargs = append(gcargs, "-pgoprofile", cfg.BuildPGO)
+	}
 
 	gcflags := str.StringList(forcedGcflags, p.Internal.Gcflags)
 	if compilingRuntime {
@@ -123,6 +128,12 @@ func (gcToolchain) gc(b *Builder, a *Action, archive string, importcfg []byte, s
 		}
 		args = append(args, "-importcfg", objdir+"importcfg")
 	}
+	if embedcfg != nil {
+		if err := b.writeFile(objdir+"embedcfg", embedcfg); err != nil {
+			return "", nil, err
+		}
+		args = append(args, "-embedcfg", objdir+"embedcfg")
+	}
 	if ofile == archive {
 		args = append(args, "-pack")
 	}
@@ -485,6 +496,58 @@ func pluginPath(a *Action) string {
 	return fmt.Sprintf("plugin/unnamed-%x", h.Sum(nil))
 }
 
+// usesPGO reports whether p is compiled with the -pgo profile.
+// The compiler only uses a profile to optimize the functions of the
+// package being compiled, so a package none of whose functions appear
+// in the profile does not need it, and does not need to be rebuilt
+// when it changes.
+func usesPGO(p *load.Package) bool {
+	if cfg.BuildPGO == "" {
+		return false
+	}
+	pkgpath := p.ImportPath
+	if cfg.BuildBuildmode == "plugin" {
+		if p.ImportPath == "command-line-arguments" {
+			// The package path depends on the build ID,
+			// which is not known yet. Assume the worst.
+			return true
+		}
+	} else if p.Name == "main" && !p.Internal.ForceLibrary {
+		pkgpath = "main"
+	}
+	pgoProfile.once.Do(readPGOProfile)
+	return pgoProfile.pkgs[objabi.PathToPrefix(pkgpath)]
+}
+
+var pgoProfile struct {
+	once sync.Once
+	pkgs map[string]bool // symbol prefixes of the packages in the profile
+}
+
+// readPGOProfile records in pgoProfile.pkgs the packages
+// whose functions appear in the -pgo profile.
+func readPGOProfile() {
+	f, err := os.Open(cfg.BuildPGO)
+	if err != nil {
+		base.Fatalf("go: -pgo: %v", err)
+	}
+	defer f.Close()
+	prof, err := profile.Parse(f)
+	if err != nil {
+		base.Fatalf("go: -pgo: %s: %v", cfg.BuildPGO, err)
+	}
+	pgoProfile.pkgs = make(map[string]bool)
+	for _, fn := range prof.Function {
+		// The package part of a symbol name ends at the
+		// first dot after the last slash.
+		name := fn.Name
+		i := strings.LastIndex(name, "/") + 1
+		if j := strings.Index(name[i:], "."); j >= 0 {
+			pgoProfile.pkgs[name[:i+j]] = true
+		}
+	}
+}
+
 func (gcToolchain) ld(b *Builder, root *Action, out, importcfg, mainpkg string) error {
 	cxx := len(root.Package.CXXFiles) > 0 || len(root.Package.SwigCXXFiles) > 0
 	for _, a := range root.Deps {
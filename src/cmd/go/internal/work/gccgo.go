@@ -60,9 +60,12 @@ func checkGccgoBin() {
 	base.Exit()
 }
 
-func (tools gccgoToolchain) gc(b *Builder, a *Action, archive string, importcfg []byte, symabis string, asmhdr bool, gofiles []string) (ofile string, output []byte, err error) {
+func (tools gccgoToolchain) gc(b *Builder, a *Action, archive string, importcfg, embedcfg []byte, symabis string, asmhdr bool, gofiles []string) (ofile string, output []byte, err error) {
 	p := a.Package
 	objdir := a.Objdir
+	if embedcfg != nil {
+		return "", nil, fmt.Errorf("%s: //go:embed not supported by gccgo", p.ImportPath)
+	}
 	out := "_go_.o"
 	ofile = objdir + out
 	gcargs := []string{"-g"}
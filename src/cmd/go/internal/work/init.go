@@ -34,6 +34,25 @@ func BuildInit() {
 		}
 		cfg.BuildPkgdir = p
 	}
+
+	// Likewise -pgo, which must also name an existing file.
+	if cfg.BuildPGO != "" {
+		if cfg.BuildToolchainName == "gccgo" {
+			fmt.Fprintf(os.Stderr, "go %s: -pgo is not supported by -compiler=gccgo\n", flag.Args()[0])
+			base.SetExitStatus(2)
+			base.Exit()
+		}
+		p, err := filepath.Abs(cfg.BuildPGO)
+		if err == nil {
+			_, err = os.Stat(p)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "go %s: -pgo: %v\n", flag.Args()[0], err)
+			base.SetExitStatus(2)
+			base.Exit()
+		}
+		cfg.BuildPGO = p
+	}
 }
 
 func instrumentInit() {
@@ -0,0 +1,51 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// go work drop
+
+package workcmd
+
+import (
+	"path/filepath"
+
+	"cmd/go/internal/base"
+)
+
+var cmdDrop = &base.Command{
+	UsageLine: "go work drop [moddirs]",
+	Short:     "remove modules from workspace file",
+	Long: `
+Drop removes the use directives for the named module directories from
+the go.work file in effect. The directories need not exist, so that
+modules that were moved or deleted can be dropped. It is an error to
+name a directory that is not in the workspace.
+	`,
+	Run: runDrop,
+}
+
+func runDrop(cmd *base.Command, args []string) {
+	if len(args) == 0 {
+		base.Fatalf("go work drop: no module directories given")
+	}
+	gowork, data, wf := readWorkFile("drop")
+	workDir := filepath.Dir(gowork)
+
+	for _, arg := range args {
+		dir := absWorkDir(base.Cwd, arg)
+		found := false
+		for _, u := range wf.Use {
+			if absWorkDir(workDir, u.Path) == dir {
+				wf.DropUse(u.Path)
+				found = true
+				break
+			}
+		}
+		if !found {
+			base.Errorf("go work drop: %s is not in the workspace", base.ShortPath(dir))
+		}
+	}
+	base.ExitIfErrors()
+
+	writeWorkFile(gowork, data, wf)
+}
@@ -0,0 +1,67 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// go work init
+
+package workcmd
+
+import (
+	"bytes"
+	"go/build"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cmd/go/internal/base"
+	"cmd/go/internal/lockedfile"
+	"cmd/go/internal/modload"
+
+	"golang.org/x/mod/modfile"
+)
+
+var cmdInit = &base.Command{
+	UsageLine: "go work init [moddirs]",
+	Short:     "initialize workspace file",
+	Long: `
+Init initializes and writes a new go.work file in the current
+directory, in effect creating a new workspace there. The file go.work
+must not already exist.
+
+Init optionally accepts paths to the workspace modules as arguments.
+Each argument must be a directory containing a go.mod file.
+	`,
+	Run: runInit,
+}
+
+func runInit(cmd *base.Command, args []string) {
+	gowork := filepath.Join(base.Cwd, "go.work")
+	if _, err := os.Stat(gowork); err == nil {
+		base.Fatalf("go work init: go.work already exists")
+	}
+
+	tags := build.Default.ReleaseTags
+	version := tags[len(tags)-1]
+	if !strings.HasPrefix(version, "go") || !modfile.GoVersionRE.MatchString(version[2:]) {
+		base.Fatalf("go: unrecognized default version %q", version)
+	}
+	wf, err := modload.ParseWorkFile(gowork, []byte("go "+version[2:]+"\n"))
+	if err != nil {
+		base.Fatalf("go: internal error: %v", err)
+	}
+
+	have := make(map[string]bool)
+	for _, arg := range args {
+		dir := absWorkDir(base.Cwd, arg)
+		checkModuleDir("init", dir)
+		if !have[dir] {
+			have[dir] = true
+			wf.AddUse(workDirPath(base.Cwd, arg))
+		}
+	}
+	base.ExitIfErrors()
+
+	if err := lockedfile.Write(gowork, bytes.NewReader(wf.Format()), 0666); err != nil {
+		base.Fatalf("go: %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// go work use
+
+package workcmd
+
+import (
+	"path/filepath"
+
+	"cmd/go/internal/base"
+)
+
+var cmdUse = &base.Command{
+	UsageLine: "go work use [moddirs]",
+	Short:     "add modules to workspace file",
+	Long: `
+Use adds use directives for the named module directories to the
+go.work file in effect, which is the one found in the current directory
+or its parents, or the one named by GOWORK. Each argument must be a
+directory containing a go.mod file. Directories already in the
+workspace are left alone.
+	`,
+	Run: runUse,
+}
+
+func runUse(cmd *base.Command, args []string) {
+	if len(args) == 0 {
+		base.Fatalf("go work use: no module directories given")
+	}
+	gowork, data, wf := readWorkFile("use")
+	workDir := filepath.Dir(gowork)
+
+	have := make(map[string]bool)
+	for _, u := range wf.Use {
+		have[absWorkDir(workDir, u.Path)] = true
+	}
+	for _, arg := range args {
+		dir := absWorkDir(base.Cwd, arg)
+		checkModuleDir("use", dir)
+		if !have[dir] {
+			have[dir] = true
+			wf.AddUse(workDirPath(workDir, arg))
+		}
+	}
+	base.ExitIfErrors()
+
+	writeWorkFile(gowork, data, wf)
+}
@@ -0,0 +1,132 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package workcmd implements the ``go work'' command.
+package workcmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cmd/go/internal/base"
+	"cmd/go/internal/lockedfile"
+	"cmd/go/internal/modload"
+)
+
+var CmdWork = &base.Command{
+	UsageLine: "go work",
+	Short:     "workspace maintenance",
+	Long: `Go work provides access to operations on workspaces.
+
+A workspace is a set of modules, each in its own directory, that are
+developed together. The workspace is described by a go.work file, which
+lists the module directories in use directives:
+
+	go 1.14
+
+	use (
+		./api
+		./server
+	)
+
+Relative directories are interpreted relative to the directory
+containing the go.work file.
+
+When the go command finds a go.work file in the current directory or
+one of its parents, it runs in workspace mode: every module listed in
+go.work is a main module. Packages in any of them may be named on the
+command line, and imports of their packages resolve to the workspace
+directories, as if each go.mod file held a directory replacement for
+the others. Replacements in the go.mod files of all the workspace
+modules apply. 'go list -m' lists all the workspace modules.
+
+In workspace mode the go.mod files of the workspace modules are never
+rewritten, so imports of packages in modules not already required
+cannot be resolved, and 'go get', 'go mod tidy' and 'go mod vendor' are
+not allowed. Checksums are read from the go.sum files of all the
+workspace modules; checksums of other modules downloaded while in
+workspace mode are recorded in go.work.sum, next to go.work.
+
+The GOWORK environment variable names the go.work file to use instead
+of searching for one; GOWORK=off disables workspace mode.
+	`,
+
+	Commands: []*base.Command{
+		cmdDrop,
+		cmdInit,
+		cmdUse,
+	},
+}
+
+// workDirPath returns the path used in go.work, whose directory is
+// workDir, to refer to the module directory dir named on the command
+// line. Relative directories are made relative to workDir.
+func workDirPath(workDir, dir string) string {
+	if filepath.IsAbs(dir) {
+		return filepath.Clean(dir)
+	}
+	abs := filepath.Join(base.Cwd, dir)
+	rel, err := filepath.Rel(workDir, abs)
+	if err != nil {
+		return abs
+	}
+	rel = filepath.ToSlash(rel)
+	if rel != "." && rel != ".." && !strings.HasPrefix(rel, "../") {
+		rel = "./" + rel
+	}
+	return rel
+}
+
+// absWorkDir returns the absolute directory for path, as written in a
+// go.work file in workDir.
+func absWorkDir(workDir, path string) string {
+	dir := filepath.FromSlash(path)
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(workDir, dir)
+	}
+	return filepath.Clean(dir)
+}
+
+// checkModuleDir reports an error if dir does not contain a go.mod file.
+func checkModuleDir(cmd, dir string) {
+	if fi, err := os.Stat(filepath.Join(dir, "go.mod")); err != nil || fi.IsDir() {
+		base.Errorf("go work %s: %s: directory does not contain a go.mod file", cmd, base.ShortPath(dir))
+	}
+}
+
+// readWorkFile reads and parses the go.work file in effect,
+// returning its path, contents, and parsed form.
+func readWorkFile(cmd string) (gowork string, data []byte, wf *modload.WorkFile) {
+	gowork = modload.FindWorkFile(base.Cwd)
+	if gowork == "" {
+		base.Fatalf("go work %s: no go.work file found\n\t(run 'go work init' to create one)", cmd)
+	}
+	data, err := lockedfile.Read(gowork)
+	if err != nil {
+		base.Fatalf("go: %v", err)
+	}
+	wf, err = modload.ParseWorkFile(gowork, data)
+	if err != nil {
+		base.Fatalf("go: errors parsing %s:\n%s", base.ShortPath(gowork), err)
+	}
+	return gowork, data, wf
+}
+
+// writeWorkFile writes wf to gowork, provided that its contents are
+// still data.
+func writeWorkFile(gowork string, data []byte, wf *modload.WorkFile) {
+	out := wf.Format()
+	err := lockedfile.Transform(gowork, func(lockedData []byte) ([]byte, error) {
+		if !bytes.Equal(lockedData, data) {
+			return nil, errors.New("go.work changed during editing; not overwriting")
+		}
+		return out, nil
+	})
+	if err != nil {
+		base.Fatalf("go: %v", err)
+	}
+}
@@ -37,6 +37,7 @@ import (
 	"cmd/go/internal/version"
 	"cmd/go/internal/vet"
 	"cmd/go/internal/work"
+	"cmd/go/internal/workcmd"
 )
 
 func init() {
@@ -58,6 +59,7 @@ func init() {
 		tool.CmdTool,
 		version.CmdVersion,
 		vet.CmdVet,
+		workcmd.CmdWork,
 
 		help.HelpBuildmode,
 		help.HelpC,
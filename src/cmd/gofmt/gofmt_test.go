@@ -6,7 +6,6 @@ package main
 
 import (
 	"bytes"
-	"flag"
 	"io/ioutil"
 	"os"
 	"os/exec"
@@ -17,8 +16,6 @@ import (
 	"text/scanner"
 )
 
-var update = flag.Bool("update", false, "update .golden files")
-
 // gofmtFlags looks for a comment of the form
 //
 //	//gofmt flags
@@ -100,7 +97,7 @@ func runTest(t *testing.T, in, out string) {
 	}
 
 	if got := buf.Bytes(); !bytes.Equal(got, expected) {
-		if *update {
+		if testing.Update() {
 			if in != out {
 				if err := ioutil.WriteFile(out, got, 0666); err != nil {
 					t.Error(err)
@@ -0,0 +1,44 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Heapdump analyzes the heap dumps written by runtime/debug.WriteHeapDump.
+
+Usage:
+
+	go tool heapdump [flags] command dump [args]
+
+The commands are:
+
+	stats dump
+		Print the number and total size of the objects of each type.
+	retained dump
+		Print the objects that retain the most memory: those reachable
+		only through them, as found from the dominator tree of the heap.
+	path dump addr
+		Print shortest paths from GC roots, such as global variables and
+		stack frames, to the heap object containing the address addr.
+	diff old new
+		Print the change in the number and total size of the objects of
+		each type from the dump old to the dump new.
+
+The flags are:
+
+	-bin exe
+		Read type information from the executable that wrote the dumps.
+	-n count
+		Print at most count entries or paths (default 20).
+
+Heap dumps do not record the types of objects. With the -bin flag,
+heapdump recovers most of them from the debug information of the
+executable, which must not have been stripped or linked with -w.
+Without it, or for objects whose type cannot be found, such as objects
+reached only through unsafe.Pointer, the type is shown as unkN, where N
+is the size of the object.
+
+Heapdump reads only dumps written by the runtime of the same Go version.
+The package that parses them, internal/heapdump, changes with the runtime
+and so cannot be imported outside the Go distribution.
+*/
+package main
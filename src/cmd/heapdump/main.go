@@ -0,0 +1,226 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"cmd/internal/objfile"
+	"flag"
+	"fmt"
+	"internal/heapdump"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+)
+
+const usageMessage = `usage: go tool heapdump [flags] command dump [args]
+
+Commands:
+	stats dump         object counts and sizes by type
+	retained dump      objects that retain the most memory
+	path dump addr     paths from GC roots to the object at addr
+	diff old new       change in object counts and sizes by type
+
+Flags:
+`
+
+var (
+	binFlag = flag.String("bin", "", "read type information from the executable `exe`")
+	nFlag   = flag.Int("n", 20, "print at most `count` entries or paths")
+)
+
+func usage() {
+	fmt.Fprint(os.Stderr, usageMessage)
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("heapdump: ")
+	flag.Usage = usage
+	flag.Parse()
+	args := flag.Args()
+	if len(args) < 2 {
+		usage()
+	}
+	w := bufio.NewWriter(os.Stdout)
+	switch cmd := args[0]; {
+	case cmd == "stats" && len(args) == 2:
+		stats(w, load(args[1]), *nFlag)
+	case cmd == "retained" && len(args) == 2:
+		retained(w, load(args[1]), *nFlag)
+	case cmd == "path" && len(args) == 3:
+		addr, err := strconv.ParseUint(args[2], 0, 64)
+		if err != nil {
+			log.Fatalf("bad address %q", args[2])
+		}
+		if err := paths(w, load(args[1]), addr, *nFlag); err != nil {
+			log.Fatal(err)
+		}
+	case cmd == "diff" && len(args) == 3:
+		diff(w, load(args[1]), load(args[2]), *nFlag)
+	default:
+		usage()
+	}
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// load reads the dump in the named file, and the types of its objects
+// if the -bin flag is set.
+func load(file string) *heapdump.Dump {
+	f, err := os.Open(file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	d, err := heapdump.Parse(f)
+	if err != nil {
+		log.Fatalf("%s: %v", file, err)
+	}
+	if *binFlag != "" {
+		if err := inferTypes(d, *binFlag); err != nil {
+			log.Fatalf("%s: %v", *binFlag, err)
+		}
+	}
+	return d
+}
+
+// inferTypes sets the types of the objects in d
+// from the debug information in the executable exe.
+func inferTypes(d *heapdump.Dump, exe string) error {
+	f, err := objfile.Open(exe)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	dw, err := f.DWARF()
+	if err != nil {
+		return err
+	}
+	syms, err := f.Symbols()
+	if err != nil {
+		return err
+	}
+	for _, s := range syms {
+		if s.Name == "runtime.types" {
+			return d.InferTypes(dw, s.Addr)
+		}
+	}
+	return fmt.Errorf("no runtime.types symbol")
+}
+
+// stats prints the number and size of the objects of the n most
+// common types in d, by size.
+func stats(w io.Writer, d *heapdump.Dump, n int) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "count\tbytes\t  type\n")
+	var count, bytes uint64
+	for i, s := range d.TypeStats() {
+		if i < n {
+			fmt.Fprintf(tw, "%d\t%d\t  %s\n", s.Count, s.Bytes, s.Type)
+		}
+		count += s.Count
+		bytes += s.Bytes
+	}
+	fmt.Fprintf(tw, "%d\t%d\t  total\n", count, bytes)
+	tw.Flush()
+}
+
+// retained prints the n objects that retain the most memory, among
+// those not retained by another object.
+func retained(w io.Writer, d *heapdump.Dump, n int) {
+	d.Dominators()
+	var objs []*heapdump.Object
+	for _, o := range d.Objects {
+		// Objects with immediate dominators are retained by them.
+		if o.Retained > 0 && o.Idom == nil {
+			objs = append(objs, o)
+		}
+	}
+	sort.Slice(objs, func(i, j int) bool {
+		if objs[i].Retained != objs[j].Retained {
+			return objs[i].Retained > objs[j].Retained
+		}
+		return objs[i].Addr < objs[j].Addr
+	})
+	if len(objs) > n {
+		objs = objs[:n]
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "retained\tsize\taddress\t  type\n")
+	for _, o := range objs {
+		fmt.Fprintf(tw, "%d\t%d\t%#x\t  %s\n", o.Retained, o.Size(), o.Addr, o.TypeName())
+	}
+	tw.Flush()
+}
+
+// paths prints up to n shortest paths from roots to the object
+// containing addr.
+func paths(w io.Writer, d *heapdump.Dump, addr uint64, n int) error {
+	o, _ := d.FindObject(addr)
+	if o == nil {
+		return fmt.Errorf("no heap object at %#x", addr)
+	}
+	fmt.Fprintf(w, "object %#x: %s, %d bytes\n", o.Addr, o.TypeName(), o.Size())
+	if site := d.AllocSamples[o.Addr]; site != nil {
+		fmt.Fprintf(w, "allocated at\n")
+		for _, f := range site.Stack {
+			fmt.Fprintf(w, "\t%s\n\t\t%s:%d\n", f.Func, f.File, f.Line)
+		}
+	}
+	ps := d.Paths(o, n)
+	if len(ps) == 0 {
+		fmt.Fprintf(w, "not reachable\n")
+		return nil
+	}
+	for i, p := range ps {
+		fmt.Fprintf(w, "path %d:\n", i+1)
+		for _, l := range p {
+			switch {
+			case l.Root != nil && l.Root.Data != nil:
+				fmt.Fprintf(w, "\t%s %#x+%#x\n", l.Root.Name, l.Root.Addr, l.Offset)
+			case l.Root != nil:
+				fmt.Fprintf(w, "\t%s\n", l.Root.Name)
+			case l.Object == o:
+				fmt.Fprintf(w, "\t%#x %s\n", l.Object.Addr, l.Object.TypeName())
+			default:
+				fmt.Fprintf(w, "\t%#x+%#x %s\n", l.Object.Addr, l.Offset, l.Object.TypeName())
+			}
+		}
+	}
+	return nil
+}
+
+// diff prints the change in the number and size of the objects of the
+// n types whose objects changed most in size from old to new.
+func diff(w io.Writer, old, new *heapdump.Dump, n int) {
+	diffs := heapdump.DiffTypes(old, new)
+	// Show the largest changes, growth or shrinkage.
+	sort.SliceStable(diffs, func(i, j int) bool {
+		return abs(diffs[i].Bytes) > abs(diffs[j].Bytes)
+	})
+	if len(diffs) > n {
+		diffs = diffs[:n]
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "count\tbytes\t  type\n")
+	for _, t := range diffs {
+		fmt.Fprintf(tw, "%+d\t%+d\t  %s\n", t.Count, t.Bytes, t.Type)
+	}
+	tw.Flush()
+}
+
+func abs(x int64) int64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,113 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"internal/heapdump"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+var leak []*[1024]byte
+
+func writeDump(t *testing.T) *heapdump.Dump {
+	f, err := ioutil.TempFile("", "heapdump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	debug.WriteHeapDump(f.Fd())
+	if _, err := f.Seek(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	d, err := heapdump.Parse(f)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	return d
+}
+
+// field returns the value of the integer in field i of the first
+// line of out whose last field is name.
+func field(t *testing.T, out string, name string, i int) int64 {
+	t.Helper()
+	for _, line := range strings.Split(out, "\n") {
+		f := strings.Fields(line)
+		if len(f) > i && f[len(f)-1] == name {
+			v, err := strconv.ParseInt(f[i], 0, 64)
+			if err != nil {
+				t.Fatalf("bad field in line %q", line)
+			}
+			return v
+		}
+	}
+	t.Fatalf("no line for %s in output:\n%s", name, out)
+	return 0
+}
+
+func TestCommands(t *testing.T) {
+	if runtime.GOOS == "js" {
+		t.Skipf("WriteHeapDump is not available on %s", runtime.GOOS)
+	}
+	old := writeDump(t)
+	for i := 0; i < 100; i++ {
+		leak = append(leak, new([1024]byte))
+	}
+	defer func() { leak = nil }()
+	d := writeDump(t)
+
+	var buf bytes.Buffer
+	stats(&buf, d, 10)
+	if n := field(t, buf.String(), "unk1024", 0); n < 100 {
+		t.Errorf("stats reports %d objects of 1024 bytes, want at least 100", n)
+	}
+	if n := field(t, buf.String(), "total", 0); n != int64(len(d.Objects)) {
+		t.Errorf("stats reports %d objects in total, want %d", n, len(d.Objects))
+	}
+
+	buf.Reset()
+	diff(&buf, old, d, 10)
+	if n := field(t, buf.String(), "unk1024", 0); n < 100 {
+		t.Errorf("diff reports %+d objects of 1024 bytes, want at least +100", n)
+	}
+
+	buf.Reset()
+	retained(&buf, d, 10)
+	out := buf.String()
+	if !strings.Contains(out, "retained") {
+		t.Fatalf("retained printed no header:\n%s", out)
+	}
+	// The largest retainer is the backing array of leak, or
+	// something that retains it.
+	lines := strings.Split(out, "\n")
+	if len(lines) < 2 {
+		t.Fatalf("retained printed no objects:\n%s", out)
+	}
+	if f := strings.Fields(lines[1]); len(f) < 1 {
+		t.Errorf("bad retained output:\n%s", out)
+	} else if n, _ := strconv.Atoi(f[0]); n < 100*1024 {
+		t.Errorf("largest object retains %d bytes, want at least %d", n, 100*1024)
+	}
+
+	buf.Reset()
+	if err := paths(&buf, d, uint64(uintptr(unsafe.Pointer(leak[10]))), 5); err != nil {
+		t.Fatal(err)
+	}
+	out = buf.String()
+	if !strings.Contains(out, "path 1:") || !strings.Contains(out, "bss segment") {
+		t.Errorf("no path from bss segment to leaked object:\n%s", out)
+	}
+	if err := paths(&buf, d, 1, 5); err == nil {
+		t.Errorf("paths succeeded for an address outside the heap")
+	}
+}
@@ -7,7 +7,6 @@ package test2json
 import (
 	"bytes"
 	"encoding/json"
-	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -18,8 +17,6 @@ import (
 	"unicode/utf8"
 )
 
-var update = flag.Bool("update", false, "rewrite testdata/*.json files")
-
 func TestGolden(t *testing.T) {
 	files, err := filepath.Glob("testdata/*.test")
 	if err != nil {
@@ -43,7 +40,7 @@ func TestGolden(t *testing.T) {
 			}
 			c.Close()
 
-			if *update {
+			if testing.Update() {
 				js := strings.TrimSuffix(file, ".test") + ".json"
 				t.Logf("rewriting %s", js)
 				if err := ioutil.WriteFile(js, buf.Bytes(), 0666); err != nil {
@@ -320,7 +320,7 @@ func analyzeAnnotations() (annotationAnalysisResult, error) {
 				task.regions = append(task.regions, regionDesc{UserRegionDesc: s, G: goid})
 			}
 			var frame trace.Frame
-			if s.Start != nil {
+			if s.Start != nil && len(s.Start.Stk) > 0 {
 				frame = *s.Start.Stk[0]
 			}
 			id := regionTypeID{Frame: frame, Type: s.Name}
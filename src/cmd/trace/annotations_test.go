@@ -160,6 +160,46 @@ func TestAnalyzeAnnotations(t *testing.T) {
 	}
 }
 
+func TestAnalyzeAnnotationsNoStacks(t *testing.T) {
+	// Trace prog0 without stacks, as if the tracer could not
+	// collect them, and check that the annotations still work.
+	classes := []trace.StackClass{trace.StackScheduling, trace.StackSyscall, trace.StackGC, trace.StackUser}
+	for _, c := range classes {
+		defer trace.SetStacks(c, trace.SetStacks(c, false))
+	}
+	if err := traceProgram(t, prog0, "TestAnalyzeAnnotationsNoStacks"); err != nil {
+		t.Fatalf("failed to trace the program: %v", err)
+	}
+
+	res, err := analyzeAnnotations()
+	if err != nil {
+		t.Fatalf("failed to analyzeAnnotations: %v", err)
+	}
+	if len(res.tasks) != 2 {
+		t.Errorf("got %d tasks, want 2", len(res.tasks))
+	}
+	for regionID, regions := range res.regions {
+		for _, r := range regions {
+			if r.Start != nil && len(r.Start.Stk) != 0 {
+				t.Errorf("region %q starts with stack %v, want none", regionID.Type, r.Start.Stk)
+			}
+		}
+	}
+
+	parsed, err := parseTrace()
+	if err != nil {
+		t.Fatalf("failed to parse the trace: %v", err)
+	}
+	params := &traceParams{
+		parsed:  parsed,
+		endTime: int64(1<<63 - 1),
+	}
+	c := viewerDataTraceConsumer(ioutil.Discard, 0, 1<<63-1)
+	if err := generateTrace(params, c); err != nil {
+		t.Fatalf("generateTrace failed: %v", err)
+	}
+}
+
 // prog1 creates a task hierarchy consisting of three tasks.
 func prog1() {
 	ctx := context.Background()
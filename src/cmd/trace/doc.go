@@ -27,6 +27,11 @@ Supported profile types are:
 Then, you can use the pprof tool to analyze the profile:
 	go tool pprof TYPE.pprof
 
+A trace may lack the stacks of some events, if the program turned them
+off with runtime/trace.SetStacks, and stacks may be incomplete where the
+tracer could not follow frame pointers, for example in cgo callbacks.
+Events without stacks are left out of the profiles above.
+
 Note that while the various profiles available when launching
 'go tool trace' work on every browser, the trace viewer itself
 (the 'view trace' page) comes from the Chrome/Chromium project
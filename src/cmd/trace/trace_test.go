@@ -7,6 +7,7 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"internal/trace"
 	"io/ioutil"
@@ -266,3 +267,54 @@ func TestDirectSemaphoreHandoff(t *testing.T) {
 		t.Fatalf("failed to parse the trace: %v", err)
 	}
 }
+
+func TestFlightRecorder(t *testing.T) {
+	// Record several generations, with goroutines running, blocking
+	// and being created across them, and check that the merged trace
+	// can be displayed.
+	fr := rtrace.NewFlightRecorder(rtrace.FlightRecorderConfig{MinAge: time.Second})
+	if err := fr.Start(); err != nil {
+		t.Fatalf("failed to start flight recorder: %v", err)
+	}
+	var wg sync.WaitGroup
+	ch := make(chan int)
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				go func() { ch <- i }()
+				<-ch
+				time.Sleep(100 * time.Microsecond)
+			}
+		}()
+	}
+	for i := 0; i < 5; i++ {
+		time.Sleep(2 * time.Millisecond)
+		// WriteTo ends the current generation.
+		if _, err := fr.WriteTo(ioutil.Discard); err != nil {
+			t.Fatalf("WriteTo failed: %v", err)
+		}
+	}
+	wg.Wait()
+	buf := new(bytes.Buffer)
+	if _, err := fr.WriteTo(buf); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	fr.Stop()
+
+	res, err := trace.Parse(buf, "")
+	if err == trace.ErrTimeOrder {
+		t.Skipf("skipping due to golang.org/issue/16755: %v", err)
+	} else if err != nil {
+		t.Fatalf("failed to parse the trace: %v", err)
+	}
+	params := &traceParams{
+		parsed:  res,
+		endTime: int64(1<<63 - 1),
+	}
+	c := viewerDataTraceConsumer(ioutil.Discard, 0, 1<<63-1)
+	if err := generateTrace(params, c); err != nil {
+		t.Fatalf("generateTrace failed: %v", err)
+	}
+}
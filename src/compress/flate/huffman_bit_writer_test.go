@@ -6,7 +6,6 @@ package flate
 
 import (
 	"bytes"
-	"flag"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -15,8 +14,6 @@ import (
 	"testing"
 )
 
-var update = flag.Bool("update", false, "update reference files")
-
 // TestBlockHuff tests huffman encoding against reference files
 // to detect possible regressions.
 // If encoding/bit allocation changes you can regenerate these files
@@ -50,14 +47,14 @@ func testBlockHuff(t *testing.T, in, out string) {
 	got := buf.Bytes()
 
 	want, err := ioutil.ReadFile(out)
-	if err != nil && !*update {
+	if err != nil && !testing.Update() {
 		t.Error(err)
 		return
 	}
 
 	t.Logf("Testing %q", in)
 	if !bytes.Equal(got, want) {
-		if *update {
+		if testing.Update() {
 			if in != out {
 				t.Logf("Updating %q", out)
 				if err := ioutil.WriteFile(out, got, 0666); err != nil {
@@ -183,7 +180,7 @@ func testBlock(t *testing.T, test huffTest, ttype string) {
 		test.want = fmt.Sprintf(test.want, ttype)
 	}
 	test.wantNoInput = fmt.Sprintf(test.wantNoInput, ttype)
-	if *update {
+	if testing.Update() {
 		if test.input != "" {
 			t.Logf("Updating %q", test.want)
 			input, err := ioutil.ReadFile(test.input)
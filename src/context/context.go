@@ -53,6 +53,7 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
+	"time/clock"
 )
 
 // A Context carries a deadline, a cancellation signal, and other values across
@@ -431,10 +432,11 @@ func WithDeadline(parent Context, d time.Time) (Context, CancelFunc) {
 	}
 	c := &timerCtx{
 		cancelCtx: newCancelCtx(parent),
+		clock:     Clock(parent),
 		deadline:  d,
 	}
 	propagateCancel(parent, c)
-	dur := time.Until(d)
+	dur := c.clock.Until(d)
 	if dur <= 0 {
 		c.cancel(true, DeadlineExceeded) // deadline has already passed
 		return c, func() { c.cancel(false, Canceled) }
@@ -442,7 +444,7 @@ func WithDeadline(parent Context, d time.Time) (Context, CancelFunc) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	if c.err == nil {
-		c.timer = time.AfterFunc(dur, func() {
+		c.timer = c.clock.AfterFunc(dur, func() {
 			c.cancel(true, DeadlineExceeded)
 		})
 	}
@@ -454,7 +456,8 @@ func WithDeadline(parent Context, d time.Time) (Context, CancelFunc) {
 // delegating to cancelCtx.cancel.
 type timerCtx struct {
 	cancelCtx
-	timer *time.Timer // Under cancelCtx.mu.
+	clock clock.Clock
+	timer clock.Timer // Under cancelCtx.mu.
 
 	deadline time.Time
 }
@@ -466,7 +469,7 @@ func (c *timerCtx) Deadline() (deadline time.Time, ok bool) {
 func (c *timerCtx) String() string {
 	return contextName(c.cancelCtx.Context) + ".WithDeadline(" +
 		c.deadline.String() + " [" +
-		time.Until(c.deadline).String() + "])"
+		c.clock.Until(c.deadline).String() + "])"
 }
 
 func (c *timerCtx) cancel(removeFromParent bool, err error) {
@@ -483,7 +486,9 @@ func (c *timerCtx) cancel(removeFromParent bool, err error) {
 	c.mu.Unlock()
 }
 
-// WithTimeout returns WithDeadline(parent, time.Now().Add(timeout)).
+// WithTimeout returns WithDeadline(parent, Clock(parent).Now().Add(timeout)),
+// which is WithDeadline(parent, time.Now().Add(timeout)) unless parent
+// was derived from a context returned by WithClock.
 //
 // Canceling this context releases resources associated with it, so code should
 // call cancel as soon as the operations running in this Context complete:
@@ -494,7 +499,67 @@ func (c *timerCtx) cancel(removeFromParent bool, err error) {
 // 		return slowOperation(ctx)
 // 	}
 func WithTimeout(parent Context, timeout time.Duration) (Context, CancelFunc) {
-	return WithDeadline(parent, time.Now().Add(timeout))
+	return WithDeadline(parent, Clock(parent).Now().Add(timeout))
+}
+
+// &clockKey is the key that a clockCtx returns its clock for.
+var clockKey int
+
+// clocksUsed is set to 1 by the first call to WithClock. Until then no
+// context carries a clock, and Clock need not search for one. This keeps
+// the lookup off the path of WithDeadline for programs that never use
+// WithClock.
+var clocksUsed int32
+
+// WithClock returns a copy of parent that carries the clock c.
+//
+// Contexts derived from the returned context with WithDeadline and
+// WithTimeout measure their deadlines with c instead of the system clock:
+// their deadlines are times of c, and they expire once c reaches them.
+// This lets tests control the expiration of contexts with a fake clock
+// (see package time/clock).
+//
+// Code that compares the Deadline of such contexts with the system clock,
+// such as net.Dialer, still sees them as times of the system clock, so a
+// fake clock used with such code should start at the current time.
+func WithClock(parent Context, c clock.Clock) Context {
+	if c == nil {
+		panic("nil clock")
+	}
+	if atomic.LoadInt32(&clocksUsed) == 0 {
+		atomic.StoreInt32(&clocksUsed, 1)
+	}
+	return &clockCtx{parent, c}
+}
+
+// Clock returns the clock carried by ctx, as set by WithClock,
+// or clock.Real() if ctx carries no clock.
+func Clock(ctx Context) clock.Clock {
+	if atomic.LoadInt32(&clocksUsed) == 0 {
+		return clock.Real()
+	}
+	if c, ok := ctx.Value(&clockKey).(clock.Clock); ok {
+		return c
+	}
+	return clock.Real()
+}
+
+// A clockCtx carries a clock. It delegates all other behavior
+// to the embedded Context.
+type clockCtx struct {
+	Context
+	clock clock.Clock
+}
+
+func (c *clockCtx) Value(key interface{}) interface{} {
+	if key == &clockKey {
+		return c.clock
+	}
+	return c.Context.Value(key)
+}
+
+func (c *clockCtx) String() string {
+	return contextName(c.Context) + ".WithClock"
 }
 
 // WithValue returns a copy of parent in which the value associated with key is
@@ -12,6 +12,7 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
+	"time/clock"
 )
 
 type testingT interface {
@@ -304,6 +305,72 @@ func XTestTimeout(t testingT) {
 	testDeadline(c, "WithTimeout+otherContext+WithTimeout", 2*time.Second, t)
 }
 
+func XTestWithClock(t testingT) {
+	start := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+	parent := WithClock(Background(), fake)
+	if got, want := fmt.Sprint(parent), "context.Background.WithClock"; got != want {
+		t.Errorf("parent.String() = %q want %q", got, want)
+	}
+	if got := Clock(parent); got != clock.Clock(fake) {
+		t.Errorf("Clock(parent) = %v want %v", got, fake)
+	}
+	if got := Clock(Background()); got != clock.Real() {
+		t.Errorf("Clock(Background()) = %v want %v", got, clock.Real())
+	}
+
+	ctx, cancel := WithTimeout(WithValue(parent, "key", "value"), time.Hour)
+	defer cancel()
+	if d, ok := ctx.Deadline(); !ok || !d.Equal(start.Add(time.Hour)) {
+		t.Errorf("ctx.Deadline() = %v, %v want %v, true", d, ok, start.Add(time.Hour))
+	}
+	child, cancelChild := WithDeadline(ctx, start.Add(30*time.Minute))
+	defer cancelChild()
+
+	fake.Advance(30*time.Minute - time.Nanosecond)
+	select {
+	case <-ctx.Done():
+		t.Fatal("ctx expired before its deadline")
+	case <-child.Done():
+		t.Fatal("child expired before its deadline")
+	default:
+	}
+	fake.Advance(time.Nanosecond)
+	select {
+	case <-child.Done():
+	default:
+		t.Fatal("child did not expire at its deadline")
+	}
+	if e := child.Err(); e != DeadlineExceeded {
+		t.Errorf("child.Err() == %v want %v", e, DeadlineExceeded)
+	}
+	select {
+	case <-ctx.Done():
+		t.Fatal("ctx expired before its deadline")
+	default:
+	}
+	fake.Advance(30 * time.Minute)
+	select {
+	case <-ctx.Done():
+	default:
+		t.Fatal("ctx did not expire at its deadline")
+	}
+
+	// Canceling the parent of a clockCtx cancels its children.
+	p, cancelParent := WithCancel(Background())
+	c, cancelC := WithTimeout(WithClock(p, fake), time.Hour)
+	defer cancelC()
+	cancelParent()
+	select {
+	case <-c.Done():
+	case <-time.After(time.Second):
+		t.Fatal("child of canceled parent not canceled")
+	}
+	if e := c.Err(); e != Canceled {
+		t.Errorf("c.Err() == %v want %v", e, Canceled)
+	}
+}
+
 func XTestCanceledTimeout(t testingT) {
 	c, _ := WithTimeout(Background(), time.Second)
 	o := otherContext{c}
@@ -17,6 +17,7 @@ func TestChildFinishesFirst(t *testing.T)              { XTestChildFinishesFirst
 func TestDeadline(t *testing.T)                        { XTestDeadline(t) }
 func TestTimeout(t *testing.T)                         { XTestTimeout(t) }
 func TestCanceledTimeout(t *testing.T)                 { XTestCanceledTimeout(t) }
+func TestWithClock(t *testing.T)                       { XTestWithClock(t) }
 func TestValues(t *testing.T)                          { XTestValues(t) }
 func TestAllocs(t *testing.T)                          { XTestAllocs(t, testing.Short, testing.AllocsPerRun) }
 func TestSimultaneousCancels(t *testing.T)             { XTestSimultaneousCancels(t) }
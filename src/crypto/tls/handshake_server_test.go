@@ -1325,7 +1325,7 @@ func BenchmarkHandshakeServer(b *testing.B) {
 func TestClientAuth(t *testing.T) {
 	var certPath, keyPath, ecdsaCertPath, ecdsaKeyPath, ed25519CertPath, ed25519KeyPath string
 
-	if *update {
+	if testing.Update() {
 		certPath = tempFile(clientCertificatePEM)
 		defer os.Remove(certPath)
 		keyPath = tempFile(clientKeyPEM)
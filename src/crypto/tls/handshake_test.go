@@ -42,20 +42,19 @@ import (
 // reference connection will always change.
 
 var (
-	update  = flag.Bool("update", false, "update golden files on failure")
 	fast    = flag.Bool("fast", false, "impose a quick, possibly flaky timeout on recorded tests")
 	keyFile = flag.String("keylog", "", "destination file for KeyLogWriter")
 )
 
 func runTestAndUpdateIfNeeded(t *testing.T, name string, run func(t *testing.T, update bool), wait bool) {
 	success := t.Run(name, func(t *testing.T) {
-		if !*update && !wait {
+		if !testing.Update() && !wait {
 			t.Parallel()
 		}
 		run(t, false)
 	})
 
-	if !success && *update {
+	if !success && testing.Update() {
 		t.Run(name+"#update", func(t *testing.T) {
 			run(t, true)
 		})
@@ -65,7 +64,7 @@ func runTestAndUpdateIfNeeded(t *testing.T, name string, run func(t *testing.T,
 // checkOpenSSLVersion ensures that the version of OpenSSL looks reasonable
 // before updating the test data.
 func checkOpenSSLVersion() error {
-	if !*update {
+	if !testing.Update() {
 		return nil
 	}
 
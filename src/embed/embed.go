@@ -0,0 +1,391 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package embed provides access to files embedded in the running Go program.
+//
+// Go source files that import "embed" can use the //go:embed directive
+// to initialize a variable of type string, []byte, or FS with the contents of
+// files read from the package directory or subdirectories at compile time.
+//
+// For example, here are three ways to embed a file named hello.txt
+// and then print its contents at run time.
+//
+// Embedding one file into a string:
+//
+//	import _ "embed"
+//
+//	//go:embed hello.txt
+//	var s string
+//	print(s)
+//
+// Embedding one file into a slice of bytes:
+//
+//	import _ "embed"
+//
+//	//go:embed hello.txt
+//	var b []byte
+//	print(string(b))
+//
+// Embedding one or more files into a file system:
+//
+//	import "embed"
+//
+//	//go:embed hello.txt
+//	var f embed.FS
+//	data, _ := f.ReadFile("hello.txt")
+//	print(string(data))
+//
+// Directives
+//
+// A //go:embed directive above a variable declaration specifies which files to embed,
+// using one or more path.Match patterns.
+//
+// The directive must immediately precede a line containing the declaration of a single variable.
+// Only blank lines and ‘//’ line comments are permitted between the directive and the declaration.
+//
+// The type of the variable must be a string type, or a slice of a byte type,
+// or FS.
+//
+// The //go:embed directive accepts multiple space-separated patterns for
+// brevity, but it can also be repeated, to avoid very long lines when there are
+// many patterns. The patterns are interpreted relative to the package directory
+// containing the source file. The path separator is a forward slash, even on
+// Windows systems. Patterns may not contain ‘.’ or ‘..’ path elements,
+// nor may they begin or end with a slash.
+// To match everything in the current directory, use ‘*’ instead of ‘.’.
+// To allow for naming files with spaces in their names, patterns can be written
+// as Go double-quoted or back-quoted string literals.
+//
+// If a pattern names a directory, all files in the subtree rooted at that directory are
+// embedded (recursively), except that files with names beginning with ‘.’ or ‘_’
+// are excluded.
+//
+// If a pattern matches no files or only empty directories, or names a file
+// outside the package's module, it is an error.
+//
+// Only a variable of type FS may be initialized from more than one file.
+//
+// The //go:embed directive can only be used with package-level variables,
+// not with local variables, and only in source files that import "embed".
+// The go command includes the embedded files in the build cache key, so
+// changing an embedded file rebuilds the package that embeds it.
+//
+// File Systems
+//
+// An FS is a read-only collection of files, usually initialized with a
+// //go:embed directive. It implements fs.FS, so it can be used with any
+// package that understands file systems, such as net/http (see http.FS)
+// and text/template and html/template (see template.ParseFS).
+package embed
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// An FS is a read-only collection of files, usually initialized with a
+// //go:embed directive. When declared without a //go:embed directive, an FS
+// is an empty file system.
+//
+// Files in an FS are named by slash-separated, unrooted paths, such as
+// "hello.txt" or "static/index.html". The name "." denotes the root
+// directory. Names may not contain "." or ".." elements other than that,
+// nor empty elements.
+//
+// An FS is safe for concurrent use by multiple goroutines.
+type FS struct {
+	// The compiler knows the layout of this struct.
+	// See cmd/compile/internal/gc's initEmbed.
+	//
+	// The files list is sorted by name but not by simple string comparison.
+	// Instead, each file's name takes the form "dir/elem" or "dir/elem/".
+	// The optional trailing slash indicates that the file is itself a directory.
+	// The files list is sorted first by dir (if dir is missing, it is taken to be ".")
+	// and then by elem, so that the entries of each directory are adjacent.
+	files *[]file
+}
+
+// split splits the name into dir and elem as described in the
+// comment in the FS struct above. isDir reports whether the
+// final trailing slash was present, indicating that name is a directory.
+func split(name string) (dir, elem string, isDir bool) {
+	if name[len(name)-1] == '/' {
+		isDir = true
+		name = name[:len(name)-1]
+	}
+	i := len(name) - 1
+	for i >= 0 && name[i] != '/' {
+		i--
+	}
+	if i < 0 {
+		return ".", name, isDir
+	}
+	return name[:i], name[i+1:], isDir
+}
+
+// trimSlash trims a trailing slash from name, if present,
+// returning the possibly shortened name.
+func trimSlash(name string) string {
+	if len(name) > 0 && name[len(name)-1] == '/' {
+		return name[:len(name)-1]
+	}
+	return name
+}
+
+// A file is a single file in the FS.
+// It implements os.FileInfo.
+type file struct {
+	// The compiler knows the layout of this struct.
+	// See cmd/compile/internal/gc's initEmbed.
+	name string
+	data string
+}
+
+var (
+	_ os.FileInfo    = (*file)(nil)
+	_ fs.ReadDirFS   = FS{}
+	_ fs.ReadFileFS  = FS{}
+	_ fs.ReadDirFile = (*openFile)(nil)
+	_ io.Seeker      = (*openFile)(nil)
+)
+
+func (f *file) Name() string       { _, elem, _ := split(f.name); return elem }
+func (f *file) Size() int64        { return int64(len(f.data)) }
+func (f *file) ModTime() time.Time { return time.Time{} }
+func (f *file) IsDir() bool        { _, _, isDir := split(f.name); return isDir }
+func (f *file) Sys() interface{}   { return nil }
+func (f *file) Mode() os.FileMode {
+	if f.IsDir() {
+		return os.ModeDir | 0555
+	}
+	return 0444
+}
+
+// dotFile is a file for the root directory,
+// which is omitted from the files list in a FS.
+var dotFile = &file{name: "./"}
+
+// validName reports whether name is a valid file name:
+// either "." or a slash-separated, unrooted path without
+// empty, "." or ".." elements.
+func validName(name string) bool {
+	if name == "." {
+		return true
+	}
+	for {
+		i := 0
+		for i < len(name) && name[i] != '/' {
+			i++
+		}
+		elem := name[:i]
+		if elem == "" || elem == "." || elem == ".." {
+			return false
+		}
+		if i == len(name) {
+			return true
+		}
+		name = name[i+1:]
+	}
+}
+
+// lookup returns the named file, or nil if it is not present.
+func (f FS) lookup(name string) *file {
+	if !validName(name) {
+		return nil
+	}
+	if name == "." {
+		return dotFile
+	}
+	if f.files == nil {
+		return nil
+	}
+
+	// Binary search to find where name would be in the list,
+	// and then check if name is at that position.
+	dir, elem, _ := split(name)
+	files := *f.files
+	i := search(files, func(fdir, felem string) bool {
+		return fdir > dir || fdir == dir && felem >= elem
+	})
+	if i < len(files) && trimSlash(files[i].name) == name {
+		return &files[i]
+	}
+	return nil
+}
+
+// search returns the index of the first file for which less
+// reports true, given the file's directory and element.
+func search(files []file, f func(dir, elem string) bool) int {
+	lo, hi := 0, len(files)
+	for lo < hi {
+		m := int(uint(lo+hi) >> 1)
+		fdir, felem, _ := split(files[m].name)
+		if f(fdir, felem) {
+			hi = m
+		} else {
+			lo = m + 1
+		}
+	}
+	return lo
+}
+
+// readDir returns the list of files corresponding to the directory dir.
+func (f FS) readDir(dir string) []file {
+	if f.files == nil {
+		return nil
+	}
+	// Binary search to find where dir starts and ends in the list
+	// and then return that slice of the list.
+	files := *f.files
+	i := search(files, func(fdir, _ string) bool { return fdir >= dir })
+	j := search(files, func(fdir, _ string) bool { return fdir > dir })
+	return files[i:j]
+}
+
+// Open opens the named file for reading and returns it as an fs.File.
+// If there is an error, it will be of type *os.PathError.
+//
+// The returned file also implements io.Seeker, and fs.ReadDirFile
+// for reading the entries of a directory.
+func (f FS) Open(name string) (fs.File, error) {
+	file := f.lookup(name)
+	if file == nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	fl := &openFile{f: file}
+	if file.IsDir() {
+		fl.entries = f.readDir(name)
+	}
+	return fl, nil
+}
+
+// ReadDir reads and returns the entire named directory,
+// sorted by file name.
+func (f FS) ReadDir(name string) ([]os.FileInfo, error) {
+	file := f.lookup(name)
+	if file == nil {
+		return nil, &os.PathError{Op: "readdir", Path: name, Err: os.ErrNotExist}
+	}
+	if !file.IsDir() {
+		return nil, &os.PathError{Op: "readdir", Path: name, Err: errNotDir}
+	}
+	return (&openFile{f: file, entries: f.readDir(name)}).Readdir(-1)
+}
+
+// ReadFile reads and returns the content of the named file.
+func (f FS) ReadFile(name string) ([]byte, error) {
+	file := f.lookup(name)
+	if file == nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	if file.IsDir() {
+		return nil, &os.PathError{Op: "read", Path: name, Err: errIsDir}
+	}
+	return []byte(file.data), nil
+}
+
+var (
+	errNotDir = errors.New("not a directory")
+	errIsDir  = errors.New("is a directory")
+)
+
+// An openFile is a file opened from an FS. Its methods behave like those
+// of an *os.File opened for reading.
+type openFile struct {
+	f       *file
+	offset  int64  // current read offset
+	entries []file // directory entries, for a directory
+	closed  bool
+}
+
+// Stat returns the os.FileInfo describing the file.
+func (f *openFile) Stat() (os.FileInfo, error) {
+	if f.closed {
+		return nil, f.err("stat", os.ErrClosed)
+	}
+	return f.f, nil
+}
+
+// Close closes the file.
+func (f *openFile) Close() error {
+	if f.closed {
+		return f.err("close", os.ErrClosed)
+	}
+	f.closed = true
+	return nil
+}
+
+// Read reads up to len(b) bytes from the file.
+// At end of file, Read returns 0, io.EOF.
+func (f *openFile) Read(b []byte) (int, error) {
+	if f.closed {
+		return 0, f.err("read", os.ErrClosed)
+	}
+	if f.f.IsDir() {
+		return 0, f.err("read", errIsDir)
+	}
+	if f.offset >= int64(len(f.f.data)) {
+		return 0, io.EOF
+	}
+	n := copy(b, f.f.data[f.offset:])
+	f.offset += int64(n)
+	return n, nil
+}
+
+// Seek sets the offset for the next Read on the file, interpreted
+// according to whence as in io.Seeker. For a directory, Seek only
+// supports rewinding to the start, to restart Readdir.
+func (f *openFile) Seek(offset int64, whence int) (int64, error) {
+	if f.closed {
+		return 0, f.err("seek", os.ErrClosed)
+	}
+	switch whence {
+	case io.SeekStart:
+		// offset += 0
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += int64(len(f.f.data))
+	default:
+		return 0, f.err("seek", os.ErrInvalid)
+	}
+	if offset < 0 || offset > int64(len(f.f.data)) {
+		return 0, f.err("seek", os.ErrInvalid)
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+// Readdir reads the contents of the directory and returns a slice of
+// up to n os.FileInfo values, in order by file name, as
+// (*os.File).Readdir does.
+func (f *openFile) Readdir(n int) ([]os.FileInfo, error) {
+	if f.closed {
+		return nil, f.err("readdir", os.ErrClosed)
+	}
+	if !f.f.IsDir() {
+		return nil, f.err("readdir", errNotDir)
+	}
+	// For a directory, the offset is the number of entries already read.
+	n0 := int64(len(f.entries)) - f.offset
+	if n > 0 && int64(n) < n0 {
+		n0 = int64(n)
+	}
+	if n0 == 0 && n > 0 {
+		return nil, io.EOF
+	}
+	list := make([]os.FileInfo, n0)
+	for i := range list {
+		list[i] = &f.entries[f.offset+int64(i)]
+	}
+	f.offset += n0
+	return list, nil
+}
+
+func (f *openFile) err(op string, err error) error {
+	return &os.PathError{Op: op, Path: trimSlash(f.f.name), Err: err}
+}
@@ -0,0 +1,148 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package embed_test
+
+import (
+	"embed"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+//go:embed testdata/hello.txt
+var helloString string
+
+//go:embed testdata/hello.txt
+var helloBytes []byte
+
+//go:embed testdata
+var testdata embed.FS
+
+//go:embed testdata/hello.txt
+//go:embed testdata/sub/*.tmpl
+var multi embed.FS
+
+var empty embed.FS
+
+func TestString(t *testing.T) {
+	if helloString != "hello, world" {
+		t.Errorf("helloString = %q, want %q", helloString, "hello, world")
+	}
+	if string(helloBytes) != "hello, world" {
+		t.Errorf("helloBytes = %q, want %q", helloBytes, "hello, world")
+	}
+}
+
+func readDirNames(t *testing.T, fsys embed.FS, dir string) []string {
+	t.Helper()
+	infos, err := fsys.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	return names
+}
+
+func TestReadDir(t *testing.T) {
+	if got, want := readDirNames(t, testdata, "."), []string{"testdata"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadDir(.) = %q, want %q", got, want)
+	}
+	// Names beginning with . or _ are omitted when embedding a directory.
+	if got, want := readDirNames(t, testdata, "testdata"), []string{"hello.txt", "sub"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadDir(testdata) = %q, want %q", got, want)
+	}
+	if got, want := readDirNames(t, multi, "testdata/sub"), []string{"t.tmpl"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadDir(testdata/sub) = %q, want %q", got, want)
+	}
+	if _, err := testdata.ReadDir("testdata/hello.txt"); err == nil {
+		t.Error("ReadDir of file succeeded")
+	}
+	if got := readDirNames(t, empty, "."); len(got) != 0 {
+		t.Errorf("ReadDir(.) of empty FS = %q, want none", got)
+	}
+}
+
+// seekDirFile is the interface implemented by the files of an FS.
+type seekDirFile interface {
+	fs.ReadDirFile
+	io.Seeker
+}
+
+func TestOpen(t *testing.T) {
+	for _, name := range []string{"testdata/.hidden", "testdata/_ugly.txt", "testdata/missing", "/testdata", "testdata/", "./testdata", "testdata/../testdata", ""} {
+		if _, err := testdata.Open(name); !os.IsNotExist(err) {
+			t.Errorf("Open(%q): err = %v, want not exist", name, err)
+		}
+	}
+
+	file, err := testdata.Open("testdata/hello.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := file.(seekDirFile)
+	info, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Name() != "hello.txt" || info.Size() != 12 || info.IsDir() || info.Mode() != 0444 {
+		t.Errorf("Stat = %s %d %v %v", info.Name(), info.Size(), info.IsDir(), info.Mode())
+	}
+	if _, err := f.Seek(7, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(f)
+	if err != nil || string(data) != "world" {
+		t.Errorf("read after Seek = %q, %v, want %q", data, err, "world")
+	}
+	if _, err := f.Readdir(-1); err == nil {
+		t.Error("Readdir of file succeeded")
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Read(make([]byte, 1)); err == nil {
+		t.Error("Read after Close succeeded")
+	}
+}
+
+func TestReaddir(t *testing.T) {
+	file, err := testdata.Open("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	d := file.(seekDirFile)
+	for _, want := range []string{"hello.txt", "sub"} {
+		list, err := d.Readdir(1)
+		if err != nil || len(list) != 1 || list[0].Name() != want {
+			t.Fatalf("Readdir(1) = %v, %v, want %s", list, err, want)
+		}
+	}
+	if list, err := d.Readdir(1); err != io.EOF {
+		t.Errorf("Readdir(1) at end = %v, %v, want io.EOF", list, err)
+	}
+	if _, err := d.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if list, err := d.Readdir(-1); err != nil || len(list) != 2 {
+		t.Errorf("Readdir(-1) after rewind = %v, %v", list, err)
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	data, err := testdata.ReadFile("testdata/sub/t.tmpl")
+	if err != nil || string(data) != "{{.}} ok" {
+		t.Errorf("ReadFile = %q, %v", data, err)
+	}
+	if _, err := testdata.ReadFile("testdata/sub"); err == nil {
+		t.Error("ReadFile of directory succeeded")
+	}
+}
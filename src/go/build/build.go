@@ -443,6 +443,18 @@ type Package struct {
 	XTestGoFiles   []string                    // _test.go files outside package
 	XTestImports   []string                    // import paths from XTestGoFiles
 	XTestImportPos map[string][]token.Position // line information for XTestImports
+
+	// //go:embed patterns found in Go source files
+	// For example, if a source file says
+	//	//go:embed a* b.c
+	// then the list will contain those two strings as separate entries.
+	// (See package embed for more details about //go:embed.)
+	EmbedPatterns        []string                    // patterns from GoFiles, CgoFiles
+	EmbedPatternPos      map[string][]token.Position // line information for EmbedPatterns
+	TestEmbedPatterns    []string                    // patterns from TestGoFiles
+	TestEmbedPatternPos  map[string][]token.Position // line information for TestEmbedPatterns
+	XTestEmbedPatterns   []string                    // patterns from XTestGoFiles
+	XTestEmbedPatternPos map[string][]token.Position // line information for XTestEmbedPatterns
 }
 
 // IsCommand reports whether the package is considered a
@@ -788,6 +800,9 @@ Found:
 	imported := make(map[string][]token.Position)
 	testImported := make(map[string][]token.Position)
 	xTestImported := make(map[string][]token.Position)
+	embedded := make(map[string][]token.Position)
+	testEmbedded := make(map[string][]token.Position)
+	xTestEmbedded := make(map[string][]token.Position)
 	allTags := make(map[string]bool)
 	fset := token.NewFileSet()
 	for _, d := range dirs {
@@ -911,6 +926,7 @@ Found:
 		}
 		var fileImports []importPos
 		isCgo := false
+		importsEmbed := false
 		for _, decl := range pf.Decls {
 			d, ok := decl.(*ast.GenDecl)
 			if !ok {
@@ -927,6 +943,9 @@ Found:
 					log.Panicf("%s: parser returned invalid quoted string: <%s>", filename, quoted)
 				}
 				fileImports = append(fileImports, importPos{path, spec.Pos()})
+				if path == "embed" {
+					importsEmbed = true
+				}
 				if path == "C" {
 					if isTest {
 						badFile(fmt.Errorf("use of cgo in test %s not supported", filename))
@@ -946,14 +965,25 @@ Found:
 			}
 		}
 
+		// The //go:embed directives can appear anywhere in the file,
+		// so files importing "embed" must be read in full.
+		var fileEmbeds []embedPos
+		if importsEmbed {
+			fileEmbeds, err = ctxt.readEmbeds(fset, filename)
+			if err != nil {
+				badFile(err)
+			}
+		}
+
 		var fileList *[]string
-		var importMap map[string][]token.Position
+		var importMap, embedMap map[string][]token.Position
 		switch {
 		case isCgo:
 			allTags["cgo"] = true
 			if ctxt.CgoEnabled {
 				fileList = &p.CgoFiles
 				importMap = imported
+				embedMap = embedded
 			} else {
 				// Ignore imports from cgo files if cgo is disabled.
 				fileList = &p.IgnoredGoFiles
@@ -961,12 +991,15 @@ Found:
 		case isXTest:
 			fileList = &p.XTestGoFiles
 			importMap = xTestImported
+			embedMap = xTestEmbedded
 		case isTest:
 			fileList = &p.TestGoFiles
 			importMap = testImported
+			embedMap = testEmbedded
 		default:
 			fileList = &p.GoFiles
 			importMap = imported
+			embedMap = embedded
 		}
 		*fileList = append(*fileList, name)
 		if importMap != nil {
@@ -974,6 +1007,11 @@ Found:
 				importMap[imp.path] = append(importMap[imp.path], fset.Position(imp.pos))
 			}
 		}
+		if embedMap != nil {
+			for _, e := range fileEmbeds {
+				embedMap[e.pattern] = append(embedMap[e.pattern], e.pos)
+			}
+		}
 	}
 
 	for tag := range allTags {
@@ -984,6 +1022,9 @@ Found:
 	p.Imports, p.ImportPos = cleanImports(imported)
 	p.TestImports, p.TestImportPos = cleanImports(testImported)
 	p.XTestImports, p.XTestImportPos = cleanImports(xTestImported)
+	p.EmbedPatterns, p.EmbedPatternPos = cleanImports(embedded)
+	p.TestEmbedPatterns, p.TestEmbedPatternPos = cleanImports(testEmbedded)
+	p.XTestEmbedPatterns, p.XTestEmbedPatternPos = cleanImports(xTestEmbedded)
 
 	// add the .S/.sx files only if we are using cgo
 	// (which means gcc will compile them).
@@ -1297,6 +1338,25 @@ func (ctxt *Context) MatchFile(dir, name string) (match bool, err error) {
 	return
 }
 
+// readEmbeds reads the full Go source file filename and returns
+// its //go:embed patterns.
+func (ctxt *Context) readEmbeds(fset *token.FileSet, filename string) ([]embedPos, error) {
+	f, err := ctxt.openFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadAll(f)
+	f.Close()
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %v", filename, err)
+	}
+	pf, err := parser.ParseFile(fset, filename, data, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	return readGoEmbeds(fset, pf)
+}
+
 // matchFile determines whether the file with the given name in the given directory
 // should be included in the package being constructed.
 // It returns the data read from the file.
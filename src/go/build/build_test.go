@@ -110,6 +110,50 @@ func TestMultiplePackageImport(t *testing.T) {
 	}
 }
 
+func TestEmbedPatterns(t *testing.T) {
+	p, err := ImportDir("testdata/embed", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"data/b.txt", "x*.txt", "y z.txt"}
+	if !reflect.DeepEqual(p.EmbedPatterns, want) {
+		t.Errorf("EmbedPatterns = %q, want %q", p.EmbedPatterns, want)
+	}
+	if pos := p.EmbedPatternPos["y z.txt"]; len(pos) != 1 || pos[0].Line != 5 {
+		t.Errorf("EmbedPatternPos[%q] = %v, want line 5", "y z.txt", pos)
+	}
+	if want := []string{"testdata/t.txt"}; !reflect.DeepEqual(p.TestEmbedPatterns, want) {
+		t.Errorf("TestEmbedPatterns = %q, want %q", p.TestEmbedPatterns, want)
+	}
+	if want := []string{"x1.txt"}; !reflect.DeepEqual(p.XTestEmbedPatterns, want) {
+		t.Errorf("XTestEmbedPatterns = %q, want %q", p.XTestEmbedPatterns, want)
+	}
+}
+
+func TestParseGoEmbed(t *testing.T) {
+	for _, tt := range []struct {
+		args string
+		want []string
+	}{
+		{" a b", []string{"a", "b"}},
+		{"\ta\t`b c`  \"d\\u0020e\"", []string{"a", "b c", "d e"}},
+		{"", nil},
+		{" \"a", nil},
+		{" `a`b", nil},
+	} {
+		got, err := parseGoEmbed(tt.args)
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("parseGoEmbed(%q) = %q, want error", tt.args, got)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseGoEmbed(%q) = %q, %v, want %q", tt.args, got, err, tt.want)
+		}
+	}
+}
+
 func TestLocalDirectory(t *testing.T) {
 	if runtime.GOOS == "darwin" {
 		switch runtime.GOARCH {
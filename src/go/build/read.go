@@ -7,7 +7,13 @@ package build
 import (
 	"bufio"
 	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
 	"io"
+	"strconv"
+	"strings"
+	"unicode"
 	"unicode/utf8"
 )
 
@@ -245,3 +251,103 @@ func readImports(f io.Reader, reportSyntaxError bool, imports *[]string) ([]byte
 
 	return r.buf, r.err
 }
+
+// An embedPos is a //go:embed pattern and where it appears.
+type embedPos struct {
+	pattern string
+	pos     token.Position
+}
+
+// readGoEmbeds returns the //go:embed patterns in the comments of f,
+// a file parsed with its comments. Only comments starting a line are
+// directives, as for the compiler.
+func readGoEmbeds(fset *token.FileSet, f *ast.File) ([]embedPos, error) {
+	var list []embedPos
+	for _, cg := range f.Comments {
+		for _, c := range cg.List {
+			if !strings.HasPrefix(c.Text, "//go:embed") {
+				continue
+			}
+			args := c.Text[len("//go:embed"):]
+			if args != "" && args[0] != ' ' && args[0] != '\t' {
+				continue
+			}
+			pos := fset.Position(c.Slash)
+			if pos.Column != 1 {
+				continue
+			}
+			patterns, err := parseGoEmbed(args)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", pos, err)
+			}
+			for _, pattern := range patterns {
+				list = append(list, embedPos{pattern, pos})
+			}
+		}
+	}
+	return list, nil
+}
+
+// parseGoEmbed parses the text following "//go:embed" to extract the
+// patterns. Patterns are separated by spaces and may be written as Go
+// string literals to include spaces.
+// It must match the parsing in cmd/compile/internal/gc/embed.go.
+func parseGoEmbed(args string) ([]string, error) {
+	var list []string
+	for args = strings.TrimSpace(args); args != ""; args = strings.TrimSpace(args) {
+		var pattern string
+	Switch:
+		switch args[0] {
+		default:
+			i := len(args)
+			for j, c := range args {
+				if unicode.IsSpace(c) {
+					i = j
+					break
+				}
+			}
+			pattern = args[:i]
+			args = args[i:]
+
+		case '`':
+			i := strings.Index(args[1:], "`")
+			if i < 0 {
+				return nil, fmt.Errorf("invalid quoted string in //go:embed: %s", args)
+			}
+			pattern = args[1 : 1+i]
+			args = args[1+i+1:]
+
+		case '"':
+			i := 1
+			for ; i < len(args); i++ {
+				if args[i] == '\\' {
+					i++
+					continue
+				}
+				if args[i] == '"' {
+					q, err := strconv.Unquote(args[:i+1])
+					if err != nil {
+						return nil, fmt.Errorf("invalid quoted string in //go:embed: %s", args[:i+1])
+					}
+					pattern = q
+					args = args[i+1:]
+					break Switch
+				}
+			}
+			if i >= len(args) {
+				return nil, fmt.Errorf("invalid quoted string in //go:embed: %s", args)
+			}
+		}
+		if args != "" {
+			r, _ := utf8.DecodeRuneInString(args)
+			if !unicode.IsSpace(r) {
+				return nil, fmt.Errorf("invalid quoted string in //go:embed: %s", args)
+			}
+		}
+		list = append(list, pattern)
+	}
+	if len(list) == 0 {
+		return nil, errors.New("usage: //go:embed pattern...")
+	}
+	return list, nil
+}
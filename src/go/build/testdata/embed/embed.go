@@ -0,0 +1,14 @@
+package p
+
+import "embed"
+
+//go:embed x*.txt "y z.txt"
+var files embed.FS
+
+func f() {
+	//go:embed indented.txt
+	_ = files
+}
+
+//go:embed `data/b.txt`
+var b []byte
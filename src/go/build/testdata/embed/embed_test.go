@@ -0,0 +1,6 @@
+package p
+
+import _ "embed"
+
+//go:embed testdata/t.txt
+var t string
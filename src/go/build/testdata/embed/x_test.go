@@ -0,0 +1,6 @@
+package p_test
+
+import _ "embed"
+
+//go:embed x1.txt
+var x string
@@ -12,16 +12,15 @@ import (
 	"go/parser"
 	"go/printer"
 	"go/token"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
 	"testing"
+	"testing/golden"
 	"text/template"
 )
 
-var update = flag.Bool("update", false, "update golden (.out) files")
 var files = flag.String("files", "", "consider only Go test files matching this regular expression")
 
 const dataDir = "testdata"
@@ -122,29 +121,9 @@ func test(t *testing.T, mode Mode) {
 			t.Error(err)
 			continue
 		}
-		got := buf.Bytes()
-
-		// update golden file if necessary
-		golden := filepath.Join(dataDir, fmt.Sprintf("%s.%d.golden", pkg.Name, mode))
-		if *update {
-			err := ioutil.WriteFile(golden, got, 0644)
-			if err != nil {
-				t.Error(err)
-			}
-			continue
-		}
-
-		// get golden file
-		want, err := ioutil.ReadFile(golden)
-		if err != nil {
-			t.Error(err)
-			continue
-		}
 
-		// compare
-		if !bytes.Equal(got, want) {
-			t.Errorf("package %s\n\tgot:\n%s\n\twant:\n%s", pkg.Name, got, want)
-		}
+		// compare with (or update) golden file testdata/pkg.mode.golden
+		golden.Check(t, fmt.Sprintf("%s.%d", pkg.Name, mode), buf.Bytes())
 	}
 }
 
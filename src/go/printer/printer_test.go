@@ -7,7 +7,6 @@ package printer
 import (
 	"bytes"
 	"errors"
-	"flag"
 	"fmt"
 	"go/ast"
 	"go/parser"
@@ -24,8 +23,6 @@ const (
 	tabwidth = 8
 )
 
-var update = flag.Bool("update", false, "update golden files")
-
 var fset = token.NewFileSet()
 
 type checkMode uint
@@ -128,7 +125,7 @@ func runcheck(t *testing.T, source, golden string, mode checkMode) {
 	}
 
 	// update golden files if necessary
-	if *update {
+	if testing.Update() {
 		if err := ioutil.WriteFile(golden, res, 0644); err != nil {
 			t.Error(err)
 		}
@@ -6,6 +6,7 @@ package template_test
 
 import (
 	"bytes"
+	"embed"
 	. "html/template"
 	"strings"
 	"testing"
@@ -121,6 +122,26 @@ func TestNumbers(t *testing.T) {
 	c.mustExecute(c.root, nil, "12.34 7.5")
 }
 
+//go:embed testdata
+var testdataFS embed.FS
+
+func TestParseFS(t *testing.T) {
+	if _, err := ParseFS(testdataFS, "testdata/missing.tmpl"); err == nil {
+		t.Error("expected error for non-existent file; got none")
+	}
+	tmpl, err := New("page.tmpl").ParseFS(testdataFS, "testdata/*.tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, "javascript:alert(1)"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), `<a href="#ZgotmplZ">link</a>`; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
 type testCase struct {
 	t    *testing.T
 	root *Template
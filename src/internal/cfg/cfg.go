@@ -37,6 +37,7 @@ const KnownEnv = `
 	GOARM
 	GOBIN
 	GOCACHE
+	GOCACHEPROG
 	GOENV
 	GOEXE
 	GOFLAGS
@@ -58,6 +59,7 @@ const KnownEnv = `
 	GOTMPDIR
 	GOTOOLDIR
 	GOWASM
+	GOWORK
 	GO_EXTLINK_ENABLED
 	PKG_CONFIG
 `
@@ -0,0 +1,228 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package diff computes line-oriented differences between texts
+// and formats them in the unified diff format.
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// A pair is a pair of values tracked for both the x and y side of a diff.
+// It is typically a pair of line indexes.
+type pair struct{ x, y int }
+
+// Diff returns a diff of the two texts old and new in the unified
+// diff format, with three lines of context. If old and new are
+// identical, Diff returns a nil slice.
+//
+// Rather than looking for the smallest possible diff, which takes
+// time quadratic in the number of lines in the worst case, Diff
+// anchors the diff on the lines that appear exactly once in each text
+// and then extends those matches as far as possible. The result
+// runs in O(n log n) time and tends to be easier to read, because it
+// does not try to match up unrelated blank lines or closing braces.
+func Diff(oldName string, old []byte, newName string, new []byte) []byte {
+	if bytes.Equal(old, new) {
+		return nil
+	}
+	x := lines(old)
+	y := lines(new)
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldName, newName)
+
+	// Walk the sequence of anchor matches, expanding each one to
+	// include the surrounding identical lines and emitting the
+	// differing lines between consecutive matches as chunks.
+	// The first and last anchors are the sentinels {0, 0} and
+	// {len(x), len(y)}, so no special cases are needed at either end.
+	const C = 3 // number of context lines
+	var (
+		done  pair     // printed up to x[:done.x] and y[:done.y]
+		chunk pair     // start lines of current chunk
+		count pair     // number of lines from each side in current chunk
+		ctext []string // lines for current chunk
+	)
+	for _, m := range anchors(x, y) {
+		if m.x < done.x {
+			// Already handled while expanding an earlier match.
+			continue
+		}
+
+		// Expand the match in both directions so that
+		// x[start.x:end.x] == y[start.y:end.y].
+		start := m
+		for start.x > done.x && start.y > done.y && x[start.x-1] == y[start.y-1] {
+			start.x--
+			start.y--
+		}
+		end := m
+		for end.x < len(x) && end.y < len(y) && x[end.x] == y[end.y] {
+			end.x++
+			end.y++
+		}
+
+		// The lines between the previous match and this one differ.
+		for _, s := range x[done.x:start.x] {
+			ctext = append(ctext, "-"+s)
+			count.x++
+		}
+		for _, s := range y[done.y:start.y] {
+			ctext = append(ctext, "+"+s)
+			count.y++
+		}
+
+		// If there are too few identical lines to end the chunk,
+		// keep them all and continue with the next match.
+		if (end.x < len(x) || end.y < len(y)) &&
+			(end.x-start.x < C || (len(ctext) > 0 && end.x-start.x < 2*C)) {
+			for _, s := range x[start.x:end.x] {
+				ctext = append(ctext, " "+s)
+				count.x++
+				count.y++
+			}
+			done = end
+			continue
+		}
+
+		// End the current chunk with up to C lines of context.
+		if len(ctext) > 0 {
+			n := end.x - start.x
+			if n > C {
+				n = C
+			}
+			for _, s := range x[start.x : start.x+n] {
+				ctext = append(ctext, " "+s)
+				count.x++
+				count.y++
+			}
+			done = pair{start.x + n, start.y + n}
+
+			// Line numbers are 1-based, except that an empty
+			// range is reported as starting at line 0.
+			if count.x > 0 {
+				chunk.x++
+			}
+			if count.y > 0 {
+				chunk.y++
+			}
+			fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", chunk.x, count.x, chunk.y, count.y)
+			for _, s := range ctext {
+				out.WriteString(s)
+			}
+			count = pair{}
+			ctext = ctext[:0]
+		}
+
+		if end.x >= len(x) && end.y >= len(y) {
+			break
+		}
+
+		// Start a new chunk with up to C lines of leading context.
+		chunk = pair{end.x - C, end.y - C}
+		for _, s := range x[chunk.x:end.x] {
+			ctext = append(ctext, " "+s)
+			count.x++
+			count.y++
+		}
+		done = end
+	}
+
+	return out.Bytes()
+}
+
+// lines returns the lines in the text x, each including its newline.
+// If the text does not end in a newline, the last line is followed
+// by the same warning about the missing newline that diff(1) prints.
+func lines(x []byte) []string {
+	l := strings.SplitAfter(string(x), "\n")
+	if l[len(l)-1] == "" {
+		l = l[:len(l)-1]
+	} else {
+		l[len(l)-1] += "\n\\ No newline at end of file\n"
+	}
+	return l
+}
+
+// anchors returns the index pairs of the longest common subsequence
+// of the lines that appear exactly once in x and exactly once in y,
+// preceded by {0, 0} and followed by {len(x), len(y)}.
+//
+// The longest common subsequence is found with Algorithm A of
+// Thomas G. Szymanski, "A Special Case of the Maximal Common
+// Subsequence Problem," Princeton TR #170 (January 1975).
+func anchors(x, y []string) []pair {
+	// Count the occurrences of each line, only distinguishing
+	// between 0, 1 and many: the x side counts as 0, -1, -2
+	// and the y side as 0, -4, -8. Negative counts leave the
+	// non-negative values free for indexes below.
+	m := make(map[string]int)
+	for _, s := range x {
+		if c := m[s]; c > -2 {
+			m[s] = c - 1
+		}
+	}
+	for _, s := range y {
+		if c := m[s]; c > -8 {
+			m[s] = c - 4
+		}
+	}
+
+	// Gather the indexes of the unique lines:
+	//	xi[i] = increasing indexes of unique lines in x.
+	//	yi[j] = increasing indexes of unique lines in y.
+	//	inv[i] = the j such that x[xi[i]] == y[yi[j]].
+	var xi, yi, inv []int
+	for i, s := range y {
+		if m[s] == -1+-4 {
+			m[s] = len(yi)
+			yi = append(yi, i)
+		}
+	}
+	for i, s := range x {
+		if j, ok := m[s]; ok && j >= 0 {
+			xi = append(xi, i)
+			inv = append(inv, j)
+		}
+	}
+
+	// The common subsequence of unique lines is the longest
+	// increasing subsequence of inv.
+	n := len(xi)
+	T := make([]int, n)
+	L := make([]int, n)
+	for i := range T {
+		T[i] = n + 1
+	}
+	for i := 0; i < n; i++ {
+		k := sort.Search(n, func(k int) bool {
+			return T[k] >= inv[i]
+		})
+		T[k] = inv[i]
+		L[i] = k + 1
+	}
+	k := 0
+	for _, v := range L {
+		if k < v {
+			k = v
+		}
+	}
+	seq := make([]pair, 2+k)
+	seq[1+k] = pair{len(x), len(y)}
+	lastj := n
+	for i := n - 1; i >= 0; i-- {
+		if L[i] == k && inv[i] < lastj {
+			seq[k] = pair{xi[i], yi[inv[i]]}
+			lastj = inv[i]
+			k--
+		}
+	}
+	seq[0] = pair{0, 0}
+	return seq
+}
@@ -0,0 +1,134 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+var diffTests = []struct {
+	old, new string
+	diff     string
+}{
+	{
+		old:  "a\nb\nc\n",
+		new:  "a\nb\nc\n",
+		diff: "",
+	},
+	{
+		old: "",
+		new: "a\nb\n",
+		diff: `--- old
++++ new
+@@ -0,0 +1,2 @@
++a
++b
+`,
+	},
+	{
+		old: "a\nb\n",
+		new: "",
+		diff: `--- old
++++ new
+@@ -1,2 +0,0 @@
+-a
+-b
+`,
+	},
+	{
+		old: "a\nb\nc\n",
+		new: "a\nB\nc\n",
+		diff: `--- old
++++ new
+@@ -1,3 +1,3 @@
+ a
+-b
++B
+ c
+`,
+	},
+	{
+		old: "a\nb\n",
+		new: "a\nb",
+		diff: `--- old
++++ new
+@@ -1,2 +1,2 @@
+ a
+-b
++b
+\ No newline at end of file
+`,
+	},
+	{
+		old: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n",
+		new: "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\n12\nthirteen\n14\n15\n",
+		diff: `--- old
++++ new
+@@ -1,6 +1,6 @@
+ 1
+ 2
+-3
++three
+ 4
+ 5
+ 6
+@@ -10,6 +10,6 @@
+ 10
+ 11
+ 12
+-13
++thirteen
+ 14
+ 15
+`,
+	},
+	{
+		old: "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+		new: "1\n2\nthree\n4\n5\n6\n7\nE\n8\n9\n",
+		diff: `--- old
++++ new
+@@ -1,9 +1,10 @@
+ 1
+ 2
+-3
++three
+ 4
+ 5
+ 6
+ 7
++E
+ 8
+ 9
+`,
+	},
+	{
+		old:  "x\n}\n\nfunc a() {\n}\n\ny\n",
+		new:  "x\n}\n\nfunc b() {\n}\n\nfunc a() {\n}\n\ny\n",
+		diff: "--- old\n+++ new\n@@ -1,6 +1,9 @@\n x\n }\n \n+func b() {\n+}\n+\n func a() {\n }\n \n",
+	},
+}
+
+func TestDiff(t *testing.T) {
+	for _, tt := range diffTests {
+		out := string(Diff("old", []byte(tt.old), "new", []byte(tt.new)))
+		if out != tt.diff {
+			t.Errorf("Diff(%q, %q):\nhave:\n%s\nwant:\n%s", tt.old, tt.new, out, tt.diff)
+		}
+	}
+}
+
+func TestDiffLarge(t *testing.T) {
+	// Diff must not take quadratic time on inputs with no common lines.
+	var old, new strings.Builder
+	for i := 0; i < 100000; i++ {
+		old.WriteString("a\n")
+		new.WriteString("b\n")
+	}
+	out := Diff("old", []byte(old.String()), "new", []byte(new.String()))
+	if n := strings.Count(string(out), "\n"); n != 2+1+2*100000 {
+		t.Errorf("diff has %d lines, want %d", n, 2+1+2*100000)
+	}
+}
@@ -0,0 +1,240 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package heapdump reads heap dumps written by runtime/debug.WriteHeapDump
+// and analyzes the object graph they describe.
+//
+// The format of a dump is described at https://golang.org/s/go15heapdump.
+// Only dumps written by the runtime of this Go version can be read.
+//
+// A dump does not record the types of heap objects. InferTypes recovers
+// them, as far as it can, from the debug information of the executable
+// that wrote the dump.
+//
+// The package is internal on purpose: the dump format follows the
+// runtime, so this API changes with it and is only for the heapdump
+// command. Programs outside the Go distribution should run
+// "go tool heapdump" instead.
+package heapdump
+
+// A Dump is the content of a heap dump.
+type Dump struct {
+	Params   Params
+	MemStats MemStats
+
+	// Objects are the allocated heap objects, in address order.
+	// They include objects that are no longer reachable but have
+	// not been freed yet.
+	Objects []*Object
+
+	// Roots are the memory outside the heap, and the other values,
+	// from which the garbage collector finds reachable objects.
+	Roots []*Root
+
+	Goroutines []*Goroutine
+	Threads    []*Thread
+
+	// Finalizers are the finalizers set on objects, and
+	// QueuedFinalizers those waiting to run.
+	Finalizers       []*Finalizer
+	QueuedFinalizers []*Finalizer
+
+	// TypeNames maps the addresses of some runtime type descriptors
+	// to the names of their types, and Itabs maps the addresses of
+	// itabs to the addresses of their type descriptors.
+	TypeNames map[uint64]string
+	Itabs     map[uint64]uint64
+
+	// AllocSites are the allocation sites sampled by the memory
+	// profiler, and AllocSamples maps the addresses of sampled
+	// objects to their allocation sites.
+	AllocSites   []*AllocSite
+	AllocSamples map[uint64]*AllocSite
+
+	g *graph // object graph, computed when first needed
+}
+
+// Params describes the program that wrote a dump.
+type Params struct {
+	BigEndian  bool
+	PtrSize    int
+	ArenaStart uint64
+	ArenaEnd   uint64
+	GOARCH     string
+	Experiment string // GOEXPERIMENT setting
+	NCPU       int
+}
+
+// An Object is an allocated heap object.
+type Object struct {
+	Addr uint64
+	Data []byte   // contents, the size of the object's size class
+	Ptrs []uint64 // offsets of the pointers in Data
+
+	// Type is the type of the object, as set by InferTypes.
+	// It is empty if the type is not known.
+	Type string
+
+	// Retained is the number of bytes that would become unreachable
+	// if the object did, including the object itself, and Idom the
+	// object's immediate dominator, the object through which all
+	// paths from roots to it pass. Idom is nil if the object is
+	// reachable from more than one root. Both are set by Dominators;
+	// Retained is 0 for unreachable objects.
+	Retained uint64
+	Idom     *Object
+
+	index int // in Dump.Objects
+}
+
+// Size returns the size of the object in bytes.
+func (o *Object) Size() uint64 {
+	return uint64(len(o.Data))
+}
+
+// A Root is memory outside the heap that may point to heap objects,
+// such as a data segment or a stack frame, or a single pointer
+// the runtime keeps, such as the function of a finalizer.
+type Root struct {
+	Name string // description of the root
+
+	// Addr and Data are the address and contents of the memory,
+	// and Ptrs the offsets of the pointers in it.
+	// For a single pointer not in dumped memory, Data is nil,
+	// and Ptr is the value of the pointer.
+	Addr uint64
+	Data []byte
+	Ptrs []uint64
+	Ptr  uint64
+
+	// Frame is the stack frame of a root that is one.
+	Frame *Frame
+}
+
+// A Goroutine is a goroutine that exists at the time of a dump.
+type Goroutine struct {
+	Addr       uint64 // address of the runtime's g
+	ID         uint64
+	SP         uint64
+	GoPC       uint64 // PC of the go statement that created it
+	Status     uint64
+	System     bool // a goroutine started by the runtime
+	WaitSince  int64
+	WaitReason string
+	Ctxt       uint64 // closure context pointer
+	Thread     uint64 // address of the runtime's m running the goroutine, if any
+	Frames     []*Frame
+}
+
+// A Frame is a stack frame of a goroutine.
+type Frame struct {
+	Func     string
+	Entry    uint64 // entry PC of the function
+	PC       uint64
+	ContinPC uint64 // PC at which execution continues, or 0
+	Depth    int    // 0 for the innermost frame
+	SP       uint64 // lowest address of the frame
+	Data     []byte
+	Ptrs     []uint64 // offsets of the pointers in Data
+}
+
+// A Thread is an operating system thread running Go code.
+type Thread struct {
+	Addr   uint64 // address of the runtime's m
+	ID     uint64
+	ProcID uint64 // operating system thread ID
+}
+
+// A Finalizer is a finalizer set on a heap object.
+type Finalizer struct {
+	Obj      uint64 // address of the object
+	Fn       uint64 // address of the finalizer's closure
+	Code     uint64 // PC of the finalizer function
+	FintType uint64 // type descriptor of the finalizer's argument
+	ObjType  uint64 // type descriptor of the pointer to the object
+}
+
+// An AllocSite is an allocation site sampled by the memory profiler.
+type AllocSite struct {
+	Size   uint64 // size of each allocation
+	Allocs uint64 // number of allocations sampled
+	Frees  uint64 // number of those that were freed
+	Stack  []StackFrame
+}
+
+// A StackFrame is a call in the stack of an allocation site.
+type StackFrame struct {
+	Func string
+	File string
+	Line int
+}
+
+// MemStats holds the runtime's memory statistics at the time of a dump.
+// The fields are as in runtime.MemStats.
+type MemStats struct {
+	Alloc        uint64
+	TotalAlloc   uint64
+	Sys          uint64
+	Lookups      uint64
+	Mallocs      uint64
+	Frees        uint64
+	HeapAlloc    uint64
+	HeapSys      uint64
+	HeapIdle     uint64
+	HeapInuse    uint64
+	HeapReleased uint64
+	HeapObjects  uint64
+	StackInuse   uint64
+	StackSys     uint64
+	MSpanInuse   uint64
+	MSpanSys     uint64
+	MCacheInuse  uint64
+	MCacheSys    uint64
+	BuckHashSys  uint64
+	GCSys        uint64
+	OtherSys     uint64
+	NextGC       uint64
+	LastGC       uint64
+	PauseTotalNs uint64
+	PauseNs      [256]uint64
+	NumGC        uint32
+}
+
+// FindObject returns the object that contains the address addr,
+// and the offset of addr in the object. It returns nil if no object
+// contains addr.
+func (d *Dump) FindObject(addr uint64) (*Object, uint64) {
+	// Find the first object past addr.
+	i, j := 0, len(d.Objects)
+	for i < j {
+		h := int(uint(i+j) >> 1)
+		if d.Objects[h].Addr <= addr {
+			i = h + 1
+		} else {
+			j = h
+		}
+	}
+	if i == 0 {
+		return nil, 0
+	}
+	o := d.Objects[i-1]
+	if addr-o.Addr >= o.Size() {
+		return nil, 0
+	}
+	return o, addr - o.Addr
+}
+
+// readPtr returns the pointer-sized word at off in b.
+func (d *Dump) readPtr(b []byte, off uint64) uint64 {
+	var v uint64
+	n := uint64(d.Params.PtrSize)
+	for i := uint64(0); i < n; i++ {
+		if d.Params.BigEndian {
+			v = v<<8 | uint64(b[off+i])
+		} else {
+			v |= uint64(b[off+i]) << (8 * i)
+		}
+	}
+	return v
+}
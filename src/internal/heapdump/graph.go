@@ -0,0 +1,319 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package heapdump
+
+import "sort"
+
+// graph is the object graph of a dump.
+//
+// Node 0 is a pseudo-root with an edge to each object a root points
+// to, and node i+1 is d.Objects[i]. Both the successors and the
+// predecessors of the nodes are stored in compressed form: the edges
+// of node v are edges[start[v]:start[v+1]].
+type graph struct {
+	n         int
+	succStart []int
+	succ      []int32
+	predStart []int
+	pred      []int32
+
+	// The edges of node 0, in order, come from these roots,
+	// at these offsets.
+	rootOf  []int32
+	rootOff []uint64
+}
+
+// graph returns the object graph of d, computing it if needed.
+func (d *Dump) graph() *graph {
+	if d.g != nil {
+		return d.g
+	}
+	g := &graph{n: len(d.Objects) + 1}
+	g.succStart = make([]int, g.n+1)
+	for i, r := range d.Roots {
+		d.rootPtrs(r, func(off uint64, o *Object) {
+			g.succ = append(g.succ, int32(o.index+1))
+			g.rootOf = append(g.rootOf, int32(i))
+			g.rootOff = append(g.rootOff, off)
+		})
+	}
+	for _, o := range d.Objects {
+		g.succStart[o.index+1] = len(g.succ)
+		d.pointers(o.Data, o.Ptrs, func(_ uint64, p *Object) {
+			g.succ = append(g.succ, int32(p.index+1))
+		})
+	}
+	g.succStart[g.n] = len(g.succ)
+
+	// Invert the edges.
+	g.predStart = make([]int, g.n+1)
+	for _, w := range g.succ {
+		g.predStart[w+1]++
+	}
+	for v := 1; v <= g.n; v++ {
+		g.predStart[v] += g.predStart[v-1]
+	}
+	next := append([]int(nil), g.predStart[:g.n]...)
+	g.pred = make([]int32, len(g.succ))
+	for v := 0; v < g.n; v++ {
+		for _, w := range g.succ[g.succStart[v]:g.succStart[v+1]] {
+			g.pred[next[w]] = int32(v)
+			next[w]++
+		}
+	}
+	d.g = g
+	return g
+}
+
+// rootPtrs calls fn for each pointer from root r to a heap object,
+// with the offset of the pointer in r and the object.
+func (d *Dump) rootPtrs(r *Root, fn func(off uint64, o *Object)) {
+	if r.Data == nil {
+		if o, _ := d.FindObject(r.Ptr); o != nil {
+			fn(0, o)
+		}
+		return
+	}
+	d.pointers(r.Data, r.Ptrs, fn)
+}
+
+// pointers calls fn for each pointer to a heap object in data,
+// at the offsets ptrs, with the offset of the pointer and the object.
+func (d *Dump) pointers(data []byte, ptrs []uint64, fn func(off uint64, o *Object)) {
+	for _, off := range ptrs {
+		if off+uint64(d.Params.PtrSize) > uint64(len(data)) {
+			continue
+		}
+		if o, _ := d.FindObject(d.readPtr(data, off)); o != nil {
+			fn(off, o)
+		}
+	}
+}
+
+// Dominators computes the dominator tree of the object graph,
+// setting the Idom and Retained fields of the objects.
+func (d *Dump) Dominators() {
+	g := d.graph()
+	idom, order := g.dominators()
+	retained := make([]uint64, g.n)
+	for _, o := range d.Objects {
+		retained[o.index+1] = o.Size()
+	}
+	// A node comes after its dominator in DFS order.
+	for i := len(order) - 1; i > 0; i-- {
+		v := order[i]
+		retained[idom[v]] += retained[v]
+	}
+	for _, o := range d.Objects {
+		v := o.index + 1
+		o.Idom = nil
+		o.Retained = 0
+		if idom[v] < 0 {
+			continue // unreachable
+		}
+		o.Retained = retained[v]
+		if idom[v] > 0 {
+			o.Idom = d.Objects[idom[v]-1]
+		}
+	}
+}
+
+// dominators returns the immediate dominator of each node of g,
+// or -1 for nodes that are not reachable from node 0, and the
+// reachable nodes in depth-first order.
+//
+// It uses the simple version of the algorithm of Lengauer and Tarjan,
+// "A Fast Algorithm for Finding Dominators in a Flowgraph",
+// TOPLAS 1(1), 1979, without recursion, since object graphs can be
+// arbitrarily deep.
+func (g *graph) dominators() (idom, order []int32) {
+	n := g.n
+	dfnum := make([]int32, n)
+	parent := make([]int32, n)
+	semi := make([]int32, n) // DFS number of the semidominator
+	ancestor := make([]int32, n)
+	label := make([]int32, n)
+	idom = make([]int32, n)
+	for v := range dfnum {
+		dfnum[v] = -1
+		idom[v] = -1
+	}
+
+	// Number the nodes in depth-first order.
+	order = make([]int32, 0, n)
+	type item struct {
+		v    int32
+		next int // index of the next edge to follow
+	}
+	visit := func(v, p int32) {
+		dfnum[v] = int32(len(order))
+		order = append(order, v)
+		parent[v] = p
+		semi[v] = dfnum[v]
+		ancestor[v] = -1
+		label[v] = v
+	}
+	visit(0, -1)
+	stack := []item{{0, g.succStart[0]}}
+	for len(stack) > 0 {
+		top := &stack[len(stack)-1]
+		if top.next == g.succStart[top.v+1] {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		w := g.succ[top.next]
+		top.next++
+		if dfnum[w] < 0 {
+			visit(w, top.v)
+			stack = append(stack, item{w, g.succStart[w]})
+		}
+	}
+
+	var path []int32
+	eval := func(v int32) int32 {
+		if ancestor[v] < 0 {
+			return v
+		}
+		// Compress the path from v to the root of its tree
+		// in the forest, deepest node first.
+		path = path[:0]
+		for x := v; ancestor[ancestor[x]] >= 0; x = ancestor[x] {
+			path = append(path, x)
+		}
+		for i := len(path) - 1; i >= 0; i-- {
+			x := path[i]
+			a := ancestor[x]
+			if semi[label[a]] < semi[label[x]] {
+				label[x] = label[a]
+			}
+			ancestor[x] = ancestor[a]
+		}
+		return label[v]
+	}
+
+	bucket := make([]int32, n) // first node whose semidominator is v
+	next := make([]int32, n)   // next node in the same bucket
+	for v := range bucket {
+		bucket[v] = -1
+	}
+	for i := len(order) - 1; i > 0; i-- {
+		w := order[i]
+		for _, v := range g.pred[g.predStart[w]:g.predStart[w+1]] {
+			if dfnum[v] < 0 {
+				continue
+			}
+			if u := eval(v); semi[u] < semi[w] {
+				semi[w] = semi[u]
+			}
+		}
+		s := order[semi[w]]
+		next[w] = bucket[s]
+		bucket[s] = w
+		p := parent[w]
+		ancestor[w] = p
+		for v := bucket[p]; v >= 0; v = next[v] {
+			if u := eval(v); semi[u] < semi[v] {
+				idom[v] = u
+			} else {
+				idom[v] = p
+			}
+		}
+		bucket[p] = -1
+	}
+	for _, w := range order[1:] {
+		if idom[w] != order[semi[w]] {
+			idom[w] = idom[idom[w]]
+		}
+	}
+	idom[0] = -1
+	return idom, order
+}
+
+// A Link is a step on a path from a root to an object:
+// the pointer at Offset in a root or an object.
+type Link struct {
+	Root   *Root
+	Object *Object
+	Offset uint64
+}
+
+// Paths returns up to n shortest paths from roots to the object o,
+// each from a different root. Each path begins with a link in a root
+// and ends with a link to o itself, with Offset 0.
+// Paths returns nil if o is not reachable.
+func (d *Dump) Paths(o *Object, n int) [][]Link {
+	g := d.graph()
+
+	// Find the edges from roots to each object.
+	byTarget := make([]int, g.succStart[1])
+	for i := range byTarget {
+		byTarget[i] = i
+	}
+	sort.SliceStable(byTarget, func(i, j int) bool {
+		return g.succ[byTarget[i]] < g.succ[byTarget[j]]
+	})
+	rootEdges := func(v int32) []int {
+		i := sort.Search(len(byTarget), func(i int) bool { return g.succ[byTarget[i]] >= v })
+		j := i
+		for j < len(byTarget) && g.succ[byTarget[j]] == v {
+			j++
+		}
+		return byTarget[i:j]
+	}
+
+	// Search backward from o. toward[v] is the next node on
+	// a shortest path from v to o.
+	var paths [][]Link
+	usedRoot := make(map[int32]bool)
+	toward := make([]int32, g.n)
+	for v := range toward {
+		toward[v] = -1
+	}
+	v0 := int32(o.index + 1)
+	toward[v0] = v0
+	queue := []int32{v0}
+	for len(queue) > 0 && len(paths) < n {
+		v := queue[0]
+		queue = queue[1:]
+		for _, e := range rootEdges(v) {
+			r := g.rootOf[e]
+			if usedRoot[r] {
+				continue
+			}
+			usedRoot[r] = true
+			path := []Link{{Root: d.Roots[r], Offset: g.rootOff[e]}}
+			for w := v; ; w = toward[w] {
+				obj := d.Objects[w-1]
+				if w == v0 {
+					path = append(path, Link{Object: obj})
+					break
+				}
+				path = append(path, Link{Object: obj, Offset: d.ptrTo(obj, d.Objects[toward[w]-1])})
+			}
+			paths = append(paths, path)
+			if len(paths) == n {
+				break
+			}
+		}
+		for _, u := range g.pred[g.predStart[v]:g.predStart[v+1]] {
+			if u > 0 && toward[u] < 0 {
+				toward[u] = v
+				queue = append(queue, u)
+			}
+		}
+	}
+	return paths
+}
+
+// ptrTo returns the offset in from of a pointer to the object to.
+func (d *Dump) ptrTo(from, to *Object) uint64 {
+	found := uint64(0)
+	d.pointers(from.Data, from.Ptrs, func(off uint64, o *Object) {
+		if o == to && found == 0 {
+			found = off
+		}
+	})
+	return found
+}
@@ -0,0 +1,329 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package heapdump
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"fmt"
+	"internal/testenv"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"testing"
+)
+
+// synthetic returns a dump with objects of the given sizes, at
+// addresses 0x1000, 0x2000 and so on, in which each pointer in
+// edges[i] is a pointer from object i, and each object in roots is
+// pointed to by a root of its own.
+func synthetic(sizes []int, edges [][]int, roots []int) *Dump {
+	d := &Dump{Params: Params{PtrSize: 8}}
+	addr := func(i int) uint64 { return uint64(i+1) << 12 }
+	for i, size := range sizes {
+		o := &Object{Addr: addr(i), Data: make([]byte, size), index: i}
+		for j, to := range edges[i] {
+			binary.LittleEndian.PutUint64(o.Data[8*j:], addr(to))
+			o.Ptrs = append(o.Ptrs, uint64(8*j))
+		}
+		d.Objects = append(d.Objects, o)
+	}
+	for _, i := range roots {
+		d.Roots = append(d.Roots, &Root{Name: "root", Ptr: addr(i)})
+	}
+	return d
+}
+
+func TestDominators(t *testing.T) {
+	// 0 -> 1, 2; 1 -> 3; 2 -> 3; 3 -> 4 -> 3; 5 is garbage.
+	sizes := []int{16, 16, 32, 64, 128, 256}
+	edges := [][]int{{1, 2}, {3}, {3}, {4}, {3}, {0}}
+	d := synthetic(sizes, edges, []int{0})
+	d.Dominators()
+	o := d.Objects
+	for _, tc := range []struct {
+		obj      int
+		idom     *Object
+		retained uint64
+	}{
+		{0, nil, 16 + 16 + 32 + 64 + 128},
+		{1, o[0], 16},
+		{2, o[0], 32},
+		{3, o[0], 64 + 128},
+		{4, o[3], 128},
+		{5, nil, 0},
+	} {
+		if got := o[tc.obj].Idom; got != tc.idom {
+			t.Errorf("object %d: idom is %v, want %v", tc.obj, got, tc.idom)
+		}
+		if got := o[tc.obj].Retained; got != tc.retained {
+			t.Errorf("object %d: retained %d bytes, want %d", tc.obj, got, tc.retained)
+		}
+	}
+
+	// A second root pointing to 2 means 0 no longer retains 2 and 3.
+	d = synthetic(sizes, edges, []int{0, 2})
+	d.Dominators()
+	o = d.Objects
+	if got, want := o[0].Retained, uint64(16+16); got != want {
+		t.Errorf("object 0: retained %d bytes, want %d", got, want)
+	}
+	if o[2].Idom != nil || o[3].Idom != nil {
+		t.Errorf("objects 2 and 3 have idoms %v and %v, want nil", o[2].Idom, o[3].Idom)
+	}
+}
+
+func TestPaths(t *testing.T) {
+	sizes := []int{16, 16, 16, 16}
+	edges := [][]int{{1}, {2}, {3}, nil}
+	d := synthetic(sizes, edges, []int{0, 2})
+	paths := d.Paths(d.Objects[3], 10)
+	if len(paths) != 2 {
+		t.Fatalf("got %d paths, want 2", len(paths))
+	}
+	// The shorter path comes first.
+	for i, want := range [][]int{{2, 3}, {0, 1, 2, 3}} {
+		path := paths[i]
+		if path[0].Root != d.Roots[1-i] {
+			t.Errorf("path %d begins at root %v, want %v", i, path[0].Root, d.Roots[1-i])
+		}
+		if len(path) != len(want)+1 {
+			t.Errorf("path %d has %d links, want %d", i, len(path), len(want)+1)
+			continue
+		}
+		for j, obj := range want {
+			if path[j+1].Object != d.Objects[obj] {
+				t.Errorf("path %d: link %d is to %v, want object %d", i, j+1, path[j+1].Object, obj)
+			}
+		}
+	}
+	if paths := d.Paths(d.Objects[3], 1); len(paths) != 1 {
+		t.Errorf("got %d paths, want 1", len(paths))
+	}
+	unreachable := synthetic(sizes, edges, nil)
+	if paths := unreachable.Paths(unreachable.Objects[3], 1); paths != nil {
+		t.Errorf("got paths to unreachable object: %v", paths)
+	}
+}
+
+type node struct {
+	next  *node
+	items []*item
+	v     interface{}
+}
+
+type item struct {
+	name string
+}
+
+type payload struct {
+	s *string
+}
+
+var testList *node
+
+// writeDump writes a heap dump of the running program and parses it.
+func writeDump(t *testing.T) *Dump {
+	if runtime.GOOS == "js" {
+		t.Skipf("WriteHeapDump is not available on %s", runtime.GOOS)
+	}
+	f, err := ioutil.TempFile("", "heapdump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	debug.WriteHeapDump(f.Fd())
+	if _, err := f.Seek(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	d, err := Parse(f)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	return d
+}
+
+func TestParse(t *testing.T) {
+	s := "payload string"
+	testList = &node{next: &node{}, items: []*item{{name: "a"}, {name: "b"}}, v: &payload{s: &s}}
+	defer func() { testList = nil }()
+
+	d := writeDump(t)
+	if d.Params.PtrSize != 4 && d.Params.PtrSize != 8 || d.Params.GOARCH != runtime.GOARCH {
+		t.Errorf("bad params %+v", d.Params)
+	}
+	if len(d.Objects) == 0 || len(d.Roots) == 0 || len(d.Goroutines) == 0 || len(d.Threads) == 0 {
+		t.Fatalf("dump has %d objects, %d roots, %d goroutines, %d threads",
+			len(d.Objects), len(d.Roots), len(d.Goroutines), len(d.Threads))
+	}
+	for i := 1; i < len(d.Objects); i++ {
+		if d.Objects[i-1].Addr+d.Objects[i-1].Size() > d.Objects[i].Addr {
+			t.Fatalf("objects %#x and %#x overlap or are out of order", d.Objects[i-1].Addr, d.Objects[i].Addr)
+		}
+	}
+	for addr, name := range d.TypeNames {
+		if name == "" || strings.HasSuffix(name, ".") {
+			t.Errorf("type at %#x has bad name %q", addr, name)
+		}
+	}
+	if d.MemStats.HeapObjects == 0 || d.MemStats.NumGC == 0 && d.MemStats.NextGC == 0 {
+		t.Errorf("bad memory statistics %+v", d.MemStats)
+	}
+
+	// The objects of testList are reachable, and all of them
+	// are retained by the first node.
+	d.Dominators()
+	list := objectOf(t, d, testList)
+	for _, p := range []interface{}{testList.next, testList.items[0], testList.v} {
+		o := objectOf(t, d, p)
+		if o.Retained == 0 {
+			t.Errorf("object %#x is not reachable", o.Addr)
+		}
+		for o != nil && o != list {
+			o = o.Idom
+		}
+		if o == nil {
+			t.Errorf("object is not dominated by testList")
+		}
+	}
+	if paths := d.Paths(objectOf(t, d, testList.items[1]), 1); len(paths) != 1 {
+		t.Errorf("found %d paths to a test object, want 1", len(paths))
+	} else if path := paths[0]; path[0].Root.Name != "bss segment" && path[0].Root.Name != "data segment" {
+		t.Errorf("path to test object begins at %q, want a data segment", path[0].Root.Name)
+	}
+	runtime.KeepAlive(s)
+
+}
+
+// objectOf returns the object in d that p points to.
+func objectOf(t *testing.T, d *Dump, p interface{}) *Object {
+	t.Helper()
+	addr := uint64(reflect.ValueOf(p).Pointer())
+	o, _ := d.FindObject(addr)
+	if o == nil {
+		t.Fatalf("no object at %#x", addr)
+	}
+	return o
+}
+
+// TestInferTypes runs testdata/dumper.go, which writes a heap dump
+// and prints the address and type of some objects, and checks that
+// InferTypes finds those types.
+func TestInferTypes(t *testing.T) {
+	testenv.MustHaveGoBuild(t)
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" || runtime.GOOS == "plan9" || runtime.GOOS == "aix" {
+		t.Skipf("reading the executable is only implemented for ELF")
+	}
+	dir, err := ioutil.TempDir("", "heapdump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	exe := filepath.Join(dir, "dumper.exe")
+	out, err := exec.Command(testenv.GoToolPath(t), "build", "-o", exe, "testdata/dumper.go").CombinedOutput()
+	if err != nil {
+		t.Fatalf("building dumper failed: %v\n%s", err, out)
+	}
+	dump := filepath.Join(dir, "dump")
+	out, err = exec.Command(exe, dump).Output()
+	if err != nil {
+		t.Fatalf("running dumper failed: %v", err)
+	}
+
+	f, err := os.Open(dump)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	d, err := Parse(f)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	ef, err := elf.Open(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ef.Close()
+	dw, err := ef.DWARF()
+	if err != nil {
+		t.Fatal(err)
+	}
+	syms, err := ef.Symbols()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types uint64
+	for _, s := range syms {
+		if s.Name == "runtime.types" {
+			types = s.Value
+		}
+	}
+	if err := d.InferTypes(dw, types); err != nil {
+		t.Fatalf("InferTypes failed: %v", err)
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		var addr uint64
+		var want string
+		if _, err := fmt.Sscanf(line, "%v %s", &addr, &want); err != nil {
+			t.Fatalf("bad dumper output %q", line)
+		}
+		o, _ := d.FindObject(addr)
+		if o == nil {
+			t.Errorf("no object at %#x", addr)
+			continue
+		}
+		if o.Type != want {
+			t.Errorf("object at %#x has type %q, want %q", addr, o.Type, want)
+		}
+	}
+	var nodes uint64
+	for _, s := range d.TypeStats() {
+		if s.Type == "main.node" {
+			nodes = s.Count
+		}
+	}
+	if nodes != 2 {
+		t.Errorf("TypeStats counts %d main.node objects, want 2", nodes)
+	}
+}
+
+func TestDiffTypes(t *testing.T) {
+	old := synthetic([]int{16, 32, 32}, make([][]int, 3), nil)
+	new := synthetic([]int{16, 32, 48, 48}, make([][]int, 4), nil)
+	new.Objects[0].Type = "T"
+	got := DiffTypes(old, new)
+	want := []TypeDiff{
+		{"unk48", 2, 96},
+		{"T", 1, 16},
+		{"unk16", -1, -16},
+		{"unk32", -1, -32},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("DiffTypes = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("DiffTypes = %v, want %v", got, want)
+			break
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	if _, err := Parse(bytes.NewReader([]byte("go1.4 heap dump\n"))); err == nil {
+		t.Errorf("Parse succeeded on a dump of another version")
+	}
+	if _, err := Parse(bytes.NewReader([]byte(header + "\x01\x80"))); err == nil {
+		t.Errorf("Parse succeeded on a truncated dump")
+	}
+}
@@ -0,0 +1,334 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package heapdump
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+)
+
+// header is the first line of a dump.
+const header = "go1.7 heap dump\n"
+
+// Record tags.
+const (
+	tagEOF             = 0
+	tagObject          = 1
+	tagOtherRoot       = 2
+	tagType            = 3
+	tagGoroutine       = 4
+	tagStackFrame      = 5
+	tagParams          = 6
+	tagFinalizer       = 7
+	tagItab            = 8
+	tagOSThread        = 9
+	tagMemStats        = 10
+	tagQueuedFinalizer = 11
+	tagData            = 12
+	tagBSS             = 13
+	tagDefer           = 14
+	tagPanic           = 15
+	tagMemProf         = 16
+	tagAllocSample     = 17
+)
+
+// Field kinds in field lists.
+const (
+	fieldKindEol   = 0
+	fieldKindPtr   = 1
+	fieldKindIface = 2
+	fieldKindEface = 3
+)
+
+// Parse reads a heap dump from r.
+func Parse(r io.Reader) (*Dump, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < len(header) || string(data[:len(header)]) != header {
+		return nil, errors.New("heapdump: not a heap dump of this Go version")
+	}
+	p := &parser{
+		data: data,
+		off:  len(header),
+		d: &Dump{
+			TypeNames:    make(map[uint64]string),
+			Itabs:        make(map[uint64]uint64),
+			AllocSamples: make(map[uint64]*AllocSite),
+		},
+		sites: make(map[uint64]*AllocSite),
+	}
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+	return p.d, nil
+}
+
+type parser struct {
+	data []byte
+	off  int
+	err  error
+	d    *Dump
+
+	g     *Goroutine            // goroutine whose frames follow
+	sites map[uint64]*AllocSite // by bucket address
+}
+
+func (p *parser) parse() error {
+	d := p.d
+	for p.err == nil {
+		off := p.off
+		tag := p.uvarint()
+		if p.err != nil {
+			break
+		}
+		switch tag {
+		case tagEOF:
+			sort.Slice(d.Objects, func(i, j int) bool {
+				return d.Objects[i].Addr < d.Objects[j].Addr
+			})
+			for i, o := range d.Objects {
+				o.index = i
+			}
+			return nil
+		case tagObject:
+			o := &Object{Addr: p.uvarint()}
+			o.Data = p.bytes()
+			o.Ptrs = p.fields()
+			d.Objects = append(d.Objects, o)
+		case tagOtherRoot:
+			name := p.string()
+			d.Roots = append(d.Roots, &Root{Name: name, Ptr: p.uvarint()})
+		case tagType:
+			addr := p.uvarint()
+			p.uvarint() // size
+			d.TypeNames[addr] = p.string()
+			p.bool() // whether an interface holds a pointer to a value
+		case tagGoroutine:
+			g := &Goroutine{Addr: p.uvarint()}
+			g.SP = p.uvarint()
+			g.ID = p.uvarint()
+			g.GoPC = p.uvarint()
+			g.Status = p.uvarint()
+			g.System = p.bool()
+			p.bool() // background, no longer used
+			g.WaitSince = int64(p.uvarint())
+			g.WaitReason = p.string()
+			g.Ctxt = p.uvarint()
+			g.Thread = p.uvarint()
+			p.uvarint() // defer
+			p.uvarint() // panic
+			d.Goroutines = append(d.Goroutines, g)
+			p.g = g
+			if g.Ctxt != 0 {
+				d.Roots = append(d.Roots, &Root{Name: fmt.Sprintf("goroutine %d context", g.ID), Ptr: g.Ctxt})
+			}
+		case tagStackFrame:
+			f := &Frame{SP: p.uvarint()}
+			f.Depth = int(p.uvarint())
+			p.uvarint() // child SP
+			f.Data = p.bytes()
+			f.Entry = p.uvarint()
+			f.PC = p.uvarint()
+			f.ContinPC = p.uvarint()
+			f.Func = p.string()
+			f.Ptrs = p.fields()
+			if p.g == nil {
+				p.fail(off, "stack frame before goroutine")
+				break
+			}
+			p.g.Frames = append(p.g.Frames, f)
+			d.Roots = append(d.Roots, &Root{
+				Name:  fmt.Sprintf("goroutine %d: %s", p.g.ID, f.Func),
+				Addr:  f.SP,
+				Data:  f.Data,
+				Ptrs:  f.Ptrs,
+				Frame: f,
+			})
+		case tagParams:
+			d.Params.BigEndian = p.bool()
+			d.Params.PtrSize = int(p.uvarint())
+			d.Params.ArenaStart = p.uvarint()
+			d.Params.ArenaEnd = p.uvarint()
+			d.Params.GOARCH = p.string()
+			d.Params.Experiment = p.string()
+			d.Params.NCPU = int(p.uvarint())
+			if s := d.Params.PtrSize; s != 4 && s != 8 {
+				p.fail(off, fmt.Sprintf("bad pointer size %d", s))
+			}
+		case tagFinalizer, tagQueuedFinalizer:
+			f := &Finalizer{Obj: p.uvarint()}
+			f.Fn = p.uvarint()
+			f.Code = p.uvarint()
+			f.FintType = p.uvarint()
+			f.ObjType = p.uvarint()
+			name := "finalizer"
+			if tag == tagFinalizer {
+				d.Finalizers = append(d.Finalizers, f)
+			} else {
+				d.QueuedFinalizers = append(d.QueuedFinalizers, f)
+				name = "queued finalizer"
+			}
+			d.Roots = append(d.Roots,
+				&Root{Name: fmt.Sprintf("%s for %#x", name, f.Obj), Ptr: f.Obj},
+				&Root{Name: fmt.Sprintf("%s function for %#x", name, f.Obj), Ptr: f.Fn})
+		case tagItab:
+			addr := p.uvarint()
+			d.Itabs[addr] = p.uvarint()
+		case tagOSThread:
+			t := &Thread{Addr: p.uvarint()}
+			t.ID = p.uvarint()
+			t.ProcID = p.uvarint()
+			d.Threads = append(d.Threads, t)
+		case tagMemStats:
+			p.memStats(&d.MemStats)
+		case tagData, tagBSS:
+			r := &Root{Name: "data segment", Addr: p.uvarint()}
+			if tag == tagBSS {
+				r.Name = "bss segment"
+			}
+			r.Data = p.bytes()
+			r.Ptrs = p.fields()
+			d.Roots = append(d.Roots, r)
+		case tagDefer:
+			p.uvarint() // address
+			p.uvarint() // goroutine
+			p.uvarint() // SP
+			p.uvarint() // PC
+			fn := p.uvarint()
+			p.uvarint() // function PC
+			p.uvarint() // link
+			if fn != 0 && p.g != nil {
+				d.Roots = append(d.Roots, &Root{Name: fmt.Sprintf("goroutine %d defer", p.g.ID), Ptr: fn})
+			}
+		case tagPanic:
+			p.uvarint() // address
+			p.uvarint() // goroutine
+			p.uvarint() // argument type
+			arg := p.uvarint()
+			p.uvarint() // defer, no longer used
+			p.uvarint() // link
+			if arg != 0 && p.g != nil {
+				d.Roots = append(d.Roots, &Root{Name: fmt.Sprintf("goroutine %d panic", p.g.ID), Ptr: arg})
+			}
+		case tagMemProf:
+			bucket := p.uvarint()
+			s := &AllocSite{Size: p.uvarint()}
+			n := p.uvarint()
+			for i := uint64(0); i < n && p.err == nil; i++ {
+				var f StackFrame
+				f.Func = p.string()
+				f.File = p.string()
+				f.Line = int(p.uvarint())
+				s.Stack = append(s.Stack, f)
+			}
+			s.Allocs = p.uvarint()
+			s.Frees = p.uvarint()
+			d.AllocSites = append(d.AllocSites, s)
+			p.sites[bucket] = s
+		case tagAllocSample:
+			addr := p.uvarint()
+			bucket := p.uvarint()
+			if s := p.sites[bucket]; s != nil {
+				d.AllocSamples[addr] = s
+			}
+		default:
+			p.fail(off, fmt.Sprintf("unknown record tag %d", tag))
+		}
+	}
+	return p.err
+}
+
+func (p *parser) memStats(s *MemStats) {
+	for _, f := range []*uint64{
+		&s.Alloc, &s.TotalAlloc, &s.Sys, &s.Lookups, &s.Mallocs, &s.Frees,
+		&s.HeapAlloc, &s.HeapSys, &s.HeapIdle, &s.HeapInuse, &s.HeapReleased, &s.HeapObjects,
+		&s.StackInuse, &s.StackSys, &s.MSpanInuse, &s.MSpanSys, &s.MCacheInuse, &s.MCacheSys,
+		&s.BuckHashSys, &s.GCSys, &s.OtherSys, &s.NextGC, &s.LastGC, &s.PauseTotalNs,
+	} {
+		*f = p.uvarint()
+	}
+	for i := range s.PauseNs {
+		s.PauseNs[i] = p.uvarint()
+	}
+	s.NumGC = uint32(p.uvarint())
+}
+
+// fail records an error in the record at offset off,
+// unless there was one already.
+func (p *parser) fail(off int, msg string) {
+	if p.err == nil {
+		p.err = fmt.Errorf("heapdump: record at offset %d: %s", off, msg)
+	}
+}
+
+func (p *parser) uvarint() uint64 {
+	var v uint64
+	for shift := uint(0); shift < 64; shift += 7 {
+		if p.off >= len(p.data) {
+			if p.err == nil {
+				p.err = io.ErrUnexpectedEOF
+			}
+			return 0
+		}
+		b := p.data[p.off]
+		p.off++
+		v |= uint64(b&0x7f) << shift
+		if b < 0x80 {
+			return v
+		}
+	}
+	p.fail(p.off, "bad varint")
+	return 0
+}
+
+func (p *parser) bool() bool {
+	return p.uvarint() != 0
+}
+
+// bytes returns the next length-prefixed byte string.
+// The result refers to the dump's data, so it must not be modified.
+func (p *parser) bytes() []byte {
+	n := p.uvarint()
+	if n > uint64(len(p.data)-p.off) {
+		if p.err == nil {
+			p.err = io.ErrUnexpectedEOF
+		}
+		return nil
+	}
+	b := p.data[p.off : p.off+int(n) : p.off+int(n)]
+	p.off += int(n)
+	return b
+}
+
+func (p *parser) string() string {
+	return string(p.bytes())
+}
+
+// fields returns the offsets of the pointers in the next field list.
+// Interface fields count as two pointers.
+func (p *parser) fields() []uint64 {
+	var ptrs []uint64
+	for p.err == nil {
+		off := p.off
+		kind := p.uvarint()
+		switch kind {
+		case fieldKindEol:
+			return ptrs
+		case fieldKindPtr:
+			ptrs = append(ptrs, p.uvarint())
+		case fieldKindIface, fieldKindEface:
+			o := p.uvarint()
+			ptrs = append(ptrs, o, o+uint64(p.d.Params.PtrSize))
+		default:
+			p.fail(off, fmt.Sprintf("unknown field kind %d", kind))
+		}
+	}
+	return nil
+}
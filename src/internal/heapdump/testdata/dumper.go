@@ -0,0 +1,71 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Dumper writes a heap dump to the file named by its argument, and
+// prints the address and expected type of some objects in the dump.
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime/debug"
+	"unsafe"
+)
+
+type node struct {
+	next  *node
+	val   [4]int
+	items []*item
+	v     interface{}
+	m     map[string]*item
+}
+
+type item struct {
+	name string
+	n    int
+}
+
+type payload struct {
+	a, b uint64
+	s    *string
+}
+
+type stringer interface {
+	String() string
+}
+
+func (p *payload) String() string {
+	return *p.s
+}
+
+var list *node
+
+func main() {
+	s := "payload string"
+	p := &payload{s: &s}
+	list = &node{
+		next:  &node{v: stringer(&payload{})},
+		items: []*item{{name: "a"}, {name: "b"}},
+		v:     p,
+		m:     map[string]*item{"x": {n: 1}},
+	}
+	f, err := os.Create(os.Args[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	debug.WriteHeapDump(f.Fd())
+	if err := f.Close(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Printf("%#x main.node\n", unsafe.Pointer(list))
+	fmt.Printf("%#x main.node\n", unsafe.Pointer(list.next))
+	fmt.Printf("%#x [...]*main.item\n", unsafe.Pointer(&list.items[0]))
+	fmt.Printf("%#x main.item\n", unsafe.Pointer(list.items[1]))
+	fmt.Printf("%#x main.payload\n", unsafe.Pointer(p))
+	fmt.Printf("%#x main.payload\n", unsafe.Pointer(list.next.v.(*payload)))
+	fmt.Printf("%#x string\n", unsafe.Pointer(p.s))
+	fmt.Printf("%#x main.item\n", unsafe.Pointer(list.m["x"]))
+}
@@ -0,0 +1,522 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package heapdump
+
+import (
+	"debug/dwarf"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TypeName returns the type of o, or, if the type is not known,
+// a name of the form "unk48" that gives the size of o.
+func (o *Object) TypeName() string {
+	if o.Type != "" {
+		return o.Type
+	}
+	return fmt.Sprintf("unk%d", o.Size())
+}
+
+// A TypeStat is the number and total size of the objects of a type.
+type TypeStat struct {
+	Type  string
+	Count uint64
+	Bytes uint64
+}
+
+// TypeStats returns the number and total size of the objects of each
+// type in d, largest total size first. Objects of unknown type are
+// grouped by size.
+func (d *Dump) TypeStats() []TypeStat {
+	m := make(map[string]*TypeStat)
+	for _, o := range d.Objects {
+		name := o.TypeName()
+		s := m[name]
+		if s == nil {
+			s = &TypeStat{Type: name}
+			m[name] = s
+		}
+		s.Count++
+		s.Bytes += o.Size()
+	}
+	stats := make([]TypeStat, 0, len(m))
+	for _, s := range m {
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Bytes != stats[j].Bytes {
+			return stats[i].Bytes > stats[j].Bytes
+		}
+		return stats[i].Type < stats[j].Type
+	})
+	return stats
+}
+
+// A TypeDiff is the change in the objects of a type between two dumps.
+type TypeDiff struct {
+	Type  string
+	Count int64 // change in the number of objects
+	Bytes int64 // change in their total size
+}
+
+// DiffTypes returns the change in the number and total size of the
+// objects of each type from the dump old to the dump new, largest
+// growth first. Types whose objects did not change are omitted.
+func DiffTypes(old, new *Dump) []TypeDiff {
+	m := make(map[string]*TypeDiff)
+	add := func(stats []TypeStat, sign int64) {
+		for _, s := range stats {
+			t := m[s.Type]
+			if t == nil {
+				t = &TypeDiff{Type: s.Type}
+				m[s.Type] = t
+			}
+			t.Count += sign * int64(s.Count)
+			t.Bytes += sign * int64(s.Bytes)
+		}
+	}
+	add(old.TypeStats(), -1)
+	add(new.TypeStats(), +1)
+	var diffs []TypeDiff
+	for _, t := range m {
+		if t.Count != 0 || t.Bytes != 0 {
+			diffs = append(diffs, *t)
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		if diffs[i].Bytes != diffs[j].Bytes {
+			return diffs[i].Bytes > diffs[j].Bytes
+		}
+		return diffs[i].Type < diffs[j].Type
+	})
+	return diffs
+}
+
+// attrGoRuntimeType is the Go-specific DWARF attribute that gives the
+// offset of the runtime type descriptor of a type from the start of
+// the executable's type descriptors.
+const attrGoRuntimeType = dwarf.Attr(0x2904)
+
+// DWARF location expression operations.
+const (
+	opAddr         = 0x03
+	opConsts       = 0x11
+	opPlus         = 0x22
+	opCallFrameCFA = 0x9c
+)
+
+// InferTypes sets the types of the objects in d, using the debug
+// information dw of the executable that wrote the dump, and types,
+// the address of the executable's runtime type descriptors, which is
+// the value of its symbol runtime.types.
+//
+// The types are found by starting at the global variables and at the
+// variables of stack frames kept in memory, and following pointers,
+// slices and interface values whose types are known. The contents of
+// an object whose type is known are followed as if it were an array of
+// that type, which is what it is for map buckets and the buffers of
+// channels. Only the pointers the runtime says are live are followed,
+// so stale memory is not misread. Objects that are reached only
+// through pointers of unknown type, such as unsafe.Pointer, remain
+// untyped.
+func (d *Dump) InferTypes(dw *dwarf.Data, types uint64) error {
+	ty := &typer{
+		d:      d,
+		dw:     dw,
+		types:  types,
+		byAddr: make(map[uint64]dwarf.Type),
+		nameOf: make(map[dwarf.Type]string),
+		ptrs:   make(map[dwarf.Type]bool),
+		funcs:  make(map[uint64][]variable),
+	}
+	for _, o := range d.Objects {
+		o.Type = ""
+	}
+	var globals []variable
+	if err := ty.readDWARF(&globals); err != nil {
+		return err
+	}
+
+	// Collect the memory outside the heap, in address order.
+	var blocks []*block
+	for _, r := range d.Roots {
+		if r.Data != nil {
+			blocks = append(blocks, &block{addr: r.Addr, data: r.Data, ptrs: sortedPtrs(r.Ptrs)})
+		}
+	}
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].addr < blocks[j].addr })
+	findBlock := func(addr uint64) *block {
+		i := sort.Search(len(blocks), func(i int) bool { return blocks[i].addr > addr })
+		if i == 0 || addr-blocks[i-1].addr >= uint64(len(blocks[i-1].data)) {
+			return nil
+		}
+		return blocks[i-1]
+	}
+	walkVar := func(addr uint64, t dwarf.Type) {
+		if b := findBlock(addr); b != nil {
+			ty.walk(b, addr-b.addr, t)
+		}
+	}
+
+	for _, v := range globals {
+		walkVar(uint64(v.off), v.typ)
+	}
+	for _, g := range d.Goroutines {
+		for _, f := range g.Frames {
+			cfa := f.SP + uint64(len(f.Data))
+			for _, v := range ty.funcs[f.Entry] {
+				walkVar(cfa+uint64(v.off), v.typ)
+			}
+		}
+	}
+	for len(ty.queue) > 0 {
+		w := ty.queue[len(ty.queue)-1]
+		ty.queue = ty.queue[:len(ty.queue)-1]
+		b := &block{addr: w.o.Addr, data: w.o.Data, ptrs: w.o.Ptrs}
+		size := uint64(w.t.Size())
+		for off := uint64(0); off+size <= w.o.Size(); off += size {
+			ty.walk(b, off, w.t)
+		}
+	}
+	return nil
+}
+
+func sortedPtrs(ptrs []uint64) []uint64 {
+	if sort.SliceIsSorted(ptrs, func(i, j int) bool { return ptrs[i] < ptrs[j] }) {
+		return ptrs
+	}
+	ptrs = append([]uint64(nil), ptrs...)
+	sort.Slice(ptrs, func(i, j int) bool { return ptrs[i] < ptrs[j] })
+	return ptrs
+}
+
+// A typer infers the types of objects.
+type typer struct {
+	d  *Dump
+	dw *dwarf.Data
+
+	types  uint64                // address of the type descriptors
+	byAddr map[uint64]dwarf.Type // by offset of runtime type descriptor
+	nameOf map[dwarf.Type]string
+	ptrs   map[dwarf.Type]bool   // whether a type contains pointers
+	funcs  map[uint64][]variable // frame variables by function entry PC
+
+	queue []typed // objects whose contents are yet to be walked
+}
+
+// A variable is a variable in the debug information. off is the
+// address of a global variable, and the offset of a frame variable
+// from the canonical frame address.
+type variable struct {
+	off int64
+	typ dwarf.Type
+}
+
+// A typed is an object and its type.
+type typed struct {
+	o *Object
+	t dwarf.Type
+}
+
+// A block is dumped memory: an object or a root.
+type block struct {
+	addr uint64
+	data []byte
+	ptrs []uint64 // offsets of the pointers in data, in increasing order
+}
+
+// readDWARF records the named types, the frame variables of each
+// function and, in globals, the global variables.
+func (ty *typer) readDWARF(globals *[]variable) error {
+	r := ty.dw.Reader()
+	depth := 0 // of the entry being read, the compilation unit at 0
+	inFunc := false
+	var fnPC uint64 // entry PC of the function being read, if inFunc
+	var fnDepth int // depth of the function being read
+	for {
+		e, err := r.Next()
+		if err != nil {
+			return err
+		}
+		if e == nil {
+			break
+		}
+		if e.Tag == 0 {
+			depth--
+			if inFunc && depth <= fnDepth {
+				inFunc = false
+			}
+			continue
+		}
+		switch e.Tag {
+		case dwarf.TagBaseType, dwarf.TagPointerType, dwarf.TagStructType,
+			dwarf.TagArrayType, dwarf.TagTypedef, dwarf.TagSubroutineType:
+			name, _ := e.Val(dwarf.AttrName).(string)
+			if name == "" {
+				break
+			}
+			t, err := ty.dw.Type(e.Offset)
+			if err != nil {
+				return err
+			}
+			ty.nameOf[t] = name
+			if addr, ok := e.Val(attrGoRuntimeType).(uint64); ok {
+				ty.byAddr[addr] = t
+			}
+		case dwarf.TagSubprogram:
+			if pc, ok := e.Val(dwarf.AttrLowpc).(uint64); ok && e.Children {
+				inFunc, fnPC, fnDepth = true, pc, depth
+			}
+		case dwarf.TagVariable, dwarf.TagFormalParameter:
+			loc, ok := e.Val(dwarf.AttrLocation).([]byte)
+			if !ok || len(loc) == 0 {
+				break
+			}
+			t, err := ty.entryType(e)
+			if err != nil || t == nil {
+				break
+			}
+			if inFunc {
+				if off, ok := cfaOffset(loc); ok {
+					ty.funcs[fnPC] = append(ty.funcs[fnPC], variable{off, t})
+				}
+			} else if depth == 1 && loc[0] == opAddr && len(loc) == 1+ty.d.Params.PtrSize {
+				addr := ty.d.readPtr(loc, 1)
+				*globals = append(*globals, variable{int64(addr), t})
+			}
+		}
+		if e.Children {
+			depth++
+		}
+	}
+	return nil
+}
+
+// entryType returns the type of the variable described by e.
+// Variables of functions that are also inlined refer to an abstract
+// entry for their type.
+func (ty *typer) entryType(e *dwarf.Entry) (dwarf.Type, error) {
+	if off, ok := e.Val(dwarf.AttrType).(dwarf.Offset); ok {
+		return ty.dw.Type(off)
+	}
+	off, ok := e.Val(dwarf.AttrAbstractOrigin).(dwarf.Offset)
+	if !ok {
+		return nil, nil
+	}
+	r := ty.dw.Reader()
+	r.Seek(off)
+	origin, err := r.Next()
+	if err != nil || origin == nil {
+		return nil, err
+	}
+	if off, ok := origin.Val(dwarf.AttrType).(dwarf.Offset); ok {
+		return ty.dw.Type(off)
+	}
+	return nil, nil
+}
+
+// cfaOffset returns the offset from the canonical frame address of a
+// variable at the location loc, if it is of that form.
+func cfaOffset(loc []byte) (int64, bool) {
+	if loc[0] != opCallFrameCFA {
+		return 0, false
+	}
+	if len(loc) == 1 {
+		return 0, true
+	}
+	if loc[1] != opConsts {
+		return 0, false
+	}
+	// Decode the signed LEB128 operand.
+	var v int64
+	var shift uint
+	i := 2
+	for ; i < len(loc); i++ {
+		b := loc[i]
+		v |= int64(b&0x7f) << shift
+		shift += 7
+		if b < 0x80 {
+			if shift < 64 && b&0x40 != 0 {
+				v |= -1 << shift
+			}
+			break
+		}
+	}
+	if i+2 != len(loc) || loc[i+1] != opPlus {
+		return 0, false
+	}
+	return v, true
+}
+
+// name returns the Go name of t.
+func (ty *typer) name(t dwarf.Type) string {
+	if n, ok := ty.nameOf[t]; ok {
+		return n
+	}
+	return t.String()
+}
+
+// hasPointers reports whether values of type t contain pointers.
+func (ty *typer) hasPointers(t dwarf.Type) bool {
+	if p, ok := ty.ptrs[t]; ok {
+		return p
+	}
+	ty.ptrs[t] = true // in case t refers to itself
+	p := false
+	switch t := t.(type) {
+	case *dwarf.PtrType, *dwarf.FuncType:
+		p = true
+	case *dwarf.TypedefType:
+		p = ty.hasPointers(t.Type)
+	case *dwarf.ArrayType:
+		p = t.Count > 0 && ty.hasPointers(t.Type)
+	case *dwarf.StructType:
+		for _, f := range t.Field {
+			if ty.hasPointers(f.Type) {
+				p = true
+				break
+			}
+		}
+	}
+	ty.ptrs[t] = p
+	return p
+}
+
+// walk follows the pointers in the value of type t at off in b.
+func (ty *typer) walk(b *block, off uint64, t dwarf.Type) {
+	if t.Size() < 0 || off+uint64(t.Size()) > uint64(len(b.data)) || !ty.hasPointers(t) {
+		return
+	}
+	switch t := t.(type) {
+	case *dwarf.TypedefType:
+		ty.walk(b, off, t.Type)
+	case *dwarf.PtrType:
+		if p, ok := ty.ptrAt(b, off); ok {
+			ty.pointsTo(p, t.Type, false)
+		}
+	case *dwarf.ArrayType:
+		size := uint64(t.Type.Size())
+		for i := int64(0); i < t.Count; i++ {
+			ty.walk(b, off+uint64(i)*size, t.Type)
+		}
+	case *dwarf.StructType:
+		switch {
+		case t.StructName == "runtime.eface":
+			ty.iface(b, off, false)
+		case t.StructName == "runtime.iface":
+			ty.iface(b, off, true)
+		case t.StructName == "string":
+			if len(t.Field) == 0 || t.Field[0].Name != "str" {
+				break
+			}
+			if pt, ok := t.Field[0].Type.(*dwarf.PtrType); ok {
+				if p, ok := ty.ptrAt(b, off); ok {
+					ty.pointsTo(p, pt.Type, true)
+				}
+			}
+		case strings.HasPrefix(t.StructName, "[]"):
+			// A slice. Its array is typed as a pointer
+			// to the element type.
+			if len(t.Field) == 0 || t.Field[0].Name != "array" {
+				break
+			}
+			if pt, ok := t.Field[0].Type.(*dwarf.PtrType); ok {
+				if p, ok := ty.ptrAt(b, off); ok {
+					ty.pointsTo(p, pt.Type, true)
+				}
+			}
+		default:
+			for _, f := range t.Field {
+				ty.walk(b, off+uint64(f.ByteOffset), f.Type)
+			}
+		}
+	}
+}
+
+// ptrAt returns the pointer at off in b,
+// if the runtime says there is a live pointer there.
+func (ty *typer) ptrAt(b *block, off uint64) (uint64, bool) {
+	if off+uint64(ty.d.Params.PtrSize) > uint64(len(b.data)) {
+		return 0, false
+	}
+	i := sort.Search(len(b.ptrs), func(i int) bool { return b.ptrs[i] >= off })
+	if i == len(b.ptrs) || b.ptrs[i] != off {
+		return 0, false
+	}
+	return ty.d.readPtr(b.data, off), true
+}
+
+// pointsTo records that p points to a value of type t, or, if array
+// is set, to an element of an array of t.
+func (ty *typer) pointsTo(p uint64, t dwarf.Type, array bool) {
+	o, off := ty.d.FindObject(p)
+	if o == nil || o.Type != "" {
+		return
+	}
+	size := t.Size()
+	if size <= 0 || uint64(size) > o.Size() {
+		return
+	}
+	if array {
+		// A slice may begin in the middle of its array.
+		if off%uint64(size) != 0 {
+			return
+		}
+		o.Type = "[...]" + ty.name(t)
+	} else {
+		if off != 0 {
+			return // a pointer to a field, not knowing the whole
+		}
+		o.Type = ty.name(t)
+	}
+	if ty.hasPointers(t) {
+		ty.queue = append(ty.queue, typed{o, t})
+	}
+}
+
+// iface follows the interface value at off in b. hasItab reports
+// whether the interface has methods, so that its first word is an
+// itab rather than a type.
+func (ty *typer) iface(b *block, off uint64, hasItab bool) {
+	ptrSize := uint64(ty.d.Params.PtrSize)
+	if off+2*ptrSize > uint64(len(b.data)) {
+		return
+	}
+	typ := ty.d.readPtr(b.data, off)
+	if hasItab {
+		typ = ty.d.Itabs[typ]
+	}
+	if typ < ty.types {
+		return
+	}
+	t := ty.byAddr[typ-ty.types]
+	if t == nil {
+		return
+	}
+	if ty.direct(t) {
+		// The data word is the value.
+		ty.walk(b, off+ptrSize, t)
+	} else if p, ok := ty.ptrAt(b, off+ptrSize); ok {
+		ty.pointsTo(p, t, false)
+	}
+}
+
+// direct reports whether values of type t are stored in interface
+// values directly, rather than pointed to, as the compiler decides.
+func (ty *typer) direct(t dwarf.Type) bool {
+	switch t := t.(type) {
+	case *dwarf.TypedefType:
+		return ty.direct(t.Type)
+	case *dwarf.PtrType, *dwarf.FuncType:
+		return true
+	case *dwarf.ArrayType:
+		return t.Count == 1 && ty.direct(t.Type)
+	case *dwarf.StructType:
+		return len(t.Field) == 1 && ty.direct(t.Field[0].Type) &&
+			!strings.HasPrefix(t.StructName, "[]") && t.StructName != "runtime.eface" && t.StructName != "runtime.iface"
+	}
+	return false
+}
@@ -5,7 +5,8 @@
 // Package profile provides a representation of profile.proto and
 // methods to encode/decode profiles in this format.
 //
-// This package is only for testing runtime/pprof.
+// This package is used by the runtime/pprof tests and by the
+// compiler, which reads CPU profiles for profile-guided optimization.
 // It is not used by production Go programs.
 package profile
 
@@ -200,7 +201,7 @@ var libRx = regexp.MustCompile(`([.]so$|[.]so[._][0-9]+)`)
 // first.
 func (p *Profile) setMain() {
 	for i := 0; i < len(p.Mapping); i++ {
-		file := strings.TrimSpace(strings.ReplaceAll(p.Mapping[i].File, "(deleted)", ""))
+		file := strings.TrimSpace(strings.Replace(p.Mapping[i].File, "(deleted)", "", -1))
 		if len(file) == 0 {
 			continue
 		}
@@ -0,0 +1,144 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+// generation is one parsed and post-processed generation of a trace.
+// The timestamps of its events are in ticks.
+type generation struct {
+	events      []*Event
+	stacks      map[uint64][]*Frame
+	ticksPerSec int64
+}
+
+// mergeGenerations joins the generations of a trace into a single
+// sequence of events, translating timestamps from ticks to nanoseconds
+// since the first event, and renumbering stacks so that their IDs are
+// unique.
+//
+// The runtime ends one generation and begins the next while the world
+// is stopped, so each generation continues where the previous one
+// ended. But it is also a complete trace, beginning with events that
+// describe the goroutines and Ps that exist at its start. Those events
+// are redundant after the first generation, so mergeGenerations drops
+// them, moving their links to the events they continue.
+func mergeGenerations(gens []generation) ([]*Event, map[uint64][]*Frame) {
+	var (
+		events []*Event
+		stacks = make(map[uint64][]*Frame)
+		stkOff uint64
+
+		// Translation of ticks to nanoseconds.
+		base      int64   // nanoseconds at the first event of the generation
+		first     int64   // ticks at the first event of the generation
+		nsPerTick float64 // at the previous generation
+
+		// State at the end of the previous generation.
+		live     = make(map[uint64]bool)   // goroutines that exist
+		running  = make(map[uint64]bool)   // goroutines that are running
+		prunning = make(map[int]bool)      // Ps that are running
+		open     = make(map[uint64]*Event) // last state change of each goroutine
+	)
+	for i, gen := range gens {
+		if i > 0 {
+			base += int64(float64(gen.events[0].Ts-first) * nsPerTick)
+		}
+		first = gen.events[0].Ts
+		// Use floating point to avoid integer overflows.
+		nsPerTick = 1e9 / float64(gen.ticksPerSec)
+
+		var maxStk uint64
+		for id, stk := range gen.stacks {
+			stacks[id+stkOff] = stk
+			if id > maxStk {
+				maxStk = id
+			}
+		}
+
+		// link links the last state change of goroutine g in an earlier
+		// generation to the event that follows the dropped event ev.
+		link := func(g uint64, ev *Event) {
+			if o := open[g]; o != nil && o.Link == nil {
+				o.Link = ev.Link
+			}
+		}
+		for _, ev := range gen.events {
+			ev.Ts = base + int64(float64(ev.Ts-first)*nsPerTick)
+			if ev.StkID != 0 {
+				ev.StkID += stkOff
+			}
+			if ev.Type == EvGoCreate && ev.Args[1] != 0 {
+				ev.Args[1] += stkOff
+			}
+
+			if i > 0 {
+				switch ev.Type {
+				case EvGoWaiting, EvGoInSyscall:
+					link(ev.G, ev)
+					continue
+				case EvGoCreate:
+					g := ev.Args[0]
+					if live[g] {
+						if !running[g] {
+							// For a running goroutine, the link is to
+							// the EvGoStart dropped below.
+							link(g, ev)
+						}
+						continue
+					}
+				case EvProcStart:
+					if prunning[ev.P] {
+						delete(prunning, ev.P)
+						continue
+					}
+				case EvGoStart, EvGoStartLabel:
+					if running[ev.G] {
+						delete(running, ev.G)
+						link(ev.G, ev)
+						continue
+					}
+				}
+			}
+
+			switch ev.Type {
+			case EvGoCreate:
+				live[ev.Args[0]] = true
+				open[ev.Args[0]] = ev
+			case EvGoStart, EvGoStartLabel:
+				open[ev.G] = ev
+			case EvGoEnd, EvGoStop:
+				delete(live, ev.G)
+				delete(open, ev.G)
+			case EvGoSched, EvGoPreempt, EvGoSysBlock, EvGoSysExit,
+				EvGoSleep, EvGoBlock, EvGoBlockSend, EvGoBlockRecv,
+				EvGoBlockSelect, EvGoBlockSync, EvGoBlockCond, EvGoBlockNet, EvGoBlockGC:
+				open[ev.G] = ev
+			case EvGoUnblock:
+				open[ev.Args[0]] = ev
+			}
+			events = append(events, ev)
+		}
+		stkOff += maxStk
+
+		// Record which goroutines and Ps are running at the end
+		// of the generation.
+		running = make(map[uint64]bool)
+		prunning = make(map[int]bool)
+		for _, ev := range gen.events {
+			switch ev.Type {
+			case EvProcStart:
+				prunning[ev.P] = true
+			case EvProcStop:
+				delete(prunning, ev.P)
+			case EvGoStart, EvGoStartLabel:
+				running[ev.G] = true
+			case EvGoEnd, EvGoStop, EvGoSched, EvGoPreempt, EvGoSysBlock,
+				EvGoSleep, EvGoBlock, EvGoBlockSend, EvGoBlockRecv,
+				EvGoBlockSelect, EvGoBlockSync, EvGoBlockCond, EvGoBlockNet, EvGoBlockGC:
+				delete(running, ev.G)
+			}
+		}
+	}
+	return events, stacks
+}
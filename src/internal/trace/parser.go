@@ -100,25 +100,30 @@ func Parse(r io.Reader, bin string) (ParseResult, error) {
 // parse parses, post-processes and verifies the trace. It returns the
 // trace version and the list of events.
 func parse(r io.Reader, bin string) (int, ParseResult, error) {
-	ver, rawEvents, strings, err := readTrace(r)
+	ver, rawGens, err := readTrace(r)
 	if err != nil {
 		return 0, ParseResult{}, err
 	}
-	events, stacks, err := parseEvents(ver, rawEvents, strings)
-	if err != nil {
-		return 0, ParseResult{}, err
-	}
-	events = removeFutile(events)
-	err = postProcessTrace(ver, events)
-	if err != nil {
-		return 0, ParseResult{}, err
-	}
-	// Attach stack traces.
-	for _, ev := range events {
-		if ev.StkID != 0 {
-			ev.Stk = stacks[ev.StkID]
+	var gens []generation
+	for _, raw := range rawGens {
+		events, stacks, ticksPerSec, err := parseEvents(ver, raw.events, raw.strings)
+		if err != nil {
+			return 0, ParseResult{}, err
+		}
+		events = removeFutile(events)
+		err = postProcessTrace(ver, events)
+		if err != nil {
+			return 0, ParseResult{}, err
+		}
+		// Attach stack traces.
+		for _, ev := range events {
+			if ev.StkID != 0 {
+				ev.Stk = stacks[ev.StkID]
+			}
 		}
+		gens = append(gens, generation{events, stacks, ticksPerSec})
 	}
+	events, stacks := mergeGenerations(gens)
 	if ver < 1007 && bin != "" {
 		if err := symbolize(events, bin); err != nil {
 			return 0, ParseResult{}, err
@@ -135,9 +140,19 @@ type rawEvent struct {
 	sargs []string
 }
 
+// rawGeneration holds the raw events and the string dictionary
+// of one trace generation.
+type rawGeneration struct {
+	events  []rawEvent
+	strings map[uint64]string
+}
+
 // readTrace does wire-format parsing and verification.
 // It does not care about specific event types and argument meaning.
-func readTrace(r io.Reader) (ver int, events []rawEvent, strings map[uint64]string, err error) {
+// A trace consists of one or more generations, each a complete trace
+// beginning with a header, as written by the runtime/trace flight
+// recorder. No event begins with the first byte of a header.
+func readTrace(r io.Reader) (ver int, gens []rawGeneration, err error) {
 	// Read and validate trace header.
 	var buf [16]byte
 	off, err := io.ReadFull(r, buf[:])
@@ -160,7 +175,8 @@ func readTrace(r io.Reader) (ver int, events []rawEvent, strings map[uint64]stri
 	}
 
 	// Read events.
-	strings = make(map[uint64]string)
+	var events []rawEvent
+	strings := make(map[uint64]string)
 	for {
 		// Read event type and number of arguments (1 byte).
 		off0 := off
@@ -175,6 +191,28 @@ func readTrace(r io.Reader) (ver int, events []rawEvent, strings map[uint64]stri
 			return
 		}
 		off += n
+		if buf[0] == 'g' {
+			// Header of the next generation.
+			n, err = io.ReadFull(r, buf[1:])
+			off += n
+			if err != nil {
+				err = fmt.Errorf("failed to read header at offset 0x%x: read %v, err %v", off0, n+1, err)
+				return
+			}
+			var genVer int
+			genVer, err = parseHeader(buf[:])
+			if err != nil {
+				return
+			}
+			if genVer != ver {
+				err = fmt.Errorf("trace generation at offset 0x%x has version %v, want %v", off0, genVer, ver)
+				return
+			}
+			gens = append(gens, rawGeneration{events, strings})
+			events = nil
+			strings = make(map[uint64]string)
+			continue
+		}
 		typ := buf[0] << 2 >> 2
 		narg := buf[0]>>6 + 1
 		inlineArgs := byte(4)
@@ -267,6 +305,7 @@ func readTrace(r io.Reader) (ver int, events []rawEvent, strings map[uint64]stri
 		}
 		events = append(events, ev)
 	}
+	gens = append(gens, rawGeneration{events, strings})
 	return
 }
 
@@ -313,8 +352,9 @@ func parseHeader(buf []byte) (int, error) {
 
 // Parse events transforms raw events into events.
 // It does analyze and verify per-event-type arguments.
-func parseEvents(ver int, rawEvents []rawEvent, strings map[uint64]string) (events []*Event, stacks map[uint64][]*Frame, err error) {
-	var ticksPerSec, lastSeq, lastTs int64
+// The timestamps of the events are in ticks, at ticksPerSec.
+func parseEvents(ver int, rawEvents []rawEvent, strings map[uint64]string) (events []*Event, stacks map[uint64][]*Frame, ticksPerSec int64, err error) {
+	var lastSeq, lastTs int64
 	var lastG uint64
 	var lastP int
 	timerGoids := make(map[uint64]bool)
@@ -479,12 +519,7 @@ func parseEvents(ver int, rawEvents []rawEvent, strings map[uint64]string) (even
 		return
 	}
 
-	// Translate cpu ticks to real time.
-	minTs := events[0].Ts
-	// Use floating point to avoid integer overflows.
-	freq := 1e9 / float64(ticksPerSec)
 	for _, ev := range events {
-		ev.Ts = int64(float64(ev.Ts-minTs) * freq)
 		// Move timers and syscalls to separate fake Ps.
 		if timerGoids[ev.G] && ev.Type == EvGoUnblock {
 			ev.P = TimerP
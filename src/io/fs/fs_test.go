@@ -0,0 +1,109 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs_test
+
+import (
+	. "io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var isValidPathTests = []struct {
+	name string
+	ok   bool
+}{
+	{".", true},
+	{"x", true},
+	{"x/y", true},
+
+	{"", false},
+	{"..", false},
+	{"/", false},
+	{"x/", false},
+	{"/x", false},
+	{"x/y/", false},
+	{"/x/y", false},
+	{"./", false},
+	{"./x", false},
+	{"x/.", false},
+	{"x/./y", false},
+	{"../", false},
+	{"../x", false},
+	{"x/..", false},
+	{"x/../y", false},
+	{"x//y", false},
+}
+
+func TestValidPath(t *testing.T) {
+	for _, tt := range isValidPathTests {
+		ok := ValidPath(tt.name)
+		if ok != tt.ok {
+			t.Errorf("ValidPath(%q) = %v, want %v", tt.name, ok, tt.ok)
+		}
+	}
+}
+
+// dirFS is a minimal FS backed by a directory in the host file system.
+type dirFS string
+
+func (dir dirFS) Open(name string) (File, error) {
+	if !ValidPath(name) {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrInvalid}
+	}
+	f, err := os.Open(filepath.Join(string(dir), filepath.FromSlash(name)))
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func TestGlob(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fs-glob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"a.txt", "b.txt", "c.go", "sub/d.txt"} {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte("hello"), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fsys := dirFS(dir)
+	for _, tt := range []struct {
+		pattern string
+		want    []string
+	}{
+		{"*.txt", []string{"a.txt", "b.txt"}},
+		{"*/*.txt", []string{"sub/d.txt"}},
+		{"c.go", []string{"c.go"}},
+		{"x.go", nil},
+	} {
+		matches, err := Glob(fsys, tt.pattern)
+		if err != nil {
+			t.Errorf("Glob(%q): %v", tt.pattern, err)
+			continue
+		}
+		if !reflect.DeepEqual(matches, tt.want) {
+			t.Errorf("Glob(%q) = %q, want %q", tt.pattern, matches, tt.want)
+		}
+	}
+
+	if _, err := Glob(fsys, "["); err == nil {
+		t.Error("Glob([) succeeded, want ErrBadPattern")
+	}
+
+	data, err := ReadFile(fsys, "sub/d.txt")
+	if err != nil || string(data) != "hello" {
+		t.Errorf(`ReadFile("sub/d.txt") = %q, %v, want "hello", nil`, data, err)
+	}
+}
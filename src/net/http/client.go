@@ -96,6 +96,9 @@ type Client struct {
 	//
 	// A Timeout of zero means no timeout.
 	//
+	// The timeout is measured by the clock of the Request's Context,
+	// which is the system clock unless set with context.WithClock.
+	//
 	// The Client cancels requests to the underlying Transport
 	// as if the Request's Context ended.
 	//
@@ -185,9 +188,11 @@ func (c *Client) send(req *Request, deadline time.Time) (resp *Response, didTime
 	return resp, nil, nil
 }
 
-func (c *Client) deadline() time.Time {
+// deadline returns the deadline of a request made with ctx, as a time of
+// ctx's clock (see context.WithClock), or the zero time if c has no Timeout.
+func (c *Client) deadline(ctx context.Context) time.Time {
 	if c.Timeout > 0 {
-		return time.Now().Add(c.Timeout)
+		return context.Clock(ctx).Now().Add(c.Timeout)
 	}
 	return time.Time{}
 }
@@ -328,6 +333,7 @@ func setRequestCancel(req *Request, rt RoundTripper, deadline time.Time) (stopTi
 	}
 	knownTransport := knownRoundTripperImpl(rt, req)
 	oldCtx := req.Context()
+	clk := context.Clock(oldCtx)
 
 	if req.Cancel == nil && knownTransport {
 		// If they already had a Request.Context that's
@@ -338,7 +344,7 @@ func setRequestCancel(req *Request, rt RoundTripper, deadline time.Time) (stopTi
 
 		var cancelCtx func()
 		req.ctx, cancelCtx = context.WithDeadline(oldCtx, deadline)
-		return cancelCtx, func() bool { return time.Now().After(deadline) }
+		return cancelCtx, func() bool { return clk.Now().After(deadline) }
 	}
 	initialReqCancel := req.Cancel // the user's original Request.Cancel, if any
 
@@ -372,7 +378,7 @@ func setRequestCancel(req *Request, rt RoundTripper, deadline time.Time) (stopTi
 		})
 	}
 
-	timer := time.NewTimer(time.Until(deadline))
+	timer := clk.NewTimer(clk.Until(deadline))
 	var timedOut atomicBool
 
 	go func() {
@@ -380,7 +386,7 @@ func setRequestCancel(req *Request, rt RoundTripper, deadline time.Time) (stopTi
 		case <-initialReqCancel:
 			doCancel()
 			timer.Stop()
-		case <-timer.C:
+		case <-timer.C():
 			timedOut.setTrue()
 			doCancel()
 		case <-stopTimerCh:
@@ -582,7 +588,7 @@ func (c *Client) do(req *Request) (retres *Response, reterr error) {
 	}
 
 	var (
-		deadline      = c.deadline()
+		deadline      = c.deadline(req.Context())
 		reqs          []*Request
 		resp          *Response
 		copyHeaders   = c.makeHeadersCopier(req)
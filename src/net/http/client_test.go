@@ -28,6 +28,7 @@ import (
 	"sync/atomic"
 	"testing"
 	"time"
+	"time/clock"
 )
 
 var robotsTxtHandler = HandlerFunc(func(w ResponseWriter, r *Request) {
@@ -1324,6 +1325,50 @@ func testClientTimeout_Headers(t *testing.T, h2 bool) {
 	}
 }
 
+// Client.Timeout measured by the clock of the request's context.
+func TestClientTimeoutClock(t *testing.T) {
+	setParallel(t)
+	defer afterTest(t)
+	donec := make(chan bool, 1)
+	cst := newClientServerTest(t, h1Mode, HandlerFunc(func(w ResponseWriter, r *Request) {
+		<-donec
+	}), optQuietLog)
+	defer cst.close()
+	defer func() { donec <- true }()
+
+	// The dialer compares the request's deadline with the system clock,
+	// so start the fake clock at the current time.
+	fake := clock.NewFake(time.Now())
+	cst.c.Timeout = time.Hour
+	req, _ := NewRequestWithContext(context.WithClock(context.Background(), fake), "GET", cst.ts.URL, nil)
+	errc := make(chan error, 1)
+	go func() {
+		res, err := cst.c.Do(req)
+		if err == nil {
+			res.Body.Close()
+		}
+		errc <- err
+	}()
+
+	fake.BlockUntil(1)
+	select {
+	case err := <-errc:
+		t.Fatalf("Do returned %v before the fake clock advanced", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	fake.Advance(time.Hour + time.Second)
+	err := <-errc
+	if err == nil {
+		t.Fatal("got response from Do; expected error")
+	}
+	if ne, ok := err.(net.Error); !ok || !ne.Timeout() {
+		t.Fatalf("got error %v; want timeout", err)
+	}
+	if got := err.Error(); !strings.Contains(got, "Client.Timeout exceeded") {
+		t.Errorf("error string = %q; missing timeout substring", got)
+	}
+}
+
 // Issue 16094: if Client.Timeout is set but not hit, a Timeout error shouldn't be
 // returned.
 func TestClientTimeoutCancel(t *testing.T) {
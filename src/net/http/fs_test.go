@@ -7,6 +7,7 @@ package http_test
 import (
 	"bufio"
 	"bytes"
+	"embed"
 	"errors"
 	"fmt"
 	"io"
@@ -408,6 +409,40 @@ func TestFileServerImplicitLeadingSlash(t *testing.T) {
 	}
 }
 
+//go:embed testdata/index.html testdata/style.css
+var testdataFS embed.FS
+
+func TestFileServerEmbedFS(t *testing.T) {
+	defer afterTest(t)
+	ts := httptest.NewServer(FileServer(FS(testdataFS)))
+	defer ts.Close()
+	get := func(suffix string) (int, string) {
+		res, err := Get(ts.URL + suffix)
+		if err != nil {
+			t.Fatalf("Get %s: %v", suffix, err)
+		}
+		b, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			t.Fatalf("ReadAll %s: %v", suffix, err)
+		}
+		res.Body.Close()
+		return res.StatusCode, string(b)
+	}
+	want, err := ioutil.ReadFile("testdata/style.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code, s := get("/testdata/style.css"); code != StatusOK || s != string(want) {
+		t.Errorf("GET style.css = %d %q, want 200 %q", code, s, want)
+	}
+	if code, s := get("/testdata/"); code != StatusOK || !strings.Contains(s, "index.html") {
+		t.Errorf("GET /testdata/ = %d %q, want index page", code, s)
+	}
+	if code, _ := get("/testdata/file"); code != StatusNotFound {
+		t.Errorf("GET unembedded file = %d, want 404", code)
+	}
+}
+
 func TestDirJoin(t *testing.T) {
 	if runtime.GOOS == "windows" {
 		t.Skip("skipping test on windows")
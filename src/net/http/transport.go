@@ -215,6 +215,8 @@ type Transport struct {
 	// time to wait for a server's response headers after fully
 	// writing the request (including its body, if any). This
 	// time does not include the time to read the response body.
+	// The timeout is measured by the clock of the Request's Context
+	// (see context.WithClock).
 	ResponseHeaderTimeout time.Duration
 
 	// ExpectContinueTimeout, if non-zero, specifies the amount of
@@ -2569,9 +2571,9 @@ func (pc *persistConn) roundTrip(req *transportRequest) (resp *Response, err err
 				if debugRoundTrip {
 					req.logf("starting timer for %v", d)
 				}
-				timer := time.NewTimer(d)
+				timer := context.Clock(req.Context()).NewTimer(d)
 				defer timer.Stop() // prevent leaks
-				respHeaderTimer = timer.C
+				respHeaderTimer = timer.C()
 			}
 		case <-pc.closech:
 			if debugRoundTrip {
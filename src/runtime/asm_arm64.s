@@ -1142,6 +1142,11 @@ TEXT runtime·return0(SB), NOSPLIT, $0
 	MOVW	$0, R0
 	RET
 
+// func getfp() uintptr
+TEXT runtime·getfp(SB),NOSPLIT|NOFRAME,$0-8
+	MOVD	R29, ret+0(FP)
+	RET
+
 // The top-most function running on a goroutine
 // returns to goexit+PCQuantum.
 TEXT runtime·goexit(SB),NOSPLIT|NOFRAME|TOPFRAME,$0-0
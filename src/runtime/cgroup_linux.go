@@ -0,0 +1,256 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+import "unsafe"
+
+// The default GOMAXPROCS follows the CPU limit that the cgroup of the
+// process places on it: cpu.max in cgroup v2, and cpu.cfs_quota_us
+// over cpu.cfs_period_us in cgroup v1. A cgroup is also bound by the
+// limits of its ancestors, so the limit is the tightest of them all.
+
+// cgroupCPUFile names the files holding the CPU limit of a cgroup,
+// as NUL-terminated paths.
+type cgroupCPUFile struct {
+	max    []byte // cpu.max, or cpu.cfs_quota_us in cgroup v1
+	period []byte // cpu.cfs_period_us in cgroup v1, nil in cgroup v2
+}
+
+// cgroupCPUFiles holds the limit files of the cgroup of the process
+// and of its ancestors. It is set by cgroupinit and not changed after,
+// so that sysmon can read the limit without allocating.
+var cgroupCPUFiles []cgroupCPUFile
+
+var (
+	procSelfCgroup    = []byte("/proc/self/cgroup\x00")
+	procSelfMountinfo = []byte("/proc/self/mountinfo\x00")
+)
+
+// cgroupinit finds the files holding the CPU limit of the process.
+// It is called during bootstrap, before cgroupCPULimit.
+func cgroupinit() {
+	dir, mount, v1 := parseCgroupCPU(readProcFile(procSelfCgroup), readProcFile(procSelfMountinfo))
+	if dir == "" {
+		return
+	}
+	for {
+		var f cgroupCPUFile
+		if v1 {
+			f.max = cgroupFilePath(dir, "cpu.cfs_quota_us")
+			f.period = cgroupFilePath(dir, "cpu.cfs_period_us")
+		} else {
+			f.max = cgroupFilePath(dir, "cpu.max")
+		}
+		cgroupCPUFiles = append(cgroupCPUFiles, f)
+		if len(dir) <= len(mount) {
+			break
+		}
+		i := len(dir) - 1
+		for dir[i] != '/' {
+			i--
+		}
+		dir = dir[:i]
+	}
+}
+
+func cgroupFilePath(dir, file string) []byte {
+	return []byte(dir + "/" + file + "\x00")
+}
+
+// readProcFile returns the contents of the file at the NUL-terminated
+// path, or "" if it cannot be read.
+func readProcFile(path []byte) string {
+	fd := open(&path[0], 0 /* O_RDONLY */, 0)
+	if fd < 0 {
+		return ""
+	}
+	b := make([]byte, 0, 4096)
+	for {
+		if len(b) == cap(b) {
+			b = append(b, 0)[:len(b)]
+		}
+		n := read(fd, unsafe.Pointer(&b[:cap(b)][len(b)]), int32(cap(b)-len(b)))
+		if n <= 0 {
+			break
+		}
+		b = b[:len(b)+int(n)]
+	}
+	closefd(fd)
+	return slicebytetostringtmp(b)
+}
+
+// parseCgroupCPU finds the directory of the cgroup whose CPU limit
+// applies to the process, given the contents of /proc/self/cgroup and
+// /proc/self/mountinfo. It returns the directory, the mount point of
+// its hierarchy, and whether that is a cgroup v1 hierarchy, or "" if
+// there is no such cgroup.
+func parseCgroupCPU(cgroup, mountinfo string) (dir, mount string, v1 bool) {
+	// Each line of /proc/self/cgroup is hierarchy-ID:controllers:path.
+	// The v1 hierarchy with the cpu controller takes precedence over
+	// the unified v2 hierarchy, ID 0, which then has no cpu controller.
+	var path, v2path string
+	for cgroup != "" {
+		var line string
+		line, cgroup = cut(cgroup, "\n")
+		id, rest := cut(line, ":")
+		controllers, p := cut(rest, ":")
+		if id == "0" && controllers == "" {
+			v2path = p
+			continue
+		}
+		for controllers != "" {
+			var c string
+			c, controllers = cut(controllers, ",")
+			if c == "cpu" {
+				path, v1 = p, true
+			}
+		}
+	}
+	if !v1 {
+		path = v2path
+	}
+	if path == "" {
+		return "", "", false
+	}
+
+	// Each line of /proc/self/mountinfo is like
+	//	36 35 98:0 /root /mnt rw,noatime master:1 - cgroup cgroup rw,cpu
+	// where the fields after the separator are the file system type,
+	// the source and the superblock options, and the fourth and fifth
+	// fields are the root of the mount within the file system and the
+	// mount point, with spaces and other special characters escaped.
+	for mountinfo != "" {
+		var line string
+		line, mountinfo = cut(mountinfo, "\n")
+		fields, super := cut(line, " - ")
+		fstype, super := cut(super, " ")
+		_, opts := cut(super, " ")
+		if v1 && (fstype != "cgroup" || !hasOption(opts, "cpu")) || !v1 && fstype != "cgroup2" {
+			continue
+		}
+		for i := 0; i < 3; i++ {
+			_, fields = cut(fields, " ")
+		}
+		root, fields := cut(fields, " ")
+		mount, _ = cut(fields, " ")
+		root, mount = unescapeMountinfo(root), unescapeMountinfo(mount)
+		rel := path
+		if root != "/" {
+			if path != root && !hasPrefix(path, root+"/") {
+				// The cgroup of the process is not under this mount.
+				continue
+			}
+			rel = path[len(root):]
+		}
+		if rel == "/" {
+			rel = ""
+		}
+		return mount + rel, mount, v1
+	}
+	return "", "", false
+}
+
+// unescapeMountinfo undoes the octal escapes, such as \040 for a space,
+// with which the kernel writes paths in /proc/self/mountinfo.
+func unescapeMountinfo(s string) string {
+	if index(s, "\\") < 0 {
+		return s
+	}
+	isOctal := func(c byte) bool { return '0' <= c && c <= '7' }
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) && isOctal(s[i+1]) && isOctal(s[i+2]) && isOctal(s[i+3]) {
+			b = append(b, (s[i+1]-'0')<<6|(s[i+2]-'0')<<3|(s[i+3]-'0'))
+			i += 3
+			continue
+		}
+		b = append(b, s[i])
+	}
+	return string(b)
+}
+
+// hasOption reports whether the comma-separated list opts holds opt.
+func hasOption(opts, opt string) bool {
+	for opts != "" {
+		var o string
+		o, opts = cut(opts, ",")
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// cut slices s around the first instance of sep, returning the text
+// before and after sep, or s and "" if sep does not appear in s.
+func cut(s, sep string) (before, after string) {
+	if i := index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):]
+	}
+	return s, ""
+}
+
+// cgroupCPULimit returns the number of CPUs the cgroup CPU limit of the
+// process lets it keep busy, rounded up, or 0 if there is no limit.
+// It is called by sysmon, so it must not allocate.
+//
+//go:nowritebarrierrec
+func cgroupCPULimit() int32 {
+	limit := int32(0)
+	for _, f := range cgroupCPUFiles {
+		var maxbuf, periodbuf [64]byte
+		max := readCgroupFile(f.max, maxbuf[:])
+		period := ""
+		if f.period != nil {
+			period = readCgroupFile(f.period, periodbuf[:])
+		}
+		if n := cpuLimitProcs(max, period); n > 0 && (limit == 0 || n < limit) {
+			limit = n
+		}
+	}
+	return limit
+}
+
+// readCgroupFile reads the file at the NUL-terminated path into buf and
+// returns its first line, or "" if it cannot be read.
+//
+//go:nowritebarrierrec
+func readCgroupFile(path []byte, buf []byte) string {
+	fd := open(&path[0], 0 /* O_RDONLY */, 0)
+	if fd < 0 {
+		return ""
+	}
+	n := read(fd, noescape(unsafe.Pointer(&buf[0])), int32(len(buf)))
+	closefd(fd)
+	if n <= 0 {
+		return ""
+	}
+	s, _ := cut(slicebytetostringtmp(buf[:n]), "\n")
+	return s
+}
+
+// cpuLimitProcs returns the number of CPUs a cgroup may keep busy,
+// rounded up, given the contents of its limit files, or 0 if it has no
+// limit. In cgroup v2, max holds both the quota and the period, as in
+// "max 100000" or "150000 100000", and period is "". In cgroup v1,
+// a quota of -1 means that there is no limit.
+func cpuLimitProcs(max, period string) int32 {
+	if period == "" {
+		max, period = cut(max, " ")
+	}
+	quota, ok := atoi(max)
+	if !ok || quota <= 0 {
+		return 0
+	}
+	p, ok := atoi(period)
+	if !ok || p <= 0 {
+		return 0
+	}
+	n := (quota-1)/p + 1
+	if n > 1<<30 {
+		n = 1 << 30
+	}
+	return int32(n)
+}
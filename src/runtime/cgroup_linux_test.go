@@ -0,0 +1,173 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	. "runtime"
+	"strings"
+	"testing"
+)
+
+const hybridMountinfo = `32 24 0:28 / /sys/fs/cgroup ro,nosuid - tmpfs tmpfs ro,mode=755
+33 32 0:29 / /sys/fs/cgroup/cpu,cpuacct rw,relatime shared:12 - cgroup cgroup rw,cpu,cpuacct
+34 32 0:30 / /sys/fs/cgroup/cpuset rw,relatime shared:13 - cgroup cgroup rw,cpuset
+35 32 0:31 / /sys/fs/cgroup/unified rw,relatime shared:14 - cgroup2 cgroup2 rw
+`
+
+func TestParseCgroupCPU(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		cgroup    string
+		mountinfo string
+		dir       string
+		mount     string
+		v1        bool
+	}{
+		{
+			name:      "v2",
+			cgroup:    "0::/system.slice/app.service\n",
+			mountinfo: "29 23 0:26 / /sys/fs/cgroup rw,nosuid - cgroup2 cgroup2 rw,nsdelegate\n",
+			dir:       "/sys/fs/cgroup/system.slice/app.service",
+			mount:     "/sys/fs/cgroup",
+		},
+		{
+			name:      "v2 namespace",
+			cgroup:    "0::/\n",
+			mountinfo: "1254 1252 0:26 / /sys/fs/cgroup ro,nosuid - cgroup2 cgroup2 rw\n",
+			dir:       "/sys/fs/cgroup",
+			mount:     "/sys/fs/cgroup",
+		},
+		{
+			name:      "hybrid",
+			cgroup:    "4:cpuset:/\n3:cpu,cpuacct:/user.slice\n0::/user.slice/session-1.scope\n",
+			mountinfo: hybridMountinfo,
+			dir:       "/sys/fs/cgroup/cpu,cpuacct/user.slice",
+			mount:     "/sys/fs/cgroup/cpu,cpuacct",
+			v1:        true,
+		},
+		{
+			name:      "v1 container",
+			cgroup:    "3:cpu,cpuacct:/docker/0123abcd\n",
+			mountinfo: "40 38 0:33 /docker/0123abcd /sys/fs/cgroup/cpu,cpuacct ro - cgroup cgroup rw,cpu,cpuacct\n",
+			dir:       "/sys/fs/cgroup/cpu,cpuacct",
+			mount:     "/sys/fs/cgroup/cpu,cpuacct",
+			v1:        true,
+		},
+		{
+			name:      "escaped",
+			cgroup:    "0::/app\n",
+			mountinfo: `29 23 0:26 / /sys/fs/cgroup\040v2 rw - cgroup2 cgroup2 rw` + "\n",
+			dir:       "/sys/fs/cgroup v2/app",
+			mount:     "/sys/fs/cgroup v2",
+		},
+		{
+			name:      "elsewhere",
+			cgroup:    "0::/system.slice/app.service\n",
+			mountinfo: "29 23 0:26 /other /mnt rw - cgroup2 cgroup2 rw\n",
+		},
+		{
+			name:      "unmounted",
+			cgroup:    "3:cpu,cpuacct:/\n",
+			mountinfo: "34 32 0:30 / /sys/fs/cgroup/cpuset rw - cgroup cgroup rw,cpuset\n",
+		},
+		{
+			name: "none",
+		},
+	} {
+		dir, mount, v1 := ParseCgroupCPU(tc.cgroup, tc.mountinfo)
+		if dir != tc.dir || mount != tc.mount || v1 != tc.v1 {
+			t.Errorf("%s: ParseCgroupCPU = %q, %q, %v, want %q, %q, %v", tc.name, dir, mount, v1, tc.dir, tc.mount, tc.v1)
+		}
+	}
+}
+
+func TestCgroupGOMAXPROCS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cgroup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// A cgroup v2 hierarchy mounted at a path with a space, which
+	// mountinfo escapes, in which the parent of the cgroup of the
+	// process limits it to 3 CPUs.
+	mount := filepath.Join(dir, "cgroup fs")
+	group := filepath.Join(mount, "app", "worker")
+	if err := os.MkdirAll(group, 0777); err != nil {
+		t.Fatal(err)
+	}
+	write := func(file, data string) {
+		t.Helper()
+		if err := ioutil.WriteFile(file, []byte(data), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cgroup := filepath.Join(dir, "cgroup")
+	mountinfo := filepath.Join(dir, "mountinfo")
+	write(cgroup, "0::/app/worker\n")
+	write(mountinfo, "29 23 0:26 / "+strings.Replace(mount, " ", `\040`, -1)+" rw,nosuid - cgroup2 cgroup2 rw\n")
+	write(filepath.Join(mount, "cpu.max"), "max 100000\n")
+	write(filepath.Join(mount, "app", "cpu.max"), "300000 100000\n")
+
+	const cpus = 8
+	for _, tc := range []struct {
+		name           string
+		cgroupmaxprocs int32  // GODEBUG=cgroupmaxprocs
+		updatemaxprocs int32  // GODEBUG=updatemaxprocs
+		env            string // GOMAXPROCS environment variable
+		call           bool   // call runtime.GOMAXPROCS before the limit changes
+		want           int    // initial GOMAXPROCS
+		wantUpdate     int    // GOMAXPROCS after the limit of the cgroup drops to 2
+	}{
+		{name: "default", cgroupmaxprocs: 1, want: 3, wantUpdate: 3},
+		{name: "cgroupmaxprocs=0", want: cpus, wantUpdate: cpus},
+		{name: "cgroupmaxprocs=0 updatemaxprocs=1", updatemaxprocs: 1, want: cpus, wantUpdate: cpus},
+		{name: "env", cgroupmaxprocs: 1, updatemaxprocs: 1, env: "5", want: 5, wantUpdate: 5},
+		{name: "call", cgroupmaxprocs: 1, updatemaxprocs: 1, call: true, want: 3, wantUpdate: 3},
+		{name: "updatemaxprocs=1", cgroupmaxprocs: 1, updatemaxprocs: 1, want: 3, wantUpdate: 2},
+	} {
+		write(filepath.Join(group, "cpu.max"), "max 100000\n")
+		SetMaxprocsTestEnv(cgroup, mountinfo, cpus, tc.cgroupmaxprocs, tc.updatemaxprocs)
+		procs := MaxprocsInit(tc.env)
+		if procs != tc.want {
+			t.Errorf("%s: initial GOMAXPROCS = %d, want %d", tc.name, procs, tc.want)
+		}
+		if tc.call {
+			GOMAXPROCS(GOMAXPROCS(0))
+		}
+		if got := MaxprocsUpdate(procs); got != procs {
+			t.Errorf("%s: GOMAXPROCS changed from %d to %d with the same limit", tc.name, procs, got)
+		}
+		write(filepath.Join(group, "cpu.max"), "200000 100000\n")
+		if got := MaxprocsUpdate(procs); got != tc.wantUpdate {
+			t.Errorf("%s: GOMAXPROCS = %d after the limit changed, want %d", tc.name, got, tc.wantUpdate)
+		}
+		RestoreMaxprocsTestEnv()
+	}
+}
+
+func TestCPULimitProcs(t *testing.T) {
+	for _, tc := range []struct {
+		max, period string
+		want        int32
+	}{
+		{"max 100000", "", 0},
+		{"200000 100000", "", 2},
+		{"150000 100000", "", 2},
+		{"50000 100000", "", 1},
+		{"-1", "100000", 0},
+		{"400000", "100000", 4},
+		{"400001", "100000", 5},
+		{"400000", "", 0},
+		{"", "", 0},
+	} {
+		if got := CPULimitProcs(tc.max, tc.period); got != tc.want {
+			t.Errorf("CPULimitProcs(%q, %q) = %d, want %d", tc.max, tc.period, got, tc.want)
+		}
+	}
+}
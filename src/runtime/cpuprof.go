@@ -48,6 +48,11 @@ var cpuprof cpuProfile
 // If hz <= 0, SetCPUProfileRate turns off profiling.
 // If the profiler is on, the rate cannot be changed without first turning it off.
 //
+// On Linux, each thread running Go code is sampled by a timer of its own,
+// which measures the CPU time of that thread, so that the profile is accurate
+// even when many threads are busy. Other threads, and other systems, are
+// sampled by a single process-wide timer.
+//
 // Most clients should use the runtime/pprof package or
 // the testing package's -test.cpuprofile flag instead of calling
 // SetCPUProfileRate directly.
@@ -82,13 +87,13 @@ func SetCPUProfileRate(hz int) {
 	unlock(&cpuprof.lock)
 }
 
-// add adds the stack trace to the profile.
+// add adds the stack trace to the profile, as n samples.
 // It is called from signal handlers and other limited environments
 // and cannot allocate memory or acquire locks that might be
 // held at the time of the signal, nor can it use substantial amounts
 // of stack.
 //go:nowritebarrierrec
-func (p *cpuProfile) add(gp *g, stk []uintptr) {
+func (p *cpuProfile) add(gp *g, stk []uintptr, n uint64) {
 	// Simple cas-lock to coordinate with setcpuprofilerate.
 	for !atomic.Cas(&prof.signalLock, 0, 1) {
 		osyield()
@@ -98,7 +103,7 @@ func (p *cpuProfile) add(gp *g, stk []uintptr) {
 		if p.numExtra > 0 || p.lostExtra > 0 || p.lostAtomic > 0 {
 			p.addExtra()
 		}
-		hdr := [1]uint64{1}
+		hdr := [1]uint64{n}
 		// Note: write "knows" that the argument is &gp.labels,
 		// because otherwise its write barrier behavior may not
 		// be correct. See the long comment there before
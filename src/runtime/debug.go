@@ -13,12 +13,19 @@ import (
 // simultaneously and returns the previous setting. If n < 1, it does not
 // change the current setting.
 // The number of logical CPUs on the local machine can be queried with NumCPU.
+// The default is the number of logical CPUs, or on Linux the number of
+// CPUs that the cgroup CPU limit of the process allows, if that is fewer.
 // This call will go away when the scheduler improves.
 func GOMAXPROCS(n int) int {
 	if GOARCH == "wasm" && n > 1 {
 		n = 1 // WebAssembly has no threads yet, so only one CPU is possible.
 	}
 
+	if n > 0 {
+		// From now on, GOMAXPROCS no longer follows the cgroup CPU limit.
+		atomic.Store(&maxprocs.custom, 1)
+	}
+
 	lock(&sched.lock)
 	ret := int(gomaxprocs)
 	unlock(&sched.lock)
@@ -0,0 +1,193 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// An EventKind is the kind of a runtime Event.
+type EventKind int
+
+const (
+	// These values must be kept identical to their corresponding
+	// event* values in the runtime.
+	EventGCStart        EventKind = 1 + iota // a garbage collection started
+	EventGCEnd                               // a garbage collection finished marking
+	EventHeapGoal                            // the heap goal changed
+	EventThreadSpike                         // the number of threads spiked
+	EventGoroutineSpike                      // the number of goroutines spiked
+	EventLost                                // events were dropped
+)
+
+var eventKinds = [...]string{
+	EventGCStart:        "GCStart",
+	EventGCEnd:          "GCEnd",
+	EventHeapGoal:       "HeapGoal",
+	EventThreadSpike:    "ThreadSpike",
+	EventGoroutineSpike: "GoroutineSpike",
+	EventLost:           "Lost",
+}
+
+func (k EventKind) String() string {
+	if k <= 0 || int(k) >= len(eventKinds) {
+		return "EventKind(" + strconv.Itoa(int(k)) + ")"
+	}
+	return eventKinds[k]
+}
+
+// An Event describes something the runtime did. The fields that do not
+// apply to the Kind of the event are zero.
+type Event struct {
+	Kind EventKind
+	Time time.Time
+
+	// GC is the number of the garbage collection, counting from 1,
+	// for EventGCStart and EventGCEnd.
+	GC uint32
+
+	// Pause is the total time the world was stopped during the
+	// garbage collection, for EventGCEnd.
+	Pause time.Duration
+
+	// HeapLive is the size of the heap in bytes: the heap allocated
+	// when the garbage collection started, for EventGCStart, and the
+	// heap it marked live, for EventGCEnd.
+	HeapLive uint64
+
+	// HeapGoal is the heap size in bytes at which the garbage
+	// collection is due to finish: the goal of the collection, for
+	// EventGCStart, and the goal of the next one, for EventGCEnd and
+	// EventHeapGoal. It is math.MaxUint64 if garbage collection is
+	// off.
+	HeapGoal uint64
+
+	// Count is the number of threads, for EventThreadSpike, or of
+	// goroutines, for EventGoroutineSpike, and the number of events
+	// dropped, for EventLost.
+	Count int64
+
+	// Prev is the number of threads or goroutines about a second
+	// before a spike.
+	Prev int64
+}
+
+// eventRecord is Event as the runtime records it.
+// It must be kept identical to runtime.eventRecord.
+type eventRecord struct {
+	kind     EventKind
+	time     int64
+	gc       uint32
+	pause    int64
+	heapLive uint64
+	heapGoal uint64
+	count    int64
+	prev     int64
+}
+
+var eventHandlers struct {
+	sync.Mutex
+	list    []*func(Event) // replaced, never modified, when handlers change
+	started bool
+}
+
+// RegisterEventHandler arranges for h to be called with each runtime
+// event from now on, until the returned function is called. Events
+// are:
+//
+//	- the start and the end of each garbage collection,
+//	- each change of the heap goal,
+//	- spikes in the number of threads or of goroutines, in which the
+//	  number at least doubles in about a second, by at least 16
+//	  threads or 1024 goroutines.
+//
+// The handlers are called one at a time, in the order the events
+// happened, by a goroutine that RegisterEventHandler starts and that
+// exits when the last handler is unregistered. The runtime holds
+// events for that goroutine in a buffer of limited size, rather than
+// wait for it: if the handlers fall behind, events are dropped and then
+// reported as EventLost. A handler may still be called with events
+// that were being delivered when it was unregistered.
+func RegisterEventHandler(h func(Event)) (unregister func()) {
+	p := &h
+	eventHandlers.Lock()
+	defer eventHandlers.Unlock()
+	eventHandlers.list = append(eventHandlers.list[:len(eventHandlers.list):len(eventHandlers.list)], p)
+	if len(eventHandlers.list) == 1 {
+		setEventsEnabled(true)
+	}
+	if !eventHandlers.started {
+		eventHandlers.started = true
+		go deliverEvents()
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() { unregisterEventHandler(p) })
+	}
+}
+
+func unregisterEventHandler(p *func(Event)) {
+	eventHandlers.Lock()
+	defer eventHandlers.Unlock()
+	var list []*func(Event)
+	for _, q := range eventHandlers.list {
+		if q != p {
+			list = append(list, q)
+		}
+	}
+	eventHandlers.list = list
+	if len(list) == 0 {
+		setEventsEnabled(false)
+	}
+}
+
+// deliverEvents reads events from the runtime and calls the handlers,
+// until there are none.
+func deliverEvents() {
+	buf := make([]eventRecord, 64)
+	for {
+		n, lost, now := readEvents(buf)
+		if n == 0 && lost == 0 {
+			// Events are off. Stop, unless a handler has been
+			// registered again since.
+			eventHandlers.Lock()
+			if len(eventHandlers.list) == 0 {
+				eventHandlers.started = false
+				eventHandlers.Unlock()
+				return
+			}
+			eventHandlers.Unlock()
+			continue
+		}
+		wall := time.Now()
+		eventHandlers.Lock()
+		list := eventHandlers.list
+		eventHandlers.Unlock()
+		for _, r := range buf[:n] {
+			e := Event{
+				Kind:     r.kind,
+				Time:     wall.Add(time.Duration(r.time - now)),
+				GC:       r.gc,
+				Pause:    time.Duration(r.pause),
+				HeapLive: r.heapLive,
+				HeapGoal: r.heapGoal,
+				Count:    r.count,
+				Prev:     r.prev,
+			}
+			for _, h := range list {
+				(*h)(e)
+			}
+		}
+		if lost > 0 {
+			// The lost events came after those in the buffer.
+			e := Event{Kind: EventLost, Time: wall, Count: int64(lost)}
+			for _, h := range list {
+				(*h)(e)
+			}
+		}
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug_test
+
+import (
+	"runtime"
+	. "runtime/debug"
+	"sync"
+	"testing"
+	"time"
+)
+
+// eventsOf registers a handler that sends the events of the given kinds
+// to the returned channel, dropping them if it is full.
+func eventsOf(kinds ...EventKind) (<-chan Event, func()) {
+	c := make(chan Event, 100)
+	unregister := RegisterEventHandler(func(e Event) {
+		for _, k := range kinds {
+			if e.Kind == k {
+				select {
+				case c <- e:
+				default:
+				}
+			}
+		}
+	})
+	return c, unregister
+}
+
+// waitEvent returns the first event from c for which ok returns true.
+func waitEvent(t *testing.T, c <-chan Event, what string, ok func(Event) bool) Event {
+	t.Helper()
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case e := <-c:
+			if ok(e) {
+				return e
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+}
+
+func TestGCEvents(t *testing.T) {
+	c, unregister := eventsOf(EventGCStart, EventGCEnd, EventHeapGoal)
+	defer unregister()
+
+	before := time.Now()
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	start := waitEvent(t, c, "GC start", func(e Event) bool {
+		return e.Kind == EventGCStart && e.GC == stats.NumGC
+	})
+	end := waitEvent(t, c, "GC end", func(e Event) bool {
+		return e.Kind == EventGCEnd && e.GC == stats.NumGC
+	})
+	if start.Time.Before(before.Add(-time.Second)) || end.Time.Before(start.Time) || end.Time.After(time.Now().Add(time.Second)) {
+		t.Errorf("GC %d started at %v and ended at %v, want between %v and now", stats.NumGC, start.Time, end.Time, before)
+	}
+	if start.HeapLive == 0 || start.HeapGoal == 0 {
+		t.Errorf("GC start has heap %d and goal %d, want nonzero", start.HeapLive, start.HeapGoal)
+	}
+	if end.Pause <= 0 || end.HeapLive == 0 || end.HeapGoal < end.HeapLive {
+		t.Errorf("GC end has pause %v, heap %d and goal %d", end.Pause, end.HeapLive, end.HeapGoal)
+	}
+
+	old := SetGCPercent(-1)
+	defer SetGCPercent(old)
+	waitEvent(t, c, "heap goal change", func(e Event) bool {
+		return e.Kind == EventHeapGoal && e.HeapGoal == ^uint64(0)
+	})
+}
+
+func TestGoroutineSpikeEvent(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping in short mode")
+	}
+	c, unregister := eventsOf(EventGoroutineSpike)
+	defer unregister()
+
+	// Let the runtime note the number of goroutines before the spike.
+	time.Sleep(2 * time.Second)
+	n := runtime.NumGoroutine()
+	block := make(chan bool)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer close(block)
+	for i := 0; i < 2*n+2000; i++ {
+		wg.Add(1)
+		go func() {
+			<-block
+			wg.Done()
+		}()
+	}
+	// The runtime may see the spike before all the goroutines start.
+	e := waitEvent(t, c, "goroutine spike", func(Event) bool { return true })
+	if e.Count < 2*e.Prev || e.Count-e.Prev < 1024 {
+		t.Errorf("spike from %d to %d goroutines, want at least double and 1024 more", e.Prev, e.Count)
+	}
+}
+
+func TestUnregisterEventHandler(t *testing.T) {
+	var mu sync.Mutex
+	var gcs []uint32
+	unregister := RegisterEventHandler(func(e Event) {
+		if e.Kind == EventGCEnd {
+			mu.Lock()
+			gcs = append(gcs, e.GC)
+			mu.Unlock()
+		}
+	})
+	c, unregister2 := eventsOf(EventGCEnd)
+	defer unregister2()
+	unregister()
+	unregister() // does nothing
+
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	waitEvent(t, c, "GC end", func(e Event) bool { return e.GC == stats.NumGC })
+	mu.Lock()
+	defer mu.Unlock()
+	for _, gc := range gcs {
+		if gc == stats.NumGC {
+			t.Errorf("unregistered handler was called for GC %d", gc)
+		}
+	}
+}
+
+func TestEventHandlerGoroutineExits(t *testing.T) {
+	n := runtime.NumGoroutine()
+	unregister := RegisterEventHandler(func(Event) {})
+	runtime.GC()
+	unregister()
+	// The goroutine that delivered events exits once it sees
+	// that there are no handlers left.
+	for deadline := time.Now().Add(10 * time.Second); runtime.NumGoroutine() > n; {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines after unregistering the only event handler, want %d", runtime.NumGoroutine(), n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestEventKindString(t *testing.T) {
+	for k, want := range map[EventKind]string{
+		EventGCStart:  "GCStart",
+		EventLost:     "Lost",
+		EventKind(0):  "EventKind(0)",
+		EventKind(99): "EventKind(99)",
+	} {
+		if got := k.String(); got != want {
+			t.Errorf("%d.String() = %q, want %q", int(k), got, want)
+		}
+	}
+}
@@ -92,6 +92,33 @@ func SetGCPercent(percent int) int {
 	return int(setGCPercent(int32(percent)))
 }
 
+// SetMemoryLimit sets a soft limit on the total amount of memory
+// used by the Go runtime: the heap and the runtime's other memory,
+// such as goroutine stacks, less memory returned to the operating
+// system. SetMemoryLimit returns the previously set limit.
+// A negative limit does not change the limit, so SetMemoryLimit(-1)
+// returns the current limit. A limit of math.MaxInt64 disables it.
+//
+// The initial setting is math.MaxInt64 unless the GOMEMLIMIT
+// environment variable is set, in which case it is the value of that
+// variable: a non-negative number of bytes, with an optional unit
+// suffix of B, KiB, MiB, GiB or TiB. GOMEMLIMIT=off disables the limit.
+//
+// As memory use approaches the limit, the garbage collector runs
+// more often and memory is returned to the operating system sooner.
+// This happens even if garbage collection is otherwise disabled by
+// SetGCPercent(-1), so the limit may be used to run the collector
+// only when memory is short.
+//
+// The limit is soft: it is not an error to exceed it, and memory use
+// will exceed it if the live heap is too large. If meeting the limit
+// requires the garbage collector to run for more than half of the
+// program's wall-clock time, the limit is relaxed so that the program
+// continues to make progress.
+func SetMemoryLimit(limit int64) int64 {
+	return setMemoryLimit(limit)
+}
+
 // FreeOSMemory forces a garbage collection followed by an
 // attempt to return as much memory to the operating system
 // as possible. (Even if this is not called, the runtime gradually
@@ -172,6 +172,68 @@ func TestSetGCPercent(t *testing.T) {
 	}
 }
 
+var setMemoryLimitSink []byte
+
+func TestSetMemoryLimit(t *testing.T) {
+	// Test that the limit is being set and returned correctly.
+	old := SetMemoryLimit(123 << 20)
+	defer SetMemoryLimit(old)
+	if got := SetMemoryLimit(-1); got != 123<<20 {
+		t.Errorf("SetMemoryLimit(-1) = %d, want %d", got, 123<<20)
+	}
+
+	// With GC otherwise off, the limit alone must trigger collections
+	// and keep the heap well below what was allocated.
+	defer SetGCPercent(SetGCPercent(-1))
+	SetMemoryLimit(64 << 20)
+	runtime.GC()
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	ngc := ms.NumGC
+	for i := 0; i < 512; i++ {
+		setMemoryLimitSink = make([]byte, 1<<20)
+		// Assists do not hold back allocations of pointer-free
+		// memory, so let the mark workers keep up. Otherwise,
+		// with few Ps, the heap marked live can far exceed
+		// the limit, and the pacer rightly gives up on it.
+		runtime.Gosched()
+	}
+	setMemoryLimitSink = nil
+	runtime.ReadMemStats(&ms)
+	if ms.NumGC == ngc {
+		t.Errorf("no GC ran after allocating 512 MB with a 64 MB limit")
+	}
+	// The memory retained right now depends on how far the background
+	// scavenger has got, so check the pacer's goal instead.
+	if ms.NextGC > 64<<20 {
+		t.Errorf("NextGC = %d MB with a 64 MB limit, want at most 64 MB", ms.NextGC>>20)
+	}
+}
+
+func TestMemoryLimitDeathSpiral(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping in short mode")
+	}
+	// A limit below the live heap cannot be met. GC must not
+	// run on every allocation trying to meet it.
+	defer SetMemoryLimit(SetMemoryLimit(1 << 20))
+	live := make([]byte, 32<<20)
+	runtime.GC()
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	ngc := ms.NumGC
+	const n = 1000
+	for i := 0; i < n; i++ {
+		setMemoryLimitSink = make([]byte, 64<<10)
+	}
+	setMemoryLimitSink = nil
+	runtime.KeepAlive(live)
+	runtime.ReadMemStats(&ms)
+	if gcs := ms.NumGC - ngc; gcs > n/2 {
+		t.Errorf("%d GCs for %d allocations with limit below live heap, want at most %d", gcs, n, n/2)
+	}
+}
+
 func abs64(a int64) int64 {
 	if a < 0 {
 		return -a
@@ -0,0 +1,128 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+import (
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// GoroutineState is the scheduling state of a goroutine.
+type GoroutineState int
+
+const (
+	// These values must be kept identical to their corresponding
+	// goroutine* values in the runtime.
+	GoroutineRunning  GoroutineState = iota // running on a thread
+	GoroutineRunnable                       // ready to run, waiting for a thread
+	GoroutineWaiting                        // blocked, as described by WaitReason
+	GoroutineSyscall                        // in a system call
+)
+
+var goroutineStates = [...]string{
+	GoroutineRunning:  "running",
+	GoroutineRunnable: "runnable",
+	GoroutineWaiting:  "waiting",
+	GoroutineSyscall:  "syscall",
+}
+
+func (s GoroutineState) String() string {
+	if s < 0 || int(s) >= len(goroutineStates) {
+		return "GoroutineState(" + strconv.Itoa(int(s)) + ")"
+	}
+	return goroutineStates[s]
+}
+
+// A Goroutine describes a goroutine at the time of a call to Goroutines.
+type Goroutine struct {
+	ID    int64
+	State GoroutineState
+
+	// WaitReason tells why a waiting goroutine is blocked, in the
+	// words of the goroutine headers printed by runtime.Stack, such
+	// as "chan receive" or "select". It is empty for goroutines in
+	// other states.
+	WaitReason string
+
+	// WaitTime is roughly how long a waiting goroutine, or one in a
+	// system call, has been blocked. The runtime only notes that a
+	// goroutine is blocked when a garbage collection sees it so, and
+	// then records the time of the previous collection, so WaitTime
+	// is zero until then and is only accurate to a GC cycle.
+	WaitTime time.Duration
+
+	// CreatedBy is the program counter of the go statement that
+	// started the goroutine, as a return address suitable for
+	// runtime.CallersFrames. It is 0 for the main goroutine.
+	CreatedBy uintptr
+
+	// LockedToThread reports whether the goroutine is locked to its
+	// thread by runtime.LockOSThread.
+	LockedToThread bool
+
+	// Labels holds the profiler labels of the goroutine, as set by
+	// runtime/pprof.SetGoroutineLabels and runtime/pprof.Do, or nil
+	// if it has none.
+	Labels map[string]string
+
+	// Stack holds the return program counters of the innermost 100
+	// frames of the goroutine's stack, in the form returned by
+	// runtime.Callers.
+	Stack []uintptr
+}
+
+// maxGoroutineFrames is the number of frames Goroutines records for
+// each goroutine, the most that runtime.Stack prints.
+const maxGoroutineFrames = 100
+
+// Goroutines returns a description of each goroutine, the calling one
+// first. Like runtime.Stack and the goroutine profile, it leaves out
+// the goroutines the runtime runs for itself. Goroutines stops the
+// world while it inspects the goroutines, for a time proportional to
+// their number and the depth of their stacks.
+func Goroutines() []Goroutine {
+	var p []Goroutine
+	n := runtime.NumGoroutine()
+	for {
+		// Allow for goroutines started in the meantime.
+		n += n/10 + 10
+		p = make([]Goroutine, n)
+		buf := make([]uintptr, n*maxGoroutineFrames)
+		for i := range p {
+			p[i].Stack = buf[:0:maxGoroutineFrames]
+			buf = buf[maxGoroutineFrames:]
+		}
+		var ok bool
+		if n, ok = readGoroutines(p); ok {
+			p = p[:n]
+			break
+		}
+	}
+
+	// Gather the stacks into a buffer of their own size, and copy
+	// the labels, which runtime/pprof shares between goroutines.
+	size := 0
+	for i := range p {
+		size += len(p[i].Stack)
+	}
+	buf := make([]uintptr, size)
+	for i := range p {
+		g := &p[i]
+		n := copy(buf, g.Stack)
+		g.Stack = buf[:n:n]
+		buf = buf[n:]
+		if len(g.Labels) == 0 {
+			g.Labels = nil
+			continue
+		}
+		labels := make(map[string]string, len(g.Labels))
+		for k, v := range g.Labels {
+			labels[k] = v
+		}
+		g.Labels = labels
+	}
+	return p
+}
@@ -0,0 +1,143 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug_test
+
+import (
+	"context"
+	"runtime"
+	. "runtime/debug"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// funcs returns the names of the functions in stk.
+func funcs(stk []uintptr) []string {
+	var names []string
+	frames := runtime.CallersFrames(stk)
+	for {
+		f, more := frames.Next()
+		names = append(names, f.Function)
+		if !more {
+			return names
+		}
+	}
+}
+
+func blockOnChan(c chan int) {
+	<-c
+}
+
+// goroutinesRun tells apart the goroutines of runs of TestGoroutines.
+var goroutinesRun int
+
+func TestGoroutines(t *testing.T) {
+	goroutinesRun++
+	run := strconv.Itoa(goroutinesRun)
+	c := make(chan int)
+	started := make(chan bool)
+	go pprof.Do(context.Background(), pprof.Labels("run", run), func(context.Context) {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		started <- true
+		blockOnChan(c)
+	})
+	<-started
+	defer close(c)
+	// Wait for the goroutine to block, and let the GC note the time:
+	// it records the time of the previous cycle, if there is one.
+	for {
+		runtime.Gosched()
+		var blocked bool
+		for _, g := range Goroutines() {
+			if g.Labels["run"] == run && g.State == GoroutineWaiting {
+				blocked = true
+			}
+		}
+		if blocked {
+			break
+		}
+	}
+	runtime.GC()
+	runtime.GC()
+
+	gs := Goroutines()
+	if len(gs) < 2 {
+		t.Fatalf("Goroutines returned %d goroutines, want at least 2", len(gs))
+	}
+	ids := make(map[int64]bool)
+	for _, g := range gs {
+		if ids[g.ID] {
+			t.Errorf("goroutine %d appears twice", g.ID)
+		}
+		ids[g.ID] = true
+	}
+
+	self := gs[0]
+	if self.State != GoroutineRunning || self.WaitReason != "" {
+		t.Errorf("calling goroutine is %v %q, want running", self.State, self.WaitReason)
+	}
+	if names := funcs(self.Stack); names[0] != "runtime/debug.Goroutines" || !contains(names, "runtime/debug_test.TestGoroutines") {
+		t.Errorf("calling goroutine has stack %v", names)
+	}
+
+	var blocked *Goroutine
+	for i := range gs {
+		if gs[i].Labels["run"] == run {
+			blocked = &gs[i]
+		}
+	}
+	if blocked == nil {
+		t.Fatalf("no goroutine with label run=%s", run)
+	}
+	if blocked.State != GoroutineWaiting || blocked.WaitReason != "chan receive" {
+		t.Errorf("blocked goroutine is %v %q, want waiting \"chan receive\"", blocked.State, blocked.WaitReason)
+	}
+	if blocked.WaitTime <= 0 {
+		t.Errorf("blocked goroutine has wait time %v after two GCs, want > 0", blocked.WaitTime)
+	}
+	if !blocked.LockedToThread {
+		t.Errorf("blocked goroutine is not locked to its thread")
+	}
+	if len(blocked.Labels) != 1 {
+		t.Errorf("blocked goroutine has labels %v, want only run=%s", blocked.Labels, run)
+	}
+	if names := funcs(blocked.Stack); !contains(names, "runtime/debug_test.blockOnChan") {
+		t.Errorf("blocked goroutine has stack %v, want blockOnChan in it", names)
+	}
+	if names := funcs([]uintptr{blocked.CreatedBy}); !strings.HasPrefix(names[0], "runtime/debug_test.TestGoroutines") {
+		t.Errorf("blocked goroutine created by %v, want TestGoroutines", names)
+	}
+
+	// The labels are a copy.
+	blocked.Labels["run"] = "changed"
+	for _, g := range Goroutines() {
+		if g.ID == blocked.ID && g.Labels["run"] != run {
+			t.Errorf("changing the returned labels changed the goroutine's labels to %v", g.Labels)
+		}
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+func TestGoroutineStateString(t *testing.T) {
+	for s, want := range map[GoroutineState]string{
+		GoroutineRunning:  "running",
+		GoroutineWaiting:  "waiting",
+		GoroutineState(7): "GoroutineState(7)",
+	} {
+		if got := s.String(); got != want {
+			t.Errorf("%d.String() = %q, want %q", int(s), got, want)
+		}
+	}
+}
@@ -5,6 +5,7 @@
 package debug
 
 import (
+	"strconv"
 	"strings"
 )
 
@@ -24,6 +25,9 @@ type BuildInfo struct {
 	Path string    // The main package path
 	Main Module    // The module containing the main package
 	Deps []*Module // Module dependencies
+
+	// Settings describes the build that produced the binary.
+	Settings []BuildSetting
 }
 
 // Module represents a module.
@@ -34,6 +38,44 @@ type Module struct {
 	Replace *Module // replaced by this module
 }
 
+// A BuildSetting is a key-value pair describing one setting that
+// influenced the build.
+//
+// Defined keys include:
+//
+//   - -buildmode: the buildmode flag used, if not "default"
+//   - -compiler: the compiler toolchain flag used (typically "gc")
+//   - -asmflags, -gcflags, -gccgoflags, -ldflags: a value of the
+//     corresponding flag, as given on the command line; the key is
+//     repeated if the flag was given more than once
+//   - -race, -msan, -trimpath: "true" if the flag was set
+//   - -tags: the comma-separated build tags, if any
+//   - -pgo: the profile used for profile-guided optimization, if any;
+//     just its file name with -trimpath
+//   - CGO_ENABLED: the effective CGO_ENABLED setting ("0" or "1")
+//   - CGO_CFLAGS, CGO_CPPFLAGS, CGO_CXXFLAGS, CGO_LDFLAGS: the cgo
+//     environment variables, if cgo is enabled and they are set
+//   - GOARCH, GOOS: the target architecture and operating system
+//   - GOARM, GO386, GOMIPS, GOMIPS64, GOPPC64, GOWASM: the
+//     architecture-specific setting for GOARCH, if any
+//   - vcs: the version control system of the source tree ("git")
+//   - vcs.revision: the revision identifier of the current commit
+//   - vcs.time: the modification time of the current commit, in RFC3339 format
+//   - vcs.modified: "true" or "false", whether the source tree had local modifications
+//
+// The vcs keys are present only in binaries built by 'go build' or
+// 'go install' from a main package inside a version control checkout,
+// and only if the version control tool is installed.
+//
+// Like the rest of BuildInfo, build settings are recorded only in
+// binaries built in module mode. Binaries built in GOPATH mode have
+// no build settings.
+type BuildSetting struct {
+	// Key and Value describe the build setting.
+	// Key must not contain an equals sign, space, tab, or newline.
+	Key, Value string
+}
+
 func readBuildInfo(data string) (*BuildInfo, bool) {
 	if len(data) < 32 {
 		return nil, false
@@ -41,10 +83,11 @@ func readBuildInfo(data string) (*BuildInfo, bool) {
 	data = data[16 : len(data)-16]
 
 	const (
-		pathLine = "path\t"
-		modLine  = "mod\t"
-		depLine  = "dep\t"
-		repLine  = "=>\t"
+		pathLine  = "path\t"
+		modLine   = "mod\t"
+		depLine   = "dep\t"
+		repLine   = "=>\t"
+		buildLine = "build\t"
 	)
 
 	info := &BuildInfo{}
@@ -99,6 +142,21 @@ func readBuildInfo(data string) (*BuildInfo, bool) {
 				Version: elem[1],
 				Sum:     elem[2],
 			}
+		case strings.HasPrefix(line, buildLine):
+			kv := line[len(buildLine):]
+			i := strings.IndexByte(kv, '=')
+			if i < 1 {
+				return nil, false
+			}
+			key, value := kv[:i], kv[i+1:]
+			if strings.HasPrefix(value, `"`) {
+				var err error
+				value, err = strconv.Unquote(value)
+				if err != nil {
+					return nil, false
+				}
+			}
+			info.Settings = append(info.Settings, BuildSetting{Key: key, Value: value})
 		}
 	}
 	return info, true
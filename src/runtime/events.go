@@ -0,0 +1,183 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+// Runtime event notifications for runtime/debug.RegisterEventHandler.
+//
+// The runtime records events in a fixed-size buffer, from which a
+// goroutine of runtime/debug reads them and calls the handlers. An
+// event is dropped, and counted as lost, if the buffer is full, so the
+// handlers never hold up the runtime.
+//
+// The reader parks when there is nothing to read. Events are recorded
+// in places that cannot make it runnable, such as with the world
+// stopped, so recording one only flags the reader for waking, and the
+// scheduler or sysmon wakes it, as they do the trace reader and the
+// forced GC helper.
+
+import (
+	"runtime/internal/atomic"
+	_ "unsafe" // for go:linkname
+)
+
+// eventRecord describes a runtime event.
+// It must be kept identical to runtime/debug.eventRecord.
+type eventRecord struct {
+	kind     int
+	time     int64 // nanotime
+	gc       uint32
+	pause    int64
+	heapLive uint64
+	heapGoal uint64
+	count    int64
+	prev     int64
+}
+
+// These values must be kept identical to the corresponding Event*
+// values in runtime/debug.
+const (
+	eventGCStart = 1 + iota
+	eventGCEnd
+	eventHeapGoal
+	eventThreadSpike
+	eventGoroutineSpike
+)
+
+const (
+	// eventBufLen is the number of events the runtime holds until
+	// runtime/debug reads them.
+	eventBufLen = 256
+
+	// eventSpikePeriod is how often, in nanoseconds, sysmon looks
+	// for spikes in the number of threads and goroutines.
+	eventSpikePeriod = 1e9
+
+	// A spike is a doubling of the number of threads or goroutines
+	// in an eventSpikePeriod, by at least this many.
+	eventThreadSpikeMin    = 16
+	eventGoroutineSpikeMin = 1024
+)
+
+var runtimeEvents struct {
+	enabled uint32 // handlers are registered; accessed atomically
+
+	lock   mutex
+	buf    [eventBufLen]eventRecord
+	head   uint32   // next event to read
+	tail   uint32   // next event to write
+	lost   uint64   // events dropped since the last read
+	reader guintptr // the goroutine parked in readEvents, or nil
+	wake   uint32   // the reader has something to read; accessed atomically
+
+	// The number of threads and goroutines at the last spike check,
+	// or 0 if events were not enabled then. Only sysmon uses these.
+	threads    int32
+	goroutines int32
+}
+
+// recordEvent hands r to runtime/debug, if it has handlers for events.
+// It may be called without a P, but not from a signal handler.
+//
+//go:nowritebarrierrec
+func recordEvent(r *eventRecord) {
+	if atomic.Load(&runtimeEvents.enabled) == 0 {
+		return
+	}
+	lock(&runtimeEvents.lock)
+	if runtimeEvents.tail-runtimeEvents.head == eventBufLen {
+		runtimeEvents.lost++
+	} else {
+		runtimeEvents.buf[runtimeEvents.tail%eventBufLen] = *r
+		runtimeEvents.tail++
+	}
+	if runtimeEvents.reader != 0 {
+		atomic.Store(&runtimeEvents.wake, 1)
+	}
+	unlock(&runtimeEvents.lock)
+}
+
+// eventsReader returns the goroutine parked in readEvents, if it has
+// something to read, for the caller to make runnable.
+//
+//go:nowritebarrierrec
+func eventsReader() *g {
+	if atomic.Load(&runtimeEvents.wake) == 0 {
+		return nil
+	}
+	lock(&runtimeEvents.lock)
+	gp := runtimeEvents.reader.ptr()
+	runtimeEvents.reader = 0
+	atomic.Store(&runtimeEvents.wake, 0)
+	unlock(&runtimeEvents.lock)
+	return gp
+}
+
+// checkSpikes records an event for each of the number of threads and
+// goroutines that has spiked since the last check. It is called by
+// sysmon every eventSpikePeriod.
+//
+//go:nowritebarrierrec
+func checkSpikes(now int64) {
+	if atomic.Load(&runtimeEvents.enabled) == 0 {
+		runtimeEvents.threads, runtimeEvents.goroutines = 0, 0
+		return
+	}
+	lock(&sched.lock)
+	threads, goroutines := mcount(), gcount()
+	unlock(&sched.lock)
+	prevThreads, prevGoroutines := runtimeEvents.threads, runtimeEvents.goroutines
+	runtimeEvents.threads, runtimeEvents.goroutines = threads, goroutines
+	if prevThreads == 0 {
+		// Events were just enabled.
+		return
+	}
+	if threads >= 2*prevThreads && threads-prevThreads >= eventThreadSpikeMin {
+		recordEvent(&eventRecord{kind: eventThreadSpike, time: now, count: int64(threads), prev: int64(prevThreads)})
+	}
+	if goroutines >= 2*prevGoroutines && goroutines-prevGoroutines >= eventGoroutineSpikeMin {
+		recordEvent(&eventRecord{kind: eventGoroutineSpike, time: now, count: int64(goroutines), prev: int64(prevGoroutines)})
+	}
+}
+
+//go:linkname setEventsEnabled runtime/debug.setEventsEnabled
+func setEventsEnabled(enabled bool) {
+	if enabled {
+		atomic.Store(&runtimeEvents.enabled, 1)
+		return
+	}
+	atomic.Store(&runtimeEvents.enabled, 0)
+	// Wake the reader, so that it sees events are off and exits.
+	lock(&runtimeEvents.lock)
+	if runtimeEvents.reader != 0 {
+		atomic.Store(&runtimeEvents.wake, 1)
+	}
+	unlock(&runtimeEvents.lock)
+}
+
+// readEvents copies recorded events into p, blocking until there is at
+// least one or events are turned off. It returns the number of events
+// copied, the number of events lost since the previous call, and the
+// current nanotime, which lets the caller turn the times of the events
+// into wall times. It returns no events and none lost only if events
+// are off. There must be only one caller at a time.
+//
+//go:linkname readEvents runtime/debug.readEvents
+func readEvents(p []eventRecord) (n int, lost uint64, now int64) {
+	for {
+		lock(&runtimeEvents.lock)
+		for runtimeEvents.head != runtimeEvents.tail && n < len(p) {
+			p[n] = runtimeEvents.buf[runtimeEvents.head%eventBufLen]
+			runtimeEvents.head++
+			n++
+		}
+		lost, runtimeEvents.lost = runtimeEvents.lost, 0
+		if n > 0 || lost > 0 || atomic.Load(&runtimeEvents.enabled) == 0 {
+			unlock(&runtimeEvents.lock)
+			return n, lost, nanotime()
+		}
+		runtimeEvents.reader.set(getg())
+		goparkunlock(&runtimeEvents.lock, waitReasonEventsIdle, traceEvGoBlock, 1)
+	}
+}
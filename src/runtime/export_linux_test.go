@@ -6,14 +6,80 @@
 
 package runtime
 
-import "unsafe"
+import (
+	"runtime/internal/atomic"
+	"unsafe"
+)
 
 var NewOSProc0 = newosproc0
 var Mincore = mincore
 var Add = add
+var ParseCgroupCPU = parseCgroupCPU
+var CPULimitProcs = cpuLimitProcs
 
 type EpollEvent epollevent
 
 func Epollctl(epfd, op, fd int32, ev unsafe.Pointer) int32 {
 	return epollctl(epfd, op, fd, (*epollevent)(ev))
 }
+
+// savedMaxprocs holds the state SetMaxprocsTestEnv replaces.
+var savedMaxprocs struct {
+	cgroup, mountinfo              []byte
+	files                          []cgroupCPUFile
+	ncpu                           int32
+	cgroupmaxprocs, updatemaxprocs int32
+	cpulimit                       int32
+	custom                         uint32
+}
+
+// SetMaxprocsTestEnv makes the GOMAXPROCS logic of schedinit, sysmon and
+// maxprocshelper read the files cgroup and mountinfo in place of
+// /proc/self/cgroup and /proc/self/mountinfo, and run as on a machine
+// with cpus CPUs and with the GODEBUG settings cgroupmaxprocs and
+// updatemaxprocs, until RestoreMaxprocsTestEnv is called.
+func SetMaxprocsTestEnv(cgroup, mountinfo string, cpus int, cgroupmaxprocs, updatemaxprocs int32) {
+	s := &savedMaxprocs
+	s.cgroup, s.mountinfo, s.files = procSelfCgroup, procSelfMountinfo, cgroupCPUFiles
+	s.ncpu = ncpu
+	s.cgroupmaxprocs, s.updatemaxprocs = debug.cgroupmaxprocs, debug.updatemaxprocs
+	s.cpulimit, s.custom = maxprocs.cpulimit, atomic.Load(&maxprocs.custom)
+
+	procSelfCgroup = []byte(cgroup + "\x00")
+	procSelfMountinfo = []byte(mountinfo + "\x00")
+	cgroupCPUFiles = nil
+	ncpu = int32(cpus)
+	debug.cgroupmaxprocs, debug.updatemaxprocs = cgroupmaxprocs, updatemaxprocs
+	maxprocs.cpulimit = 0
+	atomic.Store(&maxprocs.custom, 0)
+}
+
+// RestoreMaxprocsTestEnv undoes SetMaxprocsTestEnv.
+func RestoreMaxprocsTestEnv() {
+	s := &savedMaxprocs
+	procSelfCgroup, procSelfMountinfo, cgroupCPUFiles = s.cgroup, s.mountinfo, s.files
+	ncpu = s.ncpu
+	debug.cgroupmaxprocs, debug.updatemaxprocs = s.cgroupmaxprocs, s.updatemaxprocs
+	maxprocs.cpulimit = s.cpulimit
+	atomic.Store(&maxprocs.custom, s.custom)
+}
+
+// MaxprocsInit returns the GOMAXPROCS setting schedinit starts with,
+// given the GOMAXPROCS environment variable env.
+func MaxprocsInit(env string) int {
+	return int(maxprocsinit(env))
+}
+
+// MaxprocsUpdate reads the cgroup CPU limit again, as sysmon does, and
+// returns the setting maxprocshelper replaces procs with, or procs if
+// maxprocshelper does not run or sysmon would not wake it.
+func MaxprocsUpdate(procs int) int {
+	if !maxprocsfollowslimit() {
+		return procs
+	}
+	if n := cgroupCPULimit(); n != maxprocs.cpulimit {
+		maxprocs.cpulimit = n
+		return int(maxprocsupdate(int32(procs)))
+	}
+	return procs
+}
@@ -33,6 +33,7 @@ var Fastlog2 = fastlog2
 
 var Atoi = atoi
 var Atoi32 = atoi32
+var ParseByteCount = parseByteCount
 
 var Nanotime = nanotime
 var NetpollBreak = netpollBreak
@@ -454,6 +455,35 @@ func TracebackSystemstack(stk []uintptr, i int) int {
 	return n
 }
 
+var TraceFPUnwind = tracefpunwind
+
+// TraceStacks returns the logical PCs of the calling goroutine's stack,
+// as collected by the execution tracer with frame pointers, and by
+// gentraceback. If system is set, it collects them from the system
+// stack, as the tracer does for events on behalf of a goroutine.
+//
+//go:noinline
+func TraceStacks(system bool) (fp, gen []uintptr) {
+	gp := getg()
+	buf := make([]uintptr, traceStackSize)
+	gen = make([]uintptr, traceStackSize)
+	n := 0
+	if !system {
+		n = fpTracebackPCs(gp, getfp(), buf)
+		gen = gen[:callers(1, gen)]
+	} else {
+		systemstack(func() {
+			n = fpTracebackSaved(gp, buf)
+			gen = gen[:gcallers(gp, 0, gen)]
+		})
+	}
+	if n >= 0 {
+		fp = traceExpandPCs(buf[:n], 0)
+	}
+	// traceExpandPCs drops runtime.goexit.
+	return fp, gen[:len(gen)-1]
+}
+
 func KeepNArenaHints(n int) {
 	hint := mheap_.arenaHints
 	for i := 1; i < n; i++ {
@@ -978,3 +1008,52 @@ func MapHashCheck(m interface{}, k interface{}) (uintptr, uintptr) {
 	y := typehash(kt, noescape(p), uintptr(mh.hash0))
 	return x, y
 }
+
+const (
+	TimeHistSubBucketBits   = timeHistSubBucketBits
+	TimeHistNumSubBuckets   = timeHistNumSubBuckets
+	TimeHistNumSuperBuckets = timeHistNumSuperBuckets
+)
+
+type TimeHistogram timeHistogram
+
+// Counts returns the counts for the given bucket, subBucket indices.
+// Returns true if the bucket was valid, otherwise returns the counts
+// for the underflow bucket and false.
+func (th *TimeHistogram) Count(bucket, subBucket uint) (uint64, bool) {
+	t := (*timeHistogram)(th)
+	i := bucket*TimeHistNumSubBuckets + subBucket
+	if i >= uint(len(t.counts)) {
+		return t.underflow, false
+	}
+	return t.counts[i], true
+}
+
+func (th *TimeHistogram) Record(duration int64) {
+	(*timeHistogram)(th).record(duration)
+}
+
+var TimeHistogramMetricsBuckets = timeHistogramMetricsBuckets
+
+func ReadMetricsSlow(memStats *MemStats, samplesp unsafe.Pointer, len, cap int) {
+	stopTheWorld("ReadMetricsSlow")
+
+	// Initialize the metrics beforehand because this could
+	// allocate and skew the stats.
+	semacquire(&metricsSema)
+	initMetrics()
+	semrelease(&metricsSema)
+
+	systemstack(func() {
+		// Read memstats first. It's going to flush
+		// the mcaches which readMetrics does not do, so
+		// going the other way around may result in
+		// inconsistent statistics.
+		readmemstats_m(memStats)
+	})
+
+	// Read metrics off the system stack.
+	readMetrics(samplesp, len, cap)
+
+	startTheWorld()
+}
@@ -21,6 +21,13 @@ is GOGC=100. Setting GOGC=off disables the garbage collector entirely.
 The runtime/debug package's SetGCPercent function allows changing this
 percentage at run time. See https://golang.org/pkg/runtime/debug/#SetGCPercent.
 
+The GOMEMLIMIT variable sets a soft limit on the total memory used by the
+runtime, in bytes, with an optional unit suffix such as MiB or GiB. As memory
+use approaches the limit, the garbage collector runs more often, even with
+GOGC=off, and memory is returned to the operating system sooner.
+The runtime/debug package's SetMemoryLimit function allows changing the limit
+at run time. See https://golang.org/pkg/runtime/debug/#SetMemoryLimit.
+
 The GODEBUG variable controls debugging variables within the runtime.
 It is a comma-separated list of name=val pairs setting these named variables:
 
@@ -38,6 +45,10 @@ It is a comma-separated list of name=val pairs setting these named variables:
 	expensive checks that should not miss any errors, but will
 	cause your program to run slower.
 
+	cgroupmaxprocs: setting cgroupmaxprocs=0 makes the default GOMAXPROCS
+	the number of logical CPUs even on Linux when the cgroup of the process
+	limits its CPU time to fewer CPUs. See GOMAXPROCS below.
+
 	efence: setting efence=1 causes the allocator to run in a mode
 	where each object is allocated on a unique page and addresses are
 	never recycled.
@@ -117,7 +128,8 @@ It is a comma-separated list of name=val pairs setting these named variables:
 	processors, threads and goroutines.
 
 	schedtrace: setting schedtrace=X causes the scheduler to emit a single line to standard
-	error every X milliseconds, summarizing the scheduler state.
+	error every X milliseconds, summarizing the scheduler state. The line includes the
+	cgroup CPU limit, as cpulimit=N, if there is one.
 
 	tracebackancestors: setting tracebackancestors=N extends tracebacks with the stacks at
 	which goroutines were created, where N limits the number of ancestor goroutines to
@@ -125,6 +137,15 @@ It is a comma-separated list of name=val pairs setting these named variables:
 	IDs will refer to the ID of the goroutine at the time of creation; it's possible for this
 	ID to be reused for another goroutine. Setting N to 0 will report no ancestry information.
 
+	tracefpunwindoff: setting tracefpunwindoff=1 makes the execution tracer collect
+	stacks with the same unwinder as tracebacks, rather than by following frame pointers
+	on platforms that maintain them. That is slower, but also finds the callers of
+	frames that don't maintain frame pointers, such as those of cgo callbacks.
+
+	updatemaxprocs: setting updatemaxprocs=1 makes the runtime read the cgroup
+	CPU limit again every second and update the default GOMAXPROCS when it changes.
+	With schedtrace, each update is reported on standard error.
+
 	asyncpreemptoff: asyncpreemptoff=1 disables signal-based
 	asynchronous goroutine preemption. This makes some loops
 	non-preemptible for long periods, which may delay GC and
@@ -139,7 +160,11 @@ The GOMAXPROCS variable limits the number of operating system threads that
 can execute user-level Go code simultaneously. There is no limit to the number of threads
 that can be blocked in system calls on behalf of Go code; those do not count against
 the GOMAXPROCS limit. This package's GOMAXPROCS function queries and changes
-the limit.
+the limit. By default, the limit is the number of logical CPUs. On Linux, if the
+cgroup of the process limits its CPU time, through cpu.max in cgroup v2 or
+cpu.cfs_quota_us in cgroup v1, the default is instead that limit rounded up to
+a whole number of CPUs, if it is lower. Setting the GOMAXPROCS variable or calling
+the GOMAXPROCS function overrides the default.
 
 The GORACE variable configures the race detector, for programs built using -race.
 See https://golang.org/doc/articles/race_detector.html for details.
@@ -675,3 +675,33 @@ func countpwg(n *int, ready *sync.WaitGroup, teardown chan bool) {
 	*n--
 	countpwg(n, ready, teardown)
 }
+
+func TestParseByteCount(t *testing.T) {
+	for _, test := range []struct {
+		in  string
+		out int64
+		ok  bool
+	}{
+		{"", 0, false},
+		{"0", 0, true},
+		{"1024", 1024, true},
+		{"512B", 512, true},
+		{"4KiB", 4 << 10, true},
+		{"100MiB", 100 << 20, true},
+		{"2GiB", 2 << 30, true},
+		{"3TiB", 3 << 40, true},
+		{"9223372036854775807", 1<<63 - 1, true},
+		{"9223372036854775808", 0, false},
+		{"8388608TiB", 0, false},
+		{"KiB", 0, false},
+		{"-1", 0, false},
+		{"1.5GiB", 0, false},
+		{"1MB", 0, false},
+		{"1 MiB", 0, false},
+	} {
+		out, ok := runtime.ParseByteCount(test.in)
+		if out != test.out || ok != test.ok {
+			t.Errorf("parseByteCount(%q) = (%d, %v), want (%d, %v)", test.in, out, ok, test.out, test.ok)
+		}
+	}
+}
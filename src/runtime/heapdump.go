@@ -183,7 +183,7 @@ func dumptype(t *_type) {
 	dumpint(tagType)
 	dumpint(uint64(uintptr(unsafe.Pointer(t))))
 	dumpint(uint64(t.size))
-	if x := t.uncommon(); x == nil || t.nameOff(x.pkgpath).name() == "" {
+	if x := t.uncommon(); x == nil || t.nameOff(x.pkgpath).name() == "" || t.name() == "" {
 		dumpstr(t.string())
 	} else {
 		pkgpathstr := t.nameOff(x.pkgpath).name()
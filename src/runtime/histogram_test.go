@@ -0,0 +1,91 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime_test
+
+import (
+	"math"
+	. "runtime"
+	"testing"
+)
+
+var dummyTimeHistogram, dummyTimeHistogram2 TimeHistogram
+
+func TestTimeHistogram(t *testing.T) {
+	// We need to use a global dummy because this
+	// could get stack-allocated with a non-8-byte alignment.
+	// The result of this bad alignment is a segfault on
+	// 32-bit platforms when calling Record.
+	h := &dummyTimeHistogram
+	*h = TimeHistogram{}
+
+	// Record exactly one sample in each bucket.
+	for i := 0; i < TimeHistNumSuperBuckets; i++ {
+		var base int64
+		if i > 0 {
+			base = int64(1) << (i + TimeHistSubBucketBits - 1)
+		}
+		for j := 0; j < TimeHistNumSubBuckets; j++ {
+			v := int64(j)
+			if i > 0 {
+				v <<= i - 1
+			}
+			h.Record(base + v)
+		}
+	}
+	// Hit the underflow bucket.
+	h.Record(int64(-1))
+
+	// Check to make sure there's exactly one count in each
+	// bucket.
+	for i := uint(0); i < TimeHistNumSuperBuckets; i++ {
+		for j := uint(0); j < TimeHistNumSubBuckets; j++ {
+			c, ok := h.Count(i, j)
+			if !ok {
+				t.Errorf("hit underflow bucket unexpectedly: (%d, %d)", i, j)
+			} else if c != 1 {
+				t.Errorf("bucket (%d, %d) has count that is not 1: %d", i, j, c)
+			}
+		}
+	}
+	c, ok := h.Count(TimeHistNumSuperBuckets, 0)
+	if ok {
+		t.Errorf("expected to hit underflow bucket: (%d, %d)", TimeHistNumSuperBuckets, 0)
+	}
+	if c != 1 {
+		t.Errorf("underflow bucket has count that is not 1: %d", c)
+	}
+
+	// Check that the buckets reported to runtime/metrics agree
+	// with where samples were recorded: the i'th sample lies in
+	// [b[i+1], b[i+2]), b[0] being -Inf for underflow.
+	b := TimeHistogramMetricsBuckets()
+	if len(b) != TimeHistNumSuperBuckets*TimeHistNumSubBuckets+2 {
+		t.Fatalf("got %d buckets, want %d", len(b), TimeHistNumSuperBuckets*TimeHistNumSubBuckets+2)
+	}
+	if !math.IsInf(b[0], -1) || !math.IsInf(b[len(b)-1], 1) {
+		t.Errorf("got bucket bounds %v, %v; want -Inf, +Inf", b[0], b[len(b)-1])
+	}
+	for i := 1; i < len(b); i++ {
+		if b[i] <= b[i-1] {
+			t.Fatalf("buckets not increasing: b[%d] = %v, b[%d] = %v", i-1, b[i-1], i, b[i])
+		}
+	}
+	for i := uint(0); i < TimeHistNumSuperBuckets; i++ {
+		for j := uint(0); j < TimeHistNumSubBuckets; j++ {
+			// The last sample in each sub-bucket, in seconds.
+			k := i*TimeHistNumSubBuckets + j + 1
+			if math.IsInf(b[k+1], 1) {
+				continue
+			}
+			last := math.Round(b[k+1]*1e9) - 1
+			h := &dummyTimeHistogram2
+			*h = TimeHistogram{}
+			h.Record(int64(last))
+			if c, _ := h.Count(i, j); c != 1 {
+				t.Errorf("%v ns not recorded in bucket (%d, %d) with bounds [%v, %v)", last, i, j, b[k], b[k+1])
+			}
+		}
+	}
+}
@@ -0,0 +1,362 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+// Metrics implementation exported to runtime/metrics.
+
+import (
+	"runtime/internal/atomic"
+	"unsafe"
+)
+
+var (
+	// metrics is a map of runtime/metrics keys to
+	// data used by the runtime to sample each metric's
+	// value.
+	metricsSema uint32 = 1
+	metricsInit bool
+	metrics     map[string]metricData
+
+	// agg is used by readMetrics, and is protected by metricsSema.
+	//
+	// Managed as a global variable because its pointer will be
+	// an argument to a dynamically-defined function, and we'd
+	// like to avoid it escaping to the heap.
+	agg statAggregate
+
+	timeHistBuckets []float64
+)
+
+// Distributions sampled for runtime/metrics.
+var (
+	// gcPauseDist is the distribution of individual GC-related
+	// stop-the-world pause latencies.
+	gcPauseDist timeHistogram
+
+	// schedLatencyDist is the distribution of the time goroutines
+	// spend runnable before running, sampled by casgstatus.
+	schedLatencyDist timeHistogram
+)
+
+// gTrackingPeriod is the number of transitions to runnable between
+// samples of a goroutine's scheduling latency.
+const gTrackingPeriod = 8
+
+type metricData struct {
+	// compute is a function that populates a metricValue
+	// given a populated statAggregate structure.
+	compute func(in *statAggregate, out *metricValue)
+}
+
+// initMetrics initializes the metrics map if it hasn't been yet.
+//
+// metricsSema must be held.
+func initMetrics() {
+	if metricsInit {
+		return
+	}
+	timeHistBuckets = timeHistogramMetricsBuckets()
+	metrics = map[string]metricData{
+		"/gc/cycles/automatic:gc-cycles": {
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.gcCycles - in.gcCyclesForced
+			},
+		},
+		"/gc/cycles/forced:gc-cycles": {
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.gcCyclesForced
+			},
+		},
+		"/gc/cycles/total:gc-cycles": {
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.gcCycles
+			},
+		},
+		"/gc/gomemlimit:bytes": {
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = uint64(in.memoryLimit)
+			},
+		},
+		"/gc/heap/goal:bytes": {
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.heapGoal
+			},
+		},
+		"/gc/heap/live:bytes": {
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.heapMarked
+			},
+		},
+		"/gc/pauses:seconds": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				gcPauseDist.write(out)
+			},
+		},
+		"/memory/classes/heap/free:bytes": {
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.heapSys - in.heapInuse - in.heapReleased
+			},
+		},
+		"/memory/classes/heap/objects:bytes": {
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.heapObjects
+			},
+		},
+		"/memory/classes/heap/released:bytes": {
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.heapReleased
+			},
+		},
+		"/memory/classes/heap/stacks:bytes": {
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.stacksInuse
+			},
+		},
+		"/memory/classes/heap/unused:bytes": {
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.heapInuse - in.heapObjects
+			},
+		},
+		"/memory/classes/metadata/mcache/free:bytes": {
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.mcacheSys - in.mcacheInuse
+			},
+		},
+		"/memory/classes/metadata/mcache/inuse:bytes": {
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.mcacheInuse
+			},
+		},
+		"/memory/classes/metadata/mspan/free:bytes": {
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.mspanSys - in.mspanInuse
+			},
+		},
+		"/memory/classes/metadata/mspan/inuse:bytes": {
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.mspanInuse
+			},
+		},
+		"/memory/classes/metadata/other:bytes": {
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.gcSys
+			},
+		},
+		"/memory/classes/os-stacks:bytes": {
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.stacksSys
+			},
+		},
+		"/memory/classes/other:bytes": {
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.otherSys
+			},
+		},
+		"/memory/classes/profiling/buckets:bytes": {
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.buckhashSys
+			},
+		},
+		"/memory/classes/total:bytes": {
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.heapSys + in.stacksInuse + in.stacksSys +
+					in.mspanSys + in.mcacheSys + in.buckhashSys +
+					in.gcSys + in.otherSys
+			},
+		},
+		"/sched/goroutines:goroutines": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = uint64(gcount())
+			},
+		},
+		"/sched/latencies:seconds": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				schedLatencyDist.write(out)
+			},
+		},
+	}
+	metricsInit = true
+}
+
+// statAggregate is a snapshot of the runtime statistics used to
+// compute metrics. It is gathered under the heap lock, so metrics
+// derived from it are consistent with one another, without stopping
+// the world.
+type statAggregate struct {
+	heapSys      uint64
+	heapInuse    uint64
+	heapReleased uint64
+	heapObjects  uint64
+	heapMarked   uint64
+	heapGoal     uint64
+	stacksInuse  uint64
+	stacksSys    uint64
+	mspanInuse   uint64
+	mspanSys     uint64
+	mcacheInuse  uint64
+	mcacheSys    uint64
+	buckhashSys  uint64
+	gcSys        uint64
+	otherSys     uint64
+
+	gcCycles       uint64
+	gcCyclesForced uint64
+
+	memoryLimit int64
+}
+
+// read populates a with the current runtime statistics.
+func (a *statAggregate) read() {
+	systemstack(func() {
+		lock(&mheap_.lock)
+		a.heapSys = atomic.Load64(&memstats.heap_sys)
+		a.heapInuse = atomic.Load64(&memstats.heap_inuse)
+		a.heapReleased = atomic.Load64(&memstats.heap_released)
+		a.heapMarked = memstats.heap_marked
+		a.heapGoal = memstats.next_gc
+		a.stacksInuse = atomic.Load64(&memstats.stacks_inuse)
+		a.stacksSys = atomic.Load64(&memstats.stacks_sys)
+		a.mspanInuse = uint64(mheap_.spanalloc.inuse)
+		a.mspanSys = atomic.Load64(&memstats.mspan_sys)
+		a.mcacheInuse = uint64(mheap_.cachealloc.inuse)
+		a.mcacheSys = atomic.Load64(&memstats.mcache_sys)
+		a.buckhashSys = atomic.Load64(&memstats.buckhash_sys)
+		a.gcSys = atomic.Load64(&memstats.gc_sys)
+		a.otherSys = atomic.Load64(&memstats.other_sys)
+		a.gcCycles = uint64(atomic.Load(&memstats.numgc))
+		a.gcCyclesForced = uint64(atomic.Load(&memstats.numforcedgc))
+		a.memoryLimit = memoryLimit
+		unlock(&mheap_.lock)
+	})
+	// heap_live counts the spans cached for allocation as fully
+	// allocated, so it is an estimate of the bytes in objects.
+	// It is updated without the heap lock, so bound it by the
+	// in-use spans it must fit in.
+	a.heapObjects = atomic.Load64(&memstats.heap_live)
+	if a.heapObjects > a.heapInuse {
+		a.heapObjects = a.heapInuse
+	}
+}
+
+// metricKind is a runtime copy of runtime/metrics.ValueKind and
+// must be kept structurally identical to that type.
+type metricKind int
+
+const (
+	// These values must be kept identical to their corresponding Kind* values
+	// in the runtime/metrics package.
+	metricKindBad metricKind = iota
+	metricKindUint64
+	metricKindFloat64
+	metricKindFloat64Histogram
+)
+
+// metricSample is a runtime copy of runtime/metrics.Sample and
+// must be kept structurally identical to that type.
+type metricSample struct {
+	name  string
+	value metricValue
+}
+
+// metricValue is a runtime copy of runtime/metrics.Value and
+// must be kept structurally identical to that type.
+type metricValue struct {
+	kind    metricKind
+	scalar  uint64         // contains scalar values for scalar Kinds.
+	pointer unsafe.Pointer // contains non-scalar values.
+}
+
+// float64HistOrInit tries to pull out an existing float64Histogram
+// from the value, but if none exists, then it allocates one with
+// the given buckets.
+func (v *metricValue) float64HistOrInit(buckets []float64) *metricFloat64Histogram {
+	var hist *metricFloat64Histogram
+	if v.kind == metricKindFloat64Histogram && v.pointer != nil {
+		hist = (*metricFloat64Histogram)(v.pointer)
+	} else {
+		v.kind = metricKindFloat64Histogram
+		hist = new(metricFloat64Histogram)
+		v.pointer = unsafe.Pointer(hist)
+	}
+	hist.buckets = buckets
+	if len(hist.counts) != len(hist.buckets)-1 {
+		hist.counts = make([]uint64, len(buckets)-1)
+	}
+	return hist
+}
+
+// metricFloat64Histogram is a runtime copy of runtime/metrics.Float64Histogram
+// and must be kept structurally identical to that type.
+type metricFloat64Histogram struct {
+	counts  []uint64
+	buckets []float64
+}
+
+// write copies the counts of h into out as a Float64Histogram.
+func (h *timeHistogram) write(out *metricValue) {
+	hist := out.float64HistOrInit(timeHistBuckets)
+	// The bottom-most bucket, containing negative values, is tracked
+	// separately as underflow, so fill that in manually and then
+	// iterate over the rest.
+	hist.counts[0] = atomic.Load64(&h.underflow)
+	for i := range h.counts {
+		hist.counts[i+1] = atomic.Load64(&h.counts[i])
+	}
+}
+
+// readMetrics is the implementation of runtime/metrics.Read.
+//
+//go:linkname readMetrics runtime/metrics.runtime_readMetrics
+func readMetrics(samplesp unsafe.Pointer, len int, cap int) {
+	// Construct a slice from the args.
+	sl := slice{samplesp, len, cap}
+	samples := *(*[]metricSample)(unsafe.Pointer(&sl))
+
+	// Acquire the metricsSema but with handoff. This operation
+	// is expensive enough that queueing up goroutines and handing
+	// off between them will be noticeably better-behaved.
+	semacquire1(&metricsSema, true, 0, 0)
+
+	// Ensure the map is initialized.
+	initMetrics()
+
+	// Gather the statistics the metrics are computed from.
+	agg.read()
+
+	// Iterate over each sample.
+	for i := range samples {
+		sample := &samples[i]
+		data, ok := metrics[sample.name]
+		if !ok {
+			sample.value.kind = metricKindBad
+			continue
+		}
+		// Compute the value based on the stats we have.
+		data.compute(&agg, &sample.value)
+	}
+
+	semrelease(&metricsSema)
+}
@@ -0,0 +1,100 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package metrics_test
+
+import (
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"regexp"
+	"runtime/metrics"
+	"strings"
+	"testing"
+)
+
+func TestNames(t *testing.T) {
+	// Note that this regexp is promised in the package docs for Description. Do not change.
+	r := regexp.MustCompile("^(?P<name>/[^:]+):(?P<unit>[^:*/]+(?:[*/][^:*/]+)*)$")
+	all := metrics.All()
+	for i, d := range all {
+		if !r.MatchString(d.Name) {
+			t.Errorf("name %q does not match regexp %#q", d.Name, r)
+		}
+		if i > 0 && all[i-1].Name >= all[i].Name {
+			t.Fatalf("allDesc not sorted: %s ≥ %s", all[i-1].Name, all[i].Name)
+		}
+	}
+}
+
+func wrap(prefix, text string, width int) string {
+	doc := &strings.Builder{}
+	words := strings.Fields(text)
+	line := prefix
+	for _, w := range words {
+		if len(line)+1+len(w) > width {
+			fmt.Fprintln(doc, strings.TrimRight(line, " "))
+			line = prefix
+		}
+		line += w + " "
+	}
+	fmt.Fprintln(doc, strings.TrimRight(line, " "))
+	return doc.String()
+}
+
+func formatDesc() string {
+	var b strings.Builder
+	for i, d := range metrics.All() {
+		if i > 0 {
+			fmt.Fprintf(&b, "\n")
+		}
+		fmt.Fprintf(&b, "\t%s\n", d.Name)
+		fmt.Fprintf(&b, "%s", wrap("\t\t", d.Description, 80))
+	}
+	return b.String()
+}
+
+var generate = flag.Bool("generate", false, "update doc.go for go generate")
+
+func TestDocs(t *testing.T) {
+	want := formatDesc() + "\n"
+
+	src, err := ioutil.ReadFile("doc.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, after, found := cut(string(src), "\n\nBelow is the full list of supported metrics, ordered lexicographically.\n\n")
+	if !found {
+		t.Fatalf("cannot find Supported metrics heading in doc.go")
+	}
+	i := strings.Index(after, "*/\n")
+	if i < 0 {
+		t.Fatalf("cannot find end of comment in doc.go")
+	}
+	current := after[:i]
+	if current == want {
+		return
+	}
+	if !*generate {
+		t.Fatalf("doc.go comment does not match metrics.All(); run go generate runtime/metrics")
+	}
+	prefix := src[:len(src)-len(after)]
+	suffix := after[i:]
+	out := []byte(string(prefix) + want + suffix)
+	if out, err = format.Source(out); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile("doc.go", out, 0666); err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("updated doc.go")
+}
+
+func cut(s, sep string) (before, after string, found bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
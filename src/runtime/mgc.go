@@ -187,6 +187,7 @@ func gcinit() {
 
 	// Set gcpercent from the environment. This will also compute
 	// and set the GC trigger and goal.
+	memoryLimit = readGOMEMLIMIT()
 	_ = setGCPercent(readgogc())
 
 	work.startSema = 1
@@ -841,6 +842,13 @@ func gcSetTriggerRatio(triggerRatio float64) {
 		}
 	}
 
+	// Apply the memory limit, which may lower the goal and trigger.
+	goal, trigger = gcLimitHeapGoal(goal, trigger)
+
+	if goal != memstats.next_gc {
+		recordEvent(&eventRecord{kind: eventHeapGoal, time: nanotime(), heapGoal: goal})
+	}
+
 	// Commit to the trigger and goal.
 	memstats.gc_trigger = trigger
 	memstats.next_gc = goal
@@ -1324,6 +1332,7 @@ func gcStart(trigger gcTrigger) {
 
 	gcController.startCycle()
 	work.heapGoal = memstats.next_gc
+	recordEvent(&eventRecord{kind: eventGCStart, time: work.tSweepTerm, gc: work.cycles, heapLive: work.heap0, heapGoal: work.heapGoal})
 
 	// In STW mode, disable scheduling of user Gs. This may also
 	// disable scheduling of this goroutine, so it may block as
@@ -1373,6 +1382,7 @@ func gcStart(trigger gcTrigger) {
 	systemstack(func() {
 		now = startTheWorldWithSema(trace.enabled)
 		work.pauseNS += now - work.pauseStart
+		gcPauseDist.record(now - work.pauseStart)
 		work.tMark = now
 	})
 	// In STW mode, we could block the instant systemstack
@@ -1579,6 +1589,7 @@ top:
 			systemstack(func() {
 				now := startTheWorldWithSema(true)
 				work.pauseNS += now - work.pauseStart
+				gcPauseDist.record(now - work.pauseStart)
 			})
 			goto top
 		}
@@ -1683,6 +1694,7 @@ func gcMarkTermination(nextTriggerRatio float64) {
 	memstats.last_heap_inuse = memstats.heap_inuse
 
 	// Update GC trigger and pacing for the next cycle.
+	gcLimitEndCycle(work.tSweepTerm, nanotime())
 	gcSetTriggerRatio(nextTriggerRatio)
 
 	// Pacing changed, so the scavenger should be awoken.
@@ -1693,12 +1705,14 @@ func gcMarkTermination(nextTriggerRatio float64) {
 	sec, nsec, _ := time_now()
 	unixNow := sec*1e9 + int64(nsec)
 	work.pauseNS += now - work.pauseStart
+	gcPauseDist.record(now - work.pauseStart)
 	work.tEnd = now
 	atomic.Store64(&memstats.last_gc_unix, uint64(unixNow)) // must be Unix time to make sense to user
 	atomic.Store64(&memstats.last_gc_nanotime, uint64(now)) // monotonic time for us
 	memstats.pause_ns[memstats.numgc%uint32(len(memstats.pause_ns))] = uint64(work.pauseNS)
 	memstats.pause_end[memstats.numgc%uint32(len(memstats.pause_end))] = uint64(unixNow)
 	memstats.pause_total_ns += uint64(work.pauseNS)
+	recordEvent(&eventRecord{kind: eventGCEnd, time: now, gc: work.cycles, pause: work.pauseNS, heapLive: memstats.heap_marked, heapGoal: memstats.next_gc})
 
 	// Update work.totaltime.
 	sweepTermCpu := int64(work.stwprocs) * (work.tMark - work.tSweepTerm)
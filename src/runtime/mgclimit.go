@@ -0,0 +1,212 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Soft memory limit.
+//
+// The memory limit, set by debug.SetMemoryLimit or $GOMEMLIMIT, bounds
+// the total memory mapped and not released by the runtime: the heap and
+// the runtime's other memory, such as goroutine stacks and GC metadata.
+// It acts in two places.
+//
+// The pacer (gcSetTriggerRatio) caps the heap goal at the limit less the
+// runtime's non-heap memory and a little headroom, so GC starts earlier
+// as total memory approaches the limit. This applies even with GOGC=off,
+// which makes the limit the only trigger for GC.
+//
+// The scavenger (gcPaceScavenger) caps its retained heap goal the same
+// way, and runs at scavengeLimitPercent instead of scavengePercent of a
+// CPU while it is over that cap, so free memory is returned to the OS
+// sooner.
+//
+// The limit is soft: if the live heap alone exceeds it, collecting more
+// often cannot help, and GC would run continuously, starving the program
+// (a "death spiral"). To guard against this, each cycle whose goal was
+// set by the limit checks how much of the time since the previous cycle
+// GC was running. If it was more than memoryLimitMaxGCFraction, the next
+// goal is allowed to grow to twice the marked heap, as with GOGC=100,
+// regardless of the limit. Memory use may then exceed the limit, but
+// the program continues to make progress.
+
+package runtime
+
+import (
+	"runtime/internal/atomic"
+	_ "unsafe" // for go:linkname
+)
+
+const (
+	// memoryLimitHeadroomPercent is the portion of the memory limit,
+	// in percent, kept free to absorb allocation while GC runs and
+	// errors in the estimate of non-heap memory.
+	memoryLimitHeadroomPercent = 3
+
+	// memoryLimitMaxGCFraction is the fraction of wall-clock time
+	// GC may be running due to the memory limit before the limit
+	// is relaxed for a cycle.
+	memoryLimitMaxGCFraction = 0.5
+
+	// scavengeLimitPercent is the portion of a CPU, in percent, the
+	// background scavenger may use while the retained heap exceeds
+	// the memory limit's share.
+	scavengeLimitPercent = 10
+
+	maxInt64 = 1<<63 - 1
+)
+
+// memoryLimit is the soft limit on the memory used by the runtime,
+// in bytes. maxInt64 means no limit.
+// Protected by mheap_.lock.
+var memoryLimit int64 = maxInt64
+
+var gcLimit struct {
+	// limited reports whether the memory limit set the current
+	// heap goal.
+	limited bool
+
+	// relaxed reports whether the memory limit is relaxed for the
+	// current cycle because GC ran too much in the previous one.
+	relaxed bool
+
+	// lastEnd is the nanotime at which the last GC cycle ended.
+	lastEnd int64
+}
+
+// readGOMEMLIMIT returns the memory limit set by $GOMEMLIMIT.
+func readGOMEMLIMIT() int64 {
+	p := gogetenv("GOMEMLIMIT")
+	if p == "" || p == "off" {
+		return maxInt64
+	}
+	n, ok := parseByteCount(p)
+	if !ok {
+		print("GOMEMLIMIT=", p, "\n")
+		throw("malformed GOMEMLIMIT; see `go doc runtime/debug.SetMemoryLimit`")
+	}
+	return n
+}
+
+// parseByteCount parses a non-negative number of bytes with an
+// optional unit suffix: B, KiB, MiB, GiB or TiB.
+func parseByteCount(s string) (int64, bool) {
+	// Check the longer suffixes first: all of them end in "B".
+	units := [...]string{"B", "KiB", "MiB", "GiB", "TiB"}
+	shift := uint(0)
+	for i := len(units) - 1; i >= 0; i-- {
+		if unit := units[i]; len(s) > len(unit) && s[len(s)-len(unit):] == unit {
+			s = s[:len(s)-len(unit)]
+			shift = 10 * uint(i)
+			break
+		}
+	}
+	if s == "" {
+		return 0, false
+	}
+	n := int64(0)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < '0' || c > '9' || n > (maxInt64>>shift-int64(c-'0'))/10 {
+			return 0, false
+		}
+		n = n*10 + int64(c-'0')
+	}
+	return n << shift, true
+}
+
+//go:linkname setMemoryLimit runtime/debug.setMemoryLimit
+func setMemoryLimit(in int64) (out int64) {
+	// Run on the system stack since we grab the heap lock.
+	systemstack(func() {
+		lock(&mheap_.lock)
+		out = memoryLimit
+		if in >= 0 {
+			memoryLimit = in
+			// Update pacing in response to the new limit.
+			gcSetTriggerRatio(memstats.triggerRatio)
+		}
+		unlock(&mheap_.lock)
+	})
+	// Pacing changed, so the scavenger should be awoken.
+	wakeScavenger()
+	return out
+}
+
+// memoryLimitGoal returns the most memory the heap may retain under
+// the memory limit: the limit, less headroom and the runtime's memory
+// outside the heap. It returns ^uint64(0) if there is no limit.
+//
+// mheap_.lock must be held or the world must be stopped.
+func memoryLimitGoal() uint64 {
+	if memoryLimit == maxInt64 {
+		return ^uint64(0)
+	}
+	limit := uint64(memoryLimit)
+	limit -= limit / 100 * memoryLimitHeadroomPercent
+	nonHeap := atomic.Load64(&memstats.stacks_inuse) +
+		atomic.Load64(&memstats.stacks_sys) +
+		atomic.Load64(&memstats.mspan_sys) +
+		atomic.Load64(&memstats.mcache_sys) +
+		atomic.Load64(&memstats.buckhash_sys) +
+		atomic.Load64(&memstats.gc_sys) +
+		atomic.Load64(&memstats.other_sys)
+	if nonHeap >= limit {
+		return 0
+	}
+	return limit - nonHeap
+}
+
+// gcLimitHeapGoal applies the memory limit to the heap goal and
+// trigger computed from GOGC, returning the new goal and trigger.
+//
+// mheap_.lock must be held or the world must be stopped.
+func gcLimitHeapGoal(goal, trigger uint64) (uint64, uint64) {
+	gcLimit.limited = false
+	lgoal := memoryLimitGoal()
+	if gcLimit.relaxed {
+		// GC ran too much meeting the limit last cycle:
+		// give the heap room to grow as with GOGC=100.
+		if min := memstats.heap_marked * 2; lgoal < min {
+			lgoal = min
+		}
+	}
+	if lgoal >= goal {
+		return goal, trigger
+	}
+	gcLimit.limited = true
+	goal = lgoal
+	if goal < memstats.heap_marked {
+		// Collecting can't free the marked heap.
+		goal = memstats.heap_marked
+	}
+	// Trigger at the same fraction of the way from the marked heap
+	// to the goal as the GOGC trigger would.
+	frac := 0.7
+	if gcpercent > 0 {
+		frac = memstats.triggerRatio / (float64(gcpercent) / 100)
+	}
+	ltrigger := memstats.heap_marked + uint64(float64(goal-memstats.heap_marked)*frac)
+	if ltrigger < trigger {
+		trigger = ltrigger
+	}
+	if debug.gcpacertrace > 0 {
+		print("pacer: memory limit ", memoryLimit>>20, " MB: goal ", goal>>20, " MB, trigger ", trigger>>20, " MB\n")
+	}
+	return goal, trigger
+}
+
+// gcLimitEndCycle updates the death spiral guard at the end of a GC
+// cycle that began at start and ended at now.
+//
+// The world must be stopped.
+func gcLimitEndCycle(start, now int64) {
+	relaxed := false
+	if gcLimit.limited && gcLimit.lastEnd != 0 && now > gcLimit.lastEnd {
+		frac := float64(now-start) / float64(now-gcLimit.lastEnd)
+		relaxed = frac > memoryLimitMaxGCFraction
+		if relaxed && !gcLimit.relaxed && debug.gcpacertrace > 0 {
+			print("pacer: GC running ", int(frac*100), "% of the time under memory limit; relaxing limit\n")
+		}
+	}
+	gcLimit.relaxed = relaxed
+	gcLimit.lastEnd = now
+}
@@ -113,6 +113,7 @@ func gcPaceScavenger() {
 	// We never scavenge before the 2nd GC cycle anyway (we don't have enough
 	// information about the heap yet) so this is fine, and avoids a fault
 	// or garbage data later.
+	mheap_.scavengeLimited = false
 	if memstats.last_next_gc == 0 {
 		mheap_.scavengeGoal = ^uint64(0)
 		return
@@ -129,6 +130,16 @@ func gcPaceScavenger() {
 	// a bit more exact.
 	retainedGoal = (retainedGoal + uint64(physPageSize) - 1) &^ (uint64(physPageSize) - 1)
 
+	// Don't retain more than the memory limit allows, unless the
+	// limit is relaxed to let the program make progress. In that
+	// case, scavenge more aggressively.
+	if !gcLimit.relaxed {
+		if limitGoal := memoryLimitGoal() &^ (uint64(physPageSize) - 1); limitGoal < retainedGoal {
+			retainedGoal = limitGoal
+			mheap_.scavengeLimited = true
+		}
+	}
+
 	// Represents where we are now in the heap's contribution to RSS in bytes.
 	//
 	// Guaranteed to always be a multiple of physPageSize on systems where
@@ -250,8 +261,7 @@ func bgscavenge(c chan int) {
 	// it makes sense to also make the scavenger scale with it; if you're
 	// allocating more frequently, then presumably you're also generating
 	// more work for the scavenger.
-	const idealFraction = scavengePercent / 100.0
-	scavengeEWMA := float64(idealFraction)
+	scavengeEWMA := float64(scavengePercent / 100.0)
 
 	for {
 		released := uintptr(0)
@@ -259,6 +269,10 @@ func bgscavenge(c chan int) {
 		// Time in scavenging critical section.
 		crit := float64(0)
 
+		// Fraction of a CPU to use, which is higher while
+		// the memory limit sets the goal.
+		idealFraction := scavengePercent / 100.0
+
 		// Run on the system stack since we grab the heap lock,
 		// and a stack growth with the heap lock means a deadlock.
 		systemstack(func() {
@@ -270,6 +284,9 @@ func bgscavenge(c chan int) {
 				unlock(&mheap_.lock)
 				return
 			}
+			if mheap_.scavengeLimited {
+				idealFraction = scavengeLimitPercent / 100.0
+			}
 			unlock(&mheap_.lock)
 
 			// Scavenge one page, and measure the amount of time spent scavenging.
@@ -331,7 +348,7 @@ func bgscavenge(c chan int) {
 		// much, then scavengeEMWA < idealFraction, so we'll adjust the sleep time
 		// down.
 		adjust := scavengeEWMA / idealFraction
-		sleepTime := int64(adjust * crit / (scavengePercent / 100.0))
+		sleepTime := int64(adjust * crit / idealFraction)
 
 		// Go to sleep.
 		slept := scavengeSleep(sleepTime)
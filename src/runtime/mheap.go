@@ -103,6 +103,13 @@ type mheap struct {
 	// to the OS.
 	scavengeGoal uint64
 
+	// scavengeLimited reports whether scavengeGoal was set by the
+	// memory limit, in which case the background scavenger runs
+	// at scavengeLimitPercent instead of scavengePercent.
+	scavengeLimited bool
+
+	_ uint32 // align uint64 fields on 32-bit for atomics
+
 	// Page reclaimer state
 
 	// reclaimIndex is the page index in allArenas of next page to
@@ -52,6 +52,42 @@ type bucket struct {
 	hash    uintptr
 	size    uintptr
 	nstk    uintptr
+
+	// For memProfile buckets, the profiler labels of the allocating
+	// goroutine. lhash identifies the label set; see profLabelHash.
+	// labels is the address of one such label set, kept alive by a
+	// profLabelRef on memProfLabelRefs.
+	lhash  uint64
+	labels uintptr
+}
+
+// A profLabelRef keeps profiler labels referred to by a memProfile
+// bucket reachable, since buckets are not scanned by the GC.
+type profLabelRef struct {
+	next   *profLabelRef
+	labels unsafe.Pointer
+}
+
+// memProfLabelRefs lists the label sets referred to by memProfile
+// buckets. Buckets are never freed, so neither are these.
+// Protected by proflock.
+var memProfLabelRefs *profLabelRef
+
+// profLabelMap is a runtime copy of runtime/pprof.labelMap, the
+// profiler labels the labels field of a g points to, and must be
+// kept structurally identical to that type.
+type profLabelMap struct {
+	hash uint64
+	m    map[string]string
+}
+
+// profLabelHash returns the hash identifying the profiler labels
+// set by runtime/pprof, or 0 for none.
+func profLabelHash(labels unsafe.Pointer) uint64 {
+	if labels == nil {
+		return 0
+	}
+	return (*profLabelMap)(labels).hash
 }
 
 // A memRecord is the bucket data for a bucket of type memProfile,
@@ -201,8 +237,10 @@ func (b *bucket) bp() *blockRecord {
 	return (*blockRecord)(data)
 }
 
-// Return the bucket for stk[0:nstk], allocating new bucket if needed.
-func stkbucket(typ bucketType, size uintptr, stk []uintptr, alloc bool) *bucket {
+// Return the bucket for stk[0:nstk] and the profiler labels, allocating
+// new bucket if needed. Only memProfile buckets have labels; the caller
+// must keep labels reachable if a new bucket refers to them.
+func stkbucket(typ bucketType, size uintptr, stk []uintptr, labels unsafe.Pointer, alloc bool) *bucket {
 	if buckhash == nil {
 		buckhash = (*[buckHashSize]*bucket)(sysAlloc(unsafe.Sizeof(*buckhash), &memstats.buckhash_sys))
 		if buckhash == nil {
@@ -221,13 +259,20 @@ func stkbucket(typ bucketType, size uintptr, stk []uintptr, alloc bool) *bucket
 	h += size
 	h += h << 10
 	h ^= h >> 6
+	// hash in labels
+	lhash := profLabelHash(labels)
+	if lhash != 0 {
+		h += uintptr(lhash)
+		h += h << 10
+		h ^= h >> 6
+	}
 	// finalize
 	h += h << 3
 	h ^= h >> 11
 
 	i := int(h % buckHashSize)
 	for b := buckhash[i]; b != nil; b = b.next {
-		if b.typ == typ && b.hash == h && b.size == size && eqslice(b.stk(), stk) {
+		if b.typ == typ && b.hash == h && b.size == size && b.lhash == lhash && eqslice(b.stk(), stk) {
 			return b
 		}
 	}
@@ -241,6 +286,10 @@ func stkbucket(typ bucketType, size uintptr, stk []uintptr, alloc bool) *bucket
 	copy(b.stk(), stk)
 	b.hash = h
 	b.size = size
+	b.lhash = lhash
+	if lhash != 0 {
+		b.labels = uintptr(labels)
+	}
 	b.next = buckhash[i]
 	buckhash[i] = b
 	if typ == memProfile {
@@ -340,11 +389,34 @@ func mProf_PostSweep() {
 func mProf_Malloc(p unsafe.Pointer, size uintptr) {
 	var stk [maxStack]uintptr
 	nstk := callers(4, stk[:])
+	// Record the labels of the goroutine making the allocation,
+	// even if it is being made on the system stack.
+	mp := getg().m
+	var labels unsafe.Pointer
+	if gp := mp.curg; gp != nil && !mp.profLabelRef && profLabelHash(gp.labels) != 0 {
+		labels = gp.labels
+	}
 	lock(&proflock)
-	b := stkbucket(memProfile, size, stk[:nstk], true)
+	b := stkbucket(memProfile, size, stk[:nstk], labels, labels == nil)
+	if b == nil {
+		// A new bucket refers to labels, so keep them reachable.
+		// We cannot allocate while holding proflock, and the
+		// allocation is itself profiled, without labels.
+		unlock(&proflock)
+		mp.profLabelRef = true
+		lbl := &profLabelRef{labels: labels}
+		mp.profLabelRef = false
+		lock(&proflock)
+		b = stkbucket(memProfile, size, stk[:nstk], labels, false)
+		if b == nil {
+			b = stkbucket(memProfile, size, stk[:nstk], labels, true)
+			lbl.next = memProfLabelRefs
+			memProfLabelRefs = lbl
+		}
+	}
 	c := mProf.cycle
-	mp := b.mp()
-	mpc := &mp.future[(c+2)%uint32(len(mp.future))]
+	mr := b.mp()
+	mpc := &mr.future[(c+2)%uint32(len(mr.future))]
 	mpc.allocs++
 	mpc.alloc_bytes += size
 	unlock(&proflock)
@@ -421,7 +493,7 @@ func saveblockevent(cycles int64, skip int, which bucketType) {
 		nstk = gcallers(gp.m.curg, skip, stk[:])
 	}
 	lock(&proflock)
-	b := stkbucket(which, 0, stk[:nstk], true)
+	b := stkbucket(which, 0, stk[:nstk], nil, true)
 	b.bp().count++
 	b.bp().cycles += cycles
 	unlock(&proflock)
@@ -541,6 +613,20 @@ func (r *MemProfileRecord) Stack() []uintptr {
 // the testing package's -test.memprofile flag instead
 // of calling MemProfile directly.
 func MemProfile(p []MemProfileRecord, inuseZero bool) (n int, ok bool) {
+	return memProfileWithLabels(p, nil, inuseZero)
+}
+
+//go:linkname runtime_memProfileWithLabels runtime/pprof.runtime_memProfileWithLabels
+func runtime_memProfileWithLabels(p []MemProfileRecord, labels []unsafe.Pointer, inuseZero bool) (n int, ok bool) {
+	return memProfileWithLabels(p, labels, inuseZero)
+}
+
+// memProfileWithLabels is MemProfile, also returning in labels[i]
+// the profiler labels of the allocations recorded in p[i].
+func memProfileWithLabels(p []MemProfileRecord, labels []unsafe.Pointer, inuseZero bool) (n int, ok bool) {
+	if labels != nil && len(labels) != len(p) {
+		labels = nil
+	}
 	lock(&proflock)
 	// If we're between mProf_NextCycle and mProf_Flush, take care
 	// of flushing to the active profile so we only have to look
@@ -580,6 +666,9 @@ func MemProfile(p []MemProfileRecord, inuseZero bool) (n int, ok bool) {
 			mp := b.mp()
 			if inuseZero || mp.active.alloc_bytes != mp.active.free_bytes {
 				record(&p[idx], b)
+				if labels != nil {
+					labels[idx] = unsafe.Pointer(b.labels)
+				}
 				idx++
 			}
 		}
@@ -718,6 +807,20 @@ func ThreadCreateProfile(p []StackRecord) (n int, ok bool) {
 // Most clients should use the runtime/pprof package instead
 // of calling GoroutineProfile directly.
 func GoroutineProfile(p []StackRecord) (n int, ok bool) {
+	return goroutineProfileWithLabels(p, nil)
+}
+
+//go:linkname runtime_goroutineProfileWithLabels runtime/pprof.runtime_goroutineProfileWithLabels
+func runtime_goroutineProfileWithLabels(p []StackRecord, labels []unsafe.Pointer) (n int, ok bool) {
+	return goroutineProfileWithLabels(p, labels)
+}
+
+// goroutineProfileWithLabels is GoroutineProfile, also returning in
+// labels[i] the profiler labels of the goroutine recorded in p[i].
+func goroutineProfileWithLabels(p []StackRecord, labels []unsafe.Pointer) (n int, ok bool) {
+	if labels != nil && len(labels) != len(p) {
+		labels = nil
+	}
 	gp := getg()
 
 	isOK := func(gp1 *g) bool {
@@ -746,6 +849,11 @@ func GoroutineProfile(p []StackRecord) (n int, ok bool) {
 			saveg(pc, sp, gp, &r[0])
 		})
 		r = r[1:]
+		lbl := labels
+		if lbl != nil {
+			lbl[0] = gp.labels
+			lbl = lbl[1:]
+		}
 
 		// Save other goroutines.
 		for _, gp1 := range allgs {
@@ -757,6 +865,10 @@ func GoroutineProfile(p []StackRecord) (n int, ok bool) {
 				}
 				saveg(^uintptr(0), ^uintptr(0), gp1, &r[0])
 				r = r[1:]
+				if lbl != nil {
+					lbl[0] = gp1.labels
+					lbl = lbl[1:]
+				}
 			}
 		}
 	}
@@ -773,6 +885,116 @@ func saveg(pc, sp uintptr, gp *g, r *StackRecord) {
 	}
 }
 
+// goroutineRecord is a runtime copy of runtime/debug.Goroutine and
+// must be kept structurally identical to that type.
+type goroutineRecord struct {
+	id         int64
+	state      int
+	waitReason string
+	waitTime   int64
+	createdBy  uintptr
+	locked     bool
+	labels     map[string]string
+	stack      []uintptr
+}
+
+const (
+	// These values must be kept identical to their corresponding
+	// Goroutine* values in the runtime/debug package.
+	goroutineRunning = iota
+	goroutineRunnable
+	goroutineWaiting
+	goroutineSyscall
+)
+
+// readGoroutines is the implementation of runtime/debug.Goroutines.
+// It returns n, the number of goroutines that GoroutineProfile would
+// record. If len(p) >= n, it records the goroutines in p[:n], the
+// calling one first, and returns n, true. The stack of each record
+// must have room for the frames to record. If len(p) < n, it does
+// not change p and returns n, false.
+//
+//go:linkname readGoroutines runtime/debug.readGoroutines
+func readGoroutines(p []goroutineRecord) (n int, ok bool) {
+	gp := getg()
+
+	isOK := func(gp1 *g) bool {
+		// As in goroutineProfileWithLabels.
+		return gp1 != gp && readgstatus(gp1) != _Gdead && !isSystemGoroutine(gp1, false)
+	}
+
+	stopTheWorld("goroutines")
+
+	n = 1
+	for _, gp1 := range allgs {
+		if isOK(gp1) {
+			n++
+		}
+	}
+
+	if n <= len(p) {
+		ok = true
+		now := nanotime()
+
+		// Save current goroutine.
+		sp := getcallersp()
+		pc := getcallerpc()
+		systemstack(func() {
+			saveGoroutine(pc, sp, gp, now, &p[0])
+		})
+
+		// Save other goroutines.
+		i := 1
+		for _, gp1 := range allgs {
+			if isOK(gp1) {
+				saveGoroutine(^uintptr(0), ^uintptr(0), gp1, now, &p[i])
+				i++
+			}
+		}
+	}
+
+	startTheWorld()
+
+	return n, ok
+}
+
+// saveGoroutine records the state of gp in r at time now, with its
+// stack traced from pc and sp.
+func saveGoroutine(pc, sp uintptr, gp *g, now int64, r *goroutineRecord) {
+	status := readgstatus(gp) &^ _Gscan
+	r.id = gp.goid
+	r.waitReason = ""
+	switch status {
+	case _Grunning:
+		r.state = goroutineRunning
+	case _Gsyscall:
+		r.state = goroutineSyscall
+	case _Gwaiting:
+		r.state = goroutineWaiting
+		r.waitReason = gp.waitreason.String()
+	default:
+		// Preempted goroutines are as good as runnable.
+		r.state = goroutineRunnable
+	}
+	// As in goroutineheader, waitsince is only set by the GC.
+	r.waitTime = 0
+	if (status == _Gwaiting || status == _Gsyscall) && gp.waitsince != 0 {
+		r.waitTime = now - gp.waitsince
+	}
+	r.createdBy = gp.gopc
+	r.locked = gp.lockedm != 0
+	r.labels = nil
+	if gp.labels != nil {
+		r.labels = (*profLabelMap)(gp.labels).m
+	}
+	stk := r.stack[:cap(r.stack)]
+	n := 0
+	if len(stk) > 0 {
+		n = gentraceback(pc, sp, 0, gp, 0, &stk[0], len(stk), nil, nil, 0)
+	}
+	r.stack = stk[:n]
+}
+
 // Stack formats a stack trace of the calling goroutine into buf
 // and returns the number of bytes written to buf.
 // If all is true, Stack formats stack traces of all other goroutines
@@ -10,7 +10,15 @@ import (
 	"unsafe"
 )
 
-type mOS struct{}
+type mOS struct {
+	// profileTimer is the ID of the POSIX interval timer that
+	// samples the CPU time of this thread for the profiler. It is
+	// valid when profileTimerValid is non-zero. Only the thread
+	// itself sets them, but the signal handler reads
+	// profileTimerValid, so that is accessed atomically.
+	profileTimer      int32
+	profileTimerValid uint32
+}
 
 //go:noescape
 func futex(addr unsafe.Pointer, op int32, val uint32, ts, addr2 unsafe.Pointer, val3 uint32) int32
@@ -360,6 +368,11 @@ func minit() {
 //go:nosplit
 func unminit() {
 	unminitSignals()
+	// The m may next run on another thread, which needs
+	// a profiling timer of its own.
+	mp := getg().m
+	deleteProfileTimer(mp)
+	mp.profilehz = 0
 }
 
 //#ifdef GOARCH_386
@@ -376,6 +389,14 @@ func sigaltstack(new, old *stackt)
 //go:noescape
 func setitimer(mode int32, new, old *itimerval)
 
+//go:noescape
+func timer_create(clockid int32, sevp *sigevent, timerid *int32) int32
+
+//go:noescape
+func timer_settime(timerid int32, flags int32, new, old *itimerspec) int32
+
+func timer_delete(timerid int32) int32
+
 //go:noescape
 func rtsigprocmask(how int32, new, old *sigset, size int32)
 
@@ -498,3 +519,141 @@ func signalM(mp *m, sig int) {
 	}
 	tgkill(getpid(), int(mp.procid), sig)
 }
+
+const (
+	_CLOCK_THREAD_CPUTIME_ID = 0x3
+
+	_SIGEV_THREAD_ID = 0x4
+)
+
+type itimerspec struct {
+	it_interval timespec
+	it_value    timespec
+}
+
+// sigeventFields holds the fields of struct sigevent that the runtime uses.
+type sigeventFields struct {
+	value  uintptr
+	signo  int32
+	notify int32
+	// below here is a union; sigev_notify_thread_id is the only field we use
+	sigev_notify_thread_id int32
+}
+
+type sigevent struct {
+	sigeventFields
+
+	// Pad struct to the size the kernel reads.
+	_ [_sigev_max_size - unsafe.Sizeof(sigeventFields{})]byte
+}
+
+const _sigev_max_size = 64
+
+// setProcessCPUProfiler is called when the profiling timer changes.
+// It is called with prof.signalLock held. hz is the new timer, and is 0 if
+// profiling is being disabled.
+//
+// Each m profiles its own thread with a per-thread timer; see
+// setThreadCPUProfiler. The process-wide timer still samples the
+// threads that have no m, such as threads started by C code.
+func setProcessCPUProfiler(hz int32) {
+	setProcessCPUProfilerTimer(hz)
+}
+
+// setThreadCPUProfiler makes any thread-specific changes required to
+// implement profiling at a rate of hz.
+//
+// It creates a timer that sends SIGPROF to this thread every 1/hz
+// seconds of the thread's own CPU time. Unlike the process-wide
+// setitimer timer, whose signals the kernel delivers to whichever
+// thread happens to be running, this samples each thread fairly,
+// however many threads are busy, and works at rates well above
+// 100 Hz.
+func setThreadCPUProfiler(hz int32) {
+	mp := getg().m
+	mp.profilehz = hz
+	deleteProfileTimer(mp)
+	if hz == 0 {
+		return
+	}
+
+	// Start the timer at a random point in the first period, so
+	// that threads that only run briefly, such as GC workers on a
+	// mostly idle process, get samples in proportion to the CPU
+	// time they use, rather than none at all. 0 disarms the timer,
+	// so the start is in (0, period].
+	var spec itimerspec
+	spec.it_value.setNsec(1 + int64(fastrandn(uint32(1e9/hz))))
+	spec.it_interval.setNsec(1e9 / int64(hz))
+
+	var sevp sigevent
+	sevp.notify = _SIGEV_THREAD_ID
+	sevp.signo = _SIGPROF
+	sevp.sigev_notify_thread_id = int32(mp.procid)
+	var timerid int32
+	if ret := timer_create(_CLOCK_THREAD_CPUTIME_ID, &sevp, &timerid); ret != 0 {
+		// Without a timer of its own, this thread is sampled
+		// by the process-wide timer, as on other systems.
+		return
+	}
+	if ret := timer_settime(timerid, 0, &spec, nil); ret != 0 {
+		print("runtime: failed to configure profiling timer; timer_settime(", timerid, ") errno=", -ret, "\n")
+		throw("timer_settime")
+	}
+	mp.profileTimer = timerid
+	atomic.Store(&mp.profileTimerValid, 1)
+}
+
+// deleteProfileTimer deletes the profiling timer of mp, if it has one.
+// It must be called on mp's thread.
+//
+//go:nosplit
+func deleteProfileTimer(mp *m) {
+	if atomic.Load(&mp.profileTimerValid) == 0 {
+		return
+	}
+	timerid := mp.profileTimer
+	atomic.Store(&mp.profileTimerValid, 0)
+	mp.profileTimer = 0
+	if timer_delete(timerid) != 0 {
+		throw("runtime: failed to delete profiling timer")
+	}
+}
+
+// sigprofSamples returns the number of profiling samples a SIGPROF
+// signal received by mp stands for, or 0 if it should be ignored.
+//
+// A thread with a per-thread timer records only the signals of that
+// timer, and other threads only those of the process-wide timer, so
+// that no CPU time is counted twice. Signals from other sources,
+// such as kill, are always recorded.
+//
+// The kernel checks CPU timers only at scheduler ticks, so at rates
+// above the tick rate several periods of a per-thread timer may end
+// before it sends a signal. The signal then stands for all of them.
+//
+//go:nosplit
+func sigprofSamples(mp *m, c *sigctxt) uint64 {
+	code := int32(c.sigcode())
+	switch {
+	case code == _SI_TIMER && mp != nil && atomic.Load(&mp.profileTimerValid) != 0:
+		// For timer signals, the union in siginfo starts
+		// with si_tid and si_overrun.
+		overrun := *(*int32)(add(unsafe.Pointer(&c.info.si_addr), 4))
+		if overrun < 0 {
+			overrun = 0
+		}
+		return 1 + uint64(overrun)
+	case code == _SI_TIMER:
+		// A signal from a timer this thread no longer has.
+		return 0
+	case code == _SI_KERNEL && mp != nil && atomic.Load(&mp.profileTimerValid) != 0:
+		// The process-wide timer, on a thread with a timer
+		// of its own.
+		return 0
+	}
+	// A thread without an m may still have had a timer of its
+	// own, while it was running Go code, but it was deleted by
+	// unminit when the thread gave up its m.
+	return 1
+}
@@ -13,6 +13,8 @@ const (
 	_SS_DISABLE  = 2
 	_NSIG        = 65
 	_SI_USER     = 0
+	_SI_KERNEL   = 0x80
+	_SI_TIMER    = -0x2
 	_SIG_BLOCK   = 0
 	_SIG_UNBLOCK = 1
 	_SIG_SETMASK = 2
@@ -23,6 +23,8 @@ const (
 	_SS_DISABLE  = 2
 	_NSIG        = 128 + 1
 	_SI_USER     = 0
+	_SI_KERNEL   = 0x80
+	_SI_TIMER    = -0x3 // not -0x2, as on other architectures
 	_SIG_BLOCK   = 1
 	_SIG_UNBLOCK = 2
 	_SIG_SETMASK = 3
@@ -1104,7 +1104,7 @@ func profilem(mp *m, thread uintptr) {
 
 	gp := gFromTLS(mp)
 
-	sigprof(r.ip(), r.sp(), r.lr(), gp, mp)
+	sigprof(r.ip(), r.sp(), r.lr(), gp, mp, 1)
 }
 
 func gFromTLS(mp *m) *g {
@@ -6,6 +6,10 @@ package pprof
 
 import (
 	"context"
+	"fmt"
+	"hash/maphash"
+	"sort"
+	"strings"
 )
 
 type label struct {
@@ -24,7 +28,7 @@ type labelContextKey struct{}
 func labelValue(ctx context.Context) labelMap {
 	labels, _ := ctx.Value(labelContextKey{}).(*labelMap)
 	if labels == nil {
-		return labelMap(nil)
+		return labelMap{}
 	}
 	return *labels
 }
@@ -32,30 +36,105 @@ func labelValue(ctx context.Context) labelMap {
 // labelMap is the representation of the label set held in the context type.
 // This is an initial implementation, but it will be replaced with something
 // that admits incremental immutable modification more efficiently.
-type labelMap map[string]string
+//
+// A *labelMap is also the goroutine's label set held by the runtime.
+// The runtime reads hash, which must be the first field, to tell label
+// sets apart in heap profile records, and m for runtime/debug.Goroutines;
+// see profLabelMap in runtime/mprof.go.
+type labelMap struct {
+	hash uint64 // identifies m's contents; 0 if m is empty
+	m    map[string]string
+}
+
+var labelHashSeed = maphash.MakeSeed()
+
+// setHash sets l.hash from the contents of l.m.
+func (l *labelMap) setHash() {
+	var h maphash.Hash
+	h.SetSeed(labelHashSeed)
+	// Sum the hashes of the labels, so the result is
+	// independent of map iteration order.
+	l.hash = 0
+	for k, v := range l.m {
+		h.Reset()
+		h.WriteString(k)
+		h.WriteByte(0)
+		h.WriteString(v)
+		l.hash += h.Sum64()
+	}
+	if l.hash == 0 && len(l.m) > 0 {
+		l.hash = 1
+	}
+}
+
+// String returns the labels as {"key":"value", ...}, in key order.
+func (l *labelMap) String() string {
+	if l == nil {
+		return ""
+	}
+	keyVals := make([]string, 0, len(l.m))
+	for k, v := range l.m {
+		keyVals = append(keyVals, fmt.Sprintf("%q:%q", k, v))
+	}
+	sort.Strings(keyVals)
+	return "{" + strings.Join(keyVals, ", ") + "}"
+}
+
+// matches reports whether l includes every label in match.
+func (l *labelMap) matches(match LabelSet) bool {
+	for i, label := range match.list {
+		if l == nil {
+			return false
+		}
+		if overridden(match.list[i+1:], label.key) {
+			// As in WithLabels, a later label with the same key wins.
+			continue
+		}
+		if v, ok := l.m[label.key]; !ok || v != label.value {
+			return false
+		}
+	}
+	return true
+}
+
+// overridden reports whether list contains a label with the given key.
+func overridden(list []label, key string) bool {
+	for _, l := range list {
+		if l.key == key {
+			return true
+		}
+	}
+	return false
+}
 
 // WithLabels returns a new context.Context with the given labels added.
 // A label overwrites a prior label with the same key.
 func WithLabels(ctx context.Context, labels LabelSet) context.Context {
-	childLabels := make(labelMap)
+	childLabels := labelMap{m: make(map[string]string)}
 	parentLabels := labelValue(ctx)
 	// TODO(matloob): replace the map implementation with something
 	// more efficient so creating a child context WithLabels doesn't need
 	// to clone the map.
-	for k, v := range parentLabels {
-		childLabels[k] = v
+	for k, v := range parentLabels.m {
+		childLabels.m[k] = v
 	}
 	for _, label := range labels.list {
-		childLabels[label.key] = label.value
+		childLabels.m[label.key] = label.value
 	}
+	childLabels.setHash()
 	return context.WithValue(ctx, labelContextKey{}, &childLabels)
 }
 
 // Labels takes an even number of strings representing key-value pairs
 // and makes a LabelSet containing them.
 // A label overwrites a prior label with the same key.
-// Currently only CPU profile utilizes labels information.
-// See https://golang.org/issue/23458 for details.
+// Labels are recorded in the CPU profile, in the goroutine profile,
+// and in the heap and allocs profiles, where an allocation carries the
+// labels of the goroutine making it. Since the heap profile keeps a
+// record for each distinct label set and allocation site for the life
+// of the program, labels whose values are unique per request, such as
+// request IDs, should not be set while allocating if MemProfileRate
+// is nonzero.
 func Labels(args ...string) LabelSet {
 	if len(args)%2 != 0 {
 		panic("uneven number of arguments to pprof.Labels")
@@ -71,7 +150,7 @@ func Labels(args ...string) LabelSet {
 // whether that label exists.
 func Label(ctx context.Context, key string) (string, bool) {
 	ctxLabels := labelValue(ctx)
-	v, ok := ctxLabels[key]
+	v, ok := ctxLabels.m[key]
 	return v, ok
 }
 
@@ -79,7 +158,7 @@ func Label(ctx context.Context, key string) (string, bool) {
 // The function f should return true to continue iteration or false to stop iteration early.
 func ForLabels(ctx context.Context, f func(key, value string) bool) {
 	ctxLabels := labelValue(ctx)
-	for k, v := range ctxLabels {
+	for k, v := range ctxLabels.m {
 		if !f(k, v) {
 			break
 		}
@@ -75,6 +75,7 @@ package pprof
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"runtime"
@@ -136,6 +137,10 @@ type Profile struct {
 	m     map[interface{}][]uintptr
 	count func() int
 	write func(io.Writer, int) error
+
+	// writeMatching, if not nil, writes the samples carrying
+	// the given labels, for WriteMatching.
+	writeMatching func(io.Writer, int, LabelSet) error
 }
 
 // profiles records all registered profiles.
@@ -145,9 +150,10 @@ var profiles struct {
 }
 
 var goroutineProfile = &Profile{
-	name:  "goroutine",
-	count: countGoroutine,
-	write: writeGoroutine,
+	name:          "goroutine",
+	count:         countGoroutine,
+	write:         writeGoroutine,
+	writeMatching: writeGoroutineMatching,
 }
 
 var threadcreateProfile = &Profile{
@@ -157,15 +163,17 @@ var threadcreateProfile = &Profile{
 }
 
 var heapProfile = &Profile{
-	name:  "heap",
-	count: countHeap,
-	write: writeHeap,
+	name:          "heap",
+	count:         countHeap,
+	write:         writeHeap,
+	writeMatching: writeHeapMatching,
 }
 
 var allocsProfile = &Profile{
-	name:  "allocs",
-	count: countHeap, // identical to heap profile
-	write: writeAlloc,
+	name:          "allocs",
+	count:         countHeap, // identical to heap profile
+	write:         writeAlloc,
+	writeMatching: writeAllocMatching,
 }
 
 var blockProfile = &Profile{
@@ -351,10 +359,25 @@ func (p *Profile) WriteTo(w io.Writer, debug int) error {
 	return printCountProfile(w, debug, p.name, stackProfile(all))
 }
 
+// WriteMatching is like WriteTo, but writes only the samples whose
+// profiler labels include every label in match. See Labels for the
+// profiles that record labels; WriteMatching returns an error for
+// the others. The goroutine profile cannot be filtered with debug=2.
+func (p *Profile) WriteMatching(w io.Writer, debug int, match LabelSet) error {
+	if p.name == "" {
+		panic("pprof: use of zero Profile")
+	}
+	if p.writeMatching == nil {
+		return fmt.Errorf("pprof: profile %s does not record labels", p.name)
+	}
+	return p.writeMatching(w, debug, match)
+}
+
 type stackProfile [][]uintptr
 
 func (x stackProfile) Len() int              { return len(x) }
 func (x stackProfile) Stack(i int) []uintptr { return x[i] }
+func (x stackProfile) Label(i int) *labelMap { return nil }
 
 // A countProfile is a set of stack traces to be printed as counts
 // grouped by stack trace. There are multiple implementations:
@@ -363,6 +386,7 @@ func (x stackProfile) Stack(i int) []uintptr { return x[i] }
 type countProfile interface {
 	Len() int
 	Stack(i int) []uintptr
+	Label(i int) *labelMap
 }
 
 // printCountCycleProfile outputs block profile records (for block or mutex profiles)
@@ -400,12 +424,16 @@ func printCountCycleProfile(w io.Writer, countName, cycleName string, scaler fun
 func printCountProfile(w io.Writer, debug int, name string, p countProfile) error {
 	// Build count of each stack.
 	var buf bytes.Buffer
-	key := func(stk []uintptr) string {
+	key := func(stk []uintptr, lbls *labelMap) string {
 		buf.Reset()
 		fmt.Fprintf(&buf, "@")
 		for _, pc := range stk {
 			fmt.Fprintf(&buf, " %#x", pc)
 		}
+		if lbls != nil && lbls.hash != 0 {
+			buf.WriteString("\n# labels: ")
+			buf.WriteString(lbls.String())
+		}
 		return buf.String()
 	}
 	count := map[string]int{}
@@ -413,7 +441,7 @@ func printCountProfile(w io.Writer, debug int, name string, p countProfile) erro
 	var keys []string
 	n := p.Len()
 	for i := 0; i < n; i++ {
-		k := key(p.Stack(i))
+		k := key(p.Stack(i), p.Label(i))
 		if count[k] == 0 {
 			index[k] = i
 			keys = append(keys, k)
@@ -447,7 +475,15 @@ func printCountProfile(w io.Writer, debug int, name string, p countProfile) erro
 		// For count profiles, all stack addresses are
 		// return PCs, which is what appendLocsForStack expects.
 		locs = b.appendLocsForStack(locs[:0], p.Stack(index[k]))
-		b.pbSample(values, locs, nil)
+		var labels func()
+		if lbls := p.Label(index[k]); lbls != nil {
+			labels = func() {
+				for k, v := range lbls.m {
+					b.pbLabel(tagSample_Label, k, v, 0)
+				}
+			}
+		}
+		b.pbSample(values, locs, labels)
 	}
 	b.build()
 	return nil
@@ -516,16 +552,30 @@ func countHeap() int {
 
 // writeHeap writes the current runtime heap profile to w.
 func writeHeap(w io.Writer, debug int) error {
-	return writeHeapInternal(w, debug, "")
+	return writeHeapInternal(w, debug, "", nil)
+}
+
+// writeHeapMatching writes the samples in the current runtime heap
+// profile carrying the labels in match to w.
+func writeHeapMatching(w io.Writer, debug int, match LabelSet) error {
+	return writeHeapInternal(w, debug, "", &match)
 }
 
 // writeAlloc writes the current runtime heap profile to w
 // with the total allocation space as the default sample type.
 func writeAlloc(w io.Writer, debug int) error {
-	return writeHeapInternal(w, debug, "alloc_space")
+	return writeHeapInternal(w, debug, "alloc_space", nil)
 }
 
-func writeHeapInternal(w io.Writer, debug int, defaultSampleType string) error {
+// writeAllocMatching is writeAlloc for the samples carrying
+// the labels in match.
+func writeAllocMatching(w io.Writer, debug int, match LabelSet) error {
+	return writeHeapInternal(w, debug, "alloc_space", &match)
+}
+
+// writeHeapInternal writes the heap profile to w. If match is not nil,
+// it writes only the samples carrying the labels in match.
+func writeHeapInternal(w io.Writer, debug int, defaultSampleType string, match *LabelSet) error {
 	var memStats *runtime.MemStats
 	if debug != 0 {
 		// Read mem stats first, so that our other allocations
@@ -541,25 +591,39 @@ func writeHeapInternal(w io.Writer, debug int, defaultSampleType string) error {
 	// and also try again if we're very unlucky.
 	// The loop should only execute one iteration in the common case.
 	var p []runtime.MemProfileRecord
-	n, ok := runtime.MemProfile(nil, true)
+	var labels []unsafe.Pointer
+	n, ok := runtime_memProfileWithLabels(nil, nil, true)
 	for {
 		// Allocate room for a slightly bigger profile,
 		// in case a few more entries have been added
 		// since the call to MemProfile.
 		p = make([]runtime.MemProfileRecord, n+50)
-		n, ok = runtime.MemProfile(p, true)
+		labels = make([]unsafe.Pointer, n+50)
+		n, ok = runtime_memProfileWithLabels(p, labels, true)
 		if ok {
 			p = p[0:n]
+			labels = labels[0:n]
 			break
 		}
 		// Profile grew; try again.
 	}
 
+	if match != nil {
+		j := 0
+		for i := range p {
+			if (*labelMap)(labels[i]).matches(*match) {
+				p[j], labels[j] = p[i], labels[i]
+				j++
+			}
+		}
+		p, labels = p[:j], labels[:j]
+	}
+
 	if debug == 0 {
-		return writeHeapProto(w, p, int64(runtime.MemProfileRate), defaultSampleType)
+		return writeHeapProto(w, p, labels, int64(runtime.MemProfileRate), defaultSampleType)
 	}
 
-	sort.Slice(p, func(i, j int) bool { return p[i].InUseBytes() > p[j].InUseBytes() })
+	sort.Sort(&heapRecords{p, labels})
 
 	b := bufio.NewWriter(w)
 	tw := tabwriter.NewWriter(b, 1, 8, 1, '\t', 0)
@@ -591,6 +655,9 @@ func writeHeapInternal(w io.Writer, debug int, defaultSampleType string) error {
 			fmt.Fprintf(w, " %#x", pc)
 		}
 		fmt.Fprintf(w, "\n")
+		if lbls := (*labelMap)(labels[i]); lbls != nil {
+			fmt.Fprintf(w, "# labels: %s\n", lbls)
+		}
 		printStackRecord(w, r.Stack(), false)
 	}
 
@@ -632,6 +699,20 @@ func writeHeapInternal(w io.Writer, debug int, defaultSampleType string) error {
 	return b.Flush()
 }
 
+// heapRecords sorts heap profile records, with their labels,
+// by decreasing in-use bytes.
+type heapRecords struct {
+	p      []runtime.MemProfileRecord
+	labels []unsafe.Pointer
+}
+
+func (x *heapRecords) Len() int           { return len(x.p) }
+func (x *heapRecords) Less(i, j int) bool { return x.p[i].InUseBytes() > x.p[j].InUseBytes() }
+func (x *heapRecords) Swap(i, j int) {
+	x.p[i], x.p[j] = x.p[j], x.p[i]
+	x.labels[i], x.labels[j] = x.labels[j], x.labels[i]
+}
+
 // countThreadCreate returns the size of the current ThreadCreateProfile.
 func countThreadCreate() int {
 	n, _ := runtime.ThreadCreateProfile(nil)
@@ -640,7 +721,12 @@ func countThreadCreate() int {
 
 // writeThreadCreate writes the current runtime ThreadCreateProfile to w.
 func writeThreadCreate(w io.Writer, debug int) error {
-	return writeRuntimeProfile(w, debug, "threadcreate", runtime.ThreadCreateProfile)
+	// Until https://golang.org/issues/6104 is addressed, wrap
+	// ThreadCreateProfile because there's no point in tracking labels when we
+	// don't get any stack-traces.
+	return writeRuntimeProfile(w, debug, "threadcreate", func(p []runtime.StackRecord, _ []unsafe.Pointer) (n int, ok bool) {
+		return runtime.ThreadCreateProfile(p)
+	}, nil)
 }
 
 // countGoroutine returns the number of goroutines.
@@ -653,7 +739,16 @@ func writeGoroutine(w io.Writer, debug int) error {
 	if debug >= 2 {
 		return writeGoroutineStacks(w)
 	}
-	return writeRuntimeProfile(w, debug, "goroutine", runtime.GoroutineProfile)
+	return writeRuntimeProfile(w, debug, "goroutine", runtime_goroutineProfileWithLabels, nil)
+}
+
+// writeGoroutineMatching writes the goroutines in the current runtime
+// GoroutineProfile carrying the labels in match to w.
+func writeGoroutineMatching(w io.Writer, debug int, match LabelSet) error {
+	if debug >= 2 {
+		return errors.New("pprof: goroutine profile cannot be filtered by label with debug=2")
+	}
+	return writeRuntimeProfile(w, debug, "goroutine", runtime_goroutineProfileWithLabels, &match)
 }
 
 func writeGoroutineStacks(w io.Writer) error {
@@ -677,7 +772,9 @@ func writeGoroutineStacks(w io.Writer) error {
 	return err
 }
 
-func writeRuntimeProfile(w io.Writer, debug int, name string, fetch func([]runtime.StackRecord) (int, bool)) error {
+// writeRuntimeProfile writes the profile returned by fetch to w.
+// If match is not nil, it writes only the samples carrying the labels in match.
+func writeRuntimeProfile(w io.Writer, debug int, name string, fetch func([]runtime.StackRecord, []unsafe.Pointer) (int, bool), match *LabelSet) error {
 	// Find out how many records there are (fetch(nil)),
 	// allocate that many records, and get the data.
 	// There's a race—more records might be added between
@@ -685,27 +782,45 @@ func writeRuntimeProfile(w io.Writer, debug int, name string, fetch func([]runti
 	// and also try again if we're very unlucky.
 	// The loop should only execute one iteration in the common case.
 	var p []runtime.StackRecord
-	n, ok := fetch(nil)
+	var labels []unsafe.Pointer
+	n, ok := fetch(nil, nil)
 	for {
 		// Allocate room for a slightly bigger profile,
 		// in case a few more entries have been added
 		// since the call to ThreadProfile.
 		p = make([]runtime.StackRecord, n+10)
-		n, ok = fetch(p)
+		labels = make([]unsafe.Pointer, n+10)
+		n, ok = fetch(p, labels)
 		if ok {
 			p = p[0:n]
+			labels = labels[0:n]
 			break
 		}
 		// Profile grew; try again.
 	}
 
-	return printCountProfile(w, debug, name, runtimeProfile(p))
+	if match != nil {
+		j := 0
+		for i := range p {
+			if (*labelMap)(labels[i]).matches(*match) {
+				p[j], labels[j] = p[i], labels[i]
+				j++
+			}
+		}
+		p, labels = p[:j], labels[:j]
+	}
+
+	return printCountProfile(w, debug, name, &runtimeProfile{p, labels})
 }
 
-type runtimeProfile []runtime.StackRecord
+type runtimeProfile struct {
+	stk    []runtime.StackRecord
+	labels []unsafe.Pointer
+}
 
-func (p runtimeProfile) Len() int              { return len(p) }
-func (p runtimeProfile) Stack(i int) []uintptr { return p[i].Stack() }
+func (p *runtimeProfile) Len() int              { return len(p.stk) }
+func (p *runtimeProfile) Stack(i int) []uintptr { return p.stk[i].Stack() }
+func (p *runtimeProfile) Label(i int) *labelMap { return (*labelMap)(p.labels[i]) }
 
 var cpu struct {
 	sync.Mutex
@@ -717,6 +832,9 @@ var cpu struct {
 // While profiling, the profile will be buffered and written to w.
 // StartCPUProfile returns an error if profiling is already enabled.
 //
+// StartCPUProfile samples at 100 Hz. To choose another rate, use
+// StartCPUProfileWithOptions.
+//
 // On Unix-like systems, StartCPUProfile does not work by default for
 // Go code built with -buildmode=c-archive or -buildmode=c-shared.
 // StartCPUProfile relies on the SIGPROF signal, but that signal will
@@ -725,16 +843,40 @@ var cpu struct {
 // for syscall.SIGPROF, but note that doing so may break any profiling
 // being done by the main program.
 func StartCPUProfile(w io.Writer) error {
-	// The runtime routines allow a variable profiling rate,
-	// but in practice operating systems cannot trigger signals
-	// at more than about 500 Hz, and our processing of the
-	// signal is not cheap (mostly getting the stack trace).
-	// 100 Hz is a reasonable choice: it is frequent enough to
-	// produce useful data, rare enough not to bog down the
-	// system, and a nice round number to make it easy to
-	// convert sample counts to seconds. Instead of requiring
-	// each client to specify the frequency, we hard code it.
-	const hz = 100
+	return StartCPUProfileWithOptions(w, CPUProfileOptions{})
+}
+
+// CPUProfileOptions holds the options of a CPU profile.
+type CPUProfileOptions struct {
+	// Rate is the number of samples to take per second of CPU time.
+	// Zero means the default, 100 Hz: it is frequent enough to
+	// produce useful data, rare enough not to bog down the system,
+	// and a nice round number to make it easy to convert sample
+	// counts to seconds. Higher rates are useful for short-lived
+	// programs and for finer-grained profiles, at a cost in overhead
+	// proportional to the rate.
+	//
+	// On Linux, each thread is sampled by a timer that measures its
+	// own CPU time, so rates of several hundred Hz and more are
+	// accurate, even with many busy threads. Other systems use a
+	// single process-wide timer, which cannot trigger signals at
+	// more than about 500 Hz and may favor some threads over others.
+	Rate int
+}
+
+// defaultCPUProfileRate is the rate used when CPUProfileOptions.Rate is zero.
+const defaultCPUProfileRate = 100
+
+// StartCPUProfileWithOptions is like StartCPUProfile, but profiles
+// according to opts. It returns an error if opts.Rate is negative.
+func StartCPUProfileWithOptions(w io.Writer, opts CPUProfileOptions) error {
+	hz := opts.Rate
+	if hz < 0 {
+		return fmt.Errorf("invalid CPU profile rate %d", hz)
+	}
+	if hz == 0 {
+		hz = defaultCPUProfileRate
+	}
 
 	cpu.Lock()
 	defer cpu.Unlock()
@@ -0,0 +1,67 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pprof
+
+import (
+	"bytes"
+	"internal/profile"
+	"runtime"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestCPUProfileMultithreadMagnitude checks that a profile of several
+// busy threads, at a rate above the kernel's tick rate, accounts for
+// the CPU time the kernel reports for the process. The process-wide
+// profiling timer misses much of it when many threads are busy.
+func TestCPUProfileMultithreadMagnitude(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping in short mode")
+	}
+	const n = 4
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(n))
+
+	var before, after syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &before); err != nil {
+		t.Fatal(err)
+	}
+	var prof bytes.Buffer
+	if err := StartCPUProfileWithOptions(&prof, CPUProfileOptions{Rate: 1000}); err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var salt int
+			cpuHogger(cpuHog1, &salt, 500*time.Millisecond)
+		}()
+	}
+	wg.Wait()
+	// Stopping the profile takes CPU time to write it out.
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &after); err != nil {
+		t.Fatal(err)
+	}
+	StopCPUProfile()
+
+	p, err := profile.Parse(&prof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var profiled time.Duration
+	for _, s := range p.Sample {
+		profiled += time.Duration(s.Value[1])
+	}
+	used := time.Duration(after.Utime.Nano() + after.Stime.Nano() - before.Utime.Nano() - before.Stime.Nano())
+	t.Logf("%d threads used %v of CPU time; the profile accounts for %v", n, used, profiled)
+	// The profile misses the CPU time spent before it started and
+	// after it stopped, and is subject to sampling error.
+	if profiled < used*85/100 || profiled > used*110/100 {
+		t.Errorf("profile accounts for %v of CPU time, want about %v", profiled, used)
+	}
+}
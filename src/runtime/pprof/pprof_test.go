@@ -10,6 +10,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"internal/profile"
 	"internal/testenv"
 	"io"
 	"io/ioutil"
@@ -18,7 +19,6 @@ import (
 	"os/exec"
 	"regexp"
 	"runtime"
-	"runtime/pprof/internal/profile"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -104,6 +104,27 @@ func TestCPUProfileMultithreaded(t *testing.T) {
 	})
 }
 
+func TestCPUProfileRate(t *testing.T) {
+	var prof bytes.Buffer
+	if err := StartCPUProfileWithOptions(&prof, CPUProfileOptions{Rate: 500}); err != nil {
+		t.Fatal(err)
+	}
+	cpuHogger(cpuHog1, &salt1, 100*time.Millisecond)
+	StopCPUProfile()
+	p, err := profile.Parse(&prof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := int64(time.Second / 500); p.Period != want {
+		t.Errorf("profile has period %d, want %d", p.Period, want)
+	}
+
+	if err := StartCPUProfileWithOptions(ioutil.Discard, CPUProfileOptions{Rate: -1}); err == nil {
+		StopCPUProfile()
+		t.Errorf("StartCPUProfileWithOptions succeeded with rate -1")
+	}
+}
+
 // containsInlinedCall reports whether the function body for the function f is
 // known to contain an inlined function call within the first maxBytes bytes.
 func containsInlinedCall(f interface{}, maxBytes int) bool {
@@ -971,6 +992,117 @@ func TestGoroutineCounts(t *testing.T) {
 	time.Sleep(10 * time.Millisecond) // let goroutines exit
 }
 
+func TestGoroutineProfileLabels(t *testing.T) {
+	c := make(chan int)
+	started := make(chan bool)
+	for _, v := range []string{"a", "b"} {
+		for i := 0; i < 3; i++ {
+			go Do(context.Background(), Labels("label", v), func(context.Context) {
+				started <- true
+				func4(c)
+			})
+			<-started
+		}
+	}
+	defer close(c)
+
+	var w bytes.Buffer
+	goroutineProf := Lookup("goroutine")
+
+	// Check debug profile
+	goroutineProf.WriteTo(&w, 1)
+	prof := w.String()
+	if !strings.Contains(prof, "\n# labels: {\"label\":\"a\"}\n") {
+		t.Errorf("goroutine profile does not contain labels:\n%s", prof)
+	}
+
+	// Check proto profile
+	w.Reset()
+	goroutineProf.WriteTo(&w, 0)
+	p, err := profile.Parse(&w)
+	if err != nil {
+		t.Fatalf("error parsing protobuf profile: %v", err)
+	}
+	if err := p.CheckValid(); err != nil {
+		t.Fatalf("protobuf profile is invalid: %v", err)
+	}
+	counts := map[string]int64{}
+	for _, s := range p.Sample {
+		for _, v := range s.Label["label"] {
+			counts[v] += s.Value[0]
+		}
+	}
+	if counts["a"] != 3 || counts["b"] != 3 {
+		t.Errorf("got goroutine counts by label %v, want 3 each for a and b", counts)
+	}
+
+	// Check filtered profile
+	w.Reset()
+	if err := goroutineProf.WriteMatching(&w, 1, Labels("label", "b")); err != nil {
+		t.Fatalf("WriteMatching: %v", err)
+	}
+	prof = w.String()
+	if !strings.HasPrefix(prof, "goroutine profile: total 3\n") || strings.Contains(prof, `"a"`) {
+		t.Errorf("filtered goroutine profile includes other goroutines:\n%s", prof)
+	}
+	if err := goroutineProf.WriteMatching(&w, 2, Labels("label", "b")); err == nil {
+		t.Errorf("WriteMatching with debug=2 succeeded, want error")
+	}
+	if err := Lookup("threadcreate").WriteMatching(&w, 1, Labels("label", "b")); err == nil {
+		t.Errorf("WriteMatching on threadcreate profile succeeded, want error")
+	}
+}
+
+var heapProfileLabelsSink [][]byte
+
+func TestHeapProfileLabels(t *testing.T) {
+	defer func(old int) { runtime.MemProfileRate = old }(runtime.MemProfileRate)
+	runtime.MemProfileRate = 1
+	Do(context.Background(), Labels("heap", "labeled"), func(context.Context) {
+		for i := 0; i < 100; i++ {
+			heapProfileLabelsSink = append(heapProfileLabelsSink, make([]byte, 1024))
+		}
+	})
+	defer func() { heapProfileLabelsSink = nil }()
+	// The profile reflects allocations as of the most recently completed GC.
+	runtime.GC()
+	runtime.GC()
+
+	var w bytes.Buffer
+	if err := Lookup("heap").WriteTo(&w, 0); err != nil {
+		t.Fatalf("writing heap profile: %v", err)
+	}
+	p, err := profile.Parse(&w)
+	if err != nil {
+		t.Fatalf("error parsing protobuf profile: %v", err)
+	}
+	if err := p.CheckValid(); err != nil {
+		t.Fatalf("protobuf profile is invalid: %v", err)
+	}
+	labeled := 0
+	for _, s := range p.Sample {
+		if contains(s.Label["heap"], "labeled") {
+			labeled++
+		}
+	}
+	if labeled == 0 {
+		t.Errorf("heap profile has no samples with labels:\n%s", p)
+	}
+
+	// Check filtered profile
+	w.Reset()
+	if err := Lookup("allocs").WriteMatching(&w, 1, Labels("heap", "labeled")); err != nil {
+		t.Fatalf("WriteMatching: %v", err)
+	}
+	prof := w.String()
+	if !strings.Contains(prof, "\n# labels: {\"heap\":\"labeled\"}\n") {
+		t.Errorf("filtered heap profile does not contain labels:\n%s", prof)
+	}
+	if records, labels := strings.Count(prof, "] @ 0x"), strings.Count(prof, "\n# labels: "); records != labels {
+		t.Errorf("filtered heap profile has %d records but %d labels:\n%s", records, labels, prof)
+	}
+}
+
 func containsInOrder(s string, all ...string) bool {
 	for _, t := range all {
 		i := strings.Index(s, t)
@@ -9,10 +9,12 @@ import (
 	"math"
 	"runtime"
 	"strings"
+	"unsafe"
 )
 
 // writeHeapProto writes the current heap profile in protobuf format to w.
-func writeHeapProto(w io.Writer, p []runtime.MemProfileRecord, rate int64, defaultSampleType string) error {
+// labels[i], if not nil, is the *labelMap of the allocations in p[i].
+func writeHeapProto(w io.Writer, p []runtime.MemProfileRecord, labels []unsafe.Pointer, rate int64, defaultSampleType string) error {
 	b := newProfileBuilder(w)
 	b.pbValueType(tagProfile_PeriodType, "space", "bytes")
 	b.pb.int64Opt(tagProfile_Period, rate)
@@ -26,7 +28,7 @@ func writeHeapProto(w io.Writer, p []runtime.MemProfileRecord, rate int64, defau
 
 	values := []int64{0, 0, 0, 0}
 	var locs []uint64
-	for _, r := range p {
+	for i, r := range p {
 		hideRuntime := true
 		for tries := 0; tries < 2; tries++ {
 			stk := r.Stack()
@@ -56,10 +58,16 @@ func writeHeapProto(w io.Writer, p []runtime.MemProfileRecord, rate int64, defau
 		if r.AllocObjects > 0 {
 			blockSize = r.AllocBytes / r.AllocObjects
 		}
+		lbls := (*labelMap)(labels[i])
 		b.pbSample(values, locs, func() {
 			if blockSize != 0 {
 				b.pbLabel(tagSample_Label, "bytes", "", blockSize)
 			}
+			if lbls != nil {
+				for k, v := range lbls.m {
+					b.pbLabel(tagSample_Label, k, v, 0)
+				}
+			}
 		})
 	}
 	b.build()
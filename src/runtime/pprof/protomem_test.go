@@ -6,9 +6,10 @@ package pprof
 
 import (
 	"bytes"
+	"internal/profile"
 	"runtime"
-	"runtime/pprof/internal/profile"
 	"testing"
+	"unsafe"
 )
 
 func TestConvertMemProfile(t *testing.T) {
@@ -69,7 +70,7 @@ func TestConvertMemProfile(t *testing.T) {
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			var buf bytes.Buffer
-			if err := writeHeapProto(&buf, rec, rate, tc.defaultSampleType); err != nil {
+			if err := writeHeapProto(&buf, rec, make([]unsafe.Pointer, len(rec)), rate, tc.defaultSampleType); err != nil {
 				t.Fatalf("writing profile: %v", err)
 			}
 
@@ -6,6 +6,7 @@ package pprof
 
 import (
 	"context"
+	"runtime"
 	"unsafe"
 )
 
@@ -18,6 +19,12 @@ func runtime_setProfLabel(labels unsafe.Pointer)
 // runtime_getProfLabel is defined in runtime/proflabel.go.
 func runtime_getProfLabel() unsafe.Pointer
 
+// runtime_goroutineProfileWithLabels is defined in runtime/mprof.go.
+func runtime_goroutineProfileWithLabels(p []runtime.StackRecord, labels []unsafe.Pointer) (n int, ok bool)
+
+// runtime_memProfileWithLabels is defined in runtime/mprof.go.
+func runtime_memProfileWithLabels(p []runtime.MemProfileRecord, labels []unsafe.Pointer, inuseZero bool) (n int, ok bool)
+
 // SetGoroutineLabels sets the current goroutine's labels to match ctx.
 // A new goroutine inherits the labels of the goroutine that created it.
 // This is a lower-level API than Do, which should be used instead when possible.
@@ -240,6 +240,9 @@ func os_beforeExit() {
 // start forcegc helper goroutine
 func init() {
 	go forcegchelper()
+	if maxprocsfollowslimit() {
+		go maxprocshelper()
+	}
 }
 
 func forcegchelper() {
@@ -260,6 +263,76 @@ func forcegchelper() {
 	}
 }
 
+// defaultGOMAXPROCS returns the GOMAXPROCS setting the program runs
+// with unless told otherwise: the number of CPUs it may run on, or the
+// number its cgroup CPU limit cpulimit allows, if that is fewer.
+func defaultGOMAXPROCS(cpulimit int32) int32 {
+	procs := ncpu
+	if cpulimit > 0 && cpulimit < procs {
+		procs = cpulimit
+	}
+	return procs
+}
+
+// maxprocsinit returns the GOMAXPROCS setting the program starts with,
+// given the GOMAXPROCS environment variable env, and records the cgroup
+// CPU limit and whether env overrides it in maxprocs.
+func maxprocsinit(env string) int32 {
+	procs := ncpu
+	if debug.cgroupmaxprocs > 0 {
+		cgroupinit()
+		maxprocs.cpulimit = cgroupCPULimit()
+		procs = defaultGOMAXPROCS(maxprocs.cpulimit)
+	}
+	if n, ok := atoi32(env); ok && n > 0 {
+		procs = n
+		maxprocs.custom = 1
+	}
+	return procs
+}
+
+// maxprocsfollowslimit reports whether GOMAXPROCS follows changes
+// to the cgroup CPU limit, and so whether maxprocshelper runs.
+func maxprocsfollowslimit() bool {
+	return debug.cgroupmaxprocs > 0 && debug.updatemaxprocs > 0 && atomic.Load(&maxprocs.custom) == 0
+}
+
+// maxprocsupdate returns the GOMAXPROCS setting that replaces procs
+// once sysmon has found the cgroup CPU limit in maxprocs.cpulimit.
+// runtime.GOMAXPROCS may have been called since sysmon woke
+// maxprocshelper, and then takes precedence.
+func maxprocsupdate(procs int32) int32 {
+	if atomic.Load(&maxprocs.custom) != 0 {
+		return procs
+	}
+	return defaultGOMAXPROCS(maxprocs.cpulimit)
+}
+
+// maxprocshelper resets GOMAXPROCS to its default when sysmon finds
+// that the cgroup CPU limit has changed. It only runs with
+// GODEBUG=updatemaxprocs=1.
+func maxprocshelper() {
+	maxprocs.g = getg()
+	for {
+		lock(&maxprocs.lock)
+		if maxprocs.idle != 0 {
+			throw("maxprocs: phase error")
+		}
+		atomic.Store(&maxprocs.idle, 1)
+		goparkunlock(&maxprocs.lock, waitReasonMaxprocsIdle, traceEvGoBlock, 1)
+		// this goroutine is explicitly resumed by sysmon
+		stopTheWorld("GOMAXPROCS")
+		if procs := maxprocsupdate(gomaxprocs); procs != gomaxprocs {
+			if debug.schedtrace > 0 {
+				println("runtime: cgroup CPU limit changed, setting GOMAXPROCS from", gomaxprocs, "to", procs)
+			}
+			// newprocs will be processed by startTheWorld
+			newprocs = procs
+		}
+		startTheWorld()
+	}
+}
+
 //go:nosplit
 
 // Gosched yields the processor, allowing other goroutines to run. It does not
@@ -560,10 +633,7 @@ func schedinit() {
 	gcinit()
 
 	sched.lastpoll = uint64(nanotime())
-	procs := ncpu
-	if n, ok := atoi32(gogetenv("GOMAXPROCS")); ok && n > 0 {
-		procs = n
-	}
+	procs := maxprocsinit(gogetenv("GOMAXPROCS"))
 	if procresize(procs) != nil {
 		throw("unknown runnable goroutine during bootstrap")
 	}
@@ -814,6 +884,21 @@ func casgstatus(gp *g, oldval, newval uint32) {
 			nextYield = nanotime() + yieldDelay/2
 		}
 	}
+
+	// Sample the time every gTrackingPeriod'th runnable G
+	// waits to run, for the /sched/latencies metric.
+	switch {
+	case newval == _Grunnable:
+		gp.trackingSeq++
+		if gp.trackingSeq%gTrackingPeriod == 0 {
+			gp.runnableStamp = nanotime()
+		}
+	case oldval == _Grunnable && gp.runnableStamp != 0:
+		if newval == _Grunning {
+			schedLatencyDist.record(nanotime() - gp.runnableStamp)
+		}
+		gp.runnableStamp = 0
+	}
 }
 
 // casgstatus(gp, oldstatus, Gcopystack), assuming oldstatus is Gwaiting or Grunnable.
@@ -1523,6 +1608,11 @@ func needm(x byte) {
 	asminit()
 	minit()
 
+	// Profile this thread, which does not go through execute.
+	if hz := sched.profilehz; hz != 0 {
+		setThreadCPUProfiler(hz)
+	}
+
 	// mp.curg is now a real goroutine.
 	casgstatus(mp.curg, _Gdead, _Gsyscall)
 	atomic.Xadd(&sched.ngsys, -1)
@@ -2550,6 +2640,16 @@ top:
 			tryWakeP = true
 		}
 	}
+	if gp == nil {
+		gp = eventsReader()
+		if gp != nil {
+			casgstatus(gp, _Gwaiting, _Grunnable)
+			if trace.enabled {
+				traceGoUnpark(gp, 0)
+			}
+			tryWakeP = true
+		}
+	}
 	if gp == nil && gcBlackenEnabled != 0 {
 		gp = gcController.findRunnableGCWorker(_g_.m.p.ptr())
 		tryWakeP = tryWakeP || gp != nil
@@ -3824,14 +3924,23 @@ func _GC()                        { _GC() }
 func _LostSIGPROFDuringAtomic64() { _LostSIGPROFDuringAtomic64() }
 func _VDSO()                      { _VDSO() }
 
-// Called if we receive a SIGPROF signal.
+// Called if we receive a SIGPROF signal. The signal stands for the given
+// number of samples.
 // Called by the signal handler, may run during STW.
 //go:nowritebarrierrec
-func sigprof(pc, sp, lr uintptr, gp *g, mp *m) {
+func sigprof(pc, sp, lr uintptr, gp *g, mp *m, samples uint64) {
 	if prof.hz == 0 {
 		return
 	}
 
+	// If mp.profilehz is 0, profiling is not enabled for this
+	// thread. setcpuprofilerate disables it before it takes
+	// prof.signalLock, so recording a sample here, which takes
+	// the same lock, could deadlock.
+	if mp != nil && mp.profilehz == 0 {
+		return
+	}
+
 	// On mips{,le}, 64bit atomics are emulated with spinlocks, in
 	// runtime/internal/atomic. If SIGPROF arrives while the program is inside
 	// the critical section, it creates a deadlock (when writing the sample).
@@ -3841,7 +3950,7 @@ func sigprof(pc, sp, lr uintptr, gp *g, mp *m) {
 	if GOARCH == "mips" || GOARCH == "mipsle" || GOARCH == "arm" {
 		if f := findfunc(pc); f.valid() {
 			if hasPrefix(funcname(f), "runtime/internal/atomic") {
-				cpuprof.lostAtomic++
+				cpuprof.lostAtomic += samples
 				return
 			}
 		}
@@ -3981,7 +4090,7 @@ func sigprof(pc, sp, lr uintptr, gp *g, mp *m) {
 	}
 
 	if prof.hz != 0 {
-		cpuprof.add(gp, stk[:n])
+		cpuprof.add(gp, stk[:n], samples)
 	}
 	getg().m.mallocing--
 }
@@ -4515,6 +4624,10 @@ func checkdead() {
 // This is a variable for testing purposes. It normally doesn't change.
 var forcegcperiod int64 = 2 * 60 * 1e9
 
+// maxprocsperiod is how often, in nanoseconds, sysmon reads the cgroup
+// CPU limit again with GODEBUG=updatemaxprocs=1.
+const maxprocsperiod = 1e9
+
 // Always runs without a P, so write barriers are not allowed.
 //
 //go:nowritebarrierrec
@@ -4525,6 +4638,8 @@ func sysmon() {
 	unlock(&sched.lock)
 
 	lasttrace := int64(0)
+	lastmaxprocs := nanotime()
+	lastspikes := int64(0)
 	idle := 0 // how many cycles in succession we had not wokeup somebody
 	delay := uint32(0)
 	for {
@@ -4551,6 +4666,10 @@ func sysmon() {
 					if next-now < sleep {
 						sleep = next - now
 					}
+					if atomic.Load(&runtimeEvents.enabled) != 0 && sleep > eventSpikePeriod {
+						// Keep checking for spikes.
+						sleep = eventSpikePeriod
+					}
 					shouldRelax := sleep >= osRelaxMinNS
 					if shouldRelax {
 						osRelax(true)
@@ -4614,6 +4733,30 @@ func sysmon() {
 			injectglist(&list)
 			unlock(&forcegc.lock)
 		}
+		// check if the cgroup CPU limit has changed
+		if lastmaxprocs+maxprocsperiod <= now && atomic.Load(&maxprocs.idle) != 0 && atomic.Load(&maxprocs.custom) == 0 {
+			lastmaxprocs = now
+			if n := cgroupCPULimit(); n != maxprocs.cpulimit {
+				lock(&maxprocs.lock)
+				maxprocs.cpulimit = n
+				maxprocs.idle = 0
+				var list gList
+				list.push(maxprocs.g)
+				injectglist(&list)
+				unlock(&maxprocs.lock)
+			}
+		}
+		// report spikes in the number of threads and goroutines
+		if lastspikes+eventSpikePeriod <= now {
+			lastspikes = now
+			checkSpikes(now)
+		}
+		// wake the runtime events reader if no P has done so
+		if gp := eventsReader(); gp != nil {
+			var list gList
+			list.push(gp)
+			injectglist(&list)
+		}
 		if debug.schedtrace > 0 && lasttrace+int64(debug.schedtrace)*1000000 <= now {
 			lasttrace = now
 			schedtrace(debug.scheddetail > 0)
@@ -4765,7 +4908,11 @@ func schedtrace(detailed bool) {
 	}
 
 	lock(&sched.lock)
-	print("SCHED ", (now-starttime)/1e6, "ms: gomaxprocs=", gomaxprocs, " idleprocs=", sched.npidle, " threads=", mcount(), " spinningthreads=", sched.nmspinning, " idlethreads=", sched.nmidle, " runqueue=", sched.runqsize)
+	print("SCHED ", (now-starttime)/1e6, "ms: gomaxprocs=", gomaxprocs)
+	if maxprocs.cpulimit > 0 {
+		print(" cpulimit=", maxprocs.cpulimit)
+	}
+	print(" idleprocs=", sched.npidle, " threads=", mcount(), " spinningthreads=", sched.nmspinning, " idlethreads=", sched.nmidle, " runqueue=", sched.runqsize)
 	if detailed {
 		print(" gcwaiting=", sched.gcwaiting, " nmidlelocked=", sched.nmidlelocked, " stopwait=", sched.stopwait, " sysmonwait=", sched.sysmonwait, "\n")
 	}
@@ -317,6 +317,9 @@ var debug struct {
 	schedtrace         int32
 	tracebackancestors int32
 	asyncpreemptoff    int32
+	tracefpunwindoff   int32
+	cgroupmaxprocs     int32
+	updatemaxprocs     int32
 }
 
 var dbgvars = []dbgVar{
@@ -338,12 +341,16 @@ var dbgvars = []dbgVar{
 	{"schedtrace", &debug.schedtrace},
 	{"tracebackancestors", &debug.tracebackancestors},
 	{"asyncpreemptoff", &debug.asyncpreemptoff},
+	{"tracefpunwindoff", &debug.tracefpunwindoff},
+	{"cgroupmaxprocs", &debug.cgroupmaxprocs},
+	{"updatemaxprocs", &debug.updatemaxprocs},
 }
 
 func parsedebugvars() {
 	// defaults
 	debug.cgocheck = 1
 	debug.invalidptr = 1
+	debug.cgroupmaxprocs = 1
 
 	for p := gogetenv("GODEBUG"); p != ""; {
 		field := ""
@@ -462,6 +462,10 @@ type g struct {
 	timer          *timer         // cached timer for time.Sleep
 	selectDone     uint32         // are we participating in a select and did someone win the race?
 
+	// Scheduling latency sampling for runtime/metrics. See casgstatus.
+	trackingSeq   uint8 // transitions to runnable, to pick which to sample
+	runnableStamp int64 // nanotime when this G became runnable, if sampled; else 0
+
 	// Per-G GC state
 
 	// gcAssistBytes is this G's GC assist credit in terms of
@@ -525,6 +529,7 @@ type m struct {
 	waittraceev   byte
 	waittraceskip int
 	startingtrace bool
+	profLabelRef  bool // allocating a profLabelRef; don't record labels
 	syscalltick   uint32
 	freelink      *m // on sched.freem
 
@@ -607,7 +612,8 @@ type p struct {
 		buf [128]*mspan
 	}
 
-	tracebuf traceBufPtr
+	tracebuf        traceBufPtr
+	tracestackcache [traceStackCacheSize]traceStackPtr // recently used stacks in trace.stackTab
 
 	// traceSweep indicates the sweep events should be traced.
 	// This is used to defer the sweep start event until a span
@@ -829,6 +835,21 @@ type forcegcstate struct {
 	idle uint32
 }
 
+type maxprocsstate struct {
+	lock mutex
+	g    *g
+	idle uint32
+
+	// cpulimit is the number of CPUs the cgroup CPU limit of the
+	// process allows, as returned by cgroupCPULimit, or 0 if there
+	// is no limit.
+	cpulimit int32
+
+	// custom is set once GOMAXPROCS is set by the environment or by
+	// runtime.GOMAXPROCS, which then no longer follows the limit.
+	custom uint32
+}
+
 // startup_random_data holds random bytes initialized at startup. These come from
 // the ELF AT_RANDOM auxiliary vector (vdso_linux_amd64.go or os_linux_386.go).
 var startupRandomData []byte
@@ -972,6 +993,8 @@ const (
 	waitReasonWaitForGCCycle                          // "wait for GC cycle"
 	waitReasonGCWorkerIdle                            // "GC worker (idle)"
 	waitReasonPreempted                               // "preempted"
+	waitReasonMaxprocsIdle                            // "GOMAXPROCS updater (idle)"
+	waitReasonEventsIdle                              // "runtime events reader (idle)"
 )
 
 var waitReasonStrings = [...]string{
@@ -1001,6 +1024,8 @@ var waitReasonStrings = [...]string{
 	waitReasonWaitForGCCycle:        "wait for GC cycle",
 	waitReasonGCWorkerIdle:          "GC worker (idle)",
 	waitReasonPreempted:             "preempted",
+	waitReasonMaxprocsIdle:          "GOMAXPROCS updater (idle)",
+	waitReasonEventsIdle:            "runtime events reader (idle)",
 }
 
 func (w waitReason) String() string {
@@ -1018,6 +1043,7 @@ var (
 	gomaxprocs int32
 	ncpu       int32
 	forcegc    forcegcstate
+	maxprocs   maxprocsstate
 	sched      schedt
 	newprocs   int32
 
@@ -261,18 +261,27 @@ func clearSignalHandlers() {
 	}
 }
 
-// setProcessCPUProfiler is called when the profiling timer changes.
-// It is called with prof.lock held. hz is the new timer, and is 0 if
+// setProcessCPUProfilerTimer is called when the profiling timer changes.
+// It is called with prof.signalLock held. hz is the new timer, and is 0 if
 // profiling is being disabled. Enable or disable the signal as
-// required for -buildmode=c-archive.
-func setProcessCPUProfiler(hz int32) {
+// required for -buildmode=c-archive, and set the process-wide
+// profiling timer.
+func setProcessCPUProfilerTimer(hz int32) {
 	if hz != 0 {
 		// Enable the Go signal handler if not enabled.
 		if atomic.Cas(&handlingSig[_SIGPROF], 0, 1) {
 			atomic.Storeuintptr(&fwdSig[_SIGPROF], getsig(_SIGPROF))
 			setsig(_SIGPROF, funcPC(sighandler))
 		}
+
+		var it itimerval
+		it.it_interval.tv_sec = 0
+		it.it_interval.set_usec(1000000 / hz)
+		it.it_value = it.it_interval
+		setitimer(_ITIMER_PROF, &it, nil)
 	} else {
+		setitimer(_ITIMER_PROF, &itimerval{}, nil)
+
 		// If the Go signal handler should be disabled by default,
 		// switch back to the signal handler that was installed
 		// when we enabled profiling. We don't try to handle the case
@@ -299,20 +308,10 @@ func setProcessCPUProfiler(hz int32) {
 	}
 }
 
-// setThreadCPUProfiler makes any thread-specific changes required to
-// implement profiling at a rate of hz.
-func setThreadCPUProfiler(hz int32) {
-	var it itimerval
-	if hz == 0 {
-		setitimer(_ITIMER_PROF, &it, nil)
-	} else {
-		it.it_interval.tv_sec = 0
-		it.it_interval.set_usec(1000000 / hz)
-		it.it_value = it.it_interval
-		setitimer(_ITIMER_PROF, &it, nil)
-	}
-	_g_ := getg()
-	_g_.m.profilehz = hz
+// setThreadCPUProfilerHz records that this thread profiles at a rate
+// of hz. The process-wide timer needs no per-thread changes.
+func setThreadCPUProfilerHz(hz int32) {
+	getg().m.profilehz = hz
 }
 
 func sigpipe() {
@@ -436,7 +435,9 @@ func sigtrampgo(sig uint32, info *siginfo, ctx unsafe.Pointer) {
 	setg(g)
 	if g == nil {
 		if sig == _SIGPROF {
-			sigprofNonGoPC(c.sigpc())
+			if sigprofSamples(nil, c) != 0 {
+				sigprofNonGoPC(c.sigpc())
+			}
 			return
 		}
 		if sig == sigPreempt && preemptMSupported && debug.asyncpreemptoff == 0 {
@@ -549,7 +550,12 @@ func sighandler(sig uint32, info *siginfo, ctxt unsafe.Pointer, gp *g) {
 	c := &sigctxt{info, ctxt}
 
 	if sig == _SIGPROF {
-		sigprof(c.sigpc(), c.sigsp(), c.siglr(), gp, _g_.m)
+		// Some platforms have per-thread timers, which are used
+		// together with the process-wide timer. Avoid counting
+		// the same CPU time twice.
+		if n := sigprofSamples(_g_.m, c); n != 0 {
+			sigprof(c.sigpc(), c.sigsp(), c.siglr(), gp, _g_.m, n)
+		}
 		return
 	}
 
@@ -0,0 +1,29 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build aix darwin dragonfly freebsd netbsd openbsd solaris
+
+package runtime
+
+// setProcessCPUProfiler is called when the profiling timer changes.
+// It is called with prof.signalLock held. hz is the new timer, and is 0 if
+// profiling is being disabled.
+func setProcessCPUProfiler(hz int32) {
+	setProcessCPUProfilerTimer(hz)
+}
+
+// setThreadCPUProfiler makes any thread-specific changes required to
+// implement profiling at a rate of hz.
+func setThreadCPUProfiler(hz int32) {
+	setThreadCPUProfilerHz(hz)
+}
+
+// sigprofSamples returns the number of profiling samples a SIGPROF
+// signal received by mp stands for, or 0 if it should be ignored.
+// Without per-thread timers, every signal is one sample.
+//
+//go:nosplit
+func sigprofSamples(mp *m, c *sigctxt) uint64 {
+	return 1
+}
@@ -750,6 +750,39 @@ func TestTracebackSystemstack(t *testing.T) {
 	}
 }
 
+func traceStacksInlined(system bool) (fp, gen []uintptr) {
+	return TraceStacks(system)
+}
+
+func TestTraceFramePointerUnwind(t *testing.T) {
+	if !TraceFPUnwind() {
+		t.Skip("execution tracer does not unwind with frame pointers")
+	}
+	for _, system := range []bool{false, true} {
+		// Go through an inlined call, which the unwinder must expand.
+		fp, gen := traceStacksInlined(system)
+		if !reflect.DeepEqual(fp, gen) {
+			t.Errorf("system=%v: frame pointer unwinding found stack:%s\nwant:%s", system, formatPCs(fp), formatPCs(gen))
+		}
+	}
+}
+
+func formatPCs(pcs []uintptr) string {
+	var b bytes.Buffer
+	if len(pcs) == 0 {
+		return " none"
+	}
+	frames := CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&b, "\n\t%s:%d", frame.Function, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
+
 func TestTracebackAncestors(t *testing.T) {
 	goroutineRegex := regexp.MustCompile(`goroutine [0-9]+ \[`)
 	for _, tracebackDepth := range []int{0, 1, 5, 50} {
@@ -9,3 +9,6 @@ func stackcheck()
 
 // Called from assembly only; declared for go vet.
 func settls() // argument in DI
+
+// getfp returns the frame pointer register of its caller.
+func getfp() uintptr
@@ -7,3 +7,6 @@ package runtime
 // Called from assembly only; declared for go vet.
 func load_g()
 func save_g()
+
+// getfp returns the frame pointer register of its caller.
+func getfp() uintptr
@@ -0,0 +1,11 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !amd64,!arm64
+
+package runtime
+
+// getfp returns the frame pointer register of its caller. Only amd64
+// and arm64 maintain frame pointers, so it returns 0 elsewhere.
+func getfp() uintptr { return 0 }
@@ -10,3 +10,12 @@ package runtime
 func sbrk0() uintptr {
 	return 0
 }
+
+// cgroupinit finds the cgroup CPU limit of the process, on Linux.
+func cgroupinit() {}
+
+// cgroupCPULimit returns the cgroup CPU limit of the process, on Linux.
+// Elsewhere there is no limit.
+func cgroupCPULimit() int32 {
+	return 0
+}
@@ -13,6 +13,7 @@
 package runtime
 
 import (
+	"runtime/internal/atomic"
 	"runtime/internal/sys"
 	"unsafe"
 )
@@ -74,6 +75,50 @@ const (
 	// That means, the max event type value is 63.
 )
 
+// Classes of events whose stacks can be turned off with trace_setStacks.
+// They are the runtime/trace StackClass constants.
+const (
+	traceStackScheduling = iota
+	traceStackSyscall
+	traceStackGC
+	traceStackUser
+)
+
+// traceEvStackClass is the class of each event type that has a stack.
+var traceEvStackClass = [traceEvCount]uint8{
+	traceEvGomaxprocs:        traceStackScheduling,
+	traceEvGCStart:           traceStackGC,
+	traceEvGCSweepStart:      traceStackGC,
+	traceEvGoCreate:          traceStackScheduling,
+	traceEvGoStop:            traceStackScheduling,
+	traceEvGoSched:           traceStackScheduling,
+	traceEvGoPreempt:         traceStackScheduling,
+	traceEvGoSleep:           traceStackScheduling,
+	traceEvGoBlock:           traceStackScheduling,
+	traceEvGoUnblock:         traceStackScheduling,
+	traceEvGoBlockSend:       traceStackScheduling,
+	traceEvGoBlockRecv:       traceStackScheduling,
+	traceEvGoBlockSelect:     traceStackScheduling,
+	traceEvGoBlockSync:       traceStackScheduling,
+	traceEvGoBlockCond:       traceStackScheduling,
+	traceEvGoBlockNet:        traceStackScheduling,
+	traceEvGoSysCall:         traceStackSyscall,
+	traceEvGoUnblockLocal:    traceStackScheduling,
+	traceEvGoBlockGC:         traceStackGC,
+	traceEvGCMarkAssistStart: traceStackGC,
+	traceEvUserTaskCreate:    traceStackUser,
+	traceEvUserTaskEnd:       traceStackUser,
+	traceEvUserRegion:        traceStackUser,
+	traceEvUserLog:           traceStackUser,
+}
+
+// traceHeader begins each trace generation. Its first byte 'g' would
+// be traceEvGoUnblockLocal with one argument, but that event always
+// carries a stack after the goroutine id, so it is written with two.
+// No event begins with 'g', and readers can tell where a new
+// generation begins.
+const traceHeader = "go 1.11 trace\x00\x00\x00"
+
 const (
 	// Timestamps in trace are cputicks/traceTickDiv.
 	// This makes absolute values of timestamp diffs smaller,
@@ -89,6 +134,13 @@ const (
 	// Since events contain only stack id rather than whole stack trace,
 	// we can allow quite large values here.
 	traceStackSize = 128
+	// First word of a stack in the stack table whose PCs were collected
+	// by gentraceback, and so are already logical PCs. Otherwise the
+	// first word is the number of logical frames to skip once the
+	// physical PCs, collected by frame pointer unwinding, are expanded.
+	traceLogicalStack = ^uintptr(0)
+	// Number of entries in the per-P cache of the stack table.
+	traceStackCacheSize = 64
 	// Identifier of a fake P that is used when we trace without a real P.
 	traceGlobProc = -1
 	// Maximum number of bytes to encode uint64 in base-128.
@@ -125,6 +177,7 @@ var trace struct {
 	fullTail      traceBufPtr
 	reader        guintptr        // goroutine that called ReadTrace, or nil
 	stackTab      traceStackTable // maps stack traces to unique ids
+	stacksOff     uint32          // bit mask of traceStackClasses whose stacks are not recorded; accessed atomically
 
 	// Dictionary for traceEvString.
 	//
@@ -132,9 +185,18 @@ var trace struct {
 	//   option: pre-assign ids to all user annotation region names and tags
 	//   option: per-P cache
 	//   option: sync.Map like data structure
-	stringsLock mutex
-	strings     map[string]uint64
-	stringSeq   uint64
+	strings traceStringTable
+
+	// While traceAdvance dumps the stacks of the generation that
+	// just ended, which it does after starting the world, they are
+	// in prevStackTab and prevStrings, and the full buffers of the
+	// next generation are held back in nextHead/nextTail so that the
+	// reader gets them after the stacks. Protected by trace.lock.
+	advancing    bool
+	nextHead     traceBufPtr
+	nextTail     traceBufPtr
+	prevStackTab traceStackTable
+	prevStrings  traceStringTable
 
 	// markWorkerLabels maps gcMarkWorkerMode to string ID.
 	markWorkerLabels [len(gcMarkWorkerModeStrings)]uint64
@@ -206,6 +268,32 @@ func StartTrace() error {
 	// trace.enabled is set afterwards once we have emitted all preliminary events.
 	_g_ := getg()
 	_g_.m.startingtrace = true
+	traceBeginGeneration()
+	trace.headerWritten = false
+	trace.footerWritten = false
+	_g_.m.startingtrace = false
+	trace.enabled = true
+
+	unlock(&trace.bufLock)
+
+	startTheWorld()
+	return nil
+}
+
+// traceBeginGeneration emits the events that describe the state of all
+// goroutines and the current P at the beginning of a trace generation,
+// and resets the per-generation tracer state. A generation is what
+// would be read between StartTrace and StopTrace, a complete trace.
+// The world must be stopped and trace.bufLock held.
+func traceBeginGeneration() {
+	// The stack table was reset when the previous trace or
+	// generation ended, so the caches refer to freed stacks.
+	// Dead Ps may come back, so clear all allocated Ps.
+	for _, pp := range allp[:cap(allp)] {
+		if pp != nil {
+			pp.tracestackcache = [traceStackCacheSize]traceStackPtr{}
+		}
+	}
 
 	// Obtain current stack ID to use in all traceEvGoCreate events below.
 	mp := acquirem()
@@ -219,7 +307,7 @@ func StartTrace() error {
 			gp.traceseq = 0
 			gp.tracelastp = getg().m.p
 			// +PCQuantum because traceFrameForPC expects return PCs and subtracts PCQuantum.
-			id := trace.stackTab.put([]uintptr{gp.startpc + sys.PCQuantum})
+			id := trace.stackTab.put([]uintptr{traceLogicalStack, gp.startpc + sys.PCQuantum})
 			traceEvent(traceEvGoCreate, -1, uint64(gp.goid), uint64(id), stackID)
 		}
 		if status == _Gwaiting {
@@ -242,18 +330,14 @@ func StartTrace() error {
 	// It will lead to a false conclusion that cputicks is broken.
 	trace.ticksStart = cputicks()
 	trace.timeStart = nanotime()
-	trace.headerWritten = false
-	trace.footerWritten = false
 
 	// string to id mapping
 	//  0 : reserved for an empty string
 	//  remaining: other strings registered by traceString
-	trace.stringSeq = 0
-	trace.strings = make(map[string]uint64)
+	trace.strings.seq = 0
+	trace.strings.m = make(map[string]uint64)
 
 	trace.seqGC = 0
-	_g_.m.startingtrace = false
-	trace.enabled = true
 
 	// Register runtime goroutine labels.
 	_, pid, bufp := traceAcquireBuffer()
@@ -261,31 +345,12 @@ func StartTrace() error {
 		trace.markWorkerLabels[i], bufp = traceString(bufp, pid, label)
 	}
 	traceReleaseBuffer(pid)
-
-	unlock(&trace.bufLock)
-
-	startTheWorld()
-	return nil
 }
 
-// StopTrace stops tracing, if it was previously enabled.
-// StopTrace only returns after all the reads for the trace have completed.
-func StopTrace() {
-	// Stop the world so that we can collect the trace buffers from all p's below,
-	// and also to avoid races with traceEvent.
-	stopTheWorld("stop tracing")
-
-	// See the comment in StartTrace.
-	lock(&trace.bufLock)
-
-	if !trace.enabled {
-		unlock(&trace.bufLock)
-		startTheWorld()
-		return
-	}
-
-	traceGoSched()
-
+// traceEndGeneration queues the trace buffers of all Ps for the reader
+// and records the end time of the current trace generation.
+// The world must be stopped and trace.bufLock held.
+func traceEndGeneration() {
 	// Loop over all allocated Ps because dead Ps may still have
 	// trace buffers.
 	for _, p := range allp[:cap(allp)] {
@@ -312,12 +377,125 @@ func StopTrace() {
 		}
 		osyield()
 	}
+}
+
+// traceFrequency returns the tick frequency of the current trace
+// generation for the traceEvFrequency event.
+func traceFrequency() uint64 {
+	// Use float64 because (trace.ticksEnd - trace.ticksStart) * 1e9 can overflow int64.
+	freq := float64(trace.ticksEnd-trace.ticksStart) * 1e9 / float64(trace.timeEnd-trace.timeStart) / traceTickDiv
+	return uint64(freq)
+}
+
+// traceAdvance ends the current trace generation and begins a new
+// one without a gap, as if by StopTrace immediately followed by
+// StartTrace. The data ReadTrace returns for each generation is
+// a complete trace, starting with its own header. The flight
+// recorder in runtime/trace uses this to discard old trace data.
+//
+//go:linkname traceAdvance runtime/trace.runtime_traceAdvance
+func traceAdvance() {
+	// Wait for an earlier traceAdvance to finish dumping its stacks.
+	semacquire(&traceAdvanceSema)
+	stopTheWorld("trace advance")
+
+	// See the comment in StartTrace.
+	lock(&trace.bufLock)
+
+	if !trace.enabled {
+		unlock(&trace.bufLock)
+		startTheWorld()
+		semrelease(&traceAdvanceSema)
+		return
+	}
+
+	// End the current generation as StopTrace does, so that it is
+	// a complete trace: the goroutines that were running were stopped
+	// by stopTheWorld, except for this one, which is descheduled here
+	// and starts again in the next generation. Then write the footer
+	// that ReadTrace writes when tracing stops.
+	traceGoSched()
+	traceEndGeneration()
+	trace.enabled = false
+	bufp := traceFlush(0, 0)
+	buf := bufp.ptr()
+	buf.byte(traceEvFrequency | 0<<traceArgCountShift)
+	buf.varint(traceFrequency())
+	lock(&trace.lock)
+	traceFullQueue(bufp)
+	// Symbolizing the stacks takes a while, so rather than dump them
+	// now, set the stack table and strings of this generation aside
+	// and dump them after the world is started again. Until then the
+	// buffers of the next generation, starting with its header, are
+	// held back.
+	trace.prevStackTab = trace.stackTab
+	trace.stackTab = traceStackTable{}
+	trace.prevStrings.m, trace.prevStrings.seq = trace.strings.m, trace.strings.seq
+	trace.advancing = true
+	unlock(&trace.lock)
+
+	// Begin the next generation with a header in place of a batch.
+	bufp = traceFlush(0, 0)
+	buf = bufp.ptr()
+	buf.pos = copy(buf.arr[:], traceHeader)
+	lock(&trace.lock)
+	traceFullQueue(bufp)
+	unlock(&trace.lock)
+
+	// See the comment in StartTrace about startingtrace.
+	_g_ := getg()
+	_g_.m.startingtrace = true
+	traceBeginGeneration()
+	_g_.m.startingtrace = false
+	trace.enabled = true
+
+	unlock(&trace.bufLock)
+
+	startTheWorld()
+
+	trace.prevStackTab.dump(&trace.prevStrings)
+	lock(&trace.lock)
+	trace.advancing = false
+	if trace.nextHead != 0 {
+		traceFullQueueChain(trace.nextHead, trace.nextTail)
+		trace.nextHead, trace.nextTail = 0, 0
+	}
+	unlock(&trace.lock)
+	trace.prevStrings.m = nil
+	semrelease(&traceAdvanceSema)
+}
+
+// traceAdvanceSema serializes traceAdvance and StopTrace, so that
+// a generation's stacks are dumped before the next one ends.
+var traceAdvanceSema uint32 = 1
+
+// StopTrace stops tracing, if it was previously enabled.
+// StopTrace only returns after all the reads for the trace have completed.
+func StopTrace() {
+	semacquire(&traceAdvanceSema)
+	// Stop the world so that we can collect the trace buffers from all p's below,
+	// and also to avoid races with traceEvent.
+	stopTheWorld("stop tracing")
+
+	// See the comment in StartTrace.
+	lock(&trace.bufLock)
+
+	if !trace.enabled {
+		unlock(&trace.bufLock)
+		startTheWorld()
+		semrelease(&traceAdvanceSema)
+		return
+	}
+
+	traceGoSched()
+	traceEndGeneration()
 
 	trace.enabled = false
 	trace.shutdown = true
 	unlock(&trace.bufLock)
 
 	startTheWorld()
+	semrelease(&traceAdvanceSema)
 
 	// The world is started but we've set trace.shutdown, so new tracing can't start.
 	// Wait for the trace reader to flush pending buffers and stop.
@@ -347,7 +525,7 @@ func StopTrace() {
 		trace.empty = buf.ptr().link
 		sysFree(unsafe.Pointer(buf), unsafe.Sizeof(*buf.ptr()), &memstats.other_sys)
 	}
-	trace.strings = nil
+	trace.strings.m = nil
 	trace.shutdown = false
 	unlock(&trace.lock)
 }
@@ -387,7 +565,7 @@ func ReadTrace() []byte {
 		trace.headerWritten = true
 		trace.lockOwner = nil
 		unlock(&trace.lock)
-		return []byte("go 1.11 trace\x00\x00\x00")
+		return []byte(traceHeader)
 	}
 	// Wait for new data.
 	if trace.fullHead == 0 && !trace.shutdown {
@@ -406,16 +584,15 @@ func ReadTrace() []byte {
 	// Write footer with timer frequency.
 	if !trace.footerWritten {
 		trace.footerWritten = true
-		// Use float64 because (trace.ticksEnd - trace.ticksStart) * 1e9 can overflow int64.
-		freq := float64(trace.ticksEnd-trace.ticksStart) * 1e9 / float64(trace.timeEnd-trace.timeStart) / traceTickDiv
+		freq := traceFrequency()
 		trace.lockOwner = nil
 		unlock(&trace.lock)
 		var data []byte
 		data = append(data, traceEvFrequency|0<<traceArgCountShift)
-		data = traceAppend(data, uint64(freq))
+		data = traceAppend(data, freq)
 		// This will emit a bunch of full buffers, we will pick them up
 		// on the next iteration.
-		trace.stackTab.dump()
+		trace.stackTab.dump(&trace.strings)
 		return data
 	}
 	// Done.
@@ -467,15 +644,31 @@ func traceProcFree(pp *p) {
 	unlock(&trace.lock)
 }
 
-// traceFullQueue queues buf into queue of full buffers.
+// traceFullQueue queues buf into queue of full buffers,
+// or holds it back while traceAdvance dumps stacks.
 func traceFullQueue(buf traceBufPtr) {
 	buf.ptr().link = 0
+	if trace.advancing {
+		if trace.nextHead == 0 {
+			trace.nextHead = buf
+		} else {
+			trace.nextTail.ptr().link = buf
+		}
+		trace.nextTail = buf
+		return
+	}
+	traceFullQueueChain(buf, buf)
+}
+
+// traceFullQueueChain queues the linked buffers from head to tail
+// into queue of full buffers.
+func traceFullQueueChain(head, tail traceBufPtr) {
 	if trace.fullHead == 0 {
-		trace.fullHead = buf
+		trace.fullHead = head
 	} else {
-		trace.fullTail.ptr().link = buf
+		trace.fullTail.ptr().link = head
 	}
-	trace.fullTail = buf
+	trace.fullTail = tail
 }
 
 // traceFullDequeue dequeues from queue of full buffers.
@@ -557,6 +750,9 @@ func traceEventLocked(extraBytes int, mp *m, pid int32, bufp *traceBufPtr, ev by
 	for _, a := range args {
 		buf.varint(a)
 	}
+	if skip > 0 && atomic.Load(&trace.stacksOff)&(1<<traceEvStackClass[ev]) != 0 {
+		skip = 0
+	}
 	if skip == 0 {
 		buf.varint(0)
 	} else if skip > 0 {
@@ -576,22 +772,200 @@ func traceStackID(mp *m, buf []uintptr, skip int) uint64 {
 	_g_ := getg()
 	gp := mp.curg
 	var nstk int
-	if gp == _g_ {
-		nstk = callers(skip+1, buf)
-	} else if gp != nil {
-		gp = mp.curg
-		nstk = gcallers(gp, skip, buf)
+	if gp == nil {
+		return 0
 	}
-	if nstk > 0 {
-		nstk-- // skip runtime.goexit
+	if tracefpunwind() {
+		// Unwind with frame pointers, which is much cheaper than
+		// gentraceback. The PCs are physical, so record skip with
+		// them. They are expanded and skipped when the stack is dumped.
+		if gp == _g_ {
+			buf[0] = uintptr(skip)
+			nstk = 1 + fpTracebackPCs(gp, getfp(), buf[1:])
+		} else if n := fpTracebackSaved(gp, buf[1:]); n >= 0 {
+			buf[0] = uintptr(skip)
+			nstk = 1 + n
+		}
 	}
-	if nstk > 0 && gp.goid == 1 {
-		nstk-- // skip runtime.main
+	if nstk == 0 {
+		buf[0] = traceLogicalStack
+		if gp == _g_ {
+			nstk = callers(skip+1, buf[1:])
+		} else {
+			nstk = gcallers(gp, skip, buf[1:])
+		}
+		if nstk > 0 {
+			nstk-- // skip runtime.goexit
+		}
+		if nstk > 0 && gp.goid == 1 {
+			nstk-- // skip runtime.main
+		}
+		nstk++
+	}
+	var id uint32
+	if pp := mp.p.ptr(); pp != nil {
+		id = trace.stackTab.putCached(pp, buf[:nstk])
+	} else {
+		id = trace.stackTab.put(buf[:nstk])
 	}
-	id := trace.stackTab.put(buf[:nstk])
 	return uint64(id)
 }
 
+// tracefpunwind reports whether the tracer unwinds stacks
+// with frame pointers.
+func tracefpunwind() bool {
+	return (GOARCH == "amd64" || GOARCH == "arm64") && framepointer_enabled && debug.tracefpunwindoff == 0
+}
+
+// fpTracebackPCs stores in pcBuf the return PCs of the frames of gp's
+// stack, starting with the frame whose frame pointer is fp, and returns
+// the number of PCs stored. It stops at the end of the frame pointer
+// chain, or at a frame pointer outside gp's stack, which happens when
+// the chain passes through code that doesn't maintain frame pointers,
+// in which case the stack is incomplete.
+//
+// gp's stack must not move, so if gp is the current goroutine, the
+// caller must not have grown the stack since it obtained fp.
+//
+//go:nosplit
+func fpTracebackPCs(gp *g, fp uintptr, pcBuf []uintptr) int {
+	n := 0
+	for n < len(pcBuf) && fp != 0 {
+		if fp < gp.stack.lo || fp+2*sys.PtrSize > gp.stack.hi {
+			break
+		}
+		// The return PC is saved just above the frame pointer.
+		pcBuf[n] = *(*uintptr)(unsafe.Pointer(fp + sys.PtrSize))
+		n++
+		next := *(*uintptr)(unsafe.Pointer(fp))
+		if next != 0 && next <= fp {
+			break
+		}
+		fp = next
+	}
+	return n
+}
+
+// fpTracebackSaved is like fpTracebackPCs, but for a goroutine that is
+// not running, starting with the frame in gp.sched. It returns -1 if it
+// cannot tell the layout of that frame, in which case the caller must
+// use gentraceback.
+//
+// gp.sched describes a complete frame when gp switched to the system
+// stack with systemstack or mcall. It doesn't after morestack, which
+// saves the state of a function before its frame is set up.
+func fpTracebackSaved(gp *g, pcBuf []uintptr) int {
+	if gp.syscallsp != 0 || len(pcBuf) < 2 {
+		return -1
+	}
+	pc := gp.sched.pc
+	switch {
+	case GOARCH == "amd64" && pc == funcPC(systemstack_switch):
+		// systemstack has no frame, so the caller's return PC
+		// is at the saved SP, and the saved frame pointer is the
+		// caller's.
+		pcBuf[0] = pc
+		pcBuf[1] = *(*uintptr)(unsafe.Pointer(gp.sched.sp))
+		return 2 + fpTracebackPCs(gp, gp.sched.bp, pcBuf[2:])
+	case GOARCH == "arm64" && pc == funcPC(systemstack_switch)+8:
+		// systemstack has a frame, and the saved frame pointer
+		// is its own.
+		pcBuf[0] = pc
+		return 1 + fpTracebackPCs(gp, gp.sched.bp, pcBuf[1:])
+	case calledMcall(pc):
+		// mcall has no frame, so the saved PC and frame pointer
+		// are those of its caller.
+		pcBuf[0] = pc
+		return 1 + fpTracebackPCs(gp, gp.sched.bp, pcBuf[1:])
+	}
+	return -1
+}
+
+// calledMcall reports whether the instruction before pc is a direct
+// call to mcall, so that pc is the return PC of such a call.
+func calledMcall(pc uintptr) bool {
+	switch GOARCH {
+	case "amd64":
+		// CALL rel32
+		if *(*byte)(unsafe.Pointer(pc - 5)) != 0xe8 {
+			return false
+		}
+		rel := *(*int32)(unsafe.Pointer(pc - 4))
+		return pc+uintptr(rel) == funcPC(mcall)
+	case "arm64":
+		// BL imm26
+		inst := *(*uint32)(unsafe.Pointer(pc - 4))
+		if inst>>26 != 0x25 {
+			return false
+		}
+		off := int32(inst<<6) >> 4 // sign-extended imm26*4
+		return pc-4+uintptr(off) == funcPC(mcall)
+	}
+	return false
+}
+
+// traceExpandPCs returns the logical PCs of a stack collected by frame
+// pointer unwinding, one for each call, including inlined calls, as
+// gentraceback collects them, but dropping the first skip of them.
+func traceExpandPCs(pcs []uintptr, skip int) []uintptr {
+	logical := make([]uintptr, 0, len(pcs))
+	var cache pcvalueCache
+	lastFuncID := funcID_normal
+	for _, pc := range pcs {
+		f := findfunc(pc)
+		if !f.valid() {
+			// Not Go code. The rest of the stack can't be trusted.
+			break
+		}
+		// See the comments in gentraceback.
+		tracepc := pc
+		if pc == f.entry {
+			pc++
+		} else {
+			tracepc--
+		}
+		if inldata := funcdata(f, _FUNCDATA_InlTree); inldata != nil {
+			inltree := (*[1 << 20]inlinedCall)(inldata)
+			for {
+				ix := pcdatavalue(f, _PCDATA_InlTreeIndex, tracepc, &cache)
+				if ix < 0 {
+					break
+				}
+				if inltree[ix].funcID == funcID_wrapper && elideWrapperCalling(lastFuncID) {
+					// ignore wrappers
+				} else if skip > 0 {
+					skip--
+				} else {
+					logical = append(logical, pc)
+				}
+				lastFuncID = inltree[ix].funcID
+				tracepc = f.entry + uintptr(inltree[ix].parentPc)
+				pc = tracepc + 1
+			}
+		}
+		if f.funcID == funcID_wrapper && elideWrapperCalling(lastFuncID) {
+			// ignore wrappers
+		} else if skip > 0 {
+			skip--
+		} else {
+			logical = append(logical, pc)
+		}
+		lastFuncID = f.funcID
+	}
+	// Drop runtime.goexit, and runtime.main for the main goroutine,
+	// as traceStackID does for stacks collected by gentraceback.
+	if n := len(logical); n > 0 && findfunc(logical[n-1]).funcID == funcID_goexit {
+		logical = logical[:n-1]
+	}
+	if n := len(logical); n > 0 && findfunc(logical[n-1]).funcID == funcID_runtime_main {
+		logical = logical[:n-1]
+	}
+	if len(logical) > traceStackSize {
+		logical = logical[:traceStackSize]
+	}
+	return logical
+}
+
 // traceAcquireBuffer returns trace buffer to use and, if necessary, locks it.
 func traceAcquireBuffer() (mp *m, pid int32, bufp *traceBufPtr) {
 	mp = acquirem()
@@ -646,36 +1020,49 @@ func traceFlush(buf traceBufPtr, pid int32) traceBufPtr {
 	return buf
 }
 
+// traceStringTable maps the strings of a trace generation
+// to the ids of their traceEvString events.
+type traceStringTable struct {
+	lock mutex
+	m    map[string]uint64
+	seq  uint64
+}
+
 // traceString adds a string to the trace.strings and returns the id.
 func traceString(bufp *traceBufPtr, pid int32, s string) (uint64, *traceBufPtr) {
+	return trace.strings.put(bufp, pid, s)
+}
+
+// put adds a string to tab and returns the id.
+func (tab *traceStringTable) put(bufp *traceBufPtr, pid int32, s string) (uint64, *traceBufPtr) {
 	if s == "" {
 		return 0, bufp
 	}
 
-	lock(&trace.stringsLock)
+	lock(&tab.lock)
 	if raceenabled {
 		// raceacquire is necessary because the map access
 		// below is race annotated.
-		raceacquire(unsafe.Pointer(&trace.stringsLock))
+		raceacquire(unsafe.Pointer(&tab.lock))
 	}
 
-	if id, ok := trace.strings[s]; ok {
+	if id, ok := tab.m[s]; ok {
 		if raceenabled {
-			racerelease(unsafe.Pointer(&trace.stringsLock))
+			racerelease(unsafe.Pointer(&tab.lock))
 		}
-		unlock(&trace.stringsLock)
+		unlock(&tab.lock)
 
 		return id, bufp
 	}
 
-	trace.stringSeq++
-	id := trace.stringSeq
-	trace.strings[s] = id
+	tab.seq++
+	id := tab.seq
+	tab.m[s] = id
 
 	if raceenabled {
-		racerelease(unsafe.Pointer(&trace.stringsLock))
+		racerelease(unsafe.Pointer(&tab.lock))
 	}
-	unlock(&trace.stringsLock)
+	unlock(&tab.lock)
 
 	// memory allocation in above may trigger tracing and
 	// cause *bufp changes. Following code now works with *bufp,
@@ -759,22 +1146,67 @@ func (ts *traceStack) stack() []uintptr {
 	return (*[traceStackSize]uintptr)(unsafe.Pointer(&ts.stk))[:ts.n]
 }
 
+// equal reports whether ts is the stack trace pcs.
+func (ts *traceStack) equal(pcs []uintptr) bool {
+	if ts.n != len(pcs) {
+		return false
+	}
+	for i, pc := range ts.stack() {
+		if pc != pcs[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// logical returns the logical PCs of ts, expanding physical PCs.
+func (ts *traceStack) logical() []uintptr {
+	pcs := ts.stack()
+	if pcs[0] == traceLogicalStack {
+		return pcs[1:]
+	}
+	return traceExpandPCs(pcs[1:], int(pcs[0]))
+}
+
 // put returns a unique id for the stack trace pcs and caches it in the table,
 // if it sees the trace for the first time.
 func (tab *traceStackTable) put(pcs []uintptr) uint32 {
-	if len(pcs) == 0 {
+	if len(pcs) <= 1 {
+		return 0
+	}
+	hash := memhash(unsafe.Pointer(&pcs[0]), 0, uintptr(len(pcs))*unsafe.Sizeof(pcs[0]))
+	return tab.lookup(pcs, hash).id
+}
+
+// putCached is like put, but first looks for pcs in the stack cache of
+// pp, and adds them to it. Most events come from a few hot stacks, so
+// this mostly avoids the shared table.
+func (tab *traceStackTable) putCached(pp *p, pcs []uintptr) uint32 {
+	if len(pcs) <= 1 {
 		return 0
 	}
 	hash := memhash(unsafe.Pointer(&pcs[0]), 0, uintptr(len(pcs))*unsafe.Sizeof(pcs[0]))
+	c := &pp.tracestackcache[hash%traceStackCacheSize]
+	if stk := c.ptr(); stk != nil && stk.hash == hash && stk.equal(pcs) {
+		return stk.id
+	}
+	stk := tab.lookup(pcs, hash)
+	*c = traceStackPtr(unsafe.Pointer(stk))
+	return stk.id
+}
+
+// lookup returns the stack for pcs, whose hash is hash,
+// adding it to the table if it isn't there.
+func (tab *traceStackTable) lookup(pcs []uintptr, hash uintptr) *traceStack {
 	// First, search the hashtable w/o the mutex.
-	if id := tab.find(pcs, hash); id != 0 {
-		return id
+	if stk := tab.find(pcs, hash); stk != nil {
+		return stk
 	}
 	// Now, double check under the mutex.
 	lock(&tab.lock)
-	if id := tab.find(pcs, hash); id != 0 {
+	if stk := tab.find(pcs, hash); stk != nil {
 		unlock(&tab.lock)
-		return id
+		return stk
 	}
 	// Create new record.
 	tab.seq++
@@ -790,24 +1222,18 @@ func (tab *traceStackTable) put(pcs []uintptr) uint32 {
 	stk.link = tab.tab[part]
 	atomicstorep(unsafe.Pointer(&tab.tab[part]), unsafe.Pointer(stk))
 	unlock(&tab.lock)
-	return stk.id
+	return stk
 }
 
 // find checks if the stack trace pcs is already present in the table.
-func (tab *traceStackTable) find(pcs []uintptr, hash uintptr) uint32 {
+func (tab *traceStackTable) find(pcs []uintptr, hash uintptr) *traceStack {
 	part := int(hash % uintptr(len(tab.tab)))
-Search:
 	for stk := tab.tab[part].ptr(); stk != nil; stk = stk.link.ptr() {
-		if stk.hash == hash && stk.n == len(pcs) {
-			for i, stkpc := range stk.stack() {
-				if stkpc != pcs[i] {
-					continue Search
-				}
-			}
-			return stk.id
+		if stk.hash == hash && stk.equal(pcs) {
+			return stk
 		}
 	}
-	return 0
+	return nil
 }
 
 // newStack allocates a new stack of size n.
@@ -817,6 +1243,9 @@ func (tab *traceStackTable) newStack(n int) *traceStack {
 
 // allFrames returns all of the Frames corresponding to pcs.
 func allFrames(pcs []uintptr) []Frame {
+	if len(pcs) == 0 {
+		return nil
+	}
 	frames := make([]Frame, 0, len(pcs))
 	ci := CallersFrames(pcs)
 	for {
@@ -828,31 +1257,44 @@ func allFrames(pcs []uintptr) []Frame {
 	}
 }
 
-// dump writes all previously cached stacks to trace buffers,
-// releases all memory and resets state.
-func (tab *traceStackTable) dump() {
+// dump writes all previously cached stacks to trace buffers, using the
+// strings in stab, releases all memory and resets state.
+//
+// The buffers are queued together at the end, ahead of any held back
+// by traceAdvance.
+func (tab *traceStackTable) dump(stab *traceStringTable) {
 	var tmp [(2 + 4*traceStackSize) * traceBytesPerNumber]byte
 	bufp := traceFlush(0, 0)
+	head := bufp
+	// reserve makes room for n bytes in the buffer, starting a new
+	// one if needed. The full one stays in the chain from head.
+	reserve := func(n int) {
+		if buf := bufp.ptr(); len(buf.arr)-buf.pos < n {
+			next := traceFlush(0, 0)
+			buf.link = next
+			bufp = next
+		}
+	}
 	for _, stk := range tab.tab {
 		stk := stk.ptr()
 		for ; stk != nil; stk = stk.link.ptr() {
 			tmpbuf := tmp[:0]
 			tmpbuf = traceAppend(tmpbuf, uint64(stk.id))
-			frames := allFrames(stk.stack())
+			frames := allFrames(stk.logical())
 			tmpbuf = traceAppend(tmpbuf, uint64(len(frames)))
 			for _, f := range frames {
+				// Leave room for the function and file name,
+				// so that stab.put never flushes the buffer.
+				reserve(2 * (1 + 2*traceBytesPerNumber + traceMaxFrameString))
 				var frame traceFrame
-				frame, bufp = traceFrameForPC(bufp, 0, f)
+				frame, bufp = traceFrameForPC(bufp, 0, stab, f)
 				tmpbuf = traceAppend(tmpbuf, uint64(f.PC))
 				tmpbuf = traceAppend(tmpbuf, uint64(frame.funcID))
 				tmpbuf = traceAppend(tmpbuf, uint64(frame.fileID))
 				tmpbuf = traceAppend(tmpbuf, uint64(frame.line))
 			}
 			// Now copy to the buffer.
-			size := 1 + traceBytesPerNumber + len(tmpbuf)
-			if buf := bufp.ptr(); len(buf.arr)-buf.pos < size {
-				bufp = traceFlush(bufp, 0)
-			}
+			reserve(1 + traceBytesPerNumber + len(tmpbuf))
 			buf := bufp.ptr()
 			buf.byte(traceEvStack | 3<<traceArgCountShift)
 			buf.varint(uint64(len(tmpbuf)))
@@ -861,7 +1303,7 @@ func (tab *traceStackTable) dump() {
 	}
 
 	lock(&trace.lock)
-	traceFullQueue(bufp)
+	traceFullQueueChain(head, bufp)
 	unlock(&trace.lock)
 
 	tab.mem.drop()
@@ -874,24 +1316,27 @@ type traceFrame struct {
 	line   uint64
 }
 
-// traceFrameForPC records the frame information.
+// traceMaxFrameString is the length to which traceFrameForPC
+// truncates function and file names.
+const traceMaxFrameString = 1 << 10
+
+// traceFrameForPC records the frame information, with its strings in stab.
 // It may allocate memory.
-func traceFrameForPC(buf traceBufPtr, pid int32, f Frame) (traceFrame, traceBufPtr) {
+func traceFrameForPC(buf traceBufPtr, pid int32, stab *traceStringTable, f Frame) (traceFrame, traceBufPtr) {
 	bufp := &buf
 	var frame traceFrame
 
 	fn := f.Function
-	const maxLen = 1 << 10
-	if len(fn) > maxLen {
-		fn = fn[len(fn)-maxLen:]
+	if len(fn) > traceMaxFrameString {
+		fn = fn[len(fn)-traceMaxFrameString:]
 	}
-	frame.funcID, bufp = traceString(bufp, pid, fn)
+	frame.funcID, bufp = stab.put(bufp, pid, fn)
 	frame.line = uint64(f.Line)
 	file := f.File
-	if len(file) > maxLen {
-		file = file[len(file)-maxLen:]
+	if len(file) > traceMaxFrameString {
+		file = file[len(file)-traceMaxFrameString:]
 	}
-	frame.fileID, bufp = traceString(bufp, pid, file)
+	frame.fileID, bufp = stab.put(bufp, pid, file)
 	return frame, (*bufp)
 }
 
@@ -1039,7 +1484,7 @@ func traceGoCreate(newg *g, pc uintptr) {
 	newg.traceseq = 0
 	newg.tracelastp = getg().m.p
 	// +PCQuantum because traceFrameForPC expects return PCs and subtracts PCQuantum.
-	id := trace.stackTab.put([]uintptr{pc + sys.PCQuantum})
+	id := trace.stackTab.put([]uintptr{traceLogicalStack, pc + sys.PCQuantum})
 	traceEvent(traceEvGoCreate, 2, uint64(newg.goid), uint64(id))
 }
 
@@ -1141,6 +1586,23 @@ func traceNextGC() {
 // To access runtime functions from runtime/trace.
 // See runtime/trace/annotation.go
 
+// trace_setStacks turns the recording of stacks for events of the given
+// class on or off, and reports whether it was on.
+//go:linkname trace_setStacks runtime/trace.setStacks
+func trace_setStacks(class int, enabled bool) bool {
+	bit := uint32(1) << uint(class)
+	for {
+		old := atomic.Load(&trace.stacksOff)
+		new := old | bit
+		if enabled {
+			new = old &^ bit
+		}
+		if atomic.Cas(&trace.stacksOff, old, new) {
+			return old&bit == 0
+		}
+	}
+}
+
 //go:linkname trace_userTaskCreate runtime/trace.userTaskCreate
 func trace_userTaskCreate(id, parentID uint64, taskType string) {
 	if !trace.enabled {
@@ -150,3 +150,53 @@ func TestUserTaskRegion(t *testing.T) {
 		t.Errorf("Got user region related events\n%+v\nwant:\n%+v", pretty(got), pretty(want))
 	}
 }
+
+func TestTraceSetStacks(t *testing.T) {
+	if IsEnabled() {
+		t.Skip("skipping because -test.trace is set")
+	}
+	defer SetStacks(StackUser, SetStacks(StackUser, false))
+	if SetStacks(StackUser, false) {
+		t.Errorf("SetStacks reported stacks on after turning them off")
+	}
+
+	buf := new(bytes.Buffer)
+	if err := Start(buf); err != nil {
+		t.Fatalf("failed to start tracing: %v", err)
+	}
+	ctx := context.Background()
+	Log(ctx, "stacks", "off")
+	SetStacks(StackUser, true)
+	Log(ctx, "stacks", "on")
+	Stop()
+
+	events, _ := parseTrace(t, buf)
+	n := 0
+	for _, ev := range events {
+		if ev.Type != trace.EvUserLog || ev.SArgs[0] != "stacks" {
+			continue
+		}
+		n++
+		switch ev.SArgs[1] {
+		case "off":
+			if len(ev.Stk) != 0 {
+				t.Errorf("log with stacks off has stack:\n%s", dumpFrames(framesOf(ev.Stk)))
+			}
+		case "on":
+			if len(ev.Stk) == 0 || ev.Stk[0].Fn != "runtime/trace_test.TestTraceSetStacks" {
+				t.Errorf("log with stacks on has stack:\n%s", dumpFrames(framesOf(ev.Stk)))
+			}
+		}
+	}
+	if n != 2 {
+		t.Errorf("found %d logs, want 2", n)
+	}
+}
+
+func framesOf(stk []*trace.Frame) []frame {
+	var frames []frame
+	for _, f := range stk {
+		frames = append(frames, frame{f.Fn, f.Line})
+	}
+	return frames
+}
@@ -0,0 +1,295 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+import (
+	"errors"
+	"io"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// FlightRecorderConfig is the configuration of a FlightRecorder.
+// The zero value of each field selects a default.
+type FlightRecorderConfig struct {
+	// MinAge is a lower bound on the age of the trace data the
+	// recorder retains. That is, the recorder keeps at least the
+	// last MinAge of execution, unless that exceeds MaxBytes.
+	// The default is 10 seconds.
+	MinAge time.Duration
+
+	// MaxBytes is an upper bound on the size of the trace data the
+	// recorder retains. It takes precedence over MinAge, but the
+	// recorder always retains the most recently completed part of
+	// the trace, however large. The default is 10 MiB.
+	MaxBytes uint64
+}
+
+// A FlightRecorder traces the program continuously, keeping only the
+// most recent trace data in memory, so that it can be written out on
+// demand when something interesting happens, for example when a
+// request is slow.
+//
+// Internally, the trace is divided into generations, each a complete
+// trace that go tool trace can read on its own. The recorder starts
+// a new generation periodically, and as it fills up, and discards
+// the oldest generations that are no longer needed to cover MinAge,
+// or that do not fit in MaxBytes.
+//
+// A FlightRecorder traces the program in place of Start, so only one
+// of them can be active at a time.
+type FlightRecorder struct {
+	minAge   time.Duration
+	maxBytes uint64
+
+	// ctl serializes Start, Stop and WriteTo.
+	ctl     sync.Mutex
+	active  bool
+	stop    chan struct{} // closed to stop the rotate goroutine
+	rotated chan struct{} // closed when the rotate goroutine exits
+	readEnd chan struct{} // closed when the read goroutine exits
+	full    chan struct{} // asks the rotate goroutine to advance early
+
+	// advMu serializes calls to runtime_traceAdvance with
+	// updates of advances.
+	advMu    sync.Mutex
+	advances int // number of generations advanced past since Start
+
+	mu       sync.Mutex
+	cond     sync.Cond     // signaled when seen or readDone change
+	gens     []*generation // completed generations, oldest first
+	nbytes   uint64        // total size of gens
+	cur      *generation   // generation being read
+	seen     int           // number of generations begun since Start
+	readDone bool          // the read goroutine has read all data
+}
+
+// A generation is the data of one trace generation, as read by
+// runtime.ReadTrace.
+type generation struct {
+	chunks [][]byte
+	size   uint64
+	start  time.Time
+}
+
+// NewFlightRecorder returns a new, inactive FlightRecorder
+// with the given configuration.
+func NewFlightRecorder(cfg FlightRecorderConfig) *FlightRecorder {
+	r := &FlightRecorder{
+		minAge:   cfg.MinAge,
+		maxBytes: cfg.MaxBytes,
+	}
+	if r.minAge <= 0 {
+		r.minAge = 10 * time.Second
+	}
+	if r.maxBytes == 0 {
+		r.maxBytes = 10 << 20
+	}
+	r.cond.L = &r.mu
+	return r
+}
+
+// Start starts tracing into the flight recorder, discarding any
+// data it retained from a previous use.
+// Start returns an error if tracing is already enabled.
+func (r *FlightRecorder) Start() error {
+	r.ctl.Lock()
+	defer r.ctl.Unlock()
+	tracing.Lock()
+	defer tracing.Unlock()
+
+	if r.active {
+		return errors.New("trace: flight recorder is already started")
+	}
+	if err := runtime.StartTrace(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.gens = nil
+	r.nbytes = 0
+	r.cur = nil
+	r.seen = 0
+	r.readDone = false
+	r.mu.Unlock()
+	r.advances = 0
+	r.stop = make(chan struct{})
+	r.rotated = make(chan struct{})
+	r.readEnd = make(chan struct{})
+	r.full = make(chan struct{}, 1)
+	r.active = true
+	go r.read()
+	go r.rotate()
+	tracing.recorder = true
+	atomic.StoreInt32(&tracing.enabled, 1)
+	return nil
+}
+
+// Stop stops the flight recorder, if it is active. The recorder
+// retains its trace data, so WriteTo can still write it.
+func (r *FlightRecorder) Stop() {
+	r.ctl.Lock()
+	defer r.ctl.Unlock()
+	tracing.Lock()
+	defer tracing.Unlock()
+
+	if !r.active {
+		return
+	}
+	close(r.stop)
+	<-r.rotated
+	atomic.StoreInt32(&tracing.enabled, 0)
+	tracing.recorder = false
+	runtime.StopTrace()
+	<-r.readEnd
+	r.active = false
+}
+
+// Enabled reports whether the flight recorder is active.
+func (r *FlightRecorder) Enabled() bool {
+	r.ctl.Lock()
+	defer r.ctl.Unlock()
+	return r.active
+}
+
+// WriteTo writes the trace data retained by the flight recorder to w,
+// covering execution up to the time of the call. The data is one or
+// more complete trace generations that go tool trace reads as a
+// single trace.
+// WriteTo returns an error if the recorder has no data, that is,
+// if it was never started.
+func (r *FlightRecorder) WriteTo(w io.Writer) (n int64, err error) {
+	r.ctl.Lock()
+	defer r.ctl.Unlock()
+
+	var gens []*generation
+	r.mu.Lock()
+	if r.active {
+		// End the current generation, so the data written
+		// includes everything up to now.
+		r.mu.Unlock()
+		want := r.advance() + 1
+		r.mu.Lock()
+		for r.seen < want && !r.readDone {
+			r.cond.Wait()
+		}
+	}
+	gens = append(gens, r.gens...)
+	r.mu.Unlock()
+
+	if len(gens) == 0 {
+		return 0, errors.New("trace: flight recorder has no data")
+	}
+	for _, g := range gens {
+		for _, data := range g.chunks {
+			m, err := w.Write(data)
+			n += int64(m)
+			if err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// advance ends the current trace generation and begins a new one.
+// It returns the number of generations advanced past since Start.
+func (r *FlightRecorder) advance() int {
+	r.advMu.Lock()
+	defer r.advMu.Unlock()
+	runtime_traceAdvance()
+	r.advances++
+	return r.advances
+}
+
+// rotate advances the trace generation periodically, and whenever
+// the current generation is full, until the recorder is stopped.
+func (r *FlightRecorder) rotate() {
+	defer close(r.rotated)
+	// Advancing stops the world, so don't do it too often.
+	period := r.minAge / 2
+	if period < 10*time.Millisecond {
+		period = 10 * time.Millisecond
+	}
+	t := time.NewTicker(period)
+	defer t.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-t.C:
+		case <-r.full:
+		}
+		r.advance()
+	}
+}
+
+// read reads trace data from the runtime into the recorder until
+// tracing stops.
+func (r *FlightRecorder) read() {
+	defer close(r.readEnd)
+	for {
+		data := runtime.ReadTrace()
+		if data == nil {
+			break
+		}
+		r.add(data)
+	}
+	r.mu.Lock()
+	r.complete()
+	r.readDone = true
+	r.cond.Broadcast()
+	r.mu.Unlock()
+}
+
+// add adds a chunk of data returned by runtime.ReadTrace.
+func (r *FlightRecorder) add(data []byte) {
+	// Each generation begins with a trace header, returned as a chunk
+	// of its own. No other chunk begins with the byte 'g'.
+	if data[0] == 'g' {
+		r.mu.Lock()
+		r.complete()
+		r.cur = &generation{start: time.Now()}
+		r.seen++
+		r.cond.Broadcast()
+		r.mu.Unlock()
+	}
+	// The runtime reuses the memory of data, so copy it.
+	g := r.cur
+	g.chunks = append(g.chunks, append([]byte(nil), data...))
+	g.size += uint64(len(data))
+	if g.size >= r.maxBytes/2 {
+		select {
+		case r.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// complete retains the current generation, if any, and discards the
+// oldest generations that are no longer needed.
+// r.mu must be held.
+func (r *FlightRecorder) complete() {
+	if r.cur == nil {
+		return
+	}
+	r.gens = append(r.gens, r.cur)
+	r.nbytes += r.cur.size
+	r.cur = nil
+
+	// Keep the newest generations that span at least minAge,
+	// and the newest one, even if it is over maxBytes.
+	oldest := time.Now().Add(-r.minAge)
+	for len(r.gens) > 1 && (r.nbytes > r.maxBytes || !r.gens[1].start.After(oldest)) {
+		r.nbytes -= r.gens[0].size
+		r.gens[0] = nil
+		r.gens = r.gens[1:]
+	}
+}
+
+// runtime_traceAdvance ends the current trace generation and begins
+// a new one. It is implemented in the runtime.
+func runtime_traceAdvance()
@@ -0,0 +1,226 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace_test
+
+import (
+	"bytes"
+	"context"
+	"internal/trace"
+	"os"
+	"runtime"
+	. "runtime/trace"
+	"sync"
+	"testing"
+	"time"
+)
+
+// logged returns the values of the user log events with the given
+// category in events.
+func logged(events []*trace.Event, category string) []string {
+	var values []string
+	for _, ev := range events {
+		if ev.Type == trace.EvUserLog && ev.SArgs[0] == category {
+			values = append(values, ev.SArgs[1])
+		}
+	}
+	return values
+}
+
+func TestFlightRecorder(t *testing.T) {
+	if IsEnabled() {
+		t.Skip("skipping because -test.trace is set")
+	}
+	fr := NewFlightRecorder(FlightRecorderConfig{MinAge: 50 * time.Millisecond})
+	if fr.Enabled() {
+		t.Fatalf("new flight recorder is enabled")
+	}
+	if _, err := fr.WriteTo(new(bytes.Buffer)); err == nil {
+		t.Fatalf("WriteTo succeeded before Start")
+	}
+	if err := fr.Start(); err != nil {
+		t.Fatalf("failed to start flight recorder: %v", err)
+	}
+	defer fr.Stop()
+	if !fr.Enabled() || !IsEnabled() {
+		t.Fatalf("flight recorder is not enabled after Start")
+	}
+	if err := Start(new(bytes.Buffer)); err == nil {
+		t.Fatalf("Start succeeded while flight recorder is active")
+	}
+	// Stop must not stop the flight recorder.
+	Stop()
+	if !IsEnabled() {
+		t.Fatalf("Stop stopped flight recorder")
+	}
+
+	ctx := context.Background()
+	Log(ctx, "phase", "early")
+	// Let the early data age out of the recorder.
+	time.Sleep(500 * time.Millisecond)
+	Log(ctx, "phase", "late")
+
+	buf := new(bytes.Buffer)
+	if _, err := fr.WriteTo(buf); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	saveTrace(t, buf, "TestFlightRecorder")
+	events, _ := parseTrace(t, buf)
+	got := logged(events, "phase")
+	if len(got) != 1 || got[0] != "late" {
+		t.Errorf("flight recorder logged phases %q, want [\"late\"]", got)
+	}
+}
+
+func TestFlightRecorderStop(t *testing.T) {
+	if IsEnabled() {
+		t.Skip("skipping because -test.trace is set")
+	}
+	fr := NewFlightRecorder(FlightRecorderConfig{})
+	if err := fr.Start(); err != nil {
+		t.Fatalf("failed to start flight recorder: %v", err)
+	}
+	Log(context.Background(), "phase", "recorded")
+	fr.Stop()
+	if fr.Enabled() || IsEnabled() {
+		t.Fatalf("flight recorder is enabled after Stop")
+	}
+	fr.Stop()
+
+	// The data recorded before Stop remains available.
+	buf := new(bytes.Buffer)
+	if _, err := fr.WriteTo(buf); err != nil {
+		t.Fatalf("WriteTo failed after Stop: %v", err)
+	}
+	events, _ := parseTrace(t, buf)
+	if got := logged(events, "phase"); len(got) != 1 || got[0] != "recorded" {
+		t.Errorf("flight recorder logged phases %q, want [\"recorded\"]", got)
+	}
+
+	// Tracing can start again once the recorder is stopped.
+	if err := Start(new(bytes.Buffer)); err != nil {
+		t.Fatalf("failed to start tracing after flight recorder: %v", err)
+	}
+	Stop()
+}
+
+func TestFlightRecorderMaxBytes(t *testing.T) {
+	if IsEnabled() {
+		t.Skip("skipping because -test.trace is set")
+	}
+	const maxBytes = 256 << 10
+	fr := NewFlightRecorder(FlightRecorderConfig{MinAge: time.Hour, MaxBytes: maxBytes})
+	if err := fr.Start(); err != nil {
+		t.Fatalf("failed to start flight recorder: %v", err)
+	}
+	defer fr.Stop()
+
+	// Generate several times maxBytes of trace data, pausing
+	// now and then so the recorder can keep up.
+	ctx := context.Background()
+	for i := 0; i < 100; i++ {
+		for j := 0; j < 1000; j++ {
+			Log(ctx, "filler", "0123456789abcdef0123456789abcdef")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	buf := new(bytes.Buffer)
+	if _, err := fr.WriteTo(buf); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	// The most recent generation is always retained, and a generation
+	// only ends once it passes half of maxBytes, by up to a chunk.
+	if buf.Len() > 2*maxBytes+64<<10 {
+		t.Errorf("flight recorder wrote %d bytes, want at most about %d", buf.Len(), maxBytes)
+	}
+	parseTrace(t, buf)
+}
+
+func TestFlightRecorderStress(t *testing.T) {
+	if runtime.GOOS == "js" {
+		t.Skip("no os.Pipe on js")
+	}
+	if IsEnabled() {
+		t.Skip("skipping because -test.trace is set")
+	}
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(8))
+
+	var wg sync.WaitGroup
+	done := make(chan bool)
+
+	// Create goroutines that stay blocked, and in a syscall,
+	// across generations.
+	wg.Add(1)
+	go func() {
+		<-done
+		wg.Done()
+	}()
+	rp, wp, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	defer func() {
+		rp.Close()
+		wp.Close()
+	}()
+	wg.Add(1)
+	go func() {
+		var tmp [1]byte
+		rp.Read(tmp[:])
+		<-done
+		wg.Done()
+	}()
+	time.Sleep(time.Millisecond) // give the goroutine above time to block
+
+	fr := NewFlightRecorder(FlightRecorderConfig{MinAge: 20 * time.Millisecond})
+	if err := fr.Start(); err != nil {
+		t.Fatalf("failed to start flight recorder: %v", err)
+	}
+	defer fr.Stop()
+
+	// Keep goroutines running, blocking and being created while
+	// the recorder advances generations.
+	for p := 0; p < 8; p++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ch := make(chan int)
+			for i := 0; ; i++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				// Creating many goroutines makes the trace slow
+				// to check, so only create one occasionally.
+				if i%50 == 0 {
+					go func() { ch <- 1 }()
+					<-ch
+				}
+				_ = make([]byte, 1<<10)
+				runtime.Gosched()
+				time.Sleep(100 * time.Microsecond)
+			}
+		}()
+	}
+	deadline := time.Now().Add(200 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+
+	buf := new(bytes.Buffer)
+	if _, err := fr.WriteTo(buf); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+
+	var tmp [1]byte
+	wp.Write(tmp[:])
+	close(done)
+	wg.Wait()
+
+	saveTrace(t, buf, "TestFlightRecorderStress")
+	parseTrace(t, buf)
+}
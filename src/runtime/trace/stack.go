@@ -0,0 +1,44 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+// A StackClass is a class of trace events whose stacks can be
+// turned off with SetStacks.
+type StackClass int
+
+const (
+	// StackScheduling is the class of goroutine creation,
+	// blocking, unblocking and rescheduling events.
+	StackScheduling StackClass = iota
+
+	// StackSyscall is the class of system call events.
+	StackSyscall
+
+	// StackGC is the class of garbage collection events,
+	// including sweeping and mark assists.
+	StackGC
+
+	// StackUser is the class of user annotation events:
+	// tasks, regions and log messages.
+	StackUser
+)
+
+// SetStacks sets whether the trace records the stack of each event
+// of the given class, and returns the previous setting. By default,
+// the trace records stacks for all classes.
+//
+// Recording stacks is a significant part of the cost of tracing.
+// Turning it off for a class makes tracing cheaper, but leaves the
+// events of the class without stacks, so tools can't attribute them
+// to code. Goroutines are still named after their start functions.
+//
+// SetStacks may be called at any time, whether tracing is enabled
+// or not, and the setting applies to all later traces.
+func SetStacks(class StackClass, enabled bool) bool {
+	return setStacks(int(class), enabled)
+}
+
+// setStacks is implemented in the runtime.
+func setStacks(class int, enabled bool) bool
@@ -137,11 +137,15 @@ func Start(w io.Writer) error {
 	return nil
 }
 
-// Stop stops the current tracing, if any.
+// Stop stops the current tracing, if any, unless it was started by
+// a FlightRecorder.
 // Stop only returns after all the writes for the trace have completed.
 func Stop() {
 	tracing.Lock()
 	defer tracing.Unlock()
+	if tracing.recorder {
+		return
+	}
 	atomic.StoreInt32(&tracing.enabled, 0)
 
 	runtime.StopTrace()
@@ -150,4 +154,5 @@ func Stop() {
 var tracing struct {
 	sync.Mutex       // gate mutators (Start, Stop)
 	enabled    int32 // accessed via atomic
+	recorder   bool  // tracing was started by a FlightRecorder
 }
@@ -37,7 +37,7 @@ func runExamples(matchString func(pat, str string) (bool, error), examples []Int
 			fmt.Fprintf(os.Stderr, "testing: invalid regexp for -test.run: %s\n", err)
 			os.Exit(1)
 		}
-		if !matched {
+		if !matched || !testShard.contains(eg.Name) {
 			continue
 		}
 		ran = true
@@ -0,0 +1,77 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package golden implements support for tests that compare their
+// output against the contents of golden files.
+//
+// A golden file holds the expected output of a test. Golden files are
+// kept in the testdata directory of the package being tested and carry
+// a ".golden" suffix. A typical use is
+//
+//	func TestRender(t *testing.T) {
+//		var buf bytes.Buffer
+//		render(&buf, input)
+//		golden.Check(t, "render", buf.Bytes()) // compares with testdata/render.golden
+//	}
+//
+// When the output changes intentionally, running
+//
+//	go test -update
+//
+// rewrites the golden files of all tests that run with their current output.
+// The diffs of the rewritten files can then be reviewed with the version
+// control system.
+package golden
+
+import (
+	"bytes"
+	"internal/diff"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Path returns the name of the golden file holding the output called name:
+// testdata/name.golden, relative to the package directory. The name may
+// contain slashes to place the golden file in a subdirectory of testdata.
+func Path(name string) string {
+	return filepath.Join("testdata", filepath.FromSlash(name)+".golden")
+}
+
+// Check compares got with the contents of the golden file for name
+// (see Path). If they differ, Check reports an error that includes a
+// unified diff from the golden file to got. Check reports problems with
+// t.Error, not t.Fatal, so that a test may check several golden files and
+// see all the mismatches at once.
+//
+// If the -test.update flag is set, Check instead writes got to the golden
+// file, creating it and its directory as needed.
+func Check(t testing.TB, name string, got []byte) {
+	t.Helper()
+	file := Path(name)
+	want, err := ioutil.ReadFile(file)
+	if testing.Update() {
+		if err == nil && bytes.Equal(got, want) {
+			return
+		}
+		if err := os.MkdirAll(filepath.Dir(file), 0777); err != nil {
+			t.Error(err)
+			return
+		}
+		if err := ioutil.WriteFile(file, got, 0666); err != nil {
+			t.Error(err)
+			return
+		}
+		t.Logf("updated %s", file)
+		return
+	}
+	if err != nil {
+		t.Errorf("%v (run 'go test -update' to create it)", err)
+		return
+	}
+	if d := diff.Diff(file, want, "got", got); d != nil {
+		t.Errorf("output does not match %s (run 'go test -update' to update it):\n%s", file, d)
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golden_test
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/golden"
+)
+
+func TestPath(t *testing.T) {
+	if got, want := golden.Path("a/b"), filepath.Join("testdata", "a", "b.golden"); got != want {
+		t.Errorf("Path(%q) = %q, want %q", "a/b", got, want)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	golden.Check(t, "hello", []byte("hello, world\n"))
+}
+
+func TestUpdate(t *testing.T) {
+	if testing.Update() {
+		t.Skip("test manipulates the -test.update flag")
+	}
+	dir, err := ioutil.TempDir("", "golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	flag.Set("test.update", "true")
+	golden.Check(t, "sub/out", []byte("new output\n"))
+	flag.Set("test.update", "false")
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "testdata", "sub", "out.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "new output\n" {
+		t.Errorf("golden file contains %q, want %q", data, "new output\n")
+	}
+	golden.Check(t, "sub/out", []byte("new output\n"))
+}
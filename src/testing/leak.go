@@ -0,0 +1,119 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package testing
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// leakTimeout bounds how long a finished test waits for the goroutines
+// it started to exit before reporting them as leaked.
+var leakTimeout = 1 * time.Second
+
+// reportedLeaks records the IDs of goroutines that have already been
+// reported as leaked, so that the enclosing tests do not report them again.
+var reportedLeaks struct {
+	mu  sync.Mutex
+	ids map[uint64]bool
+}
+
+// goroutineStacks returns the stack traces of all user goroutines,
+// keyed by goroutine ID.
+func goroutineStacks() map[uint64]string {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return parseGoroutineStacks(string(buf[:n]))
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// parseGoroutineStacks splits the output of runtime.Stack into the
+// traces of the individual goroutines, keyed by goroutine ID.
+func parseGoroutineStacks(s string) map[uint64]string {
+	stacks := make(map[uint64]string)
+	for _, g := range strings.Split(s, "\n\n") {
+		g = strings.TrimSpace(g)
+		if !strings.HasPrefix(g, "goroutine ") {
+			continue
+		}
+		id := g[len("goroutine "):]
+		i := strings.IndexByte(id, ' ')
+		if i < 0 {
+			continue
+		}
+		n, err := strconv.ParseUint(id[:i], 10, 64)
+		if err != nil {
+			continue
+		}
+		stacks[n] = g
+	}
+	return stacks
+}
+
+// leakedGoroutines returns the stacks of the goroutines that were started
+// since t began running and have neither exited nor been reported before.
+func (t *T) leakedGoroutines() []string {
+	reportedLeaks.mu.Lock()
+	defer reportedLeaks.mu.Unlock()
+
+	var leaked []string
+	for id, stack := range goroutineStacks() {
+		if _, ok := t.leakBase[id]; ok || reportedLeaks.ids[id] {
+			continue
+		}
+		if allow := t.context.leakAllow; allow != "" {
+			if ok, _ := t.context.match.matchFunc(allow, stack); ok {
+				continue
+			}
+		}
+		leaked = append(leaked, stack)
+	}
+	return leaked
+}
+
+// checkLeaks fails t if goroutines started during the test are still
+// running after the test and all of its subtests have completed.
+// Goroutines that are about to exit are given up to leakTimeout to do so.
+func (t *T) checkLeaks() {
+	if t.leakBase == nil || t.isParallel || t.inParallel {
+		// Goroutines started by concurrently running tests
+		// cannot be told apart from those started by t.
+		return
+	}
+	var leaked []string
+	deadline := time.Now().Add(leakTimeout)
+	for delay := time.Millisecond; ; delay *= 2 {
+		leaked = t.leakedGoroutines()
+		if len(leaked) == 0 {
+			return
+		}
+		left := time.Until(deadline)
+		if left <= 0 {
+			break
+		}
+		if delay > left {
+			delay = left
+		}
+		time.Sleep(delay)
+	}
+
+	reportedLeaks.mu.Lock()
+	if reportedLeaks.ids == nil {
+		reportedLeaks.ids = make(map[uint64]bool)
+	}
+	for id := range parseGoroutineStacks(strings.Join(leaked, "\n\n")) {
+		reportedLeaks.ids[id] = true
+	}
+	reportedLeaks.mu.Unlock()
+
+	t.Errorf("found %d leaked goroutine(s):\n\n%s", len(leaked), strings.Join(leaked, "\n\n"))
+}
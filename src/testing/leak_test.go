@@ -0,0 +1,121 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package testing
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"time"
+)
+
+func TestParseGoroutineStacks(t *T) {
+	const trace = `goroutine 1 [running]:
+main.main()
+	/tmp/x.go:5 +0x20
+
+goroutine 17 [chan receive]:
+main.f(0xc000010000)
+	/tmp/x.go:9 +0x30
+created by main.main
+	/tmp/x.go:4 +0x10
+`
+	stacks := parseGoroutineStacks(trace)
+	if len(stacks) != 2 {
+		t.Fatalf("got %d stacks, want 2: %q", len(stacks), stacks)
+	}
+	if !strings.HasPrefix(stacks[1], "goroutine 1 [running]:") {
+		t.Errorf("stack of goroutine 1 = %q", stacks[1])
+	}
+	if !strings.HasSuffix(stacks[17], "/tmp/x.go:4 +0x10") {
+		t.Errorf("stack of goroutine 17 = %q", stacks[17])
+	}
+}
+
+func TestLeakCheck(t *T) {
+	defer func(d time.Duration) { leakTimeout = d }(leakTimeout)
+	leakTimeout = 100 * time.Millisecond
+
+	testCases := []struct {
+		desc  string
+		allow string
+		ok    bool
+		f     func(t *T, stop chan bool)
+	}{{
+		desc: "no goroutines",
+		ok:   true,
+		f:    func(t *T, stop chan bool) {},
+	}, {
+		desc: "exiting goroutine",
+		ok:   true,
+		f: func(t *T, stop chan bool) {
+			go func() {
+				time.Sleep(10 * time.Millisecond)
+			}()
+		},
+	}, {
+		desc: "leaked goroutine",
+		ok:   false,
+		f: func(t *T, stop chan bool) {
+			go leakedGoroutine(stop)
+		},
+	}, {
+		desc:  "allowed goroutine",
+		allow: "testing.leakedGoroutine",
+		ok:    true,
+		f: func(t *T, stop chan bool) {
+			go leakedGoroutine(stop)
+		},
+	}, {
+		desc: "leaked in subtest",
+		ok:   false,
+		f: func(t *T, stop chan bool) {
+			t.Run("sub", func(t *T) {
+				go leakedGoroutine(stop)
+			})
+		},
+	}, {
+		desc: "leaked in parallel subtest",
+		ok:   false,
+		f: func(t *T, stop chan bool) {
+			t.Run("sub", func(t *T) {
+				t.Parallel()
+				go leakedGoroutine(stop)
+			})
+		},
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *T) {
+			stop := make(chan bool)
+			defer close(stop)
+
+			ctx := newTestContext(1, newMatcher(regexp.MatchString, "", ""))
+			ctx.checkLeaks = true
+			ctx.leakAllow = tc.allow
+			buf := &bytes.Buffer{}
+			root := &T{
+				common: common{
+					signal: make(chan bool),
+					name:   "Test",
+					w:      buf,
+				},
+				context: ctx,
+			}
+			ok := root.Run(tc.desc, func(t *T) { tc.f(t, stop) })
+			ctx.release()
+
+			if ok != tc.ok {
+				t.Errorf("ok: got %v; want %v\n%s", ok, tc.ok, buf)
+			}
+			if got := strings.Count(buf.String(), "leaked goroutine(s)"); tc.ok && got != 0 || !tc.ok && got != 1 {
+				t.Errorf("reported leaks %d times:\n%s", got, buf)
+			}
+		})
+	}
+}
+
+func leakedGoroutine(stop chan bool) {
+	<-stop
+}
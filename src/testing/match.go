@@ -16,6 +16,7 @@ import (
 type matcher struct {
 	filter    []string
 	matchFunc func(pat, str string) (bool, error)
+	shard     *shard // if non-nil, the top-level tests to run
 
 	mu       sync.Mutex
 	subNames map[string]int64
@@ -57,6 +58,10 @@ func (m *matcher) fullName(c *common, subname string) (name string, ok, partial
 		name = m.unique(c.name, rewrite(subname))
 	}
 
+	if c != nil && c.level == 0 && !m.shard.contains(name) {
+		return name, false, false
+	}
+
 	matchMutex.Lock()
 	defer matchMutex.Unlock()
 
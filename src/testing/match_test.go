@@ -142,6 +142,24 @@ func TestMatcher(t *T) {
 	}
 }
 
+func TestMatcherShard(t *T) {
+	m := newMatcher(regexp.MatchString, "", "-test.run")
+	m.shard = &shard{index: 0, total: 2, names: map[string]bool{"TestFoo": true}}
+
+	root := &common{}
+	if _, ok, _ := m.fullName(root, "TestFoo"); !ok {
+		t.Errorf("TestFoo in shard 0/2 does not match")
+	}
+	if _, ok, _ := m.fullName(root, "TestBar"); ok {
+		t.Errorf("TestBar outside shard 0/2 matches")
+	}
+	// Subtests of a test in the shard all run.
+	parent := &common{name: "TestFoo", level: 1}
+	if _, ok, _ := m.fullName(parent, "x"); !ok {
+		t.Errorf("subtest TestFoo/x does not match")
+	}
+}
+
 func TestNaming(t *T) {
 	m := newMatcher(regexp.MatchString, "", "")
 
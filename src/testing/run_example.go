@@ -48,6 +48,7 @@ func runExample(eg InternalExample) (ok bool) {
 	// Clean up in a deferred call so we can recover if the example panics.
 	defer func() {
 		timeSpent := time.Since(start)
+		recordTestTime(eg.Name, timeSpent)
 
 		// Close pipe, restore stdout, get output.
 		w.Close()
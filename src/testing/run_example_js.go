@@ -31,6 +31,7 @@ func runExample(eg InternalExample) (ok bool) {
 	// Clean up in a deferred call so we can recover if the example panics.
 	defer func() {
 		timeSpent := time.Since(start)
+		recordTestTime(eg.Name, timeSpent)
 
 		// Restore stdout, get output and remove temporary file.
 		os.Stdout = stdout
@@ -0,0 +1,204 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package testing
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A shard is the set of top-level tests and examples run by one of
+// several test processes that split the tests between them, as selected
+// by -test.shard.
+//
+// By default a test's shard is a hash of its name, so that processes on
+// different machines agree on the assignment without sharing any state.
+// Given running times with -test.shardtimes, which every process must
+// read from the same file, the tests are instead handed out longest
+// first, each to the shard with the least total running time so far.
+// Tests without a listed time then count as taking the average time.
+type shard struct {
+	index, total int
+	names        map[string]bool // names of the tests in this shard
+}
+
+// parseShard parses a -test.shard value of the form "i/n".
+func parseShard(s string) (index, total int, err error) {
+	i := strings.Index(s, "/")
+	if i < 0 {
+		return 0, 0, fmt.Errorf("invalid -test.shard %q: want i/n", s)
+	}
+	index, err1 := strconv.Atoi(s[:i])
+	total, err2 := strconv.Atoi(s[i+1:])
+	if err1 != nil || err2 != nil || total < 1 || index < 0 || index >= total {
+		return 0, 0, fmt.Errorf("invalid -test.shard %q: want i/n with 0 <= i < n", s)
+	}
+	return index, total, nil
+}
+
+func newShard(index, total int, names []string, times map[string]time.Duration) *shard {
+	s := &shard{index: index, total: total, names: map[string]bool{}}
+	if len(times) == 0 {
+		for _, name := range names {
+			if int(hashName(name)%uint32(total)) == index {
+				s.names[name] = true
+			}
+		}
+		return s
+	}
+
+	var sum time.Duration
+	var known int
+	for _, name := range names {
+		if d, ok := times[name]; ok {
+			sum += d
+			known++
+		}
+	}
+	avg := time.Duration(1)
+	if known > 0 && sum > 0 {
+		avg = sum / time.Duration(known)
+	}
+	weight := func(name string) time.Duration {
+		if d, ok := times[name]; ok {
+			return d
+		}
+		return avg
+	}
+
+	sorted := append([]string(nil), names...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		wi, wj := weight(sorted[i]), weight(sorted[j])
+		if wi != wj {
+			return wi > wj
+		}
+		return sorted[i] < sorted[j]
+	})
+
+	load := make([]time.Duration, total)
+	for _, name := range sorted {
+		min := 0
+		for i := range load {
+			if load[i] < load[min] {
+				min = i
+			}
+		}
+		load[min] += weight(name)
+		if min == index {
+			s.names[name] = true
+		}
+	}
+	return s
+}
+
+// hashName returns the 32-bit FNV-1a hash of name.
+func hashName(name string) uint32 {
+	h := uint32(2166136261)
+	for i := 0; i < len(name); i++ {
+		h ^= uint32(name[i])
+		h *= 16777619
+	}
+	return h
+}
+
+// contains reports whether the top-level test or example called name
+// runs in this shard. A nil shard contains every test.
+func (s *shard) contains(name string) bool {
+	return s == nil || s.names[name]
+}
+
+// readTestTimes reads test running times in the format written by
+// writeTestTimes: one test per line, the name followed by a duration.
+func readTestTimes(r io.Reader) (map[string]time.Duration, error) {
+	times := map[string]time.Duration{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		f := strings.Fields(scanner.Text())
+		if len(f) == 0 {
+			continue
+		}
+		if len(f) != 2 {
+			return nil, fmt.Errorf("malformed line %q", scanner.Text())
+		}
+		d, err := time.ParseDuration(f[1])
+		if err != nil {
+			return nil, fmt.Errorf("malformed line %q: %v", scanner.Text(), err)
+		}
+		times[f[0]] = d
+	}
+	return times, scanner.Err()
+}
+
+// testTimes collects the running times of top-level tests and examples
+// for -test.timesfile.
+var testTimes struct {
+	mu    sync.Mutex
+	times map[string]time.Duration
+}
+
+func recordTestTime(name string, d time.Duration) {
+	if *timesFile == "" {
+		return
+	}
+	testTimes.mu.Lock()
+	defer testTimes.mu.Unlock()
+	if testTimes.times == nil {
+		testTimes.times = map[string]time.Duration{}
+	}
+	testTimes.times[name] = d
+}
+
+// writeTestTimes writes times to w, sorted by test name.
+func writeTestTimes(w io.Writer, times map[string]time.Duration) error {
+	names := make([]string, 0, len(times))
+	for name := range times {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	bw := bufio.NewWriter(w)
+	for _, name := range names {
+		fmt.Fprintf(bw, "%s %v\n", name, times[name])
+	}
+	return bw.Flush()
+}
+
+// setupShard computes the shard selected by -test.shard, if any.
+func (m *M) setupShard() error {
+	if *shardFlag == "" {
+		return nil
+	}
+	index, total, err := parseShard(*shardFlag)
+	if err != nil {
+		return err
+	}
+	var times map[string]time.Duration
+	if *shardTimes != "" {
+		f, err := os.Open(*shardTimes)
+		if err != nil {
+			return err
+		}
+		times, err = readTestTimes(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("reading %s: %v", *shardTimes, err)
+		}
+	}
+	var names []string
+	for _, test := range m.tests {
+		names = append(names, test.Name)
+	}
+	for _, eg := range m.examples {
+		names = append(names, eg.Name)
+	}
+	testShard = newShard(index, total, names, times)
+	return nil
+}
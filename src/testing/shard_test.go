@@ -0,0 +1,116 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package testing
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"time"
+)
+
+func TestParseShard(t *T) {
+	for _, tc := range []struct {
+		s            string
+		index, total int
+		ok           bool
+	}{
+		{"0/1", 0, 1, true},
+		{"2/3", 2, 3, true},
+		{"3/3", 0, 0, false},
+		{"-1/3", 0, 0, false},
+		{"0/0", 0, 0, false},
+		{"1", 0, 0, false},
+		{"a/b", 0, 0, false},
+	} {
+		index, total, err := parseShard(tc.s)
+		if (err == nil) != tc.ok || index != tc.index || total != tc.total {
+			t.Errorf("parseShard(%q) = %d, %d, %v; want %d, %d, ok=%v", tc.s, index, total, err, tc.index, tc.total, tc.ok)
+		}
+	}
+}
+
+func TestShardPartition(t *T) {
+	names := []string{"TestA", "TestB", "TestC", "TestD", "TestE", "ExampleF", "ExampleG"}
+	times := map[string]time.Duration{
+		"TestA": 10 * time.Second,
+		"TestB": 1 * time.Second,
+		"TestC": 4 * time.Second,
+		"TestD": 5 * time.Second,
+	}
+	for _, times := range []map[string]time.Duration{nil, times} {
+		seen := map[string]int{}
+		for i := 0; i < 3; i++ {
+			s := newShard(i, 3, names, times)
+			for name := range s.names {
+				seen[name]++
+			}
+		}
+		for _, name := range names {
+			if seen[name] != 1 {
+				t.Errorf("with times %v, %s is in %d shards, want 1", times, name, seen[name])
+			}
+		}
+	}
+}
+
+func TestShardByName(t *T) {
+	// Without times, a test's shard depends only on its name,
+	// not on the other tests a process happens to know about.
+	names := []string{"TestA", "TestB", "TestC", "TestD", "TestE", "ExampleF", "ExampleG"}
+	for i := 0; i < 3; i++ {
+		all := newShard(i, 3, names, nil)
+		for _, name := range names {
+			one := newShard(i, 3, []string{name}, nil)
+			if all.contains(name) != one.contains(name) {
+				t.Errorf("shard %d contains %s: %v with all tests, %v alone", i, name, all.contains(name), one.contains(name))
+			}
+		}
+	}
+}
+
+func TestShardBalance(t *T) {
+	names := []string{"TestA", "TestB", "TestC", "TestD"}
+	times := map[string]time.Duration{
+		"TestA": 10 * time.Second,
+		"TestB": 6 * time.Second,
+		"TestC": 3 * time.Second,
+		"TestD": 1 * time.Second,
+	}
+	var got [][]string
+	for i := 0; i < 2; i++ {
+		var shard []string
+		for name := range newShard(i, 2, names, times).names {
+			shard = append(shard, name)
+		}
+		sort.Strings(shard)
+		got = append(got, shard)
+	}
+	want := [][]string{{"TestA"}, {"TestB", "TestC", "TestD"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("shards = %v, want %v", got, want)
+	}
+}
+
+func TestTestTimesRoundTrip(t *T) {
+	times := map[string]time.Duration{
+		"TestA":    1500 * time.Millisecond,
+		"ExampleB": 20 * time.Microsecond,
+	}
+	var buf bytes.Buffer
+	if err := writeTestTimes(&buf, times); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readTestTimes(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, times) {
+		t.Errorf("read %v, want %v", got, times)
+	}
+	if _, err := readTestTimes(bytes.NewBufferString("TestA\n")); err == nil {
+		t.Errorf("readTestTimes accepted a line without a duration")
+	}
+}
@@ -271,6 +271,11 @@ func Init() {
 	// The failfast flag requests that test execution stop after the first test failure.
 	failFast = flag.Bool("test.failfast", false, "do not start new tests after the first test failure")
 
+	// The update flag requests that tests rewrite the golden files holding
+	// their expected output instead of comparing against them.
+	// See package testing/golden.
+	update = flag.Bool("test.update", false, "update golden files instead of comparing against them")
+
 	// The directory in which to create profile files and the like. When run from
 	// "go test", the binary always runs in the source directory for the package;
 	// this flag lets "go test" tell the binary to write the files in the directory where
@@ -294,6 +299,11 @@ func Init() {
 	cpuListStr = flag.String("test.cpu", "", "comma-separated `list` of cpu counts to run each test with")
 	parallel = flag.Int("test.parallel", runtime.GOMAXPROCS(0), "run at most `n` tests in parallel")
 	testlog = flag.String("test.testlogfile", "", "write test action log to `file` (for use only by cmd/go)")
+	leakCheck = flag.Bool("test.leakcheck", false, "fail tests that leave goroutines running")
+	leakAllow = flag.String("test.leakallow", "", "ignore leaked goroutines whose stack trace matches `regexp`")
+	shardFlag = flag.String("test.shard", "", "run only the tests and examples in shard `i/n` (counting from 0)")
+	shardTimes = flag.String("test.shardtimes", "", "balance -test.shard using the test running times in `file`")
+	timesFile = flag.String("test.timesfile", "", "write the running time of each test and example to `file` (for use only by cmd/go)")
 
 	initBenchmarkFlags()
 }
@@ -302,6 +312,7 @@ var (
 	// Flags, registered during Init.
 	short                *bool
 	failFast             *bool
+	update               *bool
 	outputDir            *string
 	chatty               *bool
 	count                *uint
@@ -320,8 +331,14 @@ var (
 	cpuListStr           *string
 	parallel             *int
 	testlog              *string
+	leakCheck            *bool
+	leakAllow            *string
+	shardFlag            *string
+	shardTimes           *string
+	timesFile            *string
 
-	haveExamples bool // are there examples?
+	haveExamples bool   // are there examples?
+	testShard    *shard // tests and examples selected by -test.shard
 
 	cpuList     []int
 	testlogFile *os.File
@@ -418,6 +435,21 @@ func Short() bool {
 	return *short
 }
 
+// Update reports whether the -test.update flag is set.
+// Tests that compare their output against golden files should write
+// their output to those files, rather than compare it, when Update
+// returns true.
+func Update() bool {
+	// Same as in Short.
+	if update == nil {
+		panic("testing: Update called before Init")
+	}
+	if !flag.Parsed() {
+		panic("testing: Update called before Parse")
+	}
+	return *update
+}
+
 // CoverMode reports what the test coverage mode is set to. The
 // values are "set", "count", or "atomic". The return value will be
 // empty if test coverage is not enabled.
@@ -640,7 +672,9 @@ var _ TB = (*B)(nil)
 type T struct {
 	common
 	isParallel bool
-	context    *testContext // For running tests and subtests.
+	inParallel bool              // Whether an ancestor of the test is a parallel test.
+	context    *testContext      // For running tests and subtests.
+	leakBase   map[uint64]string // Goroutines running when the test started, if checking for leaks.
 }
 
 func (c *common) private() {}
@@ -1029,7 +1063,15 @@ func tRunner(t *T, fn func(t *T)) {
 			// test. See comment in Run method.
 			t.context.release()
 		}
+		failed := t.Failed()
+		t.checkLeaks()
+		if !failed && t.Failed() {
+			atomic.AddUint32(&numFailed, 1)
+		}
 		t.report() // Report after all subtests have finished.
+		if t.level == 1 {
+			recordTestTime(t.name, t.duration)
+		}
 
 		// Do not lock t.done to allow race detector to detect race in case
 		// the user does not appropriately synchronizes a goroutine.
@@ -1045,6 +1087,9 @@ func tRunner(t *T, fn func(t *T)) {
 		}
 	}()
 
+	if t.context.checkLeaks && t.parent != nil {
+		t.leakBase = goroutineStacks()
+	}
 	t.start = time.Now()
 	t.raceErrors = -race.Errors()
 	fn(t)
@@ -1080,7 +1125,8 @@ func (t *T) Run(name string, f func(t *T)) bool {
 			creator: pc[:n],
 			chatty:  t.chatty,
 		},
-		context: t.context,
+		inParallel: t.isParallel || t.inParallel,
+		context:    t.context,
 	}
 	t.w = indenter{&t.common}
 
@@ -1120,6 +1166,10 @@ type testContext struct {
 
 	// maxParallel is a copy of the parallel flag.
 	maxParallel int
+
+	// checkLeaks and leakAllow are copies of the leakcheck and leakallow flags.
+	checkLeaks bool
+	leakAllow  string
 }
 
 func newTestContext(maxParallel int, m *matcher) *testContext {
@@ -1238,6 +1288,20 @@ func (m *M) Run() int {
 		return 2
 	}
 
+	if *leakAllow != "" {
+		if _, err := m.deps.MatchString(*leakAllow, "non-empty"); err != nil {
+			fmt.Fprintf(os.Stderr, "testing: invalid regexp in -test.leakallow (%q): %s\n", *leakAllow, err)
+			flag.Usage()
+			return 2
+		}
+	}
+
+	if err := m.setupShard(); err != nil {
+		fmt.Fprintf(os.Stderr, "testing: %v\n", err)
+		flag.Usage()
+		return 2
+	}
+
 	if len(*matchList) != 0 {
 		listTests(m.deps.MatchString, m.tests, m.benchmarks, m.examples)
 		return 0
@@ -1323,6 +1387,9 @@ func runTests(matchString func(pat, str string) (bool, error), tests []InternalT
 				break
 			}
 			ctx := newTestContext(*parallel, newMatcher(matchString, *match, "-test.run"))
+			ctx.match.shard = testShard
+			ctx.checkLeaks = *leakCheck
+			ctx.leakAllow = *leakAllow
 			t := &T{
 				common: common{
 					signal:  make(chan bool),
@@ -1421,6 +1488,23 @@ func (m *M) after() {
 }
 
 func (m *M) writeProfiles() {
+	if *timesFile != "" {
+		// Note: Not using toOutputDir.
+		// This file is for use by cmd/go, not users.
+		f, err := os.Create(*timesFile)
+		if err == nil {
+			testTimes.mu.Lock()
+			err = writeTestTimes(f, testTimes.times)
+			testTimes.mu.Unlock()
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "testing: can't write %s: %s\n", *timesFile, err)
+			os.Exit(2)
+		}
+	}
 	if *testlog != "" {
 		if err := m.deps.StopTestLog(); err != nil {
 			fmt.Fprintf(os.Stderr, "testing: can't write %s: %s\n", *testlog, err)
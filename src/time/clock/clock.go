@@ -0,0 +1,110 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package clock provides an interface for reading the current time and
+// waiting for time to pass, so that code depending on time can be tested
+// without real sleeps.
+//
+// Code that would call time.Now, time.Sleep, time.NewTimer and the like
+// instead calls the corresponding methods of a Clock. In production the
+// Clock is Real(), which calls package time. In tests it is a *Fake,
+// whose time only moves when the test says so.
+//
+// A Clock can also be attached to a context with context.WithClock;
+// deadlines and timeouts of contexts derived from such a context, and
+// the timeouts of net/http clients using them, are then measured by
+// that clock.
+package clock
+
+import "time"
+
+// A Clock tells the current time and creates timers that fire
+// after durations of that clock's time have passed.
+type Clock interface {
+	// Now returns the current time. See time.Now.
+	Now() time.Time
+
+	// Since returns the time elapsed since t. See time.Since.
+	Since(t time.Time) time.Duration
+
+	// Until returns the duration until t. See time.Until.
+	Until(t time.Time) time.Duration
+
+	// Sleep pauses the current goroutine for at least the duration d.
+	// See time.Sleep.
+	Sleep(d time.Duration)
+
+	// After waits for the duration to elapse and then sends the
+	// current time on the returned channel. See time.After.
+	After(d time.Duration) <-chan time.Time
+
+	// NewTimer creates a new Timer that will send the current time
+	// on its channel after at least duration d. See time.NewTimer.
+	NewTimer(d time.Duration) Timer
+
+	// AfterFunc waits for the duration to elapse and then calls f.
+	// See time.AfterFunc.
+	AfterFunc(d time.Duration, f func()) Timer
+
+	// NewTicker returns a new Ticker that sends the time on its
+	// channel at intervals of duration d. See time.NewTicker.
+	NewTicker(d time.Duration) Ticker
+}
+
+// A Timer represents a single event. It corresponds to time.Timer.
+type Timer interface {
+	// C returns the channel on which the time is delivered when the
+	// timer fires. It returns nil for timers created by AfterFunc.
+	C() <-chan time.Time
+
+	// Stop prevents the Timer from firing. See time.Timer.Stop.
+	Stop() bool
+
+	// Reset changes the timer to expire after duration d.
+	// See time.Timer.Reset.
+	Reset(d time.Duration) bool
+}
+
+// A Ticker delivers ticks of a clock at intervals.
+// It corresponds to time.Ticker.
+type Ticker interface {
+	// C returns the channel on which the ticks are delivered.
+	C() <-chan time.Time
+
+	// Stop turns off the ticker. See time.Ticker.Stop.
+	Stop()
+}
+
+// Real returns the Clock implemented by package time.
+func Real() Clock {
+	return realClock{}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) Since(t time.Time) time.Duration        { return time.Since(t) }
+func (realClock) Until(t time.Time) time.Duration        { return time.Until(t) }
+func (realClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) NewTimer(d time.Duration) Timer         { return realTimer{time.NewTimer(d)} }
+func (realClock) NewTicker(d time.Duration) Ticker       { return realTicker{time.NewTicker(d)} }
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return realTimer{time.AfterFunc(d, f)}
+}
+
+// realTimer and realTicker are single pointers so that
+// converting them to interfaces does not allocate.
+
+type realTimer struct{ t *time.Timer }
+
+func (t realTimer) C() <-chan time.Time        { return t.t.C }
+func (t realTimer) Stop() bool                 { return t.t.Stop() }
+func (t realTimer) Reset(d time.Duration) bool { return t.t.Reset(d) }
+
+type realTicker struct{ t *time.Ticker }
+
+func (t realTicker) C() <-chan time.Time { return t.t.C }
+func (t realTicker) Stop()               { t.t.Stop() }
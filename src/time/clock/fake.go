@@ -0,0 +1,242 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// A Fake is a Clock whose time only changes when Advance or Set is called.
+// Timers, tickers and sleeps fire deterministically, in order of their
+// expiration times, from within those calls.
+//
+// Unlike with the real clock, functions passed to AfterFunc run in the
+// goroutine calling Advance or Set, which waits for them to return before
+// firing the next timer. Timers created or reset with a non-positive
+// duration expire immediately; their functions run in the goroutine calling
+// AfterFunc or Reset, before that call returns. Timer channels are buffered
+// as with the real clock. A ticker ticks at most once per call to Advance or
+// Set: as a real ticker drops the ticks its receiver is too slow for, the
+// ticks that would follow within the same call are dropped.
+//
+// A Fake is safe for concurrent use by multiple goroutines.
+type Fake struct {
+	mu      sync.Mutex
+	changed sync.Cond // signaled when timers are added
+	now     time.Time
+	timers  []*fakeTimer // pending timers, in the order they fire
+}
+
+// NewFake returns a fake clock whose current time is now.
+func NewFake(now time.Time) *Fake {
+	f := &Fake{now: now}
+	f.changed.L = &f.mu
+	return f
+}
+
+// Now returns the fake clock's current time.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Since returns the fake time elapsed since t.
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+// Until returns the fake duration until t.
+func (f *Fake) Until(t time.Time) time.Duration {
+	return t.Sub(f.Now())
+}
+
+// Sleep blocks until the fake clock has been advanced by at least d.
+func (f *Fake) Sleep(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	<-f.NewTimer(d).C()
+}
+
+// After returns a channel on which the fake time is sent once the fake
+// clock has been advanced by at least d.
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	return f.NewTimer(d).C()
+}
+
+// NewTimer returns a Timer that fires once the fake clock has been
+// advanced by at least d.
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	t := &fakeTimer{f: f, c: make(chan time.Time, 1)}
+	t.Reset(d)
+	return t
+}
+
+// AfterFunc returns a Timer that calls fn once the fake clock has been
+// advanced by at least d. The call happens in the goroutine that
+// advances the clock.
+func (f *Fake) AfterFunc(d time.Duration, fn func()) Timer {
+	t := &fakeTimer{f: f, fn: fn}
+	t.Reset(d)
+	return t
+}
+
+// NewTicker returns a Ticker that ticks each time the fake clock passes
+// another multiple of d since the ticker was created.
+// It panics if d <= 0.
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	t := &fakeTimer{f: f, c: make(chan time.Time, 1), period: d}
+	t.Reset(d)
+	return fakeTicker{t}
+}
+
+// Advance moves the fake clock forward by d, firing in order all timers
+// and ticks that expire no later than the new time. While a timer fires,
+// Now reports its expiration time.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	end := f.now.Add(d)
+	f.mu.Unlock()
+	f.Set(end)
+}
+
+// Set sets the fake clock's time to t. If t is later than the current
+// time, Set fires the timers expiring until t, as Advance does. If t is
+// earlier, pending timers are left to fire when the clock reaches them
+// again.
+func (f *Fake) Set(t time.Time) {
+	for {
+		f.mu.Lock()
+		if len(f.timers) == 0 || f.timers[0].when.After(t) {
+			f.now = t
+			f.mu.Unlock()
+			return
+		}
+		timer := f.timers[0]
+		f.remove(timer)
+		if timer.when.After(f.now) {
+			f.now = timer.when
+		}
+		now := f.now
+		if timer.period > 0 {
+			// Skip the ticks expiring no later than t; nobody
+			// could have received them.
+			timer.when = timer.when.Add(timer.period)
+			if !timer.when.After(t) {
+				timer.when = timer.when.Add(timer.period * (t.Sub(timer.when)/timer.period + 1))
+			}
+			f.add(timer)
+		}
+		f.mu.Unlock()
+
+		if timer.fn != nil {
+			timer.fn()
+		} else {
+			select {
+			case timer.c <- now:
+			default:
+			}
+		}
+	}
+}
+
+// BlockUntil blocks until at least n timers, tickers and sleeps are
+// waiting for the fake clock to advance. Tests use it to wait for the
+// code under test to start waiting before they advance the clock.
+func (f *Fake) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.timers) < n {
+		f.changed.Wait()
+	}
+}
+
+// add schedules t after all timers expiring no later than t.
+// f.mu must be held.
+func (f *Fake) add(t *fakeTimer) {
+	i := sort.Search(len(f.timers), func(i int) bool {
+		return f.timers[i].when.After(t.when)
+	})
+	f.timers = append(f.timers, nil)
+	copy(f.timers[i+1:], f.timers[i:])
+	f.timers[i] = t
+	t.pending = true
+	f.changed.Broadcast()
+}
+
+// remove unschedules t. f.mu must be held.
+func (f *Fake) remove(t *fakeTimer) {
+	for i, ft := range f.timers {
+		if ft == t {
+			f.timers = append(f.timers[:i], f.timers[i+1:]...)
+			break
+		}
+	}
+	t.pending = false
+}
+
+// A fakeTimer is a timer or ticker of a Fake clock.
+type fakeTimer struct {
+	f      *Fake
+	c      chan time.Time // nil for AfterFunc timers
+	fn     func()         // nil for channel timers
+	period time.Duration  // non-zero for tickers
+
+	// Under f.mu.
+	when    time.Time
+	pending bool
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	if !t.pending {
+		return false
+	}
+	t.f.remove(t)
+	return true
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.f.mu.Lock()
+	active := t.pending
+	if active {
+		t.f.remove(t)
+	}
+	if d > 0 || t.period != 0 {
+		t.when = t.f.now.Add(d)
+		t.f.add(t)
+		t.f.mu.Unlock()
+		return active
+	}
+
+	// Expire right away, as a real timer would.
+	now := t.f.now
+	t.f.mu.Unlock()
+	if t.fn != nil {
+		t.fn()
+	} else {
+		select {
+		case t.c <- now:
+		default:
+		}
+	}
+	return active
+}
+
+type fakeTicker struct{ t *fakeTimer }
+
+func (t fakeTicker) C() <-chan time.Time { return t.t.c }
+func (t fakeTicker) Stop()               { t.t.Stop() }
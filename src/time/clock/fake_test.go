@@ -0,0 +1,187 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package clock_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+	"time/clock"
+)
+
+var start = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+func TestFakeNow(t *testing.T) {
+	f := clock.NewFake(start)
+	if got := f.Now(); !got.Equal(start) {
+		t.Fatalf("Now() = %v, want %v", got, start)
+	}
+	f.Advance(time.Hour)
+	if got, want := f.Now(), start.Add(time.Hour); !got.Equal(want) {
+		t.Errorf("after Advance, Now() = %v, want %v", got, want)
+	}
+	if got := f.Since(start); got != time.Hour {
+		t.Errorf("Since(start) = %v, want %v", got, time.Hour)
+	}
+	if got := f.Until(start); got != -time.Hour {
+		t.Errorf("Until(start) = %v, want %v", got, -time.Hour)
+	}
+	f.Set(start)
+	if got := f.Now(); !got.Equal(start) {
+		t.Errorf("after Set, Now() = %v, want %v", got, start)
+	}
+}
+
+func TestFakeTimer(t *testing.T) {
+	f := clock.NewFake(start)
+	timer := f.NewTimer(time.Second)
+	f.Advance(999 * time.Millisecond)
+	select {
+	case <-timer.C():
+		t.Fatal("timer fired early")
+	default:
+	}
+	f.Advance(time.Millisecond)
+	select {
+	case now := <-timer.C():
+		if want := start.Add(time.Second); !now.Equal(want) {
+			t.Errorf("timer sent %v, want %v", now, want)
+		}
+	default:
+		t.Fatal("timer did not fire")
+	}
+	if timer.Stop() {
+		t.Error("Stop of expired timer returned true")
+	}
+	if timer.Reset(time.Second) {
+		t.Error("Reset of expired timer returned true")
+	}
+	if !timer.Stop() {
+		t.Error("Stop of pending timer returned false")
+	}
+	f.Advance(time.Hour)
+	select {
+	case <-timer.C():
+		t.Fatal("stopped timer fired")
+	default:
+	}
+}
+
+func TestFakeTimerZero(t *testing.T) {
+	f := clock.NewFake(start)
+	select {
+	case <-f.After(0):
+	default:
+		t.Fatal("After(0) did not fire immediately")
+	}
+}
+
+func TestFakeOrder(t *testing.T) {
+	f := clock.NewFake(start)
+	var got []string
+	record := func(s string) func() {
+		return func() { got = append(got, s+"@"+f.Since(start).String()) }
+	}
+	f.AfterFunc(3*time.Second, record("c"))
+	f.AfterFunc(1*time.Second, record("a"))
+	f.AfterFunc(2*time.Second, record("b1"))
+	f.AfterFunc(2*time.Second, record("b2"))
+	f.AfterFunc(2*time.Second, func() {
+		f.AfterFunc(500*time.Millisecond, record("nested"))
+	})
+	f.Advance(10 * time.Second)
+	want := []string{"a@1s", "b1@2s", "b2@2s", "nested@2.5s", "c@3s"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("timers fired as %v, want %v", got, want)
+	}
+}
+
+func TestFakeTicker(t *testing.T) {
+	f := clock.NewFake(start)
+	ticker := f.NewTicker(time.Second)
+	for i := 1; i <= 3; i++ {
+		f.Advance(time.Second)
+		if now, want := <-ticker.C(), start.Add(time.Duration(i)*time.Second); !now.Equal(want) {
+			t.Errorf("tick %d at %v, want %v", i, now, want)
+		}
+	}
+
+	// Ticks the receiver is not ready for are dropped.
+	f.Advance(5 * time.Second)
+	if now, want := <-ticker.C(), start.Add(4*time.Second); !now.Equal(want) {
+		t.Errorf("tick at %v, want %v", now, want)
+	}
+	select {
+	case <-ticker.C():
+		t.Error("dropped tick was delivered")
+	default:
+	}
+
+	ticker.Stop()
+	f.Advance(5 * time.Second)
+	select {
+	case <-ticker.C():
+		t.Error("stopped ticker ticked")
+	default:
+	}
+}
+
+func TestFakeTickerCoalesce(t *testing.T) {
+	f := clock.NewFake(start)
+	ticker := f.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+	f.Advance(time.Hour) // must not fire 3.6 million ticks
+	if now, want := <-ticker.C(), start.Add(time.Millisecond); !now.Equal(want) {
+		t.Errorf("tick at %v, want %v", now, want)
+	}
+	f.Advance(time.Millisecond)
+	if now, want := <-ticker.C(), start.Add(time.Hour+time.Millisecond); !now.Equal(want) {
+		t.Errorf("tick at %v, want %v", now, want)
+	}
+}
+
+func TestFakeAfterFuncZero(t *testing.T) {
+	f := clock.NewFake(start)
+	calls := 0
+	timer := f.AfterFunc(0, func() { calls++ })
+	if calls != 1 {
+		t.Fatalf("AfterFunc(0) made %d calls before returning, want 1", calls)
+	}
+	timer.Reset(-time.Second)
+	if calls != 2 {
+		t.Fatalf("Reset(-1s) made %d calls before returning, want 1", calls-1)
+	}
+}
+
+func TestFakeSleep(t *testing.T) {
+	f := clock.NewFake(start)
+	done := make(chan time.Time)
+	go func() {
+		f.Sleep(time.Minute)
+		done <- f.Now()
+	}()
+	f.BlockUntil(1)
+	f.Advance(time.Minute)
+	if now, want := <-done, start.Add(time.Minute); !now.Equal(want) {
+		t.Errorf("Sleep returned at %v, want %v", now, want)
+	}
+}
+
+func TestReal(t *testing.T) {
+	c := clock.Real()
+	before := time.Now()
+	now := c.Now()
+	if now.Before(before) {
+		t.Errorf("Real().Now() = %v, before %v", now, before)
+	}
+	timer := c.NewTimer(time.Millisecond)
+	<-timer.C()
+	ch := make(chan bool)
+	c.AfterFunc(time.Millisecond, func() { ch <- true })
+	<-ch
+	ticker := c.NewTicker(time.Millisecond)
+	<-ticker.C()
+	ticker.Stop()
+}